@@ -1,14 +1,18 @@
 package adapter
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"net/netip"
+	"time"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/varbin"
 	"github.com/sagernet/sing/service"
 
 	"github.com/miekg/dns"
@@ -21,7 +25,23 @@ type DNSRouter interface {
 	ClearCache()
 	LookupReverseMapping(ip netip.Addr) (string, bool)
 	ResetNetwork()
-	GetDNSStats() (total, success, cached int64)
+	GetDNSStats() DNSStats
+}
+
+type DNSStats struct {
+	Total     int64
+	Success   int64
+	Cached    int64
+	Upstreams []DNSUpstreamStats
+}
+
+type DNSUpstreamStats struct {
+	Group    string
+	Tag      string
+	Queries  int64
+	Failures int64
+	Latency  time.Duration
+	Healthy  bool
 }
 
 type DNSClient interface {
@@ -31,6 +51,7 @@ type DNSClient interface {
 	LookupCache(domain string, strategy C.DomainStrategy) ([]netip.Addr, bool)
 	ExchangeCache(ctx context.Context, message *dns.Msg) (*dns.Msg, bool)
 	ClearCache()
+	Close() error
 }
 
 type DNSQueryOptions struct {
@@ -40,6 +61,9 @@ type DNSQueryOptions struct {
 	DisableCache   bool
 	RewriteTTL     *uint32
 	ClientSubnet   netip.Prefix
+	ServeStale     *bool
+	Prefetch       *bool
+	DNSSEC         *bool
 }
 
 func DNSQueryOptionsFrom(ctx context.Context, options *option.DomainResolveOptions) (*DNSQueryOptions, error) {
@@ -66,6 +90,185 @@ type RDRCStore interface {
 	SaveRDRCAsync(transportName string, qName string, qType uint16, logger logger.Logger)
 }
 
+type DNSCacheStore interface {
+	LoadDNSCache() []*DNSCacheEntry
+	SaveDNSCache(entries []*DNSCacheEntry) error
+}
+
+// DNSCacheEntry is a cached response saved across restarts, Transport is
+// the tag of the server that answered it and Options the query options
+// without the transport.
+type DNSCacheEntry struct {
+	Transport   string
+	Message     *dns.Msg
+	Expire      time.Time
+	Hits        uint32
+	Refreshable bool
+	Validated   bool
+	Options     DNSQueryOptions
+}
+
+func (e *DNSCacheEntry) MarshalBinary() ([]byte, error) {
+	message, err := e.Message.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = binary.Write(&buffer, binary.BigEndian, uint8(2))
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, e.Transport)
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, message)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, e.Expire.Unix())
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, e.Hits)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, e.Refreshable)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, e.Validated)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, uint8(e.Options.Strategy))
+	if err != nil {
+		return nil, err
+	}
+	var rewriteTTL int64 = -1
+	if e.Options.RewriteTTL != nil {
+		rewriteTTL = int64(*e.Options.RewriteTTL)
+	}
+	err = binary.Write(&buffer, binary.BigEndian, rewriteTTL)
+	if err != nil {
+		return nil, err
+	}
+	var clientSubnet string
+	if e.Options.ClientSubnet.IsValid() {
+		clientSubnet = e.Options.ClientSubnet.String()
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, clientSubnet)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range []*bool{e.Options.ServeStale, e.Options.Prefetch, e.Options.DNSSEC} {
+		err = binary.Write(&buffer, binary.BigEndian, optionalBoolByte(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func (e *DNSCacheEntry) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	err = varbin.Read(reader, binary.BigEndian, &e.Transport)
+	if err != nil {
+		return err
+	}
+	var message []byte
+	err = varbin.Read(reader, binary.BigEndian, &message)
+	if err != nil {
+		return err
+	}
+	e.Message = new(dns.Msg)
+	err = e.Message.Unpack(message)
+	if err != nil {
+		return err
+	}
+	var expire int64
+	err = binary.Read(reader, binary.BigEndian, &expire)
+	if err != nil {
+		return err
+	}
+	e.Expire = time.Unix(expire, 0)
+	err = binary.Read(reader, binary.BigEndian, &e.Hits)
+	if err != nil {
+		return err
+	}
+	if version < 2 {
+		// entries of old versions are not refreshed
+		return nil
+	}
+	err = binary.Read(reader, binary.BigEndian, &e.Refreshable)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(reader, binary.BigEndian, &e.Validated)
+	if err != nil {
+		return err
+	}
+	var strategy uint8
+	err = binary.Read(reader, binary.BigEndian, &strategy)
+	if err != nil {
+		return err
+	}
+	e.Options.Strategy = C.DomainStrategy(strategy)
+	var rewriteTTL int64
+	err = binary.Read(reader, binary.BigEndian, &rewriteTTL)
+	if err != nil {
+		return err
+	}
+	if rewriteTTL >= 0 {
+		timeToLive := uint32(rewriteTTL)
+		e.Options.RewriteTTL = &timeToLive
+	}
+	var clientSubnet string
+	err = varbin.Read(reader, binary.BigEndian, &clientSubnet)
+	if err != nil {
+		return err
+	}
+	if clientSubnet != "" {
+		e.Options.ClientSubnet, err = netip.ParsePrefix(clientSubnet)
+		if err != nil {
+			return err
+		}
+	}
+	for _, value := range []**bool{&e.Options.ServeStale, &e.Options.Prefetch, &e.Options.DNSSEC} {
+		var content uint8
+		err = binary.Read(reader, binary.BigEndian, &content)
+		if err != nil {
+			return err
+		}
+		*value = optionalBoolFromByte(content)
+	}
+	return nil
+}
+
+func optionalBoolByte(value *bool) uint8 {
+	if value == nil {
+		return 0
+	} else if *value {
+		return 2
+	} else {
+		return 1
+	}
+}
+
+func optionalBoolFromByte(content uint8) *bool {
+	if content == 0 {
+		return nil
+	}
+	value := content == 2
+	return &value
+}
+
 type DNSTransport interface {
 	Lifecycle
 	Type() string
@@ -74,6 +277,11 @@ type DNSTransport interface {
 	Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error)
 }
 
+type DNSGroupTransport interface {
+	DNSTransport
+	UpstreamStats() []DNSUpstreamStats
+}
+
 type LegacyDNSTransport interface {
 	LegacyStrategy() C.DomainStrategy
 	LegacyClientSubnet() netip.Prefix
@@ -5,6 +5,7 @@ import (
 
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 type Endpoint interface {
@@ -25,4 +26,6 @@ type EndpointManager interface {
 	Get(tag string) (Endpoint, bool)
 	Remove(tag string) error
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, endpointType string, options any) error
+	RegisterCallback(callback OutboundUpdateCallback) *list.Element[OutboundUpdateCallback]
+	UnregisterCallback(element *list.Element[OutboundUpdateCallback])
 }
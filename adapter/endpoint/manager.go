@@ -11,6 +11,7 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 var _ adapter.EndpointManager = (*Manager)(nil)
@@ -23,6 +24,7 @@ type Manager struct {
 	stage         adapter.StartStage
 	endpoints     []adapter.Endpoint
 	endpointByTag map[string]adapter.Endpoint
+	callbacks     list.List[adapter.OutboundUpdateCallback]
 }
 
 func NewManager(logger log.ContextLogger, registry adapter.EndpointRegistry) *Manager {
@@ -105,6 +107,7 @@ func (m *Manager) Remove(tag string) error {
 	m.endpoints = append(m.endpoints[:index], m.endpoints[index+1:]...)
 	started := m.started
 	m.access.Unlock()
+	defer m.notifyUpdate()
 	if started {
 		return endpoint.Close()
 	}
@@ -116,6 +119,7 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return err
 	}
+	defer m.notifyUpdate()
 	m.access.Lock()
 	defer m.access.Unlock()
 	if m.started {
@@ -145,3 +149,24 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	m.endpointByTag[tag] = endpoint
 	return nil
 }
+
+func (m *Manager) RegisterCallback(callback adapter.OutboundUpdateCallback) *list.Element[adapter.OutboundUpdateCallback] {
+	m.access.Lock()
+	defer m.access.Unlock()
+	return m.callbacks.PushBack(callback)
+}
+
+func (m *Manager) UnregisterCallback(element *list.Element[adapter.OutboundUpdateCallback]) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.callbacks.Remove(element)
+}
+
+func (m *Manager) notifyUpdate() {
+	m.access.Lock()
+	callbacks := m.callbacks.Array()
+	m.access.Unlock()
+	for _, callback := range callbacks {
+		callback()
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"net"
+	"net/netip"
 	"time"
 
 	"github.com/sagernet/sing/common/varbin"
@@ -17,9 +19,19 @@ type ClashServer interface {
 	HistoryStorage() URLTestHistoryStorage
 }
 
+// URLTestHistory is the latest test result of an outbound. A zero delay
+// means the test failed, the UDP fields are only set by groups testing UDP.
+// Download and upload are in bytes per second, set by speed tests.
 type URLTestHistory struct {
-	Time  time.Time `json:"time"`
-	Delay uint16    `json:"delay"`
+	Time      time.Time `json:"time"`
+	Delay     uint16    `json:"delay"`
+	Jitter    uint16    `json:"jitter"`
+	Loss      uint8     `json:"loss"`
+	UDPDelay  uint16    `json:"udpDelay"`
+	UDPJitter uint16    `json:"udpJitter"`
+	UDPLoss   uint8     `json:"udpLoss"`
+	Download  uint64    `json:"download"`
+	Upload    uint64    `json:"upload"`
 }
 
 type URLTestHistoryStorage interface {
@@ -44,6 +56,9 @@ type CacheFile interface {
 	StoreRDRC() bool
 	RDRCStore
 
+	StoreDNSCache() bool
+	DNSCacheStore
+
 	LoadMode() string
 	StoreMode(mode string) error
 	LoadSelected(group string) string
@@ -52,17 +67,27 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedBinary
 	SaveRuleSet(tag string, set *SavedBinary) error
+
+	StoreProcessStatistics() bool
+	LoadProcessStatistics() []*ProcessStatistics
+	// TrackProcessStatistics saves the table returned by loader periodically
+	// and once more when the cache file is closed.
+	TrackProcessStatistics(loader func() []*ProcessStatistics)
+
+	LoadDHCPLeases(tag string) []*DHCPLease
+	SaveDHCPLeases(tag string, leases []*DHCPLease) error
 }
 
 type SavedBinary struct {
 	Content     []byte
 	LastUpdated time.Time
 	LastEtag    string
+	Signature   []byte
 }
 
 func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	var buffer bytes.Buffer
-	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	err := binary.Write(&buffer, binary.BigEndian, uint8(2))
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +103,10 @@ func (s *SavedBinary) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.Signature)
+	if err != nil {
+		return nil, err
+	}
 	return buffer.Bytes(), nil
 }
 
@@ -102,6 +131,128 @@ func (s *SavedBinary) UnmarshalBinary(data []byte) error {
 	if err != nil {
 		return err
 	}
+	if version >= 2 {
+		err = varbin.Read(reader, binary.BigEndian, &s.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessStatistics is the traffic accounted to one application, keyed by
+// package name, process path or user ID.
+type ProcessStatistics struct {
+	Name              string
+	Upload            int64
+	Download          int64
+	Connections       int64
+	ActiveConnections int64
+	Outbounds         []string
+	LastSeen          time.Time
+}
+
+func (s *ProcessStatistics) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, []int64{s.Upload, s.Download, s.Connections, s.LastSeen.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, s.Outbounds)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *ProcessStatistics) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	values := make([]int64, 4)
+	err = binary.Read(reader, binary.BigEndian, values)
+	if err != nil {
+		return err
+	}
+	s.Upload, s.Download, s.Connections = values[0], values[1], values[2]
+	s.LastSeen = time.Unix(values[3], 0)
+	err = varbin.Read(reader, binary.BigEndian, &s.Outbounds)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DHCPLease is an address handed out by the DHCP server service. Static
+// leases have a zero expiry.
+type DHCPLease struct {
+	Address  netip.Addr
+	MAC      net.HardwareAddr
+	Hostname string
+	Expire   time.Time
+}
+
+func (l *DHCPLease) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.BigEndian, uint8(1))
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, l.Address.AsSlice())
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, []byte(l.MAC))
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(&buffer, binary.BigEndian, l.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(&buffer, binary.BigEndian, l.Expire.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (l *DHCPLease) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return err
+	}
+	var address []byte
+	err = varbin.Read(reader, binary.BigEndian, &address)
+	if err != nil {
+		return err
+	}
+	l.Address, _ = netip.AddrFromSlice(address)
+	var mac []byte
+	err = varbin.Read(reader, binary.BigEndian, &mac)
+	if err != nil {
+		return err
+	}
+	l.MAC = mac
+	err = varbin.Read(reader, binary.BigEndian, &l.Hostname)
+	if err != nil {
+		return err
+	}
+	var expire int64
+	err = binary.Read(reader, binary.BigEndian, &expire)
+	if err != nil {
+		return err
+	}
+	l.Expire = time.Unix(expire, 0)
 	return nil
 }
 
@@ -2,6 +2,7 @@ package adapter
 
 import (
 	"context"
+	"net"
 	"net/netip"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	M "github.com/sagernet/sing/common/metadata"
 )
 
@@ -50,6 +52,12 @@ type InboundContext struct {
 	Destination M.Socksaddr
 	User        string
 	Outbound    string
+	// RouteOutbound is set by inbounds that choose the outbound themselves,
+	// it replaces the outbound of the final route rule.
+	RouteOutbound string
+	// InboundMapping is the name of the forward inbound mapping accepting
+	// the connection.
+	InboundMapping string
 
 	// sniffer
 
@@ -86,9 +94,15 @@ type InboundContext struct {
 	SourceGeoIPCode      string
 	GeoIPCode            string
 	ProcessInfo          *process.Info
+	SourceMACAddress     net.HardwareAddr
+	SourceHostname       string
 	QueryType            uint16
 	FakeIP               bool
 
+	// RemoteDestination is shared by all copies of the metadata and records
+	// the server address chosen by outbounds with several server addresses.
+	RemoteDestination *common.TypedValue[M.Socksaddr]
+
 	// rule cache
 
 	IPCIDRMatchSource bool
@@ -6,6 +6,7 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 // Note: for proxy protocols, outbound creates early connections by default.
@@ -30,4 +31,10 @@ type OutboundManager interface {
 	Default() Outbound
 	Remove(tag string) error
 	Create(ctx context.Context, router Router, logger log.ContextLogger, tag string, outboundType string, options any) error
+	RegisterCallback(callback OutboundUpdateCallback) *list.Element[OutboundUpdateCallback]
+	UnregisterCallback(element *list.Element[OutboundUpdateCallback])
 }
+
+// OutboundUpdateCallback is called after an outbound or endpoint is created
+// or removed.
+type OutboundUpdateCallback func()
@@ -14,6 +14,7 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 var _ adapter.OutboundManager = (*Manager)(nil)
@@ -31,6 +32,7 @@ type Manager struct {
 	dependByTag             map[string][]string
 	defaultOutbound         adapter.Outbound
 	defaultOutboundFallback func() (adapter.Outbound, error)
+	callbacks               list.List[adapter.OutboundUpdateCallback]
 }
 
 func NewManager(logger logger.ContextLogger, registry adapter.OutboundRegistry, endpoint adapter.EndpointManager, defaultTag string) *Manager {
@@ -204,12 +206,19 @@ func (m *Manager) Default() adapter.Outbound {
 }
 
 func (m *Manager) Remove(tag string) error {
+	var updated bool
+	defer func() {
+		if updated {
+			m.notifyUpdate()
+		}
+	}()
 	m.access.Lock()
 	defer m.access.Unlock()
 	outbound, found := m.outboundByTag[tag]
 	if !found {
 		return os.ErrInvalid
 	}
+	updated = true
 	delete(m.outboundByTag, tag)
 	index := common.Index(m.outbounds, func(it adapter.Outbound) bool {
 		return it == outbound
@@ -263,6 +272,7 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 			}
 		}
 	}
+	defer m.notifyUpdate()
 	m.access.Lock()
 	defer m.access.Unlock()
 	if existsOutbound, loaded := m.outboundByTag[tag]; loaded {
@@ -294,3 +304,24 @@ func (m *Manager) Create(ctx context.Context, router adapter.Router, logger log.
 	}
 	return nil
 }
+
+func (m *Manager) RegisterCallback(callback adapter.OutboundUpdateCallback) *list.Element[adapter.OutboundUpdateCallback] {
+	m.access.Lock()
+	defer m.access.Unlock()
+	return m.callbacks.PushBack(callback)
+}
+
+func (m *Manager) UnregisterCallback(element *list.Element[adapter.OutboundUpdateCallback]) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.callbacks.Remove(element)
+}
+
+func (m *Manager) notifyUpdate() {
+	m.access.RLock()
+	callbacks := m.callbacks.Array()
+	m.access.RUnlock()
+	for _, callback := range callbacks {
+		callback()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"net/netip"
 	"sync"
 
 	C "github.com/sagernet/sing-box/constant"
@@ -23,6 +24,7 @@ type Router interface {
 	ConnectionRouterEx
 	RuleSet(tag string) (RuleSet, bool)
 	NeedWIFIState() bool
+	LookupASN(addr netip.Addr) (uint32, bool)
 	Rules() []Rule
 	AppendTracker(tracker ConnectionTracker)
 	ResetNetwork()
@@ -63,9 +65,11 @@ type RuleSet interface {
 type RuleSetUpdateCallback func(it RuleSet)
 
 type RuleSetMetadata struct {
-	ContainsProcessRule bool
-	ContainsWIFIRule    bool
-	ContainsIPCIDRRule  bool
+	ContainsProcessRule  bool
+	ContainsWIFIRule     bool
+	ContainsNeighborRule bool
+	ContainsASNRule      bool
+	ContainsIPCIDRRule   bool
 }
 type HTTPStartContext struct {
 	ctx             context.Context
@@ -13,6 +13,13 @@ type Service interface {
 	Tag() string
 }
 
+// DHCPLeaseProvider is implemented by services handing out addresses, their
+// leases resolve the MAC address and hostname of LAN clients.
+type DHCPLeaseProvider interface {
+	Service
+	Leases() []DHCPLease
+}
+
 type ServiceRegistry interface {
 	option.ServiceOptionsRegistry
 	Create(ctx context.Context, logger log.ContextLogger, tag string, serviceType string, options any) (Service, error)
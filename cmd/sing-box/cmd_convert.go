@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/subscription"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+
+	"github.com/spf13/cobra"
+)
+
+var commandConvertFlagType string
+
+var commandConvert = &cobra.Command{
+	Use:   "convert [path | url | -]",
+	Short: "Convert share links or Clash proxies to outbounds, or outbounds to share links",
+	Run: func(cmd *cobra.Command, args []string) {
+		source := "-"
+		if len(args) > 0 {
+			source = args[0]
+		}
+		err := convert(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	commandConvert.Flags().StringVarP(&commandConvertFlagType, "type", "t", "outbound", "output type: outbound, link")
+	mainCommand.AddCommand(commandConvert)
+}
+
+func convert(source string) error {
+	content, err := readConvertSource(source)
+	if err != nil {
+		return err
+	}
+	switch commandConvertFlagType {
+	case "outbound":
+		return convertToOutbounds(content)
+	case "link":
+		return convertToLinks(content)
+	default:
+		return E.New("unknown output type: ", commandConvertFlagType)
+	}
+}
+
+func readConvertSource(source string) ([]byte, error) {
+	if source == "-" || source == "stdin" {
+		return io.ReadAll(os.Stdin)
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	client := &http.Client{Timeout: time.Minute}
+	response, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func convertToOutbounds(content []byte) error {
+	result, err := subscription.Parse(content)
+	if err != nil {
+		return err
+	}
+	for _, err = range result.Errors {
+		log.Warn(err)
+	}
+	for _, loss := range result.Losses {
+		log.Warn("dropped ", loss)
+	}
+	options, err := badjson.Omitempty(globalCtx, option.Options{
+		Outbounds: result.Outbounds,
+	})
+	if err != nil {
+		return err
+	}
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoderContext(globalCtx, buffer)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(options)
+	if err != nil {
+		return E.Cause(err, "encode outbounds")
+	}
+	os.Stdout.Write(buffer.Bytes())
+	return nil
+}
+
+func convertToLinks(content []byte) error {
+	options, err := json.UnmarshalExtendedContext[option.Options](globalCtx, content)
+	if err != nil {
+		return E.Cause(err, "decode config")
+	}
+	for _, outbound := range options.Outbounds {
+		link, losses, err := subscription.ExportLink(outbound)
+		if err != nil {
+			log.Debug("skip ", outbound.Tag, ": ", err)
+			continue
+		}
+		for _, field := range losses {
+			log.Warn("dropped ", outbound.Tag, ": ", field)
+		}
+		os.Stdout.WriteString(link + "\n")
+	}
+	return nil
+}
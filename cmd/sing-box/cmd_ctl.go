@@ -0,0 +1,144 @@
+package main
+
+import (
+	"time"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+const commandCtlStatusInterval = time.Second
+
+var commandCtl = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running sing-box daemon",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		err := libbox.Setup(&libbox.SetupOptions{
+			BasePath: commandDaemonFlagSocketDirectory,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.PersistentFlags().StringVarP(&commandDaemonFlagSocketDirectory, "socket-directory", "s", commandDaemonDefaultSocketDirectory, "set command server socket directory")
+	mainCommand.AddCommand(commandCtl)
+}
+
+var _ libbox.CommandClientHandler = (*ctlHandler)(nil)
+
+// ctlHandler adapts the streaming command client callbacks. Unset callbacks
+// are ignored; the first disconnect is reported on done.
+type ctlHandler struct {
+	done        chan error
+	logs        func(lines []string)
+	clearLogs   func()
+	status      func(message *libbox.StatusMessage)
+	groups      func(groups []*libbox.OutboundGroup)
+	clashMode   func(modeList []string, currentMode string)
+	connections func(connections *libbox.Connections)
+}
+
+func newCtlClient(command int32, handler *ctlHandler) (*libbox.CommandClient, error) {
+	handler.done = make(chan error, 1)
+	client := libbox.NewCommandClient(handler, &libbox.CommandClientOptions{
+		Command:        command,
+		StatusInterval: int64(commandCtlStatusInterval),
+	})
+	err := client.Connect()
+	if err != nil {
+		return nil, E.Cause(err, "connect to daemon")
+	}
+	return client, nil
+}
+
+// ctlReceive connects with a streaming command and returns the first message.
+func ctlReceive[T any](command int32, setup func(handler *ctlHandler, emit func(T))) (T, error) {
+	messageChan := make(chan T, 1)
+	handler := &ctlHandler{}
+	setup(handler, func(message T) {
+		select {
+		case messageChan <- message:
+		default:
+		}
+	})
+	client, err := newCtlClient(command, handler)
+	if err != nil {
+		var defaultValue T
+		return defaultValue, err
+	}
+	defer client.Disconnect()
+	select {
+	case message := <-messageChan:
+		return message, nil
+	case err = <-handler.done:
+		var defaultValue T
+		return defaultValue, err
+	}
+}
+
+func (h *ctlHandler) Connected() {
+}
+
+func (h *ctlHandler) Disconnected(message string) {
+	select {
+	case h.done <- E.New(message):
+	default:
+	}
+}
+
+func (h *ctlHandler) ClearLogs() {
+	if h.clearLogs != nil {
+		h.clearLogs()
+	}
+}
+
+func (h *ctlHandler) WriteLogs(messageList libbox.StringIterator) {
+	if h.logs != nil {
+		h.logs(iteratorToSlice[string](messageList))
+	}
+}
+
+func (h *ctlHandler) WriteStatus(message *libbox.StatusMessage) {
+	if h.status != nil {
+		h.status(message)
+	}
+}
+
+func (h *ctlHandler) WriteGroups(message libbox.OutboundGroupIterator) {
+	if h.groups != nil {
+		h.groups(iteratorToSlice[*libbox.OutboundGroup](message))
+	}
+}
+
+func (h *ctlHandler) InitializeClashMode(modeList libbox.StringIterator, currentMode string) {
+	if h.clashMode != nil {
+		h.clashMode(iteratorToSlice[string](modeList), currentMode)
+	}
+}
+
+func (h *ctlHandler) UpdateClashMode(newMode string) {
+}
+
+func (h *ctlHandler) WriteConnections(message *libbox.Connections) {
+	if h.connections != nil {
+		h.connections(message)
+	}
+}
+
+func iteratorToSlice[T any](iterator interface {
+	HasNext() bool
+	Next() T
+},
+) []T {
+	var values []T
+	for iterator.HasNext() {
+		values = append(values, iterator.Next())
+	}
+	return values
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlConnectionsFlagAll bool
+
+var commandCtlConnections = &cobra.Command{
+	Use:   "connections",
+	Short: "List connections",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlConnections()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandCtlCloseFlagAll bool
+
+var commandCtlClose = &cobra.Command{
+	Use:   "close [connection-id]",
+	Short: "Close connection",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlClose(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtlConnections.Flags().BoolVarP(&commandCtlConnectionsFlagAll, "all", "a", false, "include closed connections")
+	commandCtl.AddCommand(commandCtlConnections)
+	commandCtlClose.Flags().BoolVarP(&commandCtlCloseFlagAll, "all", "a", false, "close all connections")
+	commandCtl.AddCommand(commandCtlClose)
+}
+
+func ctlConnections() error {
+	connections, err := ctlReceive(libbox.CommandConnections, func(handler *ctlHandler, emit func(*libbox.Connections)) {
+		handler.connections = emit
+	})
+	if err != nil {
+		return err
+	}
+	if commandCtlConnectionsFlagAll {
+		connections.FilterState(libbox.ConnectionStateAll)
+	} else {
+		connections.FilterState(libbox.ConnectionStateActive)
+	}
+	connections.SortByDate()
+	iterator := connections.Iterator()
+	for iterator.HasNext() {
+		connection := iterator.Next()
+		fmt.Fprintln(os.Stdout,
+			connection.ID,
+			connection.Network,
+			connection.Source, "->", connection.DisplayDestination(),
+			"via", connection.Outbound,
+			"up", libbox.FormatBytes(connection.UplinkTotal),
+			"down", libbox.FormatBytes(connection.DownlinkTotal),
+		)
+	}
+	return nil
+}
+
+func ctlClose(args []string) error {
+	client := libbox.NewStandaloneCommandClient()
+	if commandCtlCloseFlagAll {
+		return client.CloseConnections()
+	}
+	if len(args) == 0 {
+		return os.ErrInvalid
+	}
+	return client.CloseConnection(args[0])
+}
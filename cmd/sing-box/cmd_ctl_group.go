@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlGroups = &cobra.Command{
+	Use:   "groups",
+	Short: "List outbound groups",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlGroups()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandCtlSelect = &cobra.Command{
+	Use:   "select <group> <outbound>",
+	Short: "Select outbound in selector group",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := libbox.NewStandaloneCommandClient().SelectOutbound(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandCtlURLTest = &cobra.Command{
+	Use:   "urltest <group>",
+	Short: "Test outbounds in group",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := libbox.NewStandaloneCommandClient().URLTest(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.AddCommand(commandCtlGroups)
+	commandCtl.AddCommand(commandCtlSelect)
+	commandCtl.AddCommand(commandCtlURLTest)
+}
+
+func ctlGroups() error {
+	groups, err := ctlReceive(libbox.CommandGroup, func(handler *ctlHandler, emit func([]*libbox.OutboundGroup)) {
+		handler.groups = emit
+	})
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		fmt.Fprintln(os.Stdout, group.Tag, "["+group.Type+"]")
+		for _, item := range group.ItemList {
+			prefix := "   "
+			if item.Tag == group.Selected {
+				prefix = " * "
+			}
+			line := prefix + item.Tag + " [" + item.Type + "]"
+			if item.URLTestDelay > 0 {
+				line += fmt.Sprint(" ", item.URLTestDelay, "ms")
+			}
+			fmt.Fprintln(os.Stdout, line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlLog = &cobra.Command{
+	Use:   "log",
+	Short: "Follow service log",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlLog()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.AddCommand(commandCtlLog)
+}
+
+func ctlLog() error {
+	handler := &ctlHandler{
+		logs: func(lines []string) {
+			for _, line := range lines {
+				os.Stdout.WriteString(line + "\n")
+			}
+		},
+	}
+	client, err := newCtlClient(libbox.CommandLog, handler)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(osSignals)
+	select {
+	case <-osSignals:
+		return nil
+	case err = <-handler.done:
+		return err
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlProcesses = &cobra.Command{
+	Use:   "processes",
+	Short: "List traffic statistics per application",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlProcesses()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.AddCommand(commandCtlProcesses)
+}
+
+func ctlProcesses() error {
+	statisticsList, err := libbox.NewStandaloneCommandClient().GetProcessStatistics()
+	if err != nil {
+		return err
+	}
+	for statisticsList.HasNext() {
+		statistics := statisticsList.Next()
+		fmt.Fprintln(os.Stdout,
+			statistics.Name,
+			"up", libbox.FormatBytes(statistics.Upload),
+			"down", libbox.FormatBytes(statistics.Download),
+			"connections", statistics.ActiveConnections, "/", statistics.Connections,
+			"via", strings.Join(statistics.OutboundList, ","),
+		)
+	}
+	return nil
+}
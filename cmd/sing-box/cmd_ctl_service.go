@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlReload = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload service configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := libbox.NewStandaloneCommandClient().ServiceReload()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandCtlMode = &cobra.Command{
+	Use:   "mode [mode]",
+	Short: "Show or set clash mode",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlMode(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.AddCommand(commandCtlReload)
+	commandCtl.AddCommand(commandCtlMode)
+}
+
+type ctlClashMode struct {
+	modeList    []string
+	currentMode string
+}
+
+func ctlMode(args []string) error {
+	if len(args) == 1 {
+		return libbox.NewStandaloneCommandClient().SetClashMode(args[0])
+	}
+	mode, err := ctlReceive(libbox.CommandClashMode, func(handler *ctlHandler, emit func(ctlClashMode)) {
+		handler.clashMode = func(modeList []string, currentMode string) {
+			emit(ctlClashMode{modeList, currentMode})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(mode.modeList) == 0 {
+		return E.New("clash mode is not available")
+	}
+	for _, modeName := range mode.modeList {
+		if modeName == mode.currentMode {
+			fmt.Fprintln(os.Stdout, "*", modeName)
+		} else {
+			fmt.Fprintln(os.Stdout, " ", modeName)
+		}
+	}
+	return nil
+}
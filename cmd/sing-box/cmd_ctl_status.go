@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandCtlStatus = &cobra.Command{
+	Use:   "status",
+	Short: "Show service status",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := ctlStatus()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandCtl.AddCommand(commandCtlStatus)
+}
+
+func ctlStatus() error {
+	status, err := ctlReceive(libbox.CommandStatus, func(handler *ctlHandler, emit func(*libbox.StatusMessage)) {
+		handler.status = emit
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, "memory:", libbox.FormatMemoryBytes(status.Memory))
+	fmt.Fprintln(os.Stdout, "goroutines:", status.Goroutines)
+	fmt.Fprintln(os.Stdout, "connections:", status.ConnectionsIn, "in,", status.ConnectionsOut, "out")
+	if status.TrafficAvailable {
+		fmt.Fprintln(os.Stdout, "uplink total:", libbox.FormatBytes(status.UplinkTotal))
+		fmt.Fprintln(os.Stdout, "downlink total:", libbox.FormatBytes(status.DownlinkTotal))
+	} else {
+		fmt.Fprintln(os.Stdout, "service: not running")
+	}
+	return nil
+}
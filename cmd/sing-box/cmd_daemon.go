@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var commandDaemonFlagSocketDirectory string
+
+const commandDaemonDefaultSocketDirectory = "/run/sing-box"
+
+var commandDaemon = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run service with the command server",
+	Long:  "Run service and serve the command protocol used by the graphical clients on <socket-directory>/command.sock, see sing-box ctl.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runDaemon()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandDaemon.Flags().StringVarP(&commandDaemonFlagSocketDirectory, "socket-directory", "s", commandDaemonDefaultSocketDirectory, "set command server socket directory")
+	mainCommand.AddCommand(commandDaemon)
+}
+
+type daemon struct {
+	access        sync.Mutex
+	commandServer *libbox.CommandServer
+	service       *libbox.BoxService
+	closed        chan struct{}
+}
+
+func runDaemon() error {
+	err := setupCommandDirectory()
+	if err != nil {
+		return err
+	}
+	return serveDaemon()
+}
+
+func serveDaemon() error {
+	instance := &daemon{
+		closed: make(chan struct{}),
+	}
+	instance.commandServer = libbox.NewCommandServer(instance, 300)
+	err := instance.commandServer.Start()
+	if err != nil {
+		return E.Cause(err, "start command server")
+	}
+	defer instance.commandServer.Close()
+	err = instance.startService()
+	if err != nil {
+		return err
+	}
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(osSignals)
+	for {
+		select {
+		case osSignal := <-osSignals:
+			if osSignal == syscall.SIGHUP {
+				err = instance.ServiceReload()
+				if err != nil {
+					log.Error(E.Cause(err, "reload service"))
+				}
+				continue
+			}
+			instance.access.Lock()
+			err = instance.closeService()
+			instance.access.Unlock()
+			return err
+		case <-instance.closed:
+			// closed by the command server
+			return nil
+		}
+	}
+}
+
+func setupCommandDirectory() error {
+	err := os.MkdirAll(commandDaemonFlagSocketDirectory, 0o755)
+	if err != nil {
+		return E.Cause(err, "create socket directory")
+	}
+	workingPath, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	return libbox.Setup(&libbox.SetupOptions{
+		BasePath:    commandDaemonFlagSocketDirectory,
+		WorkingPath: workingPath,
+		TempPath:    os.TempDir(),
+	})
+}
+
+func (d *daemon) startService() error {
+	d.access.Lock()
+	defer d.access.Unlock()
+	return d.startService0()
+}
+
+func (d *daemon) startService0() error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	if disableColor {
+		if options.Log == nil {
+			options.Log = &option.LogOptions{}
+		}
+		options.Log.DisableColor = true
+	}
+	d.commandServer.ResetLog()
+	service, err := libbox.NewDaemonService(globalCtx, options, d)
+	if err != nil {
+		return err
+	}
+	err = service.Start()
+	if err != nil {
+		service.Close()
+		return E.Cause(err, "start service")
+	}
+	d.service = service
+	d.commandServer.SetService(service)
+	return nil
+}
+
+func (d *daemon) closeService() error {
+	if d.service == nil {
+		return nil
+	}
+	d.commandServer.SetService(nil)
+	err := d.service.Close()
+	d.service = nil
+	return err
+}
+
+func (d *daemon) ServiceReload() error {
+	d.access.Lock()
+	defer d.access.Unlock()
+	err := check()
+	if err != nil {
+		return err
+	}
+	err = d.closeService()
+	if err != nil {
+		log.Error(E.Cause(err, "close service"))
+	}
+	return d.startService0()
+}
+
+func (d *daemon) PostServiceClose() {
+	select {
+	case <-d.closed:
+	default:
+		close(d.closed)
+	}
+}
+
+func (d *daemon) GetSystemProxyStatus() *libbox.SystemProxyStatus {
+	return &libbox.SystemProxyStatus{}
+}
+
+func (d *daemon) SetSystemProxyEnabled(isEnabled bool) error {
+	return os.ErrInvalid
+}
+
+func (d *daemon) DisableColors() bool {
+	return true
+}
+
+func (d *daemon) WriteMessage(level log.Level, message string) {
+	d.commandServer.WriteMessage(message)
+}
@@ -0,0 +1,92 @@
+//go:build with_clash_api
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/experimental/deprecated"
+	"github.com/sagernet/sing-box/experimental/libbox"
+	"github.com/sagernet/sing-box/include"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDaemonConfig = `{
+  "log": {"disabled": true},
+  "outbounds": [
+    {"type": "selector", "tag": "select", "outbounds": ["direct-a", "direct-b"]},
+    {"type": "direct", "tag": "direct-a"},
+    {"type": "direct", "tag": "direct-b"},
+    {"type": "direct", "tag": "direct-c"}
+  ],
+  "experimental": {"cache_file": {"path": "cache.db"}}
+}`
+
+func TestDaemon(t *testing.T) {
+	workingDirectory := t.TempDir()
+	configPath := filepath.Join(workingDirectory, "config.json")
+	config := strings.Replace(testDaemonConfig, "cache.db", filepath.Join(workingDirectory, "cache.db"), 1)
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0o644))
+	configPaths = []string{configPath}
+	globalCtx = include.Context(service.ContextWith(context.Background(), deprecated.NewStderrManager(log.StdLogger())))
+	commandDaemonFlagSocketDirectory = t.TempDir()
+	require.NoError(t, setupCommandDirectory())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveDaemon()
+	}()
+
+	// the client retries until the command server is listening
+	status, err := ctlReceive(libbox.CommandStatus, func(handler *ctlHandler, emit func(*libbox.StatusMessage)) {
+		handler.status = emit
+	})
+	require.NoError(t, err)
+	require.True(t, status.TrafficAvailable)
+	require.Equal(t, "direct-a", receiveGroup(t, "select").Selected)
+
+	require.NoError(t, libbox.NewStandaloneCommandClient().SelectOutbound("select", "direct-b"))
+	require.Equal(t, "direct-b", receiveGroup(t, "select").Selected)
+
+	// reload starts a new service from the configuration on disk, while a
+	// status subscriber is connected
+	statusClient, err := newCtlClient(libbox.CommandStatus, &ctlHandler{})
+	require.NoError(t, err)
+	defer statusClient.Disconnect()
+	config = strings.Replace(config, `"direct-b"]`, `"direct-b", "direct-c"]`, 1)
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0o644))
+	require.NoError(t, libbox.NewStandaloneCommandClient().ServiceReload())
+	group := receiveGroup(t, "select")
+	require.Len(t, group.ItemList, 3)
+	// the selection is restored from the cache file
+	require.Equal(t, "direct-b", group.Selected)
+
+	require.NoError(t, libbox.NewStandaloneCommandClient().ServiceClose())
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon not closed")
+	}
+}
+
+func receiveGroup(t *testing.T, groupTag string) *libbox.OutboundGroup {
+	groups, err := ctlReceive(libbox.CommandGroup, func(handler *ctlHandler, emit func([]*libbox.OutboundGroup)) {
+		handler.groups = emit
+	})
+	require.NoError(t, err)
+	for _, group := range groups {
+		if group.Tag == groupTag {
+			return group
+		}
+	}
+	t.Fatal("group not found: ", groupTag)
+	return nil
+}
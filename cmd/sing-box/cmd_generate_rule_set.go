@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandGenerateRuleSetKeyPair = &cobra.Command{
+	Use:   "rule-set-keypair",
+	Short: "Generate rule-set signing key pair",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := generateRuleSetKeyPair()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandGenerate.AddCommand(commandGenerateRuleSetKeyPair)
+}
+
+func generateRuleSetKeyPair() error {
+	privateKey, err := srs.GenerateKey()
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString("PrivateKey: " + privateKey.String() + "\n")
+	os.Stdout.WriteString("PublicKey: " + privateKey.Public().String() + "\n")
+	return nil
+}
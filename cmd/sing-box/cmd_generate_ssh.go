@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/protocol/ssh"
+
+	"github.com/spf13/cobra"
+)
+
+var commandGenerateSSHKeyPair = &cobra.Command{
+	Use:   "ssh-keypair",
+	Short: "Generate SSH key pair",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := generateSSHKeyPair()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandGenerate.AddCommand(commandGenerateSSHKeyPair)
+}
+
+func generateSSHKeyPair() error {
+	privateKey, publicKey, err := ssh.GenerateKeyPair()
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(privateKey)
+	os.Stdout.Write(publicKey)
+	return nil
+}
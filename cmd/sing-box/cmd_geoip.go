@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/log"
 	E "github.com/sagernet/sing/common/exceptions"
 
@@ -10,6 +11,7 @@ import (
 
 var (
 	geoipReader          *maxminddb.Reader
+	geoipASNReader       *geoip.ASNReader
 	commandGeoIPFlagFile string
 )
 
@@ -30,6 +32,14 @@ func init() {
 }
 
 func geoipPreRun() error {
+	if flagGeoipLookupASN {
+		reader, err := geoip.OpenASN(commandGeoIPFlagFile)
+		if err != nil {
+			return err
+		}
+		geoipASNReader = reader
+		return nil
+	}
 	reader, err := maxminddb.Open(commandGeoIPFlagFile)
 	if err != nil {
 		return err
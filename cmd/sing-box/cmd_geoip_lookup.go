@@ -5,12 +5,15 @@ import (
 	"os"
 
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
 
 	"github.com/spf13/cobra"
 )
 
+var flagGeoipLookupASN bool
+
 var commandGeoipLookup = &cobra.Command{
 	Use:   "lookup <address>",
 	Short: "Lookup if an IP address is contained in the GeoIP database",
@@ -24,6 +27,7 @@ var commandGeoipLookup = &cobra.Command{
 }
 
 func init() {
+	commandGeoipLookup.Flags().BoolVar(&flagGeoipLookupASN, "asn", false, "Lookup autonomous system number in a MaxMind or IPinfo ASN database")
 	commandGeoip.AddCommand(commandGeoipLookup)
 }
 
@@ -36,6 +40,15 @@ func geoipLookup(address string) error {
 		os.Stdout.WriteString("private\n")
 		return nil
 	}
+	if geoipASNReader != nil {
+		asn, loaded := geoipASNReader.Lookup(addr)
+		if loaded {
+			os.Stdout.WriteString(option.ASN(asn).String() + "\n")
+			return nil
+		}
+		os.Stdout.WriteString("unknown\n")
+		return nil
+	}
 	var code string
 	_ = geoipReader.Lookup(addr.AsSlice(), &code)
 	if code != "" {
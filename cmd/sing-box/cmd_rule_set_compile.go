@@ -5,15 +5,22 @@ import (
 	"os"
 	"strings"
 
+	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 
 	"github.com/spf13/cobra"
 )
 
-var flagRuleSetCompileOutput string
+var (
+	flagRuleSetCompileOutput      string
+	flagRuleSetCompileASNDatabase string
+)
 
 const flagRuleSetCompileDefaultOutput = "<file_name>.srs"
 
@@ -32,6 +39,7 @@ var commandRuleSetCompile = &cobra.Command{
 func init() {
 	commandRuleSet.AddCommand(commandRuleSetCompile)
 	commandRuleSetCompile.Flags().StringVarP(&flagRuleSetCompileOutput, "output", "o", flagRuleSetCompileDefaultOutput, "Output file")
+	commandRuleSetCompile.Flags().StringVar(&flagRuleSetCompileASNDatabase, "asn-database", "", "ASN database used to expand asn rule items")
 }
 
 func compileRuleSet(sourcePath string) error {
@@ -55,6 +63,17 @@ func compileRuleSet(sourcePath string) error {
 	if err != nil {
 		return err
 	}
+	if flagRuleSetCompileASNDatabase != "" {
+		reader, err := geoip.OpenASN(flagRuleSetCompileASNDatabase)
+		if err != nil {
+			return E.Cause(err, "open asn database")
+		}
+		err = expandASNRules(reader, plainRuleSet.Options.Rules)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
 	var outputPath string
 	if flagRuleSetCompileOutput == flagRuleSetCompileDefaultOutput {
 		if strings.HasSuffix(sourcePath, ".json") {
@@ -78,3 +97,28 @@ func compileRuleSet(sourcePath string) error {
 	outputFile.Close()
 	return nil
 }
+
+func expandASNRules(reader *geoip.ASNReader, rules []option.HeadlessRule) error {
+	for i := range rules {
+		switch rules[i].Type {
+		case C.RuleTypeDefault, "":
+			asnList := rules[i].DefaultOptions.ASN
+			if len(asnList) == 0 {
+				continue
+			}
+			ipSet, err := reader.IPSet(common.Map(asnList, func(it option.ASN) uint32 {
+				return uint32(it)
+			}))
+			if err != nil {
+				return E.Cause(err, "expand asn rule[", i, "]")
+			}
+			rules[i].DefaultOptions.ASNIPSet = ipSet
+		case C.RuleTypeLogical:
+			err := expandASNRules(reader, rules[i].LogicalOptions.Rules)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -3,13 +3,16 @@ package main
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/convertor/adguard"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 
 	"github.com/spf13/cobra"
@@ -22,7 +25,7 @@ var (
 
 var commandRuleSetConvert = &cobra.Command{
 	Use:   "convert [source-path]",
-	Short: "Convert adguard DNS filter to rule-set",
+	Short: "Convert adguard DNS filter or third-party rule list to rule-set",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := convertRuleSet(args[0])
@@ -34,7 +37,7 @@ var commandRuleSetConvert = &cobra.Command{
 
 func init() {
 	commandRuleSet.AddCommand(commandRuleSetConvert)
-	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard")
+	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertType, "type", "t", "", "Source type, available: adguard, "+strings.Join(convertor.Formats, ", "))
 	commandRuleSetConvert.Flags().StringVarP(&flagRuleSetConvertOutput, "output", "o", flagRuleSetCompileDefaultOutput, "Output file")
 }
 
@@ -58,15 +61,18 @@ func convertRuleSet(sourcePath string) error {
 	case "":
 		return E.New("source type is required")
 	default:
-		return E.New("unsupported source type: ", flagRuleSetConvertType)
+		if !common.Contains(convertor.Formats, flagRuleSetConvertType) {
+			return E.New("unsupported source type: ", flagRuleSetConvertType)
+		}
+		rules, err = convertor.ToOptions(flagRuleSetConvertType, reader, log.StdLogger())
 	}
 	if err != nil {
 		return err
 	}
 	var outputPath string
 	if flagRuleSetConvertOutput == flagRuleSetCompileDefaultOutput {
-		if strings.HasSuffix(sourcePath, ".txt") {
-			outputPath = sourcePath[:len(sourcePath)-4] + ".srs"
+		if sourceExt := filepath.Ext(sourcePath); common.Contains([]string{".txt", ".list", ".yaml", ".yml", ".conf"}, sourceExt) {
+			outputPath = strings.TrimSuffix(sourcePath, sourceExt) + ".srs"
 		} else {
 			outputPath = sourcePath + ".srs"
 		}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -78,7 +79,15 @@ func ruleSetMatch(sourcePath string, domain string) error {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", flagRuleSetMatchFormat)
+		var rules []option.HeadlessRule
+		rules, err = convertor.ToOptions(flagRuleSetMatchFormat, bytes.NewReader(content), log.StdLogger())
+		if err != nil {
+			return err
+		}
+		ruleSet = option.PlainRuleSetCompat{
+			Version: C.RuleSetVersionCurrent,
+			Options: option.PlainRuleSet{Rules: rules},
+		}
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetSignPrivateKey     string
+	flagRuleSetSignPrivateKeyPath string
+	flagRuleSetSignDetached       bool
+	flagRuleSetSignOutput         string
+)
+
+var commandRuleSetSign = &cobra.Command{
+	Use:   "sign <rule-set path>",
+	Short: "Sign rule-set",
+	Long:  "Embed a signature into a binary rule-set, or write a minisign compatible signature file for other formats.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := signRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSetSign.Flags().StringVar(&flagRuleSetSignPrivateKey, "private-key", "", "Private key generated by `generate rule-set-keypair`")
+	commandRuleSetSign.Flags().StringVar(&flagRuleSetSignPrivateKeyPath, "private-key-path", "", "Path of the private key")
+	commandRuleSetSign.Flags().BoolVarP(&flagRuleSetSignDetached, "detached", "d", false, "Write a detached signature even for binary rule-set")
+	commandRuleSetSign.Flags().StringVarP(&flagRuleSetSignOutput, "output", "o", "", "Output file, defaults to the rule-set itself or <file_name>.minisig for detached signatures")
+	commandRuleSet.AddCommand(commandRuleSetSign)
+}
+
+func signRuleSet(sourcePath string) error {
+	keyContent := flagRuleSetSignPrivateKey
+	if flagRuleSetSignPrivateKeyPath != "" {
+		keyBytes, err := os.ReadFile(flagRuleSetSignPrivateKeyPath)
+		if err != nil {
+			return E.Cause(err, "read private key")
+		}
+		keyContent = string(keyBytes)
+	}
+	if keyContent == "" {
+		return E.New("missing private key")
+	}
+	privateKey, err := srs.ParsePrivateKey(keyContent)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	if !flagRuleSetSignDetached && isBinaryRuleSet(content) {
+		outputPath := flagRuleSetSignOutput
+		if outputPath == "" {
+			outputPath = sourcePath
+		}
+		return os.WriteFile(outputPath, srs.Sign(content, privateKey), 0o644)
+	}
+	outputPath := flagRuleSetSignOutput
+	if outputPath == "" {
+		outputPath = sourcePath + ".minisig"
+	}
+	trustedComment := "timestamp:" + strconv.FormatInt(time.Now().Unix(), 10) + "\tfile:" + filepath.Base(sourcePath)
+	return os.WriteFile(outputPath, srs.SignDetached(content, privateKey, trustedComment), 0o644)
+}
+
+func isBinaryRuleSet(content []byte) bool {
+	return len(content) >= len(srs.MagicBytes) && [3]byte(content) == srs.MagicBytes
+}
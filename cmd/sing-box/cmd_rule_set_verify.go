@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagRuleSetVerifyPublicKey []string
+	flagRuleSetVerifySignature string
+	flagRuleSetVerifySHA256    string
+)
+
+var commandRuleSetVerify = &cobra.Command{
+	Use:   "verify <rule-set path>",
+	Short: "Verify rule-set signature or checksum",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := verifyRuleSet(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSetVerify.Flags().StringArrayVarP(&flagRuleSetVerifyPublicKey, "public-key", "k", nil, "Trusted public key")
+	commandRuleSetVerify.Flags().StringVarP(&flagRuleSetVerifySignature, "signature", "s", "", "Detached signature path, the embedded signature is checked if not set")
+	commandRuleSetVerify.Flags().StringVar(&flagRuleSetVerifySHA256, "sha256", "", "Expected SHA256 checksum")
+	commandRuleSet.AddCommand(commandRuleSetVerify)
+}
+
+func verifyRuleSet(sourcePath string) error {
+	if len(flagRuleSetVerifyPublicKey) == 0 && flagRuleSetVerifySHA256 == "" {
+		return E.New("missing public key or sha256")
+	}
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	if flagRuleSetVerifySHA256 != "" {
+		expected, err := hex.DecodeString(flagRuleSetVerifySHA256)
+		if err != nil {
+			return E.Cause(err, "decode sha256")
+		}
+		checksum := sha256.Sum256(content)
+		if !bytes.Equal(checksum[:], expected) {
+			return E.New("sha256 mismatch: got ", hex.EncodeToString(checksum[:]))
+		}
+	}
+	if len(flagRuleSetVerifyPublicKey) > 0 {
+		var publicKeys []*srs.PublicKey
+		for _, keyContent := range flagRuleSetVerifyPublicKey {
+			publicKey, err := srs.ParsePublicKey(keyContent)
+			if err != nil {
+				return err
+			}
+			publicKeys = append(publicKeys, publicKey)
+		}
+		if flagRuleSetVerifySignature != "" {
+			signature, err := os.ReadFile(flagRuleSetVerifySignature)
+			if err != nil {
+				return E.Cause(err, "read signature")
+			}
+			err = srs.VerifyDetached(content, signature, publicKeys)
+		} else {
+			err = srs.Verify(content, publicKeys)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	os.Stdout.WriteString("verified\n")
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandSpeedTestFlagDownloadURL     string
+	commandSpeedTestFlagUploadURL       string
+	commandSpeedTestFlagStreams         int
+	commandSpeedTestFlagDuration        string
+	commandSpeedTestFlagDisableDownload bool
+	commandSpeedTestFlagDisableUpload   bool
+)
+
+var commandSpeedTest = &cobra.Command{
+	Use:   "speedtest",
+	Short: "Measure download and upload throughput",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := speedTest()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandSpeedTest.Flags().StringVar(&commandSpeedTestFlagDownloadURL, "download-url", urltest.DefaultSpeedTestDownloadURL, "Set download URL")
+	commandSpeedTest.Flags().StringVar(&commandSpeedTestFlagUploadURL, "upload-url", urltest.DefaultSpeedTestUploadURL, "Set upload URL")
+	commandSpeedTest.Flags().IntVarP(&commandSpeedTestFlagStreams, "streams", "n", urltest.DefaultSpeedTestStreams, "Set number of parallel streams")
+	commandSpeedTest.Flags().StringVarP(&commandSpeedTestFlagDuration, "duration", "d", urltest.DefaultSpeedTestDuration.String(), "Set duration of each direction")
+	commandSpeedTest.Flags().BoolVar(&commandSpeedTestFlagDisableDownload, "no-download", false, "Skip download test")
+	commandSpeedTest.Flags().BoolVar(&commandSpeedTestFlagDisableUpload, "no-upload", false, "Skip upload test")
+	commandTools.AddCommand(commandSpeedTest)
+}
+
+func speedTest() error {
+	duration, err := time.ParseDuration(commandSpeedTestFlagDuration)
+	if err != nil {
+		return E.Cause(err, "parse duration")
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	dialer, err := createDialer(instance, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	result, err := urltest.SpeedTest(globalCtx, urltest.SpeedTestOptions{
+		DownloadURL:     commandSpeedTestFlagDownloadURL,
+		UploadURL:       commandSpeedTestFlagUploadURL,
+		Streams:         commandSpeedTestFlagStreams,
+		Duration:        duration,
+		DisableDownload: commandSpeedTestFlagDisableDownload,
+		DisableUpload:   commandSpeedTestFlagDisableUpload,
+	}, dialer)
+	if err != nil {
+		return err
+	}
+	if result.Delay > 0 {
+		os.Stdout.WriteString(F.ToString("latency: ", result.Delay, " ms\n"))
+	}
+	if !commandSpeedTestFlagDisableDownload {
+		os.Stdout.WriteString("download: " + formatBitrate(result.Download) + "\n")
+	}
+	if !commandSpeedTestFlagDisableUpload {
+		os.Stdout.WriteString("upload: " + formatBitrate(result.Upload) + "\n")
+	}
+	return nil
+}
+
+func formatBitrate(bytesPerSecond uint64) string {
+	return strconv.FormatFloat(float64(bytesPerSecond)*8/1e6, 'f', 2, 64) + " Mbps"
+}
@@ -0,0 +1,301 @@
+package clash
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DomainToOptions converts a rule-provider of the domain behavior, where
+// "+.example.com" matches the domain and its subdomains, ".example.com" only
+// its subdomains and "*" a single label.
+func DomainToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	ruleLines, err := readLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rule         option.DefaultHeadlessRule
+		ignoredLines int
+	)
+	for _, ruleLine := range ruleLines {
+		if !addDomain(&rule, ruleLine) {
+			ignoredLines++
+			logger.Debug("ignored invalid domain: ", ruleLine)
+		}
+	}
+	return buildRules(logger, len(ruleLines), ignoredLines, rule)
+}
+
+// IPCIDRToOptions converts a rule-provider of the ipcidr behavior.
+func IPCIDRToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	ruleLines, err := readLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rule         option.DefaultHeadlessRule
+		ignoredLines int
+	)
+	for _, ruleLine := range ruleLines {
+		ipCIDR, loaded := parseIPCIDR(ruleLine)
+		if !loaded {
+			ignoredLines++
+			logger.Debug("ignored invalid IPCIDR: ", ruleLine)
+			continue
+		}
+		rule.IPCIDR = append(rule.IPCIDR, ipCIDR)
+	}
+	return buildRules(logger, len(ruleLines), ignoredLines, rule)
+}
+
+// ClassicalToOptions converts a rule-provider of the classical behavior.
+// Surge rule lists and QuantumultX filters share its TYPE,value syntax and
+// are accepted too; policies and options after the value are ignored.
+func ClassicalToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	ruleLines, err := readLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	// lines are OR'ed, so items that sing-box would AND go to separate rules
+	var (
+		destinationRule option.DefaultHeadlessRule
+		sourceRule      option.DefaultHeadlessRule
+		portRule        option.DefaultHeadlessRule
+		sourcePortRule  option.DefaultHeadlessRule
+		processRule     option.DefaultHeadlessRule
+		networkRule     option.DefaultHeadlessRule
+		ignoredLines    int
+	)
+	for _, ruleLine := range ruleLines {
+		ruleType, value, loaded := strings.Cut(ruleLine, ",")
+		if !loaded {
+			ignoredLines++
+			logger.Debug("ignored invalid rule: ", ruleLine)
+			continue
+		}
+		ruleType = strings.ToUpper(strings.TrimSpace(ruleType))
+		value, _, _ = strings.Cut(value, ",")
+		value = strings.TrimSpace(value)
+		var (
+			supported  bool
+			ipCIDR     string
+			ports      []uint16
+			portRanges []string
+		)
+		switch ruleType {
+		case "DOMAIN", "HOST":
+			supported = M.IsDomainName(value)
+			if supported {
+				destinationRule.Domain = append(destinationRule.Domain, value)
+			}
+		case "DOMAIN-SUFFIX", "HOST-SUFFIX":
+			supported = M.IsDomainName(strings.TrimPrefix(value, "."))
+			if supported {
+				destinationRule.DomainSuffix = append(destinationRule.DomainSuffix, value)
+			}
+		case "DOMAIN-KEYWORD", "HOST-KEYWORD":
+			supported = value != ""
+			if supported {
+				destinationRule.DomainKeyword = append(destinationRule.DomainKeyword, value)
+			}
+		case "DOMAIN-REGEX":
+			_, err = regexp.Compile(value)
+			supported = err == nil
+			if supported {
+				destinationRule.DomainRegex = append(destinationRule.DomainRegex, value)
+			}
+		case "DOMAIN-WILDCARD", "HOST-WILDCARD":
+			supported = value != ""
+			if supported {
+				destinationRule.DomainRegex = append(destinationRule.DomainRegex, wildcardRegex(value))
+			}
+		case "IP-CIDR", "IP-CIDR6", "IP6-CIDR":
+			ipCIDR, supported = parseIPCIDR(value)
+			if supported {
+				destinationRule.IPCIDR = append(destinationRule.IPCIDR, ipCIDR)
+			}
+		case "SRC-IP-CIDR", "SRC-IP":
+			ipCIDR, supported = parseIPCIDR(value)
+			if supported {
+				sourceRule.SourceIPCIDR = append(sourceRule.SourceIPCIDR, ipCIDR)
+			}
+		case "DST-PORT", "DEST-PORT":
+			ports, portRanges, supported = parsePorts(value)
+			if supported {
+				portRule.Port = append(portRule.Port, ports...)
+				portRule.PortRange = append(portRule.PortRange, portRanges...)
+			}
+		case "SRC-PORT":
+			ports, portRanges, supported = parsePorts(value)
+			if supported {
+				sourcePortRule.SourcePort = append(sourcePortRule.SourcePort, ports...)
+				sourcePortRule.SourcePortRange = append(sourcePortRule.SourcePortRange, portRanges...)
+			}
+		case "PROCESS-NAME":
+			supported = value != ""
+			if supported {
+				processRule.ProcessName = append(processRule.ProcessName, value)
+			}
+		case "PROCESS-PATH":
+			supported = value != ""
+			if supported {
+				processRule.ProcessPath = append(processRule.ProcessPath, value)
+			}
+		case "NETWORK":
+			value = strings.ToLower(value)
+			supported = value == N.NetworkTCP || value == N.NetworkUDP
+			if supported {
+				networkRule.Network = append(networkRule.Network, value)
+			}
+		}
+		if !supported {
+			ignoredLines++
+			logger.Debug("ignored unsupported rule: ", ruleLine)
+		}
+	}
+	return buildRules(logger, len(ruleLines), ignoredLines, destinationRule, sourceRule, portRule, sourcePortRule, processRule, networkRule)
+}
+
+// readLines returns the entries of the payload of a YAML rule-provider,
+// or of a plain text list without comments.
+func readLines(reader io.Reader) ([]string, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var provider struct {
+		Payload *[]string `yaml:"payload"`
+	}
+	if yaml.Unmarshal(content, &provider) == nil && provider.Payload != nil {
+		var ruleLines []string
+		for _, ruleLine := range *provider.Payload {
+			ruleLine = strings.TrimSpace(ruleLine)
+			if ruleLine != "" {
+				ruleLines = append(ruleLines, ruleLine)
+			}
+		}
+		return ruleLines, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var ruleLines []string
+	for scanner.Scan() {
+		ruleLine := strings.TrimSpace(scanner.Text())
+		if ruleLine == "" || ruleLine[0] == '#' || ruleLine[0] == ';' || strings.HasPrefix(ruleLine, "//") {
+			continue
+		}
+		ruleLines = append(ruleLines, ruleLine)
+	}
+	return ruleLines, scanner.Err()
+}
+
+func buildRules(logger logger.Logger, totalLines int, ignoredLines int, defaultRules ...option.DefaultHeadlessRule) ([]option.HeadlessRule, error) {
+	var rules []option.HeadlessRule
+	for _, defaultRule := range defaultRules {
+		if defaultRule.IsValid() {
+			rules = append(rules, option.HeadlessRule{
+				Type:           C.RuleTypeDefault,
+				DefaultOptions: defaultRule,
+			})
+		}
+	}
+	if len(rules) == 0 {
+		return nil, E.New("rule-set is empty or all rules are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", totalLines-ignoredLines, "/", totalLines)
+	}
+	return rules, nil
+}
+
+func addDomain(rule *option.DefaultHeadlessRule, domain string) bool {
+	switch {
+	case strings.HasPrefix(domain, "+."):
+		domain = domain[2:]
+		if !M.IsDomainName(domain) {
+			return false
+		}
+		rule.DomainSuffix = append(rule.DomainSuffix, domain)
+	case strings.HasPrefix(domain, "."):
+		if !M.IsDomainName(domain[1:]) {
+			return false
+		}
+		rule.DomainSuffix = append(rule.DomainSuffix, domain)
+	case strings.Contains(domain, "*"):
+		rule.DomainRegex = append(rule.DomainRegex, wildcardRegex(domain))
+	default:
+		if !M.IsDomainName(domain) {
+			return false
+		}
+		rule.Domain = append(rule.Domain, domain)
+	}
+	return true
+}
+
+func parseIPCIDR(value string) (string, bool) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.String(), true
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), true
+	}
+	return "", false
+}
+
+// parsePorts parses ports and ranges separated by "/", such as "80/8000-8080".
+func parsePorts(value string) ([]uint16, []string, bool) {
+	var (
+		ports      []uint16
+		portRanges []string
+	)
+	for _, portItem := range strings.Split(value, "/") {
+		from, to, isRange := strings.Cut(portItem, "-")
+		fromPort, err := strconv.ParseUint(strings.TrimSpace(from), 10, 16)
+		if err != nil {
+			return nil, nil, false
+		}
+		if !isRange {
+			ports = append(ports, uint16(fromPort))
+			continue
+		}
+		toPort, err := strconv.ParseUint(strings.TrimSpace(to), 10, 16)
+		if err != nil || toPort < fromPort {
+			return nil, nil, false
+		}
+		portRanges = append(portRanges, F.ToString(fromPort, ":", toPort))
+	}
+	return ports, portRanges, true
+}
+
+// wildcardRegex converts a wildcard where "*" matches any characters within
+// a label and "?" a single one.
+func wildcardRegex(wildcard string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, char := range wildcard {
+		switch char {
+		case '*':
+			builder.WriteString(`[^.]*`)
+		case '?':
+			builder.WriteString(`[^.]`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
@@ -0,0 +1,32 @@
+package clash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLines(t *testing.T) {
+	t.Parallel()
+	for _, content := range []string{
+		"payload:\n  - '+.example.com' # comment\n  - \"example.org\"\n  - example.net\n",
+		"payload: ['+.example.com', \"example.org\", example.net]\n",
+		"# comment\n+.example.com\n\n// comment\nexample.org\n; comment\nexample.net\n",
+	} {
+		ruleLines, err := readLines(strings.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, []string{"+.example.com", "example.org", "example.net"}, ruleLines)
+	}
+}
+
+func TestDomainToOptions(t *testing.T) {
+	t.Parallel()
+	rules, err := DomainToOptions(strings.NewReader("payload: ['+.example.com', 'example.org', '.example.net']"), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, []string{"example.org"}, []string(rules[0].DefaultOptions.Domain))
+	require.Equal(t, []string{"example.com", ".example.net"}, []string(rules[0].DefaultOptions.DomainSuffix))
+}
@@ -0,0 +1,40 @@
+package convertor
+
+import (
+	"io"
+
+	"github.com/sagernet/sing-box/common/convertor/clash"
+	"github.com/sagernet/sing-box/common/convertor/dnsmasq"
+	"github.com/sagernet/sing-box/common/convertor/hosts"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+var Formats = []string{
+	C.RuleSetFormatClashDomain,
+	C.RuleSetFormatClashIPCIDR,
+	C.RuleSetFormatClashClassical,
+	C.RuleSetFormatSurgeList,
+	C.RuleSetFormatHosts,
+	C.RuleSetFormatDnsmasq,
+}
+
+// ToOptions converts a third-party rule list in one of Formats.
+func ToOptions(format string, reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	switch format {
+	case C.RuleSetFormatClashDomain:
+		return clash.DomainToOptions(reader, logger)
+	case C.RuleSetFormatClashIPCIDR:
+		return clash.IPCIDRToOptions(reader, logger)
+	case C.RuleSetFormatClashClassical, C.RuleSetFormatSurgeList:
+		return clash.ClassicalToOptions(reader, logger)
+	case C.RuleSetFormatHosts:
+		return hosts.ToOptions(reader, logger)
+	case C.RuleSetFormatDnsmasq:
+		return dnsmasq.ToOptions(reader, logger)
+	default:
+		return nil, E.New("unknown rule-set format: ", format)
+	}
+}
@@ -0,0 +1,97 @@
+package convertor_test
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/route/rule"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func matchAny(t *testing.T, format string, content string, metadata adapter.InboundContext) bool {
+	rules, err := convertor.ToOptions(format, strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	for _, ruleOptions := range rules {
+		currentRule, err := rule.NewHeadlessRule(context.Background(), ruleOptions)
+		require.NoError(t, err)
+		if currentRule.Match(&metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClashDomain(t *testing.T) {
+	t.Parallel()
+	content := `payload:
+  # comment
+  - '+.example.org'
+  - ".example.com"
+  - "*.example.net"
+  - example.edu
+`
+	matchDomain := []string{
+		"example.org",
+		"www.example.org",
+		"www.example.com",
+		"www.example.net",
+		"example.edu",
+	}
+	notMatchDomain := []string{
+		"example.com",
+		"example.net",
+		"a.www.example.net",
+		"www.example.edu",
+	}
+	for _, domain := range matchDomain {
+		require.True(t, matchAny(t, C.RuleSetFormatClashDomain, content, adapter.InboundContext{Domain: domain}), domain)
+	}
+	for _, domain := range notMatchDomain {
+		require.False(t, matchAny(t, C.RuleSetFormatClashDomain, content, adapter.InboundContext{Domain: domain}), domain)
+	}
+}
+
+func TestClassical(t *testing.T) {
+	t.Parallel()
+	content := `DOMAIN-SUFFIX,example.org
+DOMAIN-KEYWORD,sagernet
+IP-CIDR,10.0.0.0/8,no-resolve
+DST-PORT,8000-8080
+USER-AGENT,curl*
+host, example.com, proxy
+`
+	rules, err := convertor.ToOptions(C.RuleSetFormatSurgeList, strings.NewReader(content), logger.NOP())
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.True(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Domain: "www.example.org"}))
+	require.True(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Domain: "example.com"}))
+	require.True(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Domain: "sing.sagernet.net"}))
+	require.True(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Destination: M.SocksaddrFrom(netip.MustParseAddr("10.1.2.3"), 443)}))
+	require.True(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Destination: M.SocksaddrFrom(netip.MustParseAddr("1.1.1.1"), 8053)}))
+	require.False(t, matchAny(t, C.RuleSetFormatSurgeList, content, adapter.InboundContext{Destination: M.SocksaddrFrom(netip.MustParseAddr("1.1.1.1"), 443)}))
+}
+
+func TestHostsAndDnsmasq(t *testing.T) {
+	t.Parallel()
+	hostsContent := `127.0.0.1 localhost
+0.0.0.0 ads.example.org tracker.example.org # blocked
+`
+	require.True(t, matchAny(t, C.RuleSetFormatHosts, hostsContent, adapter.InboundContext{Domain: "ads.example.org"}))
+	require.False(t, matchAny(t, C.RuleSetFormatHosts, hostsContent, adapter.InboundContext{Domain: "localhost"}))
+	require.False(t, matchAny(t, C.RuleSetFormatHosts, hostsContent, adapter.InboundContext{Domain: "www.ads.example.org"}))
+	dnsmasqContent := `server=/example.org/example.com/114.114.114.114
+ipset=/example.net/gfwlist
+cache-size=1000
+`
+	require.True(t, matchAny(t, C.RuleSetFormatDnsmasq, dnsmasqContent, adapter.InboundContext{Domain: "www.example.com"}))
+	require.True(t, matchAny(t, C.RuleSetFormatDnsmasq, dnsmasqContent, adapter.InboundContext{Domain: "example.net"}))
+	require.False(t, matchAny(t, C.RuleSetFormatDnsmasq, dnsmasqContent, adapter.InboundContext{Domain: "example.edu"}))
+}
@@ -0,0 +1,80 @@
+package dnsmasq
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var domainOptions = []string{
+	"server",
+	"local",
+	"address",
+	"ipset",
+	"nftset",
+}
+
+// ToOptions converts dnsmasq configuration lines with domain lists, such as
+// server=/example.com/1.1.1.1 or address=/example.com/0.0.0.0, to a rule
+// matching the listed domains and their subdomains.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		domains      []string
+		totalLines   int
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		ruleLine := strings.TrimSpace(scanner.Text())
+		if ruleLine == "" || ruleLine[0] == '#' {
+			continue
+		}
+		totalLines++
+		name, value, loaded := strings.Cut(ruleLine, "=")
+		if !loaded || !common.Contains(domainOptions, strings.TrimSpace(name)) || !strings.HasPrefix(value, "/") {
+			ignoredLines++
+			logger.Debug("ignored unsupported dnsmasq line: ", ruleLine)
+			continue
+		}
+		// the value after the last slash is the server or address
+		lineDomains := strings.Split(value[1:], "/")
+		lineDomains = lineDomains[:len(lineDomains)-1]
+		var lineValid bool
+		for _, domain := range lineDomains {
+			domain = strings.TrimPrefix(domain, ".")
+			if !M.IsDomainName(domain) {
+				logger.Debug("ignored invalid domain: ", domain)
+				continue
+			}
+			domains = append(domains, domain)
+			lineValid = true
+		}
+		if !lineValid {
+			ignoredLines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, E.New("dnsmasq rule-set is empty or all rules are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", totalLines-ignoredLines, "/", totalLines)
+	}
+	return []option.HeadlessRule{
+		{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultHeadlessRule{
+				DomainSuffix: common.Uniq(domains),
+			},
+		},
+	}, nil
+}
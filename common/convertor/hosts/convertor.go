@@ -0,0 +1,83 @@
+package hosts
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var localHostnames = []string{
+	"localhost",
+	"localhost.localdomain",
+	"local",
+	"broadcasthost",
+	"ip6-localhost",
+	"ip6-loopback",
+	"ip6-localnet",
+	"ip6-mcastprefix",
+	"ip6-allnodes",
+	"ip6-allrouters",
+	"ip6-allhosts",
+}
+
+// ToOptions converts a hosts file, such as a blocklist, to a rule matching
+// every hostname in it. Hostnames of the local host are skipped.
+func ToOptions(reader io.Reader, logger logger.Logger) ([]option.HeadlessRule, error) {
+	scanner := bufio.NewScanner(reader)
+	var (
+		domains      []string
+		totalLines   int
+		ignoredLines int
+	)
+	for scanner.Scan() {
+		ruleLine := scanner.Text()
+		if commentIndex := strings.IndexByte(ruleLine, '#'); commentIndex != -1 {
+			ruleLine = ruleLine[:commentIndex]
+		}
+		fields := strings.Fields(ruleLine)
+		if len(fields) == 0 {
+			continue
+		}
+		totalLines++
+		if _, err := netip.ParseAddr(fields[0]); err != nil || len(fields) < 2 {
+			ignoredLines++
+			logger.Debug("ignored invalid hosts line: ", ruleLine)
+			continue
+		}
+		for _, hostname := range fields[1:] {
+			if common.Contains(localHostnames, hostname) {
+				continue
+			}
+			if !M.IsDomainName(hostname) {
+				logger.Debug("ignored invalid hostname: ", hostname)
+				continue
+			}
+			domains = append(domains, hostname)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, E.New("hosts rule-set is empty or all rules are unsupported")
+	}
+	if ignoredLines > 0 {
+		logger.Info("parsed rules: ", totalLines-ignoredLines, "/", totalLines)
+	}
+	return []option.HeadlessRule{
+		{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultHeadlessRule{
+				Domain: common.Uniq(domains),
+			},
+		},
+	}, nil
+}
@@ -3,6 +3,7 @@ package dialer
 import (
 	"context"
 	"net"
+	"net/netip"
 	"sync"
 	"time"
 
@@ -131,6 +132,15 @@ func (d *resolveDialer) ListenPacket(ctx context.Context, destination M.Socksadd
 	return bufio.NewNATPacketConn(bufio.NewPacketConn(conn), M.SocksaddrFrom(destinationAddress, destination.Port), destination), nil
 }
 
+func (d *resolveDialer) lookup(ctx context.Context, domain string) ([]netip.Addr, error) {
+	err := d.initialize()
+	if err != nil {
+		return nil, err
+	}
+	ctx = log.ContextWithOverrideLevel(ctx, log.LevelDebug)
+	return d.router.Lookup(ctx, domain, d.queryOptions)
+}
+
 func (d *resolveDialer) QueryOptions() adapter.DNSQueryOptions {
 	return d.queryOptions
 }
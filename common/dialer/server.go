@@ -0,0 +1,461 @@
+package dialer
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	serverStrategyHappyEyeballs = "happy_eyeballs"
+	serverStrategyRandom        = "random"
+	serverStrategyLowestRTT     = "lowest_rtt"
+
+	defaultServerCooldown = time.Minute
+	// connection attempt delay recommended by RFC 8305
+	serverAttemptDelay = 250 * time.Millisecond
+)
+
+var _ N.Dialer = (*serverDialer)(nil)
+
+type serverLookupDialer interface {
+	lookup(ctx context.Context, domain string) ([]netip.Addr, error)
+}
+
+func NewServer(ctx context.Context, options option.DialerOptions, serverOptions option.ServerOptions) (N.Dialer, error) {
+	return NewServerWithOptions(Options{
+		Context:        ctx,
+		Options:        options,
+		RemoteIsDomain: serverOptions.ServerIsDomain(),
+	}, serverOptions)
+}
+
+// NewServerWithOptions creates a dialer that spreads connections to the
+// server over every address in serverOptions. Without alternative servers
+// it is the same as NewWithOptions.
+func NewServerWithOptions(options Options, serverOptions option.ServerOptions) (N.Dialer, error) {
+	switch serverOptions.ServerStrategy {
+	case "", serverStrategyHappyEyeballs, serverStrategyRandom, serverStrategyLowestRTT:
+	default:
+		return nil, E.New("unknown server strategy: ", serverOptions.ServerStrategy)
+	}
+	dialer, err := NewWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(serverOptions.Servers) == 0 {
+		return dialer, nil
+	}
+	strategy := serverOptions.ServerStrategy
+	if strategy == "" {
+		strategy = serverStrategyHappyEyeballs
+	}
+	cooldown := time.Duration(serverOptions.ServerCooldown)
+	if cooldown == 0 {
+		cooldown = defaultServerCooldown
+	}
+	return &serverDialer{
+		dialer:   dialer,
+		server:   serverOptions.Build(),
+		servers:  serverOptions.BuildAll(),
+		strategy: strategy,
+		cooldown: cooldown,
+		states:   make(map[M.Socksaddr]*serverState),
+	}, nil
+}
+
+type serverDialer struct {
+	dialer   N.Dialer
+	server   M.Socksaddr
+	servers  []M.Socksaddr
+	strategy string
+	cooldown time.Duration
+	access   sync.Mutex
+	states   map[M.Socksaddr]*serverState
+}
+
+type serverState struct {
+	failedAt time.Time
+	rtt      time.Duration
+}
+
+func (d *serverDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if !d.isServer(destination) {
+		return d.dialer.DialContext(ctx, network, destination)
+	}
+	addresses, err := d.resolve(ctx, destination.Port)
+	if err != nil {
+		return nil, err
+	}
+	if N.NetworkName(network) == N.NetworkUDP {
+		return d.dialUDP(ctx, network, addresses)
+	}
+	var (
+		conn    net.Conn
+		address M.Socksaddr
+	)
+	if d.strategy == serverStrategyHappyEyeballs {
+		conn, address, err = d.dialParallel(ctx, network, addresses)
+	} else {
+		conn, address, err = d.dialSerial(ctx, network, addresses)
+	}
+	if err != nil {
+		return nil, err
+	}
+	storeRemoteDestination(ctx, address)
+	return conn, nil
+}
+
+func (d *serverDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	if !d.isServer(destination) {
+		return d.dialer.ListenPacket(ctx, destination)
+	}
+	addresses, err := d.resolve(ctx, destination.Port)
+	if err != nil {
+		return nil, err
+	}
+	var errors []error
+	for _, address := range addresses {
+		conn, err := d.dialer.ListenPacket(ctx, address)
+		if err != nil {
+			d.markFailure(ctx, address)
+			errors = append(errors, err)
+			continue
+		}
+		storeRemoteDestination(ctx, address)
+		conn = &serverPacketConn{PacketConn: conn, serverReplyMonitor: serverReplyMonitor{dialer: d, address: address}}
+		if address == destination {
+			return conn, nil
+		}
+		return bufio.NewNATPacketConn(bufio.NewPacketConn(conn), address, destination), nil
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (d *serverDialer) Upstream() any {
+	return d.dialer
+}
+
+func (d *serverDialer) isServer(destination M.Socksaddr) bool {
+	return destination.AddrString() == d.server.AddrString() && (d.server.Port == 0 || destination.Port == d.server.Port)
+}
+
+// resolve expands the configured servers to the addresses to try, in the
+// order of the strategy. Addresses still in cooldown are tried last.
+func (d *serverDialer) resolve(ctx context.Context, port uint16) ([]M.Socksaddr, error) {
+	lookupDialer, canLookup := d.dialer.(serverLookupDialer)
+	var (
+		addresses []M.Socksaddr
+		errors    []error
+	)
+	for _, server := range d.servers {
+		if server.Port == 0 {
+			server.Port = port
+		}
+		if !server.IsFqdn() || !canLookup {
+			addresses = append(addresses, server)
+			continue
+		}
+		serverAddrs, err := lookupDialer.lookup(ctx, server.Fqdn)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "lookup ", server.Fqdn))
+			continue
+		}
+		for _, serverAddr := range serverAddrs {
+			addresses = append(addresses, M.SocksaddrFrom(serverAddr, server.Port))
+		}
+	}
+	addresses = common.Uniq(addresses)
+	if len(addresses) == 0 {
+		return nil, E.Errors(errors...)
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	now := time.Now()
+	var available, cooling []M.Socksaddr
+	for _, address := range addresses {
+		state := d.states[address]
+		if state != nil && now.Sub(state.failedAt) < d.cooldown {
+			cooling = append(cooling, address)
+		} else {
+			available = append(available, address)
+		}
+	}
+	switch d.strategy {
+	case serverStrategyHappyEyeballs:
+		available = interleaveAddresses(available)
+	case serverStrategyRandom:
+		rand.Shuffle(len(available), func(i, j int) {
+			available[i], available[j] = available[j], available[i]
+		})
+	case serverStrategyLowestRTT:
+		// addresses without a measurement go first so that they get one
+		sort.SliceStable(available, func(i, j int) bool {
+			return d.rtt(available[i]) < d.rtt(available[j])
+		})
+	}
+	sort.SliceStable(cooling, func(i, j int) bool {
+		return d.states[cooling[i]].failedAt.Before(d.states[cooling[j]].failedAt)
+	})
+	return append(available, cooling...), nil
+}
+
+func (d *serverDialer) rtt(address M.Socksaddr) time.Duration {
+	state := d.states[address]
+	if state == nil {
+		return 0
+	}
+	return state.rtt
+}
+
+func (d *serverDialer) dialSerial(ctx context.Context, network string, addresses []M.Socksaddr) (net.Conn, M.Socksaddr, error) {
+	var errors []error
+	for _, address := range addresses {
+		startAt := time.Now()
+		conn, err := d.dialer.DialContext(ctx, network, address)
+		if err != nil {
+			d.markFailure(ctx, address)
+			errors = append(errors, err)
+			continue
+		}
+		d.markSuccess(address, time.Since(startAt))
+		return conn, address, nil
+	}
+	return nil, M.Socksaddr{}, E.Errors(errors...)
+}
+
+// dialUDP connects to the first address that can be dialed. Connecting a UDP
+// socket says nothing about the server, so the address is only marked once
+// the server replies or fails to.
+func (d *serverDialer) dialUDP(ctx context.Context, network string, addresses []M.Socksaddr) (net.Conn, error) {
+	var errors []error
+	for _, address := range addresses {
+		conn, err := d.dialer.DialContext(ctx, network, address)
+		if err != nil {
+			d.markFailure(ctx, address)
+			errors = append(errors, err)
+			continue
+		}
+		storeRemoteDestination(ctx, address)
+		return &serverUDPConn{Conn: conn, serverReplyMonitor: serverReplyMonitor{dialer: d, address: address}}, nil
+	}
+	return nil, E.Errors(errors...)
+}
+
+// dialParallel races the addresses as described in RFC 8305: a new attempt
+// starts every serverAttemptDelay, or as soon as the previous one fails.
+func (d *serverDialer) dialParallel(ctx context.Context, network string, addresses []M.Socksaddr) (net.Conn, M.Socksaddr, error) {
+	returned := make(chan struct{})
+	defer close(returned)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type dialResult struct {
+		net.Conn
+		error
+		address M.Socksaddr
+		rtt     time.Duration
+	}
+	results := make(chan dialResult) // unbuffered
+	var (
+		next    int
+		pending int
+		errors  []error
+	)
+	startAttempt := func() {
+		address := addresses[next]
+		next++
+		pending++
+		go func() {
+			startAt := time.Now()
+			conn, err := d.dialer.DialContext(ctx, network, address)
+			select {
+			case results <- dialResult{Conn: conn, error: err, address: address, rtt: time.Since(startAt)}:
+			case <-returned:
+				if conn != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+	startAttempt()
+	for {
+		var attemptDelay <-chan time.Time
+		if next < len(addresses) {
+			attemptDelay = time.After(serverAttemptDelay)
+		}
+		select {
+		case <-attemptDelay:
+			startAttempt()
+		case result := <-results:
+			pending--
+			if result.error == nil {
+				d.markSuccess(result.address, result.rtt)
+				return result.Conn, result.address, nil
+			}
+			d.markFailure(ctx, result.address)
+			errors = append(errors, result.error)
+			if next < len(addresses) {
+				startAttempt()
+			} else if pending == 0 {
+				return nil, M.Socksaddr{}, E.Errors(errors...)
+			}
+		}
+	}
+}
+
+func (d *serverDialer) markSuccess(address M.Socksaddr, rtt time.Duration) {
+	d.access.Lock()
+	defer d.access.Unlock()
+	state := d.states[address]
+	if state == nil {
+		state = &serverState{}
+		d.states[address] = state
+	}
+	state.failedAt = time.Time{}
+	if state.rtt == 0 {
+		state.rtt = rtt
+	} else {
+		state.rtt = (state.rtt*7 + rtt*3) / 10
+	}
+}
+
+func (d *serverDialer) markFailure(ctx context.Context, address M.Socksaddr) {
+	if ctx.Err() != nil {
+		// canceled by the caller, not the address's fault
+		return
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	state := d.states[address]
+	if state == nil {
+		state = &serverState{}
+		d.states[address] = state
+	}
+	state.failedAt = time.Now()
+}
+
+// serverReplyMonitor judges a UDP server by its replies: a socket that sent
+// packets but is closed without receiving any counts as a failed attempt,
+// which puts the address into cooldown for the next dial.
+type serverReplyMonitor struct {
+	dialer  *serverDialer
+	address M.Socksaddr
+	sentAt  atomic.Int64
+	replied atomic.Bool
+}
+
+func (m *serverReplyMonitor) onWrite() {
+	m.sentAt.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+func (m *serverReplyMonitor) onRead() {
+	if m.replied.Load() || !m.replied.CompareAndSwap(false, true) {
+		return
+	}
+	var rtt time.Duration
+	if sentAt := m.sentAt.Load(); sentAt != 0 {
+		rtt = time.Since(time.Unix(0, sentAt))
+	}
+	m.dialer.markSuccess(m.address, rtt)
+}
+
+func (m *serverReplyMonitor) onClose() {
+	if m.sentAt.Load() != 0 && !m.replied.Load() {
+		m.dialer.markFailure(context.Background(), m.address)
+	}
+}
+
+type serverUDPConn struct {
+	net.Conn
+	serverReplyMonitor
+}
+
+func (c *serverUDPConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	if err == nil {
+		c.onRead()
+	}
+	return
+}
+
+func (c *serverUDPConn) Write(b []byte) (n int, err error) {
+	c.onWrite()
+	return c.Conn.Write(b)
+}
+
+func (c *serverUDPConn) Close() error {
+	c.onClose()
+	return c.Conn.Close()
+}
+
+func (c *serverUDPConn) Upstream() any {
+	return c.Conn
+}
+
+type serverPacketConn struct {
+	net.PacketConn
+	serverReplyMonitor
+}
+
+func (c *serverPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	n, addr, err = c.PacketConn.ReadFrom(p)
+	if err == nil {
+		c.onRead()
+	}
+	return
+}
+
+func (c *serverPacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	c.onWrite()
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func (c *serverPacketConn) Close() error {
+	c.onClose()
+	return c.PacketConn.Close()
+}
+
+func (c *serverPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+// interleaveAddresses alternates address families starting with IPv6, as
+// recommended by RFC 8305, keeping the configured order within a family.
+func interleaveAddresses(addresses []M.Socksaddr) []M.Socksaddr {
+	addresses6 := common.Filter(addresses, func(it M.Socksaddr) bool {
+		return it.IsIPv6()
+	})
+	others := common.Filter(addresses, func(it M.Socksaddr) bool {
+		return !it.IsIPv6()
+	})
+	interleaved := make([]M.Socksaddr, 0, len(addresses))
+	for i := 0; i < len(addresses6) || i < len(others); i++ {
+		if i < len(addresses6) {
+			interleaved = append(interleaved, addresses6[i])
+		}
+		if i < len(others) {
+			interleaved = append(interleaved, others[i])
+		}
+	}
+	return interleaved
+}
+
+func storeRemoteDestination(ctx context.Context, address M.Socksaddr) {
+	metadata := adapter.ContextFrom(ctx)
+	if metadata != nil && metadata.RemoteDestination != nil {
+		metadata.RemoteDestination.Store(address)
+	}
+}
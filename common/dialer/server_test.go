@@ -0,0 +1,213 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDialerFailover(t *testing.T) {
+	t.Parallel()
+	upstream := &testServerUpstream{failed: map[string]bool{"10.0.0.1:443": true}}
+	dialer := newTestServerDialer(t, upstream, serverStrategyHappyEyeballs)
+
+	conn, err := dialer.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("10.0.0.1:443"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.2:443", conn.RemoteAddr().String())
+	conn.Close()
+
+	// the failed address stays in cooldown
+	upstream.reset()
+	conn, err = dialer.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("10.0.0.1:443"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.2:443", conn.RemoteAddr().String())
+	conn.Close()
+	require.Equal(t, []string{"10.0.0.2:443"}, upstream.dialed())
+
+	// other destinations are not affected
+	conn, err = dialer.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("10.0.0.3:80"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3:80", conn.RemoteAddr().String())
+	conn.Close()
+}
+
+func TestServerDialerUDPCooldown(t *testing.T) {
+	t.Parallel()
+	upstream := &testServerUpstream{}
+	dialer := newTestServerDialer(t, upstream, serverStrategyRandom)
+	destination := M.ParseSocksaddr("10.0.0.1:443")
+
+	conn, err := dialer.DialContext(context.Background(), N.NetworkUDP, destination)
+	require.NoError(t, err)
+	first := conn.RemoteAddr().String()
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	// closed without a reply
+	conn.Close()
+
+	conn, err = dialer.DialContext(context.Background(), N.NetworkUDP, destination)
+	require.NoError(t, err)
+	second := conn.RemoteAddr().String()
+	require.NotEqual(t, first, second)
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 5))
+	require.NoError(t, err)
+	conn.Close()
+
+	for range 3 {
+		conn, err = dialer.DialContext(context.Background(), N.NetworkUDP, destination)
+		require.NoError(t, err)
+		require.Equal(t, second, conn.RemoteAddr().String())
+		conn.Close()
+	}
+}
+
+func TestServerDialerListenPacket(t *testing.T) {
+	t.Parallel()
+	upstream := &testServerUpstream{}
+	dialer := newTestServerDialer(t, upstream, serverStrategyLowestRTT)
+	destination := M.ParseSocksaddr("10.0.0.1:443")
+
+	conn, err := dialer.ListenPacket(context.Background(), destination)
+	require.NoError(t, err)
+	_, err = conn.WriteTo([]byte("hello"), destination.UDPAddr())
+	require.NoError(t, err)
+	conn.Close()
+	require.Equal(t, []string{"10.0.0.1:443"}, upstream.dialed())
+
+	// the address that never replied is tried last, and packets from the
+	// alternative are reported as coming from the configured server
+	upstream.reset()
+	conn, err = dialer.ListenPacket(context.Background(), destination)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, []string{"10.0.0.2:443"}, upstream.dialed())
+	_, err = conn.WriteTo([]byte("hello"), destination.UDPAddr())
+	require.NoError(t, err)
+	_, addr, err := conn.ReadFrom(make([]byte, 5))
+	require.NoError(t, err)
+	require.Equal(t, destination.String(), M.SocksaddrFromNet(addr).String())
+}
+
+func TestServerDialerStrategy(t *testing.T) {
+	t.Parallel()
+	_, err := NewServerWithOptions(Options{Context: context.Background()}, option.ServerOptions{
+		Server:         "10.0.0.1",
+		ServerPort:     443,
+		ServerStrategy: "fastest",
+	})
+	require.ErrorContains(t, err, "unknown server strategy")
+
+	require.Equal(t, []string{"[2001:db8::1]:443", "10.0.0.1:443", "[2001:db8::2]:443", "10.0.0.2:443"}, sockaddrStrings(interleaveAddresses([]M.Socksaddr{
+		M.ParseSocksaddr("10.0.0.1:443"),
+		M.ParseSocksaddr("10.0.0.2:443"),
+		M.ParseSocksaddr("[2001:db8::1]:443"),
+		M.ParseSocksaddr("[2001:db8::2]:443"),
+	})))
+}
+
+func newTestServerDialer(t *testing.T, upstream *testServerUpstream, strategy string) *serverDialer {
+	serverOptions := option.ServerOptions{
+		Server:         "10.0.0.1",
+		ServerPort:     443,
+		Servers:        []string{"10.0.0.2"},
+		ServerStrategy: strategy,
+	}
+	return &serverDialer{
+		dialer:   upstream,
+		server:   serverOptions.Build(),
+		servers:  serverOptions.BuildAll(),
+		strategy: strategy,
+		cooldown: time.Minute,
+		states:   make(map[M.Socksaddr]*serverState),
+	}
+}
+
+func sockaddrStrings(addresses []M.Socksaddr) []string {
+	var result []string
+	for _, address := range addresses {
+		result = append(result, address.String())
+	}
+	return result
+}
+
+// testServerUpstream fails dials to the addresses in failed, and hands out
+// connections that echo what was written to them.
+type testServerUpstream struct {
+	access sync.Mutex
+	failed map[string]bool
+	dials  []string
+}
+
+func (u *testServerUpstream) dialed() []string {
+	u.access.Lock()
+	defer u.access.Unlock()
+	return u.dials
+}
+
+func (u *testServerUpstream) reset() {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.dials = nil
+}
+
+func (u *testServerUpstream) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.dials = append(u.dials, destination.String())
+	if u.failed[destination.String()] {
+		return nil, E.New("connection refused")
+	}
+	return &testEchoConn{remote: destination}, nil
+}
+
+func (u *testServerUpstream) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.dials = append(u.dials, destination.String())
+	return &testEchoConn{remote: destination}, nil
+}
+
+type testEchoConn struct {
+	net.Conn
+	remote  M.Socksaddr
+	pending []byte
+}
+
+func (c *testEchoConn) Read(b []byte) (int, error) {
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *testEchoConn) Write(b []byte) (int, error) {
+	c.pending = append(c.pending, b...)
+	return len(b), nil
+}
+
+func (c *testEchoConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, err := c.Read(p)
+	return n, c.remote.UDPAddr(), err
+}
+
+func (c *testEchoConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return c.Write(p)
+}
+
+func (c *testEchoConn) RemoteAddr() net.Addr {
+	return c.remote.TCPAddr()
+}
+
+func (c *testEchoConn) Close() error {
+	return nil
+}
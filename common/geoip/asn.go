@@ -0,0 +1,93 @@
+package geoip
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go4.org/netipx"
+)
+
+// ASNReader reads autonomous system numbers from a MaxMind GeoLite2-ASN or
+// IPinfo ASN database.
+type ASNReader struct {
+	reader *maxminddb.Reader
+}
+
+// asnRecord covers both the MaxMind (numeric) and IPinfo ("AS13335") layouts.
+type asnRecord struct {
+	AutonomousSystemNumber uint32 `maxminddb:"autonomous_system_number"`
+	ASN                    string `maxminddb:"asn"`
+}
+
+func (r asnRecord) Number() uint32 {
+	if r.AutonomousSystemNumber != 0 {
+		return r.AutonomousSystemNumber
+	}
+	if r.ASN != "" {
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(r.ASN), "AS"), 10, 32)
+		if err == nil {
+			return uint32(asn)
+		}
+	}
+	return 0
+}
+
+func OpenASN(path string) (*ASNReader, error) {
+	database, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if database.Metadata.DatabaseType == "sing-geoip" {
+		database.Close()
+		return nil, E.New("incorrect database type, expected an ASN database, got sing-geoip")
+	}
+	return &ASNReader{database}, nil
+}
+
+func (r *ASNReader) Lookup(addr netip.Addr) (uint32, bool) {
+	var record asnRecord
+	err := r.reader.Lookup(addr.AsSlice(), &record)
+	if err != nil {
+		return 0, false
+	}
+	asn := record.Number()
+	return asn, asn != 0
+}
+
+// IPSet collects all networks announced by the given autonomous systems.
+func (r *ASNReader) IPSet(asnList []uint32) (*netipx.IPSet, error) {
+	asnMap := make(map[uint32]bool, len(asnList))
+	for _, asn := range asnList {
+		asnMap[asn] = true
+	}
+	var builder netipx.IPSetBuilder
+	networks := r.reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var record asnRecord
+		ipNet, err := networks.Network(&record)
+		if err != nil {
+			return nil, err
+		}
+		if !asnMap[record.Number()] {
+			continue
+		}
+		prefix, loaded := netipx.FromStdIPNet(ipNet)
+		if !loaded {
+			return nil, E.New("invalid network: ", ipNet)
+		}
+		builder.AddPrefix(prefix)
+	}
+	err := networks.Err()
+	if err != nil {
+		return nil, err
+	}
+	return builder.IPSet()
+}
+
+func (r *ASNReader) Close() error {
+	return r.reader.Close()
+}
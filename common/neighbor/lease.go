@@ -0,0 +1,101 @@
+package neighbor
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+type leaseTable struct {
+	hostnameByMAC     map[string]string
+	hostnameByAddress map[netip.Addr]string
+	macByAddress      map[netip.Addr]net.HardwareAddr
+}
+
+func newLeaseTable() *leaseTable {
+	return &leaseTable{
+		hostnameByMAC:     make(map[string]string),
+		hostnameByAddress: make(map[netip.Addr]string),
+		macByAddress:      make(map[netip.Addr]net.HardwareAddr),
+	}
+}
+
+func (t *leaseTable) add(address netip.Addr, mac net.HardwareAddr, hostname string) {
+	if hostname == "*" {
+		hostname = ""
+	}
+	if address.IsValid() {
+		address = address.Unmap()
+		if mac != nil {
+			t.macByAddress[address] = mac
+		}
+		if hostname != "" {
+			t.hostnameByAddress[address] = hostname
+		}
+	}
+	if mac != nil && hostname != "" {
+		t.hostnameByMAC[mac.String()] = hostname
+	}
+}
+
+// parseLeases reads dnsmasq and odhcpd lease files, ISC dhcpd.leases files
+// and /etc/ethers style "MAC hostname" mapping files.
+func parseLeases(table *leaseTable, reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	var (
+		inLease      bool
+		leaseAddress netip.Addr
+		leaseMAC     net.HardwareAddr
+		leaseName    string
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if inLease {
+			switch {
+			case line == "}":
+				table.add(leaseAddress, leaseMAC, leaseName)
+				inLease = false
+			case len(fields) == 3 && fields[0] == "hardware":
+				leaseMAC, _ = net.ParseMAC(strings.TrimSuffix(fields[2], ";"))
+			case len(fields) >= 2 && fields[0] == "client-hostname":
+				leaseName = strings.Trim(strings.TrimSuffix(strings.Join(fields[1:], " "), ";"), "\"")
+			}
+			continue
+		}
+		switch {
+		case len(fields) >= 2 && fields[0] == "lease":
+			inLease = true
+			leaseAddress, _ = netip.ParseAddr(fields[1])
+			leaseMAC = nil
+			leaseName = ""
+		case len(fields) >= 4 && isNumber(fields[0]):
+			// dnsmasq: <expiry> <mac> <address> <hostname> <client id>
+			mac, _ := net.ParseMAC(fields[1])
+			address, _ := netip.ParseAddr(fields[2])
+			table.add(address, mac, fields[3])
+		case len(fields) == 2:
+			mac, err := net.ParseMAC(fields[0])
+			if err != nil {
+				continue
+			}
+			if address, err := netip.ParseAddr(fields[1]); err == nil {
+				table.add(address, mac, "")
+			} else {
+				table.add(netip.Addr{}, mac, fields[1])
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err == nil
+}
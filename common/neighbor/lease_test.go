@@ -0,0 +1,34 @@
+package neighbor
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLeases(t *testing.T) {
+	t.Parallel()
+	table := newLeaseTable()
+	err := parseLeases(table, strings.NewReader(`
+1735689600 aa:bb:cc:dd:ee:01 192.168.1.10 tablet-a 01:aa:bb:cc:dd:ee:01
+1735689600 aa:bb:cc:dd:ee:02 192.168.1.11 * *
+duid 00:01:00:01:2c:5e:4b:9f:aa:bb:cc:dd:ee:ff
+lease 192.168.1.12 {
+  starts 3 2025/01/01 00:00:00;
+  hardware ethernet aa:bb:cc:dd:ee:03;
+  client-hostname "tablet-b";
+}
+AA-BB-CC-DD-EE-04 printer
+aa:bb:cc:dd:ee:05 192.168.1.14
+`))
+	require.NoError(t, err)
+	require.Equal(t, "tablet-a", table.hostnameByAddress[netip.MustParseAddr("192.168.1.10")])
+	require.Equal(t, "tablet-a", table.hostnameByMAC["aa:bb:cc:dd:ee:01"])
+	require.NotContains(t, table.hostnameByAddress, netip.MustParseAddr("192.168.1.11"))
+	require.Equal(t, "aa:bb:cc:dd:ee:02", table.macByAddress[netip.MustParseAddr("192.168.1.11")].String())
+	require.Equal(t, "tablet-b", table.hostnameByMAC["aa:bb:cc:dd:ee:03"])
+	require.Equal(t, "printer", table.hostnameByMAC["aa:bb:cc:dd:ee:04"])
+	require.Equal(t, "aa:bb:cc:dd:ee:05", table.macByAddress[netip.MustParseAddr("192.168.1.14")].String())
+}
@@ -0,0 +1,161 @@
+package neighbor
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/fswatch"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// minRefreshInterval limits how often lookups re-read the kernel
+// neighbour table.
+const minRefreshInterval = time.Second
+
+// Resolver maps LAN source addresses to MAC addresses using the kernel
+// neighbour table, and to hostnames using DHCP lease or mapping files and
+// the leases of the built-in DHCP server.
+type Resolver struct {
+	logger     logger.Logger
+	leaseFiles []string
+	providers  []adapter.DHCPLeaseProvider
+	watcher    *fswatch.Watcher
+	readTable  func() (map[netip.Addr]net.HardwareAddr, error)
+
+	access      sync.RWMutex
+	leases      *leaseTable
+	neighbors   map[netip.Addr]net.HardwareAddr
+	lastRefresh time.Time
+}
+
+func NewResolver(logger logger.Logger, leaseFiles []string, providers []adapter.DHCPLeaseProvider) *Resolver {
+	return &Resolver{
+		logger:     logger,
+		leaseFiles: leaseFiles,
+		providers:  providers,
+		leases:     newLeaseTable(),
+		readTable:  readNeighbors,
+	}
+}
+
+func (r *Resolver) Start() error {
+	r.reloadLeases()
+	if len(r.leaseFiles) > 0 {
+		watcher, err := fswatch.NewWatcher(fswatch.Options{
+			Path: r.leaseFiles,
+			Callback: func(path string) {
+				r.reloadLeases()
+			},
+		})
+		if err != nil {
+			return E.Cause(err, "watch lease files")
+		}
+		err = watcher.Start()
+		if err != nil {
+			return E.Cause(err, "watch lease files")
+		}
+		r.watcher = watcher
+	}
+	return nil
+}
+
+func (r *Resolver) Close() error {
+	return common.Close(common.PtrOrNil(r.watcher))
+}
+
+func (r *Resolver) reloadLeases() {
+	table := newLeaseTable()
+	for _, path := range r.leaseFiles {
+		file, err := os.Open(path)
+		if err != nil {
+			r.logger.Warn(E.Cause(err, "read lease file ", path))
+			continue
+		}
+		err = parseLeases(table, file)
+		file.Close()
+		if err != nil {
+			r.logger.Warn(E.Cause(err, "parse lease file ", path))
+		}
+	}
+	r.access.Lock()
+	r.leases = table
+	r.access.Unlock()
+}
+
+// LookupMAC returns the hardware address of a directly connected client.
+// The neighbour table is refreshed on hits too, since DHCP may have moved
+// the address to another device.
+func (r *Resolver) LookupMAC(address netip.Addr) (net.HardwareAddr, bool) {
+	address = address.Unmap()
+	r.access.RLock()
+	neighbors := r.neighbors
+	stale := time.Since(r.lastRefresh) >= minRefreshInterval
+	r.access.RUnlock()
+	if stale {
+		newNeighbors, err := r.readTable()
+		r.access.Lock()
+		r.lastRefresh = time.Now()
+		if err == nil {
+			r.neighbors = newNeighbors
+			neighbors = newNeighbors
+		}
+		r.access.Unlock()
+	}
+	mac, loaded := neighbors[address]
+	if loaded {
+		return mac, true
+	}
+	lease, loaded := r.lookupLease(func(lease adapter.DHCPLease) bool {
+		return lease.Address == address
+	})
+	if loaded {
+		return lease.MAC, true
+	}
+	r.access.RLock()
+	defer r.access.RUnlock()
+	mac, loaded = r.leases.macByAddress[address]
+	return mac, loaded
+}
+
+// LookupHostname returns the hostname a client registered with DHCP, or the
+// name assigned to its MAC address in a mapping file.
+func (r *Resolver) LookupHostname(address netip.Addr, mac net.HardwareAddr) (string, bool) {
+	address = address.Unmap()
+	lease, loaded := r.lookupLease(func(lease adapter.DHCPLease) bool {
+		if mac != nil {
+			return bytes.Equal(lease.MAC, mac)
+		}
+		return lease.Address == address
+	})
+	if loaded && lease.Hostname != "" {
+		return lease.Hostname, true
+	}
+	r.access.RLock()
+	defer r.access.RUnlock()
+	if mac != nil {
+		hostname, loaded := r.leases.hostnameByMAC[mac.String()]
+		if loaded {
+			return hostname, true
+		}
+	}
+	hostname, loaded := r.leases.hostnameByAddress[address]
+	return hostname, loaded
+}
+
+func (r *Resolver) lookupLease(match func(lease adapter.DHCPLease) bool) (adapter.DHCPLease, bool) {
+	for _, provider := range r.providers {
+		for _, lease := range provider.Leases() {
+			if match(lease) {
+				return lease, true
+			}
+		}
+	}
+	return adapter.DHCPLease{}, false
+}
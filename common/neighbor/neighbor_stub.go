@@ -0,0 +1,13 @@
+//go:build !linux
+
+package neighbor
+
+import (
+	"net"
+	"net/netip"
+	"os"
+)
+
+func readNeighbors() (map[netip.Addr]net.HardwareAddr, error) {
+	return nil, os.ErrInvalid
+}
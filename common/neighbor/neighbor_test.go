@@ -0,0 +1,107 @@
+package neighbor
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+	leaseFile := filepath.Join(t.TempDir(), "dnsmasq.leases")
+	require.NoError(t, os.WriteFile(leaseFile, []byte(`
+1735689600 aa:bb:cc:dd:ee:01 192.0.2.10 tablet-a *
+aa:bb:cc:dd:ee:02 kids-phone
+`), 0o644))
+	provider := &testLeaseProvider{leases: []adapter.DHCPLease{{
+		Address: netip.MustParseAddr("192.0.2.20"),
+		MAC:     mustParseMAC("aa:bb:cc:dd:ee:02"),
+	}, {
+		Address:  netip.MustParseAddr("192.0.2.30"),
+		MAC:      mustParseMAC("aa:bb:cc:dd:ee:03"),
+		Hostname: "laptop",
+	}}}
+	resolver := NewResolver(logger.NOP(), []string{leaseFile}, []adapter.DHCPLeaseProvider{provider})
+	require.NoError(t, resolver.Start())
+	defer resolver.Close()
+
+	mac, loaded := resolver.LookupMAC(netip.MustParseAddr("::ffff:192.0.2.10"))
+	require.True(t, loaded)
+	require.Equal(t, "aa:bb:cc:dd:ee:01", mac.String())
+	hostname, loaded := resolver.LookupHostname(netip.MustParseAddr("192.0.2.10"), mac)
+	require.True(t, loaded)
+	require.Equal(t, "tablet-a", hostname)
+
+	// leases of the built-in server resolve the MAC address, and a lease
+	// without a hostname falls back to the mapping file
+	mac, loaded = resolver.LookupMAC(netip.MustParseAddr("192.0.2.20"))
+	require.True(t, loaded)
+	require.Equal(t, "aa:bb:cc:dd:ee:02", mac.String())
+	hostname, loaded = resolver.LookupHostname(netip.MustParseAddr("192.0.2.20"), mac)
+	require.True(t, loaded)
+	require.Equal(t, "kids-phone", hostname)
+	hostname, loaded = resolver.LookupHostname(netip.MustParseAddr("192.0.2.30"), nil)
+	require.True(t, loaded)
+	require.Equal(t, "laptop", hostname)
+
+	_, loaded = resolver.LookupMAC(netip.MustParseAddr("192.0.2.40"))
+	require.False(t, loaded)
+	_, loaded = resolver.LookupHostname(netip.MustParseAddr("192.0.2.40"), nil)
+	require.False(t, loaded)
+
+	// lease files are reloaded when they change
+	require.NoError(t, os.WriteFile(leaseFile, []byte("1735689600 aa:bb:cc:dd:ee:04 192.0.2.40 tablet-b *\n"), 0o644))
+	require.Eventually(t, func() bool {
+		hostname, _ = resolver.LookupHostname(netip.MustParseAddr("192.0.2.40"), nil)
+		return hostname == "tablet-b"
+	}, 5*time.Second, 10*time.Millisecond)
+	_, loaded = resolver.LookupHostname(netip.MustParseAddr("192.0.2.10"), nil)
+	require.False(t, loaded)
+}
+
+func TestResolverRefreshNeighbors(t *testing.T) {
+	t.Parallel()
+	address := netip.MustParseAddr("192.0.2.10")
+	table := map[netip.Addr]net.HardwareAddr{address: mustParseMAC("aa:bb:cc:dd:ee:01")}
+	resolver := NewResolver(logger.NOP(), nil, nil)
+	resolver.readTable = func() (map[netip.Addr]net.HardwareAddr, error) {
+		return table, nil
+	}
+	mac, loaded := resolver.LookupMAC(address)
+	require.True(t, loaded)
+	require.Equal(t, "aa:bb:cc:dd:ee:01", mac.String())
+
+	// a hit on a stale table sees the address moved to another device
+	table = map[netip.Addr]net.HardwareAddr{address: mustParseMAC("aa:bb:cc:dd:ee:02")}
+	mac, _ = resolver.LookupMAC(address)
+	require.Equal(t, "aa:bb:cc:dd:ee:01", mac.String())
+	resolver.lastRefresh = time.Now().Add(-minRefreshInterval)
+	mac, loaded = resolver.LookupMAC(address)
+	require.True(t, loaded)
+	require.Equal(t, "aa:bb:cc:dd:ee:02", mac.String())
+}
+
+func mustParseMAC(address string) net.HardwareAddr {
+	mac, err := net.ParseMAC(address)
+	if err != nil {
+		panic(err)
+	}
+	return mac
+}
+
+type testLeaseProvider struct {
+	adapter.DHCPLeaseProvider
+	leases []adapter.DHCPLease
+}
+
+func (p *testLeaseProvider) Leases() []adapter.DHCPLease {
+	return p.leases
+}
@@ -30,6 +30,24 @@ type Info struct {
 	UserId      int32
 }
 
+// StatisticsName returns the name that traffic of the process is accounted
+// to: its package name, path or user ID, or empty when all are unknown.
+func StatisticsName(info *Info) string {
+	if info == nil {
+		return ""
+	}
+	if info.PackageName != "" {
+		return info.PackageName
+	}
+	if info.ProcessPath != "" {
+		return info.ProcessPath
+	}
+	if info.UserId != -1 {
+		return F.ToString("uid:", info.UserId)
+	}
+	return ""
+}
+
 func FindProcessInfo(searcher Searcher, ctx context.Context, network string, source netip.AddrPort, destination netip.AddrPort) (*Info, error) {
 	info, err := searcher.FindProcessInfo(ctx, network, source, destination)
 	if err != nil {
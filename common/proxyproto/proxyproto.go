@@ -0,0 +1,192 @@
+// Package proxyproto implements the header of the HAProxy PROXY protocol,
+// versions 1 and 2, for stream connections.
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const (
+	Version1 = 1
+	Version2 = 2
+
+	maxV1HeaderLen = 107
+
+	commandLocal = 0x0
+	commandProxy = 0x1
+
+	familyUnspec = 0x00
+	familyTCP4   = 0x11
+	familyTCP6   = 0x21
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+// Header holds the addresses carried by a PROXY protocol header. Source and
+// Destination are invalid for LOCAL (v2) and UNKNOWN (v1) headers.
+type Header struct {
+	Version     uint8
+	Source      M.Socksaddr
+	Destination M.Socksaddr
+}
+
+// ReadHeader reads a version 1 or 2 header without consuming any payload.
+func ReadHeader(reader io.Reader) (*Header, error) {
+	prefix := make([]byte, len(v2Signature))
+	_, err := io.ReadFull(reader, prefix[:len(v1Prefix)])
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(prefix[:len(v1Prefix)], v1Prefix) {
+		return readV1(reader)
+	}
+	_, err = io.ReadFull(reader, prefix[len(v1Prefix):])
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix, v2Signature) {
+		return nil, E.New("missing PROXY protocol header")
+	}
+	return readV2(reader)
+}
+
+func readV1(reader io.Reader) (*Header, error) {
+	line := make([]byte, 0, maxV1HeaderLen-len(v1Prefix))
+	var current [1]byte
+	for {
+		_, err := io.ReadFull(reader, current[:])
+		if err != nil {
+			return nil, err
+		}
+		if current[0] == '\n' && len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+			break
+		}
+		line = append(line, current[0])
+		if len(line) > maxV1HeaderLen-len(v1Prefix)-2 {
+			return nil, E.New("PROXY protocol v1 header too long")
+		}
+	}
+	fields := strings.Split(string(line), " ")
+	header := &Header{Version: Version1}
+	if fields[0] == "UNKNOWN" {
+		return header, nil
+	}
+	if len(fields) != 5 || (fields[0] != "TCP4" && fields[0] != "TCP6") {
+		return nil, E.New("invalid PROXY protocol v1 header: ", string(line))
+	}
+	sourceAddr, err := netip.ParseAddr(fields[1])
+	if err != nil {
+		return nil, E.Cause(err, "parse source address")
+	}
+	destinationAddr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, E.Cause(err, "parse destination address")
+	}
+	sourcePort, err := strconv.ParseUint(fields[3], 10, 16)
+	if err != nil {
+		return nil, E.Cause(err, "parse source port")
+	}
+	destinationPort, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, E.Cause(err, "parse destination port")
+	}
+	header.Source = M.SocksaddrFrom(sourceAddr, uint16(sourcePort))
+	header.Destination = M.SocksaddrFrom(destinationAddr, uint16(destinationPort))
+	return header, nil
+}
+
+func readV2(reader io.Reader) (*Header, error) {
+	var fixed [4]byte
+	_, err := io.ReadFull(reader, fixed[:])
+	if err != nil {
+		return nil, err
+	}
+	if fixed[0]>>4 != Version2 {
+		return nil, E.New("unknown PROXY protocol version: ", fixed[0]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[2:]))
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return nil, err
+	}
+	header := &Header{Version: Version2}
+	switch fixed[0] & 0x0F {
+	case commandLocal:
+		return header, nil
+	case commandProxy:
+	default:
+		return nil, E.New("unknown PROXY protocol command: ", fixed[0]&0x0F)
+	}
+	switch fixed[1] {
+	case familyTCP4:
+		if len(payload) < 12 {
+			return nil, E.New("invalid PROXY protocol v2 address length")
+		}
+		header.Source = M.SocksaddrFrom(netip.AddrFrom4([4]byte(payload[0:4])), binary.BigEndian.Uint16(payload[8:]))
+		header.Destination = M.SocksaddrFrom(netip.AddrFrom4([4]byte(payload[4:8])), binary.BigEndian.Uint16(payload[10:]))
+	case familyTCP6:
+		if len(payload) < 36 {
+			return nil, E.New("invalid PROXY protocol v2 address length")
+		}
+		header.Source = M.SocksaddrFrom(netip.AddrFrom16([16]byte(payload[0:16])), binary.BigEndian.Uint16(payload[32:]))
+		header.Destination = M.SocksaddrFrom(netip.AddrFrom16([16]byte(payload[16:32])), binary.BigEndian.Uint16(payload[34:]))
+	default:
+		// other families carry no addresses we can use
+	}
+	return header, nil
+}
+
+// WriteHeader appends a header for a TCP connection from source to
+// destination. An UNKNOWN (v1) or LOCAL (v2) header is written if the
+// addresses are not IP addresses of the same family.
+func WriteHeader(buffer *buf.Buffer, version uint8, source M.Socksaddr, destination M.Socksaddr) error {
+	source = source.Unwrap()
+	destination = destination.Unwrap()
+	known := source.IsIP() && destination.IsIP() && source.Addr.Is4() == destination.Addr.Is4()
+	switch version {
+	case Version1:
+		if !known {
+			_, err := buffer.WriteString("PROXY UNKNOWN\r\n")
+			return err
+		}
+		family := "TCP4"
+		if source.Addr.Is6() {
+			family = "TCP6"
+		}
+		_, err := buffer.WriteString("PROXY " + family + " " + source.Addr.String() + " " + destination.Addr.String() + " " +
+			strconv.Itoa(int(source.Port)) + " " + strconv.Itoa(int(destination.Port)) + "\r\n")
+		return err
+	case Version2:
+		buffer.Write(v2Signature)
+		if !known {
+			buffer.Write([]byte{Version2<<4 | commandLocal, familyUnspec, 0, 0})
+			return nil
+		}
+		family := byte(familyTCP4)
+		if source.Addr.Is6() {
+			family = familyTCP6
+		}
+		addresses := append(source.Addr.AsSlice(), destination.Addr.AsSlice()...)
+		addresses = binary.BigEndian.AppendUint16(addresses, source.Port)
+		addresses = binary.BigEndian.AppendUint16(addresses, destination.Port)
+		buffer.Write([]byte{Version2<<4 | commandProxy, family})
+		binary.Write(buffer, binary.BigEndian, uint16(len(addresses)))
+		_, err := buffer.Write(addresses)
+		return err
+	default:
+		return E.New("unknown PROXY protocol version: ", version)
+	}
+}
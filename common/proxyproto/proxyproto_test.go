@@ -0,0 +1,52 @@
+package proxyproto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/proxyproto"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeader(t *testing.T) {
+	t.Parallel()
+	for _, version := range []uint8{proxyproto.Version1, proxyproto.Version2} {
+		for _, addresses := range [][2]M.Socksaddr{
+			{M.ParseSocksaddr("192.168.1.2:12345"), M.ParseSocksaddr("10.0.0.1:443")},
+			{M.ParseSocksaddr("[2001:db8::1]:12345"), M.ParseSocksaddr("[2001:db8::2]:443")},
+		} {
+			buffer := buf.New()
+			require.NoError(t, proxyproto.WriteHeader(buffer, version, addresses[0], addresses[1]))
+			buffer.WriteString("payload")
+			reader := bytes.NewReader(buffer.Bytes())
+			buffer.Release()
+			header, err := proxyproto.ReadHeader(reader)
+			require.NoError(t, err)
+			require.Equal(t, version, header.Version)
+			require.Equal(t, addresses[0], header.Source)
+			require.Equal(t, addresses[1], header.Destination)
+			require.Equal(t, reader.Len(), len("payload"))
+		}
+	}
+}
+
+func TestHeaderUnknown(t *testing.T) {
+	t.Parallel()
+	for _, version := range []uint8{proxyproto.Version1, proxyproto.Version2} {
+		buffer := buf.New()
+		require.NoError(t, proxyproto.WriteHeader(buffer, version, M.ParseSocksaddr("example.com:80"), M.ParseSocksaddr("10.0.0.1:443")))
+		header, err := proxyproto.ReadHeader(bytes.NewReader(buffer.Bytes()))
+		buffer.Release()
+		require.NoError(t, err)
+		require.False(t, header.Source.IsValid())
+	}
+}
+
+func TestHeaderMissing(t *testing.T) {
+	t.Parallel()
+	_, err := proxyproto.ReadHeader(bytes.NewReader([]byte("GET / HTTP/1.1\r\n\r\n")))
+	require.Error(t, err)
+}
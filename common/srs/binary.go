@@ -41,6 +41,9 @@ const (
 	ruleItemNetworkType
 	ruleItemNetworkIsExpensive
 	ruleItemNetworkIsConstrained
+	ruleItemSourceMACAddress
+	ruleItemSourceHostname
+	ruleItemASN
 	ruleItemFinal uint8 = 0xFF
 )
 
@@ -194,6 +197,16 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 			if recover {
 				rule.IPCIDR = common.Map(rule.IPSet.Prefixes(), netip.Prefix.String)
 			}
+		case ruleItemASN:
+			var asnList []uint32
+			asnList, err = varbin.ReadValue[[]uint32](reader, binary.BigEndian)
+			if err != nil {
+				return
+			}
+			rule.ASN = common.Map(asnList, func(it uint32) option.ASN {
+				return option.ASN(it)
+			})
+			rule.ASNIPSet, err = readIPSet(reader)
 		case ruleItemSourcePort:
 			rule.SourcePort, err = readRuleItemUint16(reader)
 		case ruleItemSourcePortRange:
@@ -230,6 +243,10 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 			rule.NetworkIsExpensive = true
 		case ruleItemNetworkIsConstrained:
 			rule.NetworkIsConstrained = true
+		case ruleItemSourceMACAddress:
+			rule.SourceMACAddress, err = readRuleItemString(reader)
+		case ruleItemSourceHostname:
+			rule.SourceHostname, err = readRuleItemString(reader)
 		case ruleItemFinal:
 			err = binary.Read(reader, binary.BigEndian, &rule.Invert)
 			return
@@ -365,6 +382,46 @@ func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule, gen
 			return err
 		}
 	}
+	if len(rule.ASN) > 0 {
+		if generateVersion < C.RuleSetVersion4 {
+			return E.New("asn rule item is only supported in version 4 or later")
+		}
+		if rule.ASNIPSet == nil {
+			return E.New("asn rule item requires an ASN database to compile")
+		}
+		err = writer.WriteByte(ruleItemASN)
+		if err != nil {
+			return err
+		}
+		err = varbin.Write(writer, binary.BigEndian, common.Map(rule.ASN, func(it option.ASN) uint32 {
+			return uint32(it)
+		}))
+		if err != nil {
+			return err
+		}
+		err = writeIPSet(writer, rule.ASNIPSet)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rule.SourceMACAddress) > 0 {
+		if generateVersion < C.RuleSetVersion4 {
+			return E.New("source_mac_address rule item is only supported in version 4 or later")
+		}
+		err = writeRuleItemString(writer, ruleItemSourceMACAddress, rule.SourceMACAddress)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rule.SourceHostname) > 0 {
+		if generateVersion < C.RuleSetVersion4 {
+			return E.New("source_hostname rule item is only supported in version 4 or later")
+		}
+		err = writeRuleItemString(writer, ruleItemSourceHostname, rule.SourceHostname)
+		if err != nil {
+			return err
+		}
+	}
 	if len(rule.WIFISSID) > 0 {
 		err = writeRuleItemString(writer, ruleItemWIFISSID, rule.WIFISSID)
 		if err != nil {
@@ -0,0 +1,65 @@
+package srs_test
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+	"go4.org/netipx"
+)
+
+func TestASNRoundTrip(t *testing.T) {
+	t.Parallel()
+	var builder netipx.IPSetBuilder
+	builder.AddPrefix(netip.MustParsePrefix("104.16.0.0/13"))
+	builder.AddPrefix(netip.MustParsePrefix("2606:4700::/32"))
+	ipSet, err := builder.IPSet()
+	require.NoError(t, err)
+	rule := option.HeadlessRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultHeadlessRule{
+			ASN:      []option.ASN{13335, 209242},
+			ASNIPSet: ipSet,
+		},
+	}
+	var buffer bytes.Buffer
+	require.NoError(t, srs.Write(&buffer, option.PlainRuleSet{Rules: []option.HeadlessRule{rule}}, C.RuleSetVersion4))
+	ruleSet, err := srs.Read(bytes.NewReader(buffer.Bytes()), false)
+	require.NoError(t, err)
+	require.Equal(t, uint8(C.RuleSetVersion4), ruleSet.Version)
+	require.Len(t, ruleSet.Options.Rules, 1)
+	readRule := ruleSet.Options.Rules[0].DefaultOptions
+	require.Equal(t, []option.ASN{13335, 209242}, []option.ASN(readRule.ASN))
+	require.Equal(t, ipSet.Prefixes(), readRule.ASNIPSet.Prefixes())
+
+	// asn items can only be compiled once expanded, and need version 4
+	require.ErrorContains(t, srs.Write(&bytes.Buffer{}, option.PlainRuleSet{Rules: []option.HeadlessRule{rule}}, C.RuleSetVersion3), "version 4")
+	rule.DefaultOptions.ASNIPSet = nil
+	require.ErrorContains(t, srs.Write(&bytes.Buffer{}, option.PlainRuleSet{Rules: []option.HeadlessRule{rule}}, C.RuleSetVersion4), "ASN database")
+}
+
+func TestNeighborRoundTrip(t *testing.T) {
+	t.Parallel()
+	rule := option.HeadlessRule{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultHeadlessRule{
+			SourceMACAddress: []string{"aa:bb:cc:dd:ee:01"},
+			SourceHostname:   []string{"kids-tablet", "kids-phone"},
+		},
+	}
+	var buffer bytes.Buffer
+	require.NoError(t, srs.Write(&buffer, option.PlainRuleSet{Rules: []option.HeadlessRule{rule}}, C.RuleSetVersion4))
+	ruleSet, err := srs.Read(bytes.NewReader(buffer.Bytes()), false)
+	require.NoError(t, err)
+	require.Len(t, ruleSet.Options.Rules, 1)
+	readRule := ruleSet.Options.Rules[0].DefaultOptions
+	require.Equal(t, []string{"aa:bb:cc:dd:ee:01"}, []string(readRule.SourceMACAddress))
+	require.Equal(t, []string{"kids-tablet", "kids-phone"}, []string(readRule.SourceHostname))
+
+	require.ErrorContains(t, srs.Write(&bytes.Buffer{}, option.PlainRuleSet{Rules: []option.HeadlessRule{rule}}, C.RuleSetVersion3), "version 4")
+}
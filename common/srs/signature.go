@@ -0,0 +1,222 @@
+package srs
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Keys and detached signatures use the minisign formats, so public keys and
+// signatures can be exchanged with minisign. Private keys are stored
+// unencrypted in the same layout as public keys.
+
+var (
+	signatureAlgorithm          = [2]byte{'E', 'd'}
+	signatureAlgorithmPrehashed = [2]byte{'E', 'D'}
+
+	// SignatureMagic ends a binary rule-set with an embedded signature. The
+	// signature follows the compressed rules, which older readers ignore.
+	SignatureMagic = [4]byte{0x53, 0x52, 0x53, 0x53} // SRSS
+)
+
+const (
+	keyIDLength             = 8
+	embeddedSignatureLength = keyIDLength + ed25519.SignatureSize + len(SignatureMagic)
+	trustedCommentPrefix    = "trusted comment: "
+	untrustedCommentPrefix  = "untrusted comment: "
+)
+
+type PublicKey struct {
+	ID  [keyIDLength]byte
+	Key ed25519.PublicKey
+}
+
+type PrivateKey struct {
+	ID  [keyIDLength]byte
+	Key ed25519.PrivateKey
+}
+
+func GenerateKey() (*PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := &PrivateKey{Key: key}
+	_, err = rand.Read(privateKey.ID[:])
+	if err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// ParsePublicKey parses a minisign public key, with or without its comment
+// line, or a bare base64 encoded ed25519 public key.
+func ParsePublicKey(content string) (*PublicKey, error) {
+	keyBytes, err := decodeKeyLine(content)
+	if err != nil {
+		return nil, E.Cause(err, "decode public key")
+	}
+	var publicKey PublicKey
+	switch len(keyBytes) {
+	case ed25519.PublicKeySize:
+		publicKey.Key = keyBytes
+	case len(signatureAlgorithm) + keyIDLength + ed25519.PublicKeySize:
+		if [2]byte(keyBytes) != signatureAlgorithm {
+			return nil, E.New("unsupported public key algorithm: ", string(keyBytes[:2]))
+		}
+		copy(publicKey.ID[:], keyBytes[2:])
+		publicKey.Key = keyBytes[2+keyIDLength:]
+	default:
+		return nil, E.New("invalid public key length: ", len(keyBytes))
+	}
+	return &publicKey, nil
+}
+
+func (k *PublicKey) String() string {
+	return base64.StdEncoding.EncodeToString(bytes.Join([][]byte{signatureAlgorithm[:], k.ID[:], k.Key}, nil))
+}
+
+func ParsePrivateKey(content string) (*PrivateKey, error) {
+	keyBytes, err := decodeKeyLine(content)
+	if err != nil {
+		return nil, E.Cause(err, "decode private key")
+	}
+	if len(keyBytes) != len(signatureAlgorithm)+keyIDLength+ed25519.PrivateKeySize || [2]byte(keyBytes) != signatureAlgorithm {
+		return nil, E.New("invalid private key")
+	}
+	var privateKey PrivateKey
+	copy(privateKey.ID[:], keyBytes[2:])
+	privateKey.Key = keyBytes[2+keyIDLength:]
+	return &privateKey, nil
+}
+
+func (k *PrivateKey) String() string {
+	return base64.StdEncoding.EncodeToString(bytes.Join([][]byte{signatureAlgorithm[:], k.ID[:], k.Key}, nil))
+}
+
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{
+		ID:  k.ID,
+		Key: k.Key.Public().(ed25519.PublicKey),
+	}
+}
+
+// Sign returns the binary rule-set with an embedded signature, replacing
+// any existing one.
+func Sign(content []byte, privateKey *PrivateKey) []byte {
+	content, _ = SplitSignature(content)
+	signed := make([]byte, 0, len(content)+embeddedSignatureLength)
+	signed = append(signed, content...)
+	signed = append(signed, privateKey.ID[:]...)
+	signed = append(signed, ed25519.Sign(privateKey.Key, content)...)
+	return append(signed, SignatureMagic[:]...)
+}
+
+// SplitSignature separates the embedded signature from a binary rule-set,
+// it returns a nil signature if there is none.
+func SplitSignature(content []byte) ([]byte, []byte) {
+	if len(content) < embeddedSignatureLength || [4]byte(content[len(content)-len(SignatureMagic):]) != SignatureMagic {
+		return content, nil
+	}
+	split := len(content) - embeddedSignatureLength
+	return content[:split], content[split : len(content)-len(SignatureMagic)]
+}
+
+// Verify checks the embedded signature of a binary rule-set.
+func Verify(content []byte, publicKeys []*PublicKey) error {
+	content, signature := SplitSignature(content)
+	if signature == nil {
+		return E.New("missing embedded signature")
+	}
+	var keyID [keyIDLength]byte
+	copy(keyID[:], signature)
+	return verifyWithKeys(publicKeys, keyID, content, signature[keyIDLength:])
+}
+
+// SignDetached creates a minisign signature file for content.
+func SignDetached(content []byte, privateKey *PrivateKey, trustedComment string) []byte {
+	hash := blake2b.Sum512(content)
+	signature := ed25519.Sign(privateKey.Key, hash[:])
+	globalSignature := ed25519.Sign(privateKey.Key, append(append([]byte{}, signature...), trustedComment...))
+	var builder strings.Builder
+	builder.WriteString(untrustedCommentPrefix + "signature from sing-box secret key " + strings.ToUpper(hex.EncodeToString(privateKey.ID[:])) + "\n")
+	builder.WriteString(base64.StdEncoding.EncodeToString(bytes.Join([][]byte{signatureAlgorithmPrehashed[:], privateKey.ID[:], signature}, nil)) + "\n")
+	builder.WriteString(trustedCommentPrefix + trustedComment + "\n")
+	builder.WriteString(base64.StdEncoding.EncodeToString(globalSignature) + "\n")
+	return []byte(builder.String())
+}
+
+// VerifyDetached checks a minisign signature file, or a bare base64 encoded
+// ed25519 signature, for content.
+func VerifyDetached(content []byte, signatureContent []byte, publicKeys []*PublicKey) error {
+	lines := strings.Split(strings.TrimSpace(string(signatureContent)), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	if len(lines) == 1 {
+		signature, err := base64.StdEncoding.DecodeString(lines[0])
+		if err != nil {
+			return E.Cause(err, "decode signature")
+		}
+		return verifyWithKeys(publicKeys, [keyIDLength]byte{}, content, signature)
+	}
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], untrustedCommentPrefix) || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return E.New("invalid minisign signature")
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return E.Cause(err, "decode signature")
+	}
+	if len(signatureBytes) != len(signatureAlgorithm)+keyIDLength+ed25519.SignatureSize {
+		return E.New("invalid signature length: ", len(signatureBytes))
+	}
+	var keyID [keyIDLength]byte
+	copy(keyID[:], signatureBytes[2:])
+	signature := signatureBytes[2+keyIDLength:]
+	switch [2]byte(signatureBytes) {
+	case signatureAlgorithm:
+	case signatureAlgorithmPrehashed:
+		hash := blake2b.Sum512(content)
+		content = hash[:]
+	default:
+		return E.New("unsupported signature algorithm: ", string(signatureBytes[:2]))
+	}
+	err = verifyWithKeys(publicKeys, keyID, content, signature)
+	if err != nil {
+		return err
+	}
+	globalSignature, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return E.Cause(err, "decode global signature")
+	}
+	trustedComment := strings.TrimPrefix(lines[2], trustedCommentPrefix)
+	return verifyWithKeys(publicKeys, keyID, append(append([]byte{}, signature...), trustedComment...), globalSignature)
+}
+
+func verifyWithKeys(publicKeys []*PublicKey, keyID [keyIDLength]byte, message []byte, signature []byte) error {
+	for _, publicKey := range publicKeys {
+		if keyID != [keyIDLength]byte{} && publicKey.ID != [keyIDLength]byte{} && keyID != publicKey.ID {
+			continue
+		}
+		if ed25519.Verify(publicKey.Key, message, signature) {
+			return nil
+		}
+	}
+	return E.New("signature verification failed")
+}
+
+func decodeKeyLine(content string) ([]byte, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, untrustedCommentPrefix) {
+		_, content, _ = strings.Cut(content, "\n")
+		content = strings.TrimSpace(content)
+	}
+	return base64.StdEncoding.DecodeString(content)
+}
@@ -0,0 +1,55 @@
+package srs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedSignature(t *testing.T) {
+	t.Parallel()
+	privateKey, err := srs.GenerateKey()
+	require.NoError(t, err)
+	publicKey, err := srs.ParsePublicKey(privateKey.Public().String())
+	require.NoError(t, err)
+	var buffer bytes.Buffer
+	err = srs.Write(&buffer, option.PlainRuleSet{
+		Rules: []option.HeadlessRule{{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultHeadlessRule{
+				Domain: []string{"example.com"},
+			},
+		}},
+	}, C.RuleSetVersionCurrent)
+	require.NoError(t, err)
+	require.Error(t, srs.Verify(buffer.Bytes(), []*srs.PublicKey{publicKey}))
+	signed := srs.Sign(buffer.Bytes(), privateKey)
+	require.NoError(t, srs.Verify(signed, []*srs.PublicKey{publicKey}))
+	require.Equal(t, signed, srs.Sign(signed, privateKey))
+	ruleSet, err := srs.Read(bytes.NewReader(signed), false)
+	require.NoError(t, err)
+	require.Len(t, ruleSet.Options.Rules, 1)
+	require.True(t, ruleSet.Options.Rules[0].DefaultOptions.DomainMatcher.Match("example.com"))
+	signed[len(signed)/2] ^= 0xFF
+	require.Error(t, srs.Verify(signed, []*srs.PublicKey{publicKey}))
+}
+
+func TestDetachedSignature(t *testing.T) {
+	t.Parallel()
+	privateKey, err := srs.GenerateKey()
+	require.NoError(t, err)
+	otherKey, err := srs.GenerateKey()
+	require.NoError(t, err)
+	content := []byte("DOMAIN-SUFFIX,example.com\n")
+	signature := srs.SignDetached(content, privateKey, "file:example.list")
+	require.NoError(t, srs.VerifyDetached(content, signature, []*srs.PublicKey{otherKey.Public(), privateKey.Public()}))
+	require.Error(t, srs.VerifyDetached(content, signature, []*srs.PublicKey{otherKey.Public()}))
+	require.Error(t, srs.VerifyDetached(append(content, '#'), signature, []*srs.PublicKey{privateKey.Public()}))
+	tampered := bytes.Replace(signature, []byte("file:example.list"), []byte("file:other.list"), 1)
+	require.Error(t, srs.VerifyDetached(content, tampered, []*srs.PublicKey{privateKey.Public()}))
+}
@@ -0,0 +1,410 @@
+package subscription
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	N "github.com/sagernet/sing/common/network"
+
+	"gopkg.in/yaml.v3"
+)
+
+type clashConfig struct {
+	Proxies []map[string]any `yaml:"proxies"`
+}
+
+// ParseClash converts the proxies of a Clash configuration.
+func ParseClash(content []byte) (*Result, error) {
+	var config clashConfig
+	err := yaml.Unmarshal(content, &config)
+	if err != nil {
+		return nil, E.Cause(err, "decode clash config")
+	}
+	result := &Result{}
+	for index, proxy := range config.Proxies {
+		outbound, losses, err := parseClashProxy(proxy)
+		if err != nil {
+			result.Errors = append(result.Errors, E.Cause(err, "parse proxies[", index, "]"))
+			continue
+		}
+		result.add(outbound, losses)
+	}
+	return result, nil
+}
+
+func parseClashProxy(proxy map[string]any) (option.Outbound, []string, error) {
+	fields := newFieldReader(proxy)
+	outbound := option.Outbound{
+		Tag: fields.string("name"),
+	}
+	proxyType := fields.string("type")
+	serverOptions := option.ServerOptions{
+		Server:     fields.string("server"),
+		ServerPort: uint16(fields.int("port")),
+	}
+	if serverOptions.Server == "" {
+		return option.Outbound{}, nil, E.New("missing server")
+	}
+	if outbound.Tag == "" {
+		outbound.Tag = serverTag(serverOptions)
+	}
+	var network option.NetworkList
+	if fields.has("udp") && !fields.bool("udp") {
+		network = N.NetworkTCP
+	}
+	var err error
+	switch proxyType {
+	case "ss":
+		options := &option.ShadowsocksOutboundOptions{
+			ServerOptions: serverOptions,
+			Method:        fields.string("cipher"),
+			Password:      fields.string("password"),
+			Network:       network,
+			Multiplex:     readClashMultiplex(fields),
+		}
+		if fields.bool("udp-over-tcp") {
+			options.UDPOverTCP = &option.UDPOverTCPOptions{
+				Enabled: true,
+				Version: uint8(fields.int("udp-over-tcp-version")),
+			}
+		}
+		options.Plugin, options.PluginOptions, err = readClashPlugin(fields)
+		outbound.Type = C.TypeShadowsocks
+		outbound.Options = options
+	case "vmess":
+		options := &option.VMessOutboundOptions{
+			ServerOptions:       serverOptions,
+			UUID:                fields.string("uuid"),
+			AlterId:             fields.int("alterId"),
+			Security:            fields.string("cipher"),
+			GlobalPadding:       fields.bool("global-padding"),
+			AuthenticatedLength: fields.bool("authenticated-length"),
+			PacketEncoding:      fields.string("packet-encoding"),
+			Network:             network,
+			Multiplex:           readClashMultiplex(fields),
+		}
+		if options.Security == "" {
+			options.Security = "auto"
+		}
+		options.TLS = readClashTLS(fields, fields.bool("tls"))
+		options.Transport, err = readClashTransport(fields)
+		outbound.Type = C.TypeVMess
+		outbound.Options = options
+	case "vless":
+		options := &option.VLESSOutboundOptions{
+			ServerOptions: serverOptions,
+			UUID:          fields.string("uuid"),
+			Flow:          fields.string("flow"),
+			Network:       network,
+			Multiplex:     readClashMultiplex(fields),
+		}
+		if packetEncoding := fields.string("packet-encoding"); packetEncoding != "" {
+			options.PacketEncoding = &packetEncoding
+		}
+		options.TLS = readClashTLS(fields, fields.bool("tls"))
+		options.Transport, err = readClashTransport(fields)
+		outbound.Type = C.TypeVLESS
+		outbound.Options = options
+	case "trojan":
+		options := &option.TrojanOutboundOptions{
+			ServerOptions: serverOptions,
+			Password:      fields.string("password"),
+			Network:       network,
+			Multiplex:     readClashMultiplex(fields),
+		}
+		options.TLS = readClashTLS(fields, true)
+		options.Transport, err = readClashTransport(fields)
+		outbound.Type = C.TypeTrojan
+		outbound.Options = options
+	case "hysteria2", "hy2":
+		options := &option.Hysteria2OutboundOptions{
+			ServerOptions: serverOptions,
+			Password:      fields.string("password", "auth"),
+			UpMbps:        parseClashBandwidth(fields.string("up")),
+			DownMbps:      parseClashBandwidth(fields.string("down")),
+			HopInterval:   badoption.Duration(time.Duration(fields.int("hop-interval")) * time.Second),
+			Network:       network,
+		}
+		if ports := fields.string("ports"); ports != "" {
+			options.ServerPorts = parsePortRanges(ports)
+		}
+		if obfs := fields.string("obfs"); obfs != "" {
+			options.Obfs = &option.Hysteria2Obfs{
+				Type:     obfs,
+				Password: fields.string("obfs-password"),
+			}
+		}
+		options.TLS = readClashTLS(fields, true)
+		outbound.Type = C.TypeHysteria2
+		outbound.Options = options
+	case "tuic":
+		options := &option.TUICOutboundOptions{
+			ServerOptions:     serverOptions,
+			UUID:              fields.string("uuid"),
+			Password:          fields.string("password"),
+			CongestionControl: fields.string("congestion-controller"),
+			UDPRelayMode:      fields.string("udp-relay-mode"),
+			UDPOverStream:     fields.bool("udp-over-stream"),
+			ZeroRTTHandshake:  fields.bool("reduce-rtt"),
+			Heartbeat:         badoption.Duration(time.Duration(fields.int("heartbeat-interval")) * time.Millisecond),
+			Network:           network,
+		}
+		options.TLS = readClashTLS(fields, true)
+		options.TLS.DisableSNI = fields.bool("disable-sni")
+		outbound.Type = C.TypeTUIC
+		outbound.Options = options
+	case "anytls":
+		options := &option.AnyTLSOutboundOptions{
+			ServerOptions:            serverOptions,
+			Password:                 fields.string("password"),
+			IdleSessionCheckInterval: badoption.Duration(time.Duration(fields.int("idle-session-check-interval")) * time.Second),
+			IdleSessionTimeout:       badoption.Duration(time.Duration(fields.int("idle-session-timeout")) * time.Second),
+			MinIdleSession:           fields.int("min-idle-session"),
+		}
+		options.TLS = readClashTLS(fields, true)
+		outbound.Type = C.TypeAnyTLS
+		outbound.Options = options
+	case "socks5":
+		options := &option.SOCKSOutboundOptions{
+			ServerOptions: serverOptions,
+			Username:      fields.string("username"),
+			Password:      fields.string("password"),
+			Network:       network,
+		}
+		if fields.bool("tls") {
+			fields.used["tls"] = false
+		}
+		outbound.Type = C.TypeSOCKS
+		outbound.Options = options
+	case "http":
+		options := &option.HTTPOutboundOptions{
+			ServerOptions: serverOptions,
+			Username:      fields.string("username"),
+			Password:      fields.string("password"),
+			Headers:       toHTTPHeader(fields.stringMap("headers")),
+		}
+		options.TLS = readClashTLS(fields, fields.bool("tls"))
+		outbound.Type = C.TypeHTTP
+		outbound.Options = options
+	default:
+		return option.Outbound{}, nil, E.New("unsupported proxy type: ", proxyType)
+	}
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	return outbound, fields.unused(), nil
+}
+
+func readClashTLS(fields *fieldReader, enabled bool) *option.OutboundTLSOptions {
+	if !enabled {
+		return nil
+	}
+	tlsOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: fields.string("servername", "sni"),
+		Insecure:   fields.bool("skip-cert-verify"),
+		ALPN:       fields.strings("alpn"),
+	}
+	fingerprint := fields.string("client-fingerprint")
+	if fields.has("reality-opts") {
+		realityFields := fields.object("reality-opts")
+		tlsOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: realityFields.string("public-key"),
+			ShortID:   realityFields.string("short-id"),
+		}
+		if fingerprint == "" {
+			fingerprint = defaultFingerprint
+		}
+	}
+	if fingerprint != "" {
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+	return tlsOptions
+}
+
+func readClashTransport(fields *fieldReader) (*option.V2RayTransportOptions, error) {
+	switch network := fields.string("network"); network {
+	case "", "tcp":
+		return nil, nil
+	case "ws":
+		websocketFields := fields.object("ws-opts")
+		headers := toHTTPHeader(websocketFields.stringMap("headers"))
+		if websocketFields.bool("v2ray-http-upgrade") {
+			options := option.V2RayHTTPUpgradeOptions{
+				Path:    websocketFields.string("path"),
+				Headers: headers,
+			}
+			if host, loaded := headers["Host"]; loaded && len(host) > 0 {
+				options.Host = host[0]
+				delete(headers, "Host")
+			}
+			return &option.V2RayTransportOptions{
+				Type:               C.V2RayTransportTypeHTTPUpgrade,
+				HTTPUpgradeOptions: options,
+			}, nil
+		}
+		options := option.V2RayWebsocketOptions{
+			Path:                websocketFields.string("path"),
+			Headers:             headers,
+			MaxEarlyData:        uint32(websocketFields.int("max-early-data")),
+			EarlyDataHeaderName: websocketFields.string("early-data-header-name"),
+		}
+		return &option.V2RayTransportOptions{
+			Type:             C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: options,
+		}, nil
+	case "grpc":
+		grpcFields := fields.object("grpc-opts")
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: grpcFields.string("grpc-service-name"),
+			},
+		}, nil
+	case "h2":
+		h2Fields := fields.object("h2-opts")
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: h2Fields.strings("host"),
+				Path: h2Fields.string("path"),
+			},
+		}, nil
+	case "http":
+		httpFields := fields.object("http-opts")
+		options := option.V2RayHTTPOptions{
+			Method: httpFields.string("method"),
+		}
+		if paths := httpFields.strings("path"); len(paths) > 0 {
+			options.Path = paths[0]
+		}
+		if headers, _ := httpFields.values["headers"].(map[string]any); len(headers) > 0 {
+			httpFields.used["headers"] = true
+			options.Headers = make(badoption.HTTPHeader)
+			headerFields := newFieldReader(headers)
+			for key := range headers {
+				values := headerFields.strings(key)
+				if key == "Host" {
+					options.Host = values
+				} else {
+					options.Headers[key] = values
+				}
+			}
+		}
+		return &option.V2RayTransportOptions{
+			Type:        C.V2RayTransportTypeHTTP,
+			HTTPOptions: options,
+		}, nil
+	default:
+		return nil, E.New("unsupported network: ", network)
+	}
+}
+
+func readClashMultiplex(fields *fieldReader) *option.OutboundMultiplexOptions {
+	if !fields.has("smux") {
+		return nil
+	}
+	multiplexFields := fields.object("smux")
+	if !multiplexFields.bool("enabled") {
+		return nil
+	}
+	options := &option.OutboundMultiplexOptions{
+		Enabled:        true,
+		Protocol:       multiplexFields.string("protocol"),
+		MaxConnections: multiplexFields.int("max-connections"),
+		MinStreams:     multiplexFields.int("min-streams"),
+		MaxStreams:     multiplexFields.int("max-streams"),
+		Padding:        multiplexFields.bool("padding"),
+	}
+	if multiplexFields.has("brutal-opts") {
+		brutalFields := multiplexFields.object("brutal-opts")
+		if brutalFields.bool("enabled") {
+			options.Brutal = &option.BrutalOptions{
+				Enabled:  true,
+				UpMbps:   parseClashBandwidth(brutalFields.string("up")),
+				DownMbps: parseClashBandwidth(brutalFields.string("down")),
+			}
+		}
+	}
+	return options
+}
+
+// readClashPlugin converts the Clash plugin options to the SIP003 format.
+func readClashPlugin(fields *fieldReader) (string, string, error) {
+	plugin := fields.string("plugin")
+	if plugin == "" {
+		return "", "", nil
+	}
+	pluginFields := fields.object("plugin-opts")
+	var pluginOptions []string
+	switch plugin {
+	case "obfs":
+		pluginOptions = append(pluginOptions, "obfs="+pluginFields.string("mode"))
+		if host := pluginFields.string("host"); host != "" {
+			pluginOptions = append(pluginOptions, "obfs-host="+host)
+		}
+		return "obfs-local", strings.Join(pluginOptions, ";"), nil
+	case "v2ray-plugin":
+		if mode := pluginFields.string("mode"); mode != "" {
+			pluginOptions = append(pluginOptions, "mode="+mode)
+		}
+		if pluginFields.bool("tls") {
+			pluginOptions = append(pluginOptions, "tls")
+		}
+		if host := pluginFields.string("host"); host != "" {
+			pluginOptions = append(pluginOptions, "host="+host)
+		}
+		if path := pluginFields.string("path"); path != "" {
+			pluginOptions = append(pluginOptions, "path="+path)
+		}
+		if pluginFields.bool("mux") {
+			pluginOptions = append(pluginOptions, "mux=1")
+		}
+		return plugin, strings.Join(pluginOptions, ";"), nil
+	default:
+		return "", "", E.New("unsupported plugin: ", plugin)
+	}
+}
+
+// parseClashBandwidth reads bandwidths such as "100" and "100 Mbps" in Mbps.
+func parseClashBandwidth(bandwidth string) int {
+	bandwidth = strings.TrimSpace(bandwidth)
+	end := strings.IndexFunc(bandwidth, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end == -1 {
+		end = len(bandwidth)
+	}
+	value, err := strconv.ParseFloat(bandwidth[:end], 64)
+	if err != nil {
+		return 0
+	}
+	unit := strings.ToLower(strings.TrimSpace(bandwidth[end:]))
+	switch unit {
+	case "gbps", "g":
+		value *= 1000
+	case "kbps", "k":
+		value /= 1000
+	}
+	return int(value)
+}
+
+func toHTTPHeader(headers map[string]string) badoption.HTTPHeader {
+	if len(headers) == 0 {
+		return nil
+	}
+	httpHeader := make(badoption.HTTPHeader, len(headers))
+	for key, value := range headers {
+		httpHeader[key] = badoption.Listable[string]{value}
+	}
+	return httpHeader
+}
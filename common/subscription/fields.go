@@ -0,0 +1,184 @@
+package subscription
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	F "github.com/sagernet/sing/common/format"
+)
+
+// fieldReader reads the fields of a share link or a Clash proxy and
+// remembers which were read, the others are reported as lossy.
+type fieldReader struct {
+	prefix string
+	values map[string]any
+	used   map[string]bool
+	nested []*fieldReader
+}
+
+func newFieldReader(values map[string]any) *fieldReader {
+	return &fieldReader{
+		values: values,
+		used:   make(map[string]bool),
+	}
+}
+
+func newQueryReader(query url.Values) *fieldReader {
+	values := make(map[string]any, len(query))
+	for key, value := range query {
+		if len(value) > 0 {
+			values[key] = value[0]
+		}
+	}
+	return newFieldReader(values)
+}
+
+func (r *fieldReader) has(key string) bool {
+	_, loaded := r.values[key]
+	return loaded
+}
+
+func (r *fieldReader) load(key string) (any, bool) {
+	value, loaded := r.values[key]
+	if loaded {
+		r.used[key] = true
+	}
+	return value, loaded
+}
+
+// ignore marks fields that carry no information for sing-box.
+func (r *fieldReader) ignore(keys ...string) {
+	for _, key := range keys {
+		r.used[key] = true
+	}
+}
+
+func (r *fieldReader) string(keys ...string) string {
+	for _, key := range keys {
+		value, loaded := r.load(key)
+		if !loaded || value == nil {
+			continue
+		}
+		switch typedValue := value.(type) {
+		case string:
+			return typedValue
+		case map[string]any, []any:
+			continue
+		default:
+			return toString(typedValue)
+		}
+	}
+	return ""
+}
+
+func (r *fieldReader) int(keys ...string) int {
+	value := r.string(keys...)
+	if value == "" {
+		return 0
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		floatValue, floatErr := strconv.ParseFloat(value, 64)
+		if floatErr != nil {
+			return 0
+		}
+		return int(floatValue)
+	}
+	return intValue
+}
+
+func (r *fieldReader) bool(keys ...string) bool {
+	switch strings.ToLower(r.string(keys...)) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// strings reads a list, or a comma separated string.
+func (r *fieldReader) strings(keys ...string) []string {
+	for _, key := range keys {
+		value, loaded := r.load(key)
+		if !loaded || value == nil {
+			continue
+		}
+		switch typedValue := value.(type) {
+		case []any:
+			var values []string
+			for _, item := range typedValue {
+				values = append(values, toString(item))
+			}
+			return values
+		case string:
+			if typedValue == "" {
+				return nil
+			}
+			return strings.Split(typedValue, ",")
+		default:
+			return []string{toString(typedValue)}
+		}
+	}
+	return nil
+}
+
+func (r *fieldReader) object(key string) *fieldReader {
+	value, _ := r.load(key)
+	values, _ := value.(map[string]any)
+	if values == nil {
+		values = make(map[string]any)
+	}
+	nested := newFieldReader(values)
+	nested.prefix = r.prefix + key + "."
+	r.nested = append(r.nested, nested)
+	return nested
+}
+
+// stringMap reads an object of strings, such as HTTP headers.
+func (r *fieldReader) stringMap(key string) map[string]string {
+	value, _ := r.load(key)
+	values, _ := value.(map[string]any)
+	if len(values) == 0 {
+		return nil
+	}
+	stringValues := make(map[string]string, len(values))
+	for itemKey, itemValue := range values {
+		stringValues[itemKey] = toString(itemValue)
+	}
+	return stringValues
+}
+
+// unused returns the fields that were not read, with their path.
+func (r *fieldReader) unused() []string {
+	var fields []string
+	for key, value := range r.values {
+		if r.used[key] {
+			continue
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		fields = append(fields, r.prefix+key)
+	}
+	for _, nested := range r.nested {
+		fields = append(fields, nested.unused()...)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func toString(value any) string {
+	switch typedValue := value.(type) {
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(typedValue), 'f', -1, 32)
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return F.ToString(typedValue)
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
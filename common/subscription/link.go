@@ -0,0 +1,458 @@
+package subscription
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+const (
+	// the WebSocket early data header used by Xray links
+	earlyDataHeaderName = "Sec-WebSocket-Protocol"
+	defaultFingerprint  = "chrome"
+)
+
+// ParseLink converts a share link to an outbound. It also returns the
+// fields of the link that were dropped.
+func ParseLink(link string) (option.Outbound, []string, error) {
+	scheme, _, loaded := strings.Cut(link, "://")
+	if !loaded {
+		return option.Outbound{}, nil, E.New("invalid link: ", link)
+	}
+	var (
+		outbound option.Outbound
+		losses   []string
+		err      error
+	)
+	switch strings.ToLower(scheme) {
+	case "vmess":
+		outbound, losses, err = parseVMessLink(link)
+	case "vless":
+		outbound, losses, err = parseVLESSLink(link)
+	case "trojan":
+		outbound, losses, err = parseTrojanLink(link)
+	case "ss":
+		outbound, losses, err = parseShadowsocksLink(link)
+	case "hysteria2", "hy2":
+		outbound, losses, err = parseHysteria2Link(link)
+	case "tuic":
+		outbound, losses, err = parseTUICLink(link)
+	case "anytls":
+		outbound, losses, err = parseAnyTLSLink(link)
+	default:
+		return option.Outbound{}, nil, E.New("unsupported link: ", scheme)
+	}
+	if err != nil {
+		return option.Outbound{}, nil, E.Cause(err, "parse ", scheme, " link")
+	}
+	return outbound, losses, nil
+}
+
+func parseVMessLink(link string) (option.Outbound, []string, error) {
+	content, err := decodeBase64(strings.TrimPrefix(link, "vmess://"))
+	if err != nil {
+		return option.Outbound{}, nil, E.Cause(err, "decode")
+	}
+	var values map[string]any
+	err = json.Unmarshal(content, &values)
+	if err != nil {
+		return option.Outbound{}, nil, E.Cause(err, "decode")
+	}
+	fields := newFieldReader(values)
+	fields.ignore("v")
+	options := &option.VMessOutboundOptions{
+		ServerOptions: option.ServerOptions{
+			Server:     fields.string("add"),
+			ServerPort: uint16(fields.int("port")),
+		},
+		UUID:     fields.string("id"),
+		AlterId:  fields.int("aid"),
+		Security: fields.string("scy"),
+	}
+	if options.Security == "" {
+		options.Security = "auto"
+	}
+	if options.Server == "" || options.ServerPort == 0 {
+		return option.Outbound{}, nil, E.New("missing server")
+	}
+	network := fields.string("net")
+	headerType := fields.string("type")
+	// gRPC uses path for the service name in VMess links
+	if network == "grpc" && !fields.has("serviceName") {
+		fields.values["serviceName"] = fields.values["path"]
+		delete(fields.values, "path")
+	}
+	options.Transport, err = readLinkTransport(fields, network, headerType)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	options.TLS = readLinkTLS(fields, fields.string("tls"))
+	tag := fields.string("ps")
+	if tag == "" {
+		tag = serverTag(options.ServerOptions)
+	}
+	return option.Outbound{Type: C.TypeVMess, Tag: tag, Options: options}, fields.unused(), nil
+}
+
+func parseVLESSLink(link string) (option.Outbound, []string, error) {
+	linkURL, serverOptions, err := parseLinkURL(link, 0)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	query := newQueryReader(linkURL.Query())
+	if query.string("encryption") != "none" {
+		query.used["encryption"] = false
+	}
+	options := &option.VLESSOutboundOptions{
+		ServerOptions: serverOptions,
+		UUID:          linkURL.User.Username(),
+		Flow:          query.string("flow"),
+	}
+	if options.UUID == "" {
+		return option.Outbound{}, nil, E.New("missing uuid")
+	}
+	options.Transport, err = readLinkTransport(query, query.string("type"), query.string("headerType"))
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	options.TLS = readLinkTLS(query, query.string("security"))
+	return option.Outbound{Type: C.TypeVLESS, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+func parseTrojanLink(link string) (option.Outbound, []string, error) {
+	linkURL, serverOptions, err := parseLinkURL(link, 443)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	query := newQueryReader(linkURL.Query())
+	options := &option.TrojanOutboundOptions{
+		ServerOptions: serverOptions,
+		Password:      linkURL.User.Username(),
+	}
+	options.Transport, err = readLinkTransport(query, query.string("type"), query.string("headerType"))
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	security := query.string("security")
+	if security == "" {
+		security = "tls"
+	}
+	options.TLS = readLinkTLS(query, security)
+	return option.Outbound{Type: C.TypeTrojan, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+// parseShadowsocksLink accepts SIP002 links, with the user info in base64
+// or, as used by Shadowsocks 2022, in plain text, and legacy links where
+// everything before the tag is base64 encoded.
+func parseShadowsocksLink(link string) (option.Outbound, []string, error) {
+	content, tag, _ := strings.Cut(strings.TrimPrefix(link, "ss://"), "#")
+	if !strings.Contains(content, "@") {
+		decoded, err := decodeBase64(content)
+		if err != nil {
+			return option.Outbound{}, nil, E.Cause(err, "decode")
+		}
+		content = string(decoded)
+	}
+	link = "ss://" + content
+	if tag != "" {
+		link += "#" + tag
+	}
+	linkURL, serverOptions, err := parseLinkURL(link, 0)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	options := &option.ShadowsocksOutboundOptions{
+		ServerOptions: serverOptions,
+	}
+	if password, loaded := linkURL.User.Password(); loaded {
+		options.Method = linkURL.User.Username()
+		options.Password = password
+	} else {
+		userInfo, err := decodeBase64(linkURL.User.Username())
+		if err != nil {
+			return option.Outbound{}, nil, E.Cause(err, "decode user info")
+		}
+		var found bool
+		options.Method, options.Password, found = strings.Cut(string(userInfo), ":")
+		if !found {
+			return option.Outbound{}, nil, E.New("missing password")
+		}
+	}
+	query := newQueryReader(linkURL.Query())
+	if plugin := query.string("plugin"); plugin != "" {
+		options.Plugin, options.PluginOptions, _ = strings.Cut(plugin, ";")
+		if options.Plugin == "simple-obfs" {
+			options.Plugin = "obfs-local"
+		}
+	}
+	return option.Outbound{Type: C.TypeShadowsocks, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+func parseHysteria2Link(link string) (option.Outbound, []string, error) {
+	linkURL, serverOptions, err := parseLinkURL(link, 443)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	query := newQueryReader(linkURL.Query())
+	options := &option.Hysteria2OutboundOptions{
+		ServerOptions: serverOptions,
+		Password:      linkURL.User.Username(),
+		UpMbps:        query.int("upmbps"),
+		DownMbps:      query.int("downmbps"),
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: query.string("sni"),
+				Insecure:   query.bool("insecure"),
+				ALPN:       query.strings("alpn"),
+			},
+		},
+	}
+	if password, loaded := linkURL.User.Password(); loaded {
+		options.Password += ":" + password
+	}
+	if obfs := query.string("obfs"); obfs != "" {
+		options.Obfs = &option.Hysteria2Obfs{
+			Type:     obfs,
+			Password: query.string("obfs-password"),
+		}
+	}
+	if ports := query.string("mport"); ports != "" {
+		options.ServerPorts = parsePortRanges(ports)
+	}
+	return option.Outbound{Type: C.TypeHysteria2, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+func parseTUICLink(link string) (option.Outbound, []string, error) {
+	linkURL, serverOptions, err := parseLinkURL(link, 0)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	query := newQueryReader(linkURL.Query())
+	password, _ := linkURL.User.Password()
+	options := &option.TUICOutboundOptions{
+		ServerOptions:     serverOptions,
+		UUID:              linkURL.User.Username(),
+		Password:          password,
+		CongestionControl: query.string("congestion_control"),
+		UDPRelayMode:      query.string("udp_relay_mode"),
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: query.string("sni"),
+				Insecure:   query.bool("allow_insecure", "insecure"),
+				DisableSNI: query.bool("disable_sni"),
+				ALPN:       query.strings("alpn"),
+			},
+		},
+	}
+	return option.Outbound{Type: C.TypeTUIC, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+func parseAnyTLSLink(link string) (option.Outbound, []string, error) {
+	linkURL, serverOptions, err := parseLinkURL(link, 443)
+	if err != nil {
+		return option.Outbound{}, nil, err
+	}
+	query := newQueryReader(linkURL.Query())
+	options := &option.AnyTLSOutboundOptions{
+		ServerOptions: serverOptions,
+		Password:      linkURL.User.Username(),
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: query.string("sni"),
+				Insecure:   query.bool("insecure", "allowInsecure"),
+				ALPN:       query.strings("alpn"),
+			},
+		},
+	}
+	if fingerprint := query.string("fp"); fingerprint != "" {
+		options.TLS.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+	return option.Outbound{Type: C.TypeAnyTLS, Tag: linkTag(linkURL), Options: options}, query.unused(), nil
+}
+
+func parseLinkURL(link string, defaultPort uint16) (*url.URL, option.ServerOptions, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, option.ServerOptions{}, err
+	}
+	if linkURL.User == nil {
+		linkURL.User = url.User("")
+	}
+	serverOptions := option.ServerOptions{
+		Server:     linkURL.Hostname(),
+		ServerPort: defaultPort,
+	}
+	if serverOptions.Server == "" {
+		return nil, option.ServerOptions{}, E.New("missing server")
+	}
+	if port := linkURL.Port(); port != "" {
+		portNumber, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, option.ServerOptions{}, E.New("invalid port: ", port)
+		}
+		serverOptions.ServerPort = uint16(portNumber)
+	}
+	if serverOptions.ServerPort == 0 {
+		return nil, option.ServerOptions{}, E.New("missing port")
+	}
+	return linkURL, serverOptions, nil
+}
+
+func linkTag(linkURL *url.URL) string {
+	if linkURL.Fragment != "" {
+		return linkURL.Fragment
+	}
+	return linkURL.Host
+}
+
+// readLinkTLS reads the TLS fields shared by VMess, VLESS and Trojan links.
+func readLinkTLS(fields *fieldReader, security string) *option.OutboundTLSOptions {
+	switch security {
+	case "tls", "xtls", "reality":
+	default:
+		return nil
+	}
+	tlsOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: fields.string("sni", "peer"),
+		Insecure:   fields.bool("allowInsecure", "insecure"),
+		ALPN:       fields.strings("alpn"),
+	}
+	fingerprint := fields.string("fp")
+	if security == "reality" {
+		tlsOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: fields.string("pbk"),
+			ShortID:   fields.string("sid"),
+		}
+		// REALITY requires uTLS
+		if fingerprint == "" {
+			fingerprint = defaultFingerprint
+		}
+	}
+	if fingerprint != "" {
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+	return tlsOptions
+}
+
+// readLinkTransport reads the V2Ray transport fields shared by VMess, VLESS
+// and Trojan links.
+func readLinkTransport(fields *fieldReader, network string, headerType string) (*option.V2RayTransportOptions, error) {
+	switch network {
+	case "", "tcp", "raw":
+		if headerType != "http" {
+			return nil, nil
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: fields.strings("host"),
+				Path: fields.string("path"),
+			},
+		}, nil
+	case "ws", "websocket":
+		websocketOptions := option.V2RayWebsocketOptions{
+			Path:    fields.string("path"),
+			Headers: hostHeader(fields.string("host")),
+		}
+		if path, rawQuery, loaded := strings.Cut(websocketOptions.Path, "?"); loaded {
+			query, _ := url.ParseQuery(rawQuery)
+			if earlyData, err := strconv.ParseUint(query.Get("ed"), 10, 32); err == nil {
+				query.Del("ed")
+				websocketOptions.Path = path
+				if len(query) > 0 {
+					websocketOptions.Path += "?" + query.Encode()
+				}
+				websocketOptions.MaxEarlyData = uint32(earlyData)
+				websocketOptions.EarlyDataHeaderName = earlyDataHeaderName
+			}
+		}
+		return &option.V2RayTransportOptions{
+			Type:             C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: websocketOptions,
+		}, nil
+	case "grpc":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: fields.string("serviceName"),
+			},
+		}, nil
+	case "http", "h2":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: fields.strings("host"),
+				Path: fields.string("path"),
+			},
+		}, nil
+	case "httpupgrade":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTPUpgrade,
+			HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+				Host: fields.string("host"),
+				Path: fields.string("path"),
+			},
+		}, nil
+	case "xhttp", "splithttp":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeXHTTP,
+			XHTTPOptions: option.V2RayXHTTPOptions{
+				Host: fields.string("host"),
+				Path: fields.string("path"),
+				Mode: fields.string("mode"),
+			},
+		}, nil
+	case "quic":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeQUIC,
+		}, nil
+	default:
+		return nil, E.New("unsupported transport: ", network)
+	}
+}
+
+func hostHeader(host string) badoption.HTTPHeader {
+	if host == "" {
+		return nil
+	}
+	return badoption.HTTPHeader{"Host": {host}}
+}
+
+func serverTag(serverOptions option.ServerOptions) string {
+	return serverOptions.Server + ":" + strconv.Itoa(int(serverOptions.ServerPort))
+}
+
+// parsePortRanges converts port ranges such as "1000-2000,3000" to the
+// sing-box format.
+func parsePortRanges(ports string) badoption.Listable[string] {
+	var portRanges badoption.Listable[string]
+	for _, portRange := range strings.Split(ports, ",") {
+		portRange = strings.TrimSpace(portRange)
+		if portRange == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(portRange, "-")
+		if !isRange {
+			to = from
+		}
+		portRanges = append(portRanges, from+":"+to)
+	}
+	return portRanges
+}
@@ -0,0 +1,341 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+// ExportLink converts an outbound to a share link. It also returns the
+// fields of the outbound that the link cannot express.
+func ExportLink(outbound option.Outbound) (string, []string, error) {
+	var (
+		link string
+		err  error
+	)
+	switch options := outbound.Options.(type) {
+	case *option.VMessOutboundOptions:
+		link, err = exportVMessLink(outbound.Tag, options)
+	case *option.VLESSOutboundOptions:
+		link = exportVLESSLink(outbound.Tag, options)
+	case *option.TrojanOutboundOptions:
+		link = exportTrojanLink(outbound.Tag, options)
+	case *option.ShadowsocksOutboundOptions:
+		link = exportShadowsocksLink(outbound.Tag, options)
+	case *option.Hysteria2OutboundOptions:
+		link = exportHysteria2Link(outbound.Tag, options)
+	case *option.TUICOutboundOptions:
+		link = exportTUICLink(outbound.Tag, options)
+	case *option.AnyTLSOutboundOptions:
+		link = exportAnyTLSLink(outbound.Tag, options)
+	default:
+		return "", nil, E.New("unsupported outbound type: ", outbound.Type)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	// the dropped fields are those not restored by parsing the link back
+	parsed, _, err := ParseLink(link)
+	if err != nil {
+		return "", nil, E.Cause(err, "parse exported link")
+	}
+	losses, err := diffOptions(outbound.Options, parsed.Options)
+	if err != nil {
+		return "", nil, err
+	}
+	return link, losses, nil
+}
+
+func exportVMessLink(tag string, options *option.VMessOutboundOptions) (string, error) {
+	query := make(url.Values)
+	writeLinkTransport(query, options.Transport)
+	writeLinkTLS(query, options.TLS)
+	values := map[string]any{
+		"v":    "2",
+		"ps":   tag,
+		"add":  options.Server,
+		"port": strconv.Itoa(int(options.ServerPort)),
+		"id":   options.UUID,
+		"aid":  strconv.Itoa(options.AlterId),
+		"scy":  options.Security,
+		"net":  "tcp",
+		"type": "none",
+	}
+	for key := range query {
+		value := query.Get(key)
+		switch key {
+		case "type":
+			values["net"] = value
+		case "headerType":
+			values["type"] = value
+		case "security":
+			values["tls"] = value
+		case "serviceName":
+			values["path"] = value
+		default:
+			values[key] = value
+		}
+	}
+	content, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(content), nil
+}
+
+func exportVLESSLink(tag string, options *option.VLESSOutboundOptions) string {
+	query := make(url.Values)
+	query.Set("encryption", "none")
+	if options.Flow != "" {
+		query.Set("flow", options.Flow)
+	}
+	writeLinkTransport(query, options.Transport)
+	writeLinkTLS(query, options.TLS)
+	return buildLink("vless", url.User(options.UUID), options.ServerOptions, query, tag)
+}
+
+func exportTrojanLink(tag string, options *option.TrojanOutboundOptions) string {
+	query := make(url.Values)
+	writeLinkTransport(query, options.Transport)
+	writeLinkTLS(query, options.TLS)
+	if !query.Has("security") {
+		query.Set("security", "none")
+	}
+	return buildLink("trojan", url.User(options.Password), options.ServerOptions, query, tag)
+}
+
+func exportShadowsocksLink(tag string, options *option.ShadowsocksOutboundOptions) string {
+	var userInfo *url.Userinfo
+	if strings.HasPrefix(options.Method, "2022-") {
+		userInfo = url.UserPassword(options.Method, options.Password)
+	} else {
+		userInfo = url.User(base64.RawURLEncoding.EncodeToString([]byte(options.Method + ":" + options.Password)))
+	}
+	query := make(url.Values)
+	if options.Plugin != "" {
+		plugin := options.Plugin
+		if options.PluginOptions != "" {
+			plugin += ";" + options.PluginOptions
+		}
+		query.Set("plugin", plugin)
+	}
+	return buildLink("ss", userInfo, options.ServerOptions, query, tag)
+}
+
+func exportHysteria2Link(tag string, options *option.Hysteria2OutboundOptions) string {
+	query := make(url.Values)
+	if options.TLS != nil {
+		writeQueryTLS(query, options.TLS, "insecure")
+	}
+	if options.Obfs != nil && options.Obfs.Type != "" {
+		query.Set("obfs", options.Obfs.Type)
+		query.Set("obfs-password", options.Obfs.Password)
+	}
+	if len(options.ServerPorts) > 0 {
+		portRanges := make([]string, 0, len(options.ServerPorts))
+		for _, portRange := range options.ServerPorts {
+			from, to, _ := strings.Cut(portRange, ":")
+			if to == "" || to == from {
+				portRanges = append(portRanges, from)
+			} else {
+				portRanges = append(portRanges, from+"-"+to)
+			}
+		}
+		query.Set("mport", strings.Join(portRanges, ","))
+	}
+	if options.UpMbps > 0 {
+		query.Set("upmbps", strconv.Itoa(options.UpMbps))
+	}
+	if options.DownMbps > 0 {
+		query.Set("downmbps", strconv.Itoa(options.DownMbps))
+	}
+	return buildLink("hysteria2", url.User(options.Password), options.ServerOptions, query, tag)
+}
+
+func exportTUICLink(tag string, options *option.TUICOutboundOptions) string {
+	query := make(url.Values)
+	if options.CongestionControl != "" {
+		query.Set("congestion_control", options.CongestionControl)
+	}
+	if options.UDPRelayMode != "" {
+		query.Set("udp_relay_mode", options.UDPRelayMode)
+	}
+	if options.TLS != nil {
+		writeQueryTLS(query, options.TLS, "allow_insecure")
+		if options.TLS.DisableSNI {
+			query.Set("disable_sni", "1")
+		}
+	}
+	return buildLink("tuic", url.UserPassword(options.UUID, options.Password), options.ServerOptions, query, tag)
+}
+
+func exportAnyTLSLink(tag string, options *option.AnyTLSOutboundOptions) string {
+	query := make(url.Values)
+	if options.TLS != nil {
+		writeQueryTLS(query, options.TLS, "insecure")
+		if options.TLS.UTLS != nil && options.TLS.UTLS.Enabled {
+			query.Set("fp", options.TLS.UTLS.Fingerprint)
+		}
+	}
+	return buildLink("anytls", url.User(options.Password), options.ServerOptions, query, tag)
+}
+
+func buildLink(scheme string, userInfo *url.Userinfo, serverOptions option.ServerOptions, query url.Values, tag string) string {
+	linkURL := url.URL{
+		Scheme:   scheme,
+		User:     userInfo,
+		Host:     net.JoinHostPort(serverOptions.Server, strconv.Itoa(int(serverOptions.ServerPort))),
+		RawQuery: query.Encode(),
+		Fragment: tag,
+	}
+	return linkURL.String()
+}
+
+// writeQueryTLS writes the TLS fields of the QUIC based links.
+func writeQueryTLS(query url.Values, tlsOptions *option.OutboundTLSOptions, insecureKey string) {
+	if tlsOptions.ServerName != "" {
+		query.Set("sni", tlsOptions.ServerName)
+	}
+	if tlsOptions.Insecure {
+		query.Set(insecureKey, "1")
+	}
+	if len(tlsOptions.ALPN) > 0 {
+		query.Set("alpn", strings.Join(tlsOptions.ALPN, ","))
+	}
+}
+
+// writeLinkTLS is the reverse of readLinkTLS.
+func writeLinkTLS(query url.Values, tlsOptions *option.OutboundTLSOptions) {
+	if tlsOptions == nil || !tlsOptions.Enabled {
+		return
+	}
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		query.Set("security", "reality")
+		query.Set("pbk", tlsOptions.Reality.PublicKey)
+		if tlsOptions.Reality.ShortID != "" {
+			query.Set("sid", tlsOptions.Reality.ShortID)
+		}
+	} else {
+		query.Set("security", "tls")
+	}
+	writeQueryTLS(query, tlsOptions, "allowInsecure")
+	if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+		query.Set("fp", tlsOptions.UTLS.Fingerprint)
+	}
+}
+
+// writeLinkTransport is the reverse of readLinkTransport.
+func writeLinkTransport(query url.Values, transport *option.V2RayTransportOptions) {
+	if transport == nil {
+		return
+	}
+	setIfNotEmpty := func(key string, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	query.Set("type", transport.Type)
+	switch transport.Type {
+	case C.V2RayTransportTypeHTTP:
+		setIfNotEmpty("host", strings.Join(transport.HTTPOptions.Host, ","))
+		setIfNotEmpty("path", transport.HTTPOptions.Path)
+	case C.V2RayTransportTypeWebsocket:
+		path := transport.WebsocketOptions.Path
+		if transport.WebsocketOptions.MaxEarlyData > 0 && transport.WebsocketOptions.EarlyDataHeaderName == earlyDataHeaderName {
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			path += separator + "ed=" + strconv.FormatUint(uint64(transport.WebsocketOptions.MaxEarlyData), 10)
+		}
+		setIfNotEmpty("path", path)
+		if host := transport.WebsocketOptions.Headers["Host"]; len(host) > 0 {
+			query.Set("host", host[0])
+		}
+	case C.V2RayTransportTypeGRPC:
+		setIfNotEmpty("serviceName", transport.GRPCOptions.ServiceName)
+	case C.V2RayTransportTypeHTTPUpgrade:
+		setIfNotEmpty("host", transport.HTTPUpgradeOptions.Host)
+		setIfNotEmpty("path", transport.HTTPUpgradeOptions.Path)
+	case C.V2RayTransportTypeXHTTP:
+		setIfNotEmpty("host", transport.XHTTPOptions.Host)
+		setIfNotEmpty("path", transport.XHTTPOptions.Path)
+		setIfNotEmpty("mode", transport.XHTTPOptions.Mode)
+	}
+}
+
+// diffOptions returns the paths of the fields set in original that differ
+// in converted.
+func diffOptions(original any, converted any) ([]string, error) {
+	originalValue, err := toJSONValue(original)
+	if err != nil {
+		return nil, err
+	}
+	convertedValue, err := toJSONValue(converted)
+	if err != nil {
+		return nil, err
+	}
+	var losses []string
+	diffValue("", originalValue, convertedValue, &losses)
+	sort.Strings(losses)
+	return losses, nil
+}
+
+func diffValue(path string, original any, converted any, losses *[]string) {
+	if originalMap, isMap := original.(map[string]any); isMap {
+		convertedMap, _ := converted.(map[string]any)
+		for key, value := range originalMap {
+			if path == "" {
+				diffValue(key, value, convertedMap[key], losses)
+			} else {
+				diffValue(path+"."+key, value, convertedMap[key], losses)
+			}
+		}
+		return
+	}
+	switch originalValue := original.(type) {
+	case nil:
+		return
+	case string:
+		if originalValue == "" {
+			return
+		}
+	case bool:
+		if !originalValue {
+			return
+		}
+	case float64:
+		if originalValue == 0 {
+			return
+		}
+	case []any:
+		if len(originalValue) == 0 {
+			return
+		}
+	}
+	if !reflect.DeepEqual(original, converted) {
+		*losses = append(*losses, path)
+	}
+}
+
+func toJSONValue(value any) (any, error) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var jsonValue any
+	err = json.Unmarshal(content, &jsonValue)
+	if err != nil {
+		return nil, err
+	}
+	return jsonValue, nil
+}
@@ -0,0 +1,137 @@
+// Package subscription converts share links and Clash proxies to sing-box
+// outbounds, and outbounds back to share links.
+package subscription
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+// Loss is a field that could not be converted and was dropped.
+type Loss struct {
+	Tag   string
+	Field string
+}
+
+func (l Loss) String() string {
+	return l.Tag + ": " + l.Field
+}
+
+type Result struct {
+	Outbounds []option.Outbound
+	Losses    []Loss
+	// Errors holds the entries that could not be converted at all.
+	Errors []error
+}
+
+// Parse converts a subscription, which is a Clash configuration or a list
+// of share links, optionally base64 encoded. Entries that fail are skipped
+// and reported in Result.Errors, it only fails if nothing was converted.
+func Parse(content []byte) (*Result, error) {
+	content = bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if len(content) == 0 {
+		return nil, E.New("empty subscription")
+	}
+	if !bytes.Contains(content, []byte("://")) && !isClash(content) {
+		decoded, err := decodeBase64(string(content))
+		if err == nil {
+			content = bytes.TrimSpace(decoded)
+		}
+	}
+	var (
+		result *Result
+		err    error
+	)
+	if isClash(content) {
+		result, err = ParseClash(content)
+	} else {
+		result = ParseLinks(string(content))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Outbounds) == 0 {
+		if len(result.Errors) > 0 {
+			return nil, E.Errors(result.Errors...)
+		}
+		return nil, E.New("no supported proxies found")
+	}
+	return result, nil
+}
+
+// ParseLinks converts share links, one per line.
+func ParseLinks(content string) *Result {
+	result := &Result{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		outbound, losses, err := ParseLink(line)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+		result.add(outbound, losses)
+	}
+	return result
+}
+
+func (r *Result) add(outbound option.Outbound, losses []string) {
+	outbound.Tag = r.uniqueTag(outbound.Tag)
+	r.Outbounds = append(r.Outbounds, outbound)
+	for _, field := range losses {
+		r.Losses = append(r.Losses, Loss{Tag: outbound.Tag, Field: field})
+	}
+}
+
+// uniqueTag numbers duplicated names, which are common in subscriptions.
+func (r *Result) uniqueTag(tag string) string {
+	exists := func(tag string) bool {
+		for _, outbound := range r.Outbounds {
+			if outbound.Tag == tag {
+				return true
+			}
+		}
+		return false
+	}
+	if !exists(tag) {
+		return tag
+	}
+	for i := 2; ; i++ {
+		newTag := F.ToString(tag, " ", i)
+		if !exists(newTag) {
+			return newTag
+		}
+	}
+}
+
+func isClash(content []byte) bool {
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("proxies:")) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBase64 accepts standard and URL encodings, with or without
+// padding, as found in share links.
+func decodeBase64(content string) ([]byte, error) {
+	content = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == ' ' {
+			return -1
+		}
+		return r
+	}, content)
+	content = strings.TrimRight(content, "=")
+	if strings.ContainsAny(content, "-_") {
+		return base64.RawURLEncoding.DecodeString(content)
+	}
+	return base64.RawStdEncoding.DecodeString(content)
+}
@@ -0,0 +1,408 @@
+package subscription_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/subscription"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkRoundTrip(t *testing.T) {
+	t.Parallel()
+	server := option.ServerOptions{Server: "example.org", ServerPort: 443}
+	tls := func(serverName string, alpn ...string) option.OutboundTLSOptionsContainer {
+		return option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+			Enabled:    true,
+			ServerName: serverName,
+			ALPN:       alpn,
+		}}
+	}
+	reality := option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: "www.microsoft.com",
+		UTLS:       &option.OutboundUTLSOptions{Enabled: true, Fingerprint: "chrome"},
+		Reality: &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: "jNXHt1yRo0vDuchQlIP6Z0ZvjT3KtzVI-T4E7RoLJS0",
+			ShortID:   "0123abcd",
+		},
+	}}
+	transports := []*option.V2RayTransportOptions{
+		nil,
+		{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Path:                "/ws",
+				Headers:             badoption.HTTPHeader{"Host": {"cdn.example.org"}},
+				MaxEarlyData:        2048,
+				EarlyDataHeaderName: "Sec-WebSocket-Protocol",
+			},
+		},
+		{
+			Type:        C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{ServiceName: "grpc"},
+		},
+		{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: []string{"a.example.org", "b.example.org"},
+				Path: "/h2",
+			},
+		},
+		{
+			Type: C.V2RayTransportTypeHTTPUpgrade,
+			HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{
+				Host: "cdn.example.org",
+				Path: "/upgrade",
+			},
+		},
+		{
+			Type: C.V2RayTransportTypeXHTTP,
+			XHTTPOptions: option.V2RayXHTTPOptions{
+				Host: "cdn.example.org",
+				Path: "/xhttp",
+				Mode: "stream-one",
+			},
+		},
+		{
+			Type: C.V2RayTransportTypeQUIC,
+		},
+	}
+	var outbounds []option.Outbound
+	for _, transport := range transports {
+		outbounds = append(outbounds, option.Outbound{
+			Type: C.TypeVMess,
+			Tag:  "vmess 节点",
+			Options: &option.VMessOutboundOptions{
+				ServerOptions:               server,
+				UUID:                        "b831381d-6324-4d53-ad4f-8cda48b30811",
+				Security:                    "auto",
+				AlterId:                     1,
+				OutboundTLSOptionsContainer: tls("example.org", "h2", "http/1.1"),
+				Transport:                   transport,
+			},
+		}, option.Outbound{
+			Type: C.TypeVLESS,
+			Tag:  "vless",
+			Options: &option.VLESSOutboundOptions{
+				ServerOptions: server,
+				UUID:          "b831381d-6324-4d53-ad4f-8cda48b30811",
+				Transport:     transport,
+			},
+		}, option.Outbound{
+			Type: C.TypeTrojan,
+			Tag:  "trojan",
+			Options: &option.TrojanOutboundOptions{
+				ServerOptions:               server,
+				Password:                    "pass@word#1",
+				OutboundTLSOptionsContainer: tls("example.org"),
+				Transport:                   transport,
+			},
+		})
+	}
+	outbounds = append(outbounds, []option.Outbound{
+		{
+			Type: C.TypeVLESS,
+			Tag:  "vless reality",
+			Options: &option.VLESSOutboundOptions{
+				ServerOptions:               server,
+				UUID:                        "b831381d-6324-4d53-ad4f-8cda48b30811",
+				Flow:                        "xtls-rprx-vision",
+				OutboundTLSOptionsContainer: reality,
+			},
+		},
+		{
+			Type: C.TypeTrojan,
+			Tag:  "trojan plain",
+			Options: &option.TrojanOutboundOptions{
+				ServerOptions: server,
+				Password:      "password",
+			},
+		},
+		{
+			Type: C.TypeShadowsocks,
+			Tag:  "ss",
+			Options: &option.ShadowsocksOutboundOptions{
+				ServerOptions: server,
+				Method:        "aes-128-gcm",
+				Password:      "pass:word",
+			},
+		},
+		{
+			Type: C.TypeShadowsocks,
+			Tag:  "ss 2022",
+			Options: &option.ShadowsocksOutboundOptions{
+				ServerOptions: option.ServerOptions{Server: "2001:db8::1", ServerPort: 8388},
+				Method:        "2022-blake3-aes-128-gcm",
+				Password:      "AAAAAAAAAAAAAAAAAAAAAA==",
+			},
+		},
+		{
+			Type: C.TypeShadowsocks,
+			Tag:  "ss plugin",
+			Options: &option.ShadowsocksOutboundOptions{
+				ServerOptions: server,
+				Method:        "chacha20-ietf-poly1305",
+				Password:      "password",
+				Plugin:        "obfs-local",
+				PluginOptions: "obfs=http;obfs-host=example.org",
+			},
+		},
+		{
+			Type: C.TypeHysteria2,
+			Tag:  "hysteria2",
+			Options: &option.Hysteria2OutboundOptions{
+				ServerOptions: server,
+				ServerPorts:   []string{"20000:30000", "40000:40000"},
+				UpMbps:        50,
+				DownMbps:      100,
+				Obfs:          &option.Hysteria2Obfs{Type: "salamander", Password: "obfs"},
+				Password:      "user:pass",
+				OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+					Enabled:    true,
+					ServerName: "example.org",
+					Insecure:   true,
+					ALPN:       []string{"h3"},
+				}},
+			},
+		},
+		{
+			Type: C.TypeTUIC,
+			Tag:  "tuic",
+			Options: &option.TUICOutboundOptions{
+				ServerOptions:     server,
+				UUID:              "b831381d-6324-4d53-ad4f-8cda48b30811",
+				Password:          "password",
+				CongestionControl: "bbr",
+				UDPRelayMode:      "quic",
+				OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+					Enabled:    true,
+					ServerName: "example.org",
+					DisableSNI: true,
+					Insecure:   true,
+					ALPN:       []string{"h3"},
+				}},
+			},
+		},
+		{
+			Type: C.TypeAnyTLS,
+			Tag:  "anytls",
+			Options: &option.AnyTLSOutboundOptions{
+				ServerOptions: server,
+				Password:      "password",
+				OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+					Enabled:    true,
+					ServerName: "example.org",
+					UTLS:       &option.OutboundUTLSOptions{Enabled: true, Fingerprint: "firefox"},
+				}},
+			},
+		},
+	}...)
+	for _, outbound := range outbounds {
+		link, losses, err := subscription.ExportLink(outbound)
+		require.NoError(t, err, outbound.Tag)
+		require.Empty(t, losses, link)
+		parsed, losses, err := subscription.ParseLink(link)
+		require.NoError(t, err, link)
+		require.Empty(t, losses, link)
+		require.Equal(t, outbound, parsed, link)
+		exported, _, err := subscription.ExportLink(parsed)
+		require.NoError(t, err)
+		require.Equal(t, link, exported)
+	}
+}
+
+func TestParseLink(t *testing.T) {
+	t.Parallel()
+	outbound, losses, err := subscription.ParseLink("vless://b831381d-6324-4d53-ad4f-8cda48b30811@example.org:443?encryption=none&flow=xtls-rprx-vision&security=reality&sni=www.microsoft.com&pbk=key&sid=ab&type=tcp&headerType=none&spx=%2F#reality")
+	require.NoError(t, err)
+	require.Equal(t, "reality", outbound.Tag)
+	require.Equal(t, []string{"spx"}, losses)
+	options := outbound.Options.(*option.VLESSOutboundOptions)
+	require.Equal(t, "xtls-rprx-vision", options.Flow)
+	require.Nil(t, options.Transport)
+	require.Equal(t, "chrome", options.TLS.UTLS.Fingerprint)
+	require.Equal(t, "key", options.TLS.Reality.PublicKey)
+
+	outbound, _, err = subscription.ParseLink("ss://YWVzLTEyOC1nY206cGFzc3dvcmRAMTI3LjAuMC4xOjgzODg#legacy")
+	require.NoError(t, err)
+	require.Equal(t, &option.ShadowsocksOutboundOptions{
+		ServerOptions: option.ServerOptions{Server: "127.0.0.1", ServerPort: 8388},
+		Method:        "aes-128-gcm",
+		Password:      "password",
+	}, outbound.Options)
+
+	outbound, _, err = subscription.ParseLink("trojan://password@example.org#")
+	require.NoError(t, err)
+	require.Equal(t, "example.org", outbound.Tag)
+	require.Equal(t, uint16(443), outbound.Options.(*option.TrojanOutboundOptions).ServerPort)
+
+	_, _, err = subscription.ParseLink("vless://uuid@example.org:443?type=kcp")
+	require.Error(t, err)
+	_, _, err = subscription.ParseLink("ssr://example")
+	require.Error(t, err)
+}
+
+func TestExportLinkLosses(t *testing.T) {
+	t.Parallel()
+	_, losses, err := subscription.ExportLink(option.Outbound{
+		Type: C.TypeVMess,
+		Tag:  "vmess",
+		Options: &option.VMessOutboundOptions{
+			ServerOptions: option.ServerOptions{Server: "example.org", ServerPort: 443},
+			UUID:          "b831381d-6324-4d53-ad4f-8cda48b30811",
+			Security:      "auto",
+			Multiplex:     &option.OutboundMultiplexOptions{Enabled: true},
+			DialerOptions: option.DialerOptions{Detour: "direct"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"detour", "multiplex.enabled"}, losses)
+
+	_, _, err = subscription.ExportLink(option.Outbound{Type: C.TypeDirect, Options: &option.DirectOutboundOptions{}})
+	require.Error(t, err)
+}
+
+func TestParseClash(t *testing.T) {
+	t.Parallel()
+	result, err := subscription.Parse([]byte(`
+mixed-port: 7890
+proxies:
+  - name: node
+    type: vmess
+    server: example.org
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    udp: false
+    tls: true
+    servername: example.org
+    skip-cert-verify: true
+    network: ws
+    ws-opts:
+      path: /ws
+      headers:
+        Host: cdn.example.org
+      max-early-data: 2048
+      early-data-header-name: Sec-WebSocket-Protocol
+      unknown: value
+  - name: node
+    type: ss
+    server: 127.0.0.1
+    port: 8388
+    cipher: aes-128-gcm
+    password: password
+    plugin: obfs
+    plugin-opts:
+      mode: tls
+      host: example.org
+  - name: hy2
+    type: hysteria2
+    server: example.org
+    port: 443
+    ports: 20000-30000
+    password: password
+    up: 30 Mbps
+    down: 100
+    sni: example.org
+    fingerprint: abcd
+  - name: vless
+    type: vless
+    server: example.org
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    flow: xtls-rprx-vision
+    tls: true
+    servername: www.microsoft.com
+    client-fingerprint: safari
+    reality-opts:
+      public-key: key
+      short-id: ab
+    smux:
+      enabled: true
+      protocol: h2mux
+  - name: snell
+    type: snell
+    server: example.org
+    port: 443
+`))
+	require.NoError(t, err)
+	require.Len(t, result.Outbounds, 4)
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, []subscription.Loss{
+		{Tag: "node", Field: "ws-opts.unknown"},
+		{Tag: "hy2", Field: "fingerprint"},
+	}, result.Losses)
+
+	require.Equal(t, option.Outbound{
+		Type: C.TypeVMess,
+		Tag:  "node",
+		Options: &option.VMessOutboundOptions{
+			ServerOptions: option.ServerOptions{Server: "example.org", ServerPort: 443},
+			UUID:          "b831381d-6324-4d53-ad4f-8cda48b30811",
+			Security:      "auto",
+			Network:       "tcp",
+			OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: "example.org",
+				Insecure:   true,
+			}},
+			Transport: &option.V2RayTransportOptions{
+				Type: C.V2RayTransportTypeWebsocket,
+				WebsocketOptions: option.V2RayWebsocketOptions{
+					Path:                "/ws",
+					Headers:             badoption.HTTPHeader{"Host": {"cdn.example.org"}},
+					MaxEarlyData:        2048,
+					EarlyDataHeaderName: "Sec-WebSocket-Protocol",
+				},
+			},
+		},
+	}, result.Outbounds[0])
+
+	require.Equal(t, "node 2", result.Outbounds[1].Tag)
+	shadowsocksOptions := result.Outbounds[1].Options.(*option.ShadowsocksOutboundOptions)
+	require.Equal(t, "obfs-local", shadowsocksOptions.Plugin)
+	require.Equal(t, "obfs=tls;obfs-host=example.org", shadowsocksOptions.PluginOptions)
+
+	hysteria2Options := result.Outbounds[2].Options.(*option.Hysteria2OutboundOptions)
+	require.Equal(t, badoption.Listable[string]{"20000:30000"}, hysteria2Options.ServerPorts)
+	require.Equal(t, 30, hysteria2Options.UpMbps)
+	require.Equal(t, 100, hysteria2Options.DownMbps)
+
+	vlessOptions := result.Outbounds[3].Options.(*option.VLESSOutboundOptions)
+	require.Equal(t, "safari", vlessOptions.TLS.UTLS.Fingerprint)
+	require.Equal(t, "ab", vlessOptions.TLS.Reality.ShortID)
+	require.Equal(t, "h2mux", vlessOptions.Multiplex.Protocol)
+}
+
+func TestParseBase64(t *testing.T) {
+	t.Parallel()
+	links := strings.Join([]string{
+		"trojan://password@example.org:443#node",
+		"trojan://password@example.org:8443#node",
+		"hysteria2://password@example.org:443/?sni=example.org#hy2",
+		"unknown://link",
+	}, "\r\n")
+	for _, content := range []string{
+		links,
+		base64.StdEncoding.EncodeToString([]byte(links)),
+		base64.RawURLEncoding.EncodeToString([]byte(links)),
+	} {
+		result, err := subscription.Parse([]byte(content))
+		require.NoError(t, err)
+		require.Len(t, result.Outbounds, 3)
+		require.Len(t, result.Errors, 1)
+		require.Equal(t, "node", result.Outbounds[0].Tag)
+		require.Equal(t, "node 2", result.Outbounds[1].Tag)
+		require.Equal(t, "hy2", result.Outbounds[2].Tag)
+	}
+	_, err := subscription.Parse([]byte("unknown://link"))
+	require.Error(t, err)
+}
@@ -0,0 +1,332 @@
+package urltest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	defaultLink      = "https://www.gstatic.com/generate_204"
+	defaultDNSServer = "1.1.1.1"
+	defaultDNSQuery  = "www.gstatic.com"
+	udpSampleTimeout = 2 * time.Second
+	maxBodySize      = 1 << 20
+)
+
+// Options configures a test. The method is chosen by the scheme of URL:
+//
+//	https://www.gstatic.com/generate_204  HTTP request over TCP
+//	dns://1.1.1.1:53/www.gstatic.com      DNS query over UDP
+//	stun://stun.l.google.com:19302        STUN binding request over UDP
+type Options struct {
+	URL string
+	// ExpectedStatus lists the accepted HTTP status codes, any status is
+	// accepted if empty.
+	ExpectedStatus []int
+	// ExpectedBody must be contained in the HTTP response body, which is
+	// fetched with GET instead of HEAD if set.
+	ExpectedBody string
+	Samples      int
+}
+
+type Result struct {
+	Delay  uint16
+	Jitter uint16
+	// Loss is the percentage of failed samples.
+	Loss uint8
+}
+
+// Method measures up to options.Samples round trips to link through detour,
+// and returns the delays of the successful ones.
+type Method func(ctx context.Context, link *url.URL, options Options, detour N.Dialer) ([]time.Duration, error)
+
+var (
+	methodAccess sync.RWMutex
+	methods      = map[string]Method{
+		"http":  testHTTP,
+		"https": testHTTP,
+		"dns":   testDNS,
+		"stun":  testSTUN,
+	}
+)
+
+func RegisterMethod(scheme string, method Method) {
+	methodAccess.Lock()
+	defer methodAccess.Unlock()
+	methods[scheme] = method
+}
+
+func loadMethod(scheme string) Method {
+	methodAccess.RLock()
+	defer methodAccess.RUnlock()
+	return methods[scheme]
+}
+
+// ParseURL parses a test URL and checks that its method is known.
+func ParseURL(link string) (*url.URL, error) {
+	if link == "" {
+		link = defaultLink
+	}
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if loadMethod(linkURL.Scheme) == nil {
+		return nil, E.New("unknown test method: ", linkURL.Scheme)
+	}
+	return linkURL, nil
+}
+
+func Test(ctx context.Context, options Options, detour N.Dialer) (*Result, error) {
+	linkURL, err := ParseURL(options.URL)
+	if err != nil {
+		return nil, err
+	}
+	if options.Samples <= 0 {
+		options.Samples = 1
+	}
+	delays, err := loadMethod(linkURL.Scheme)(ctx, linkURL, options, detour)
+	if len(delays) == 0 {
+		if err == nil {
+			err = E.New("all samples failed")
+		}
+		return nil, err
+	}
+	var (
+		total  time.Duration
+		jitter time.Duration
+	)
+	for i, delay := range delays {
+		total += delay
+		if i > 0 {
+			jitter += (delay - delays[i-1]).Abs()
+		}
+	}
+	result := &Result{
+		Delay: uint16(total / time.Duration(len(delays)) / time.Millisecond),
+		Loss:  uint8((options.Samples - len(delays)) * 100 / options.Samples),
+	}
+	if result.Delay == 0 {
+		// zero is reserved for failed tests
+		result.Delay = 1
+	}
+	if len(delays) > 1 {
+		result.Jitter = uint16(jitter / time.Duration(len(delays)-1) / time.Millisecond)
+	}
+	return result, nil
+}
+
+func testHTTP(ctx context.Context, link *url.URL, options Options, detour N.Dialer) ([]time.Duration, error) {
+	var (
+		delays []time.Duration
+		errors []error
+	)
+	for i := 0; i < options.Samples; i++ {
+		delay, err := httpSample(ctx, link, options, detour)
+		if err != nil {
+			errors = append(errors, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		delays = append(delays, delay)
+	}
+	return delays, E.Errors(errors...)
+}
+
+func httpSample(ctx context.Context, link *url.URL, options Options, detour N.Dialer) (time.Duration, error) {
+	hostname := link.Hostname()
+	port := link.Port()
+	if port == "" {
+		switch link.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+
+	start := time.Now()
+	instance, err := detour.DialContext(ctx, "tcp", M.ParseSocksaddrHostPortStr(hostname, port))
+	if err != nil {
+		return 0, err
+	}
+	defer instance.Close()
+	if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](instance); isEarlyConn && earlyConn.NeedHandshake() {
+		start = time.Now()
+	}
+	method := http.MethodHead
+	if options.ExpectedBody != "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, link.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return instance, nil
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(ctx),
+				RootCAs: adapter.RootPoolFromContext(ctx),
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: C.TCPTimeout,
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if len(options.ExpectedStatus) > 0 && !common.Contains(options.ExpectedStatus, resp.StatusCode) {
+		return 0, E.New("unexpected status: ", resp.Status)
+	}
+	if options.ExpectedBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		if err != nil {
+			return 0, E.Cause(err, "read body")
+		}
+		if !strings.Contains(string(body), options.ExpectedBody) {
+			return 0, E.New("unexpected body")
+		}
+	}
+	return time.Since(start), nil
+}
+
+func testDNS(ctx context.Context, link *url.URL, options Options, detour N.Dialer) ([]time.Duration, error) {
+	server := link.Hostname()
+	if server == "" {
+		server = defaultDNSServer
+	}
+	query := strings.Trim(link.Path, "/")
+	if query == "" {
+		query = defaultDNSQuery
+	}
+	return testPacket(ctx, M.ParseSocksaddrHostPortStr(server, portOr(link, "53")), options.Samples, detour, func() ([]byte, func([]byte) bool, error) {
+		message := new(mDNS.Msg)
+		message.SetQuestion(mDNS.Fqdn(query), mDNS.TypeA)
+		request, err := message.Pack()
+		if err != nil {
+			return nil, nil, err
+		}
+		return request, func(response []byte) bool {
+			var responseMessage mDNS.Msg
+			return responseMessage.Unpack(response) == nil && responseMessage.Response && responseMessage.Id == message.Id
+		}, nil
+	})
+}
+
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112A442
+	stunHeaderLength    = 20
+)
+
+func testSTUN(ctx context.Context, link *url.URL, options Options, detour N.Dialer) ([]time.Duration, error) {
+	if link.Hostname() == "" {
+		return nil, E.New("missing STUN server")
+	}
+	return testPacket(ctx, M.ParseSocksaddrHostPortStr(link.Hostname(), portOr(link, "3478")), options.Samples, detour, func() ([]byte, func([]byte) bool, error) {
+		request := make([]byte, stunHeaderLength)
+		binary.BigEndian.PutUint16(request, stunBindingRequest)
+		binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+		_, err := rand.Read(request[8:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return request, func(response []byte) bool {
+			return len(response) >= stunHeaderLength &&
+				binary.BigEndian.Uint16(response) == stunBindingResponse &&
+				bytes.Equal(response[4:stunHeaderLength], request[4:])
+		}, nil
+	})
+}
+
+func portOr(link *url.URL, defaultPort string) string {
+	if port := link.Port(); port != "" {
+		return port
+	}
+	return defaultPort
+}
+
+// testPacket sends a request per sample over a single packet connection,
+// a sample without a matching response within udpSampleTimeout is lost.
+func testPacket(ctx context.Context, destination M.Socksaddr, samples int, detour N.Dialer, newRequest func() ([]byte, func([]byte) bool, error)) ([]time.Duration, error) {
+	conn, err := detour.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	packetConn := bufio.NewPacketConn(conn)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	var (
+		delays   []time.Duration
+		lastErr  error
+		response = make([]byte, 2048)
+	)
+	for i := 0; i < samples && ctx.Err() == nil; i++ {
+		request, isResponse, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		_, err = bufio.WritePacket(packetConn, request, destination)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		conn.SetReadDeadline(start.Add(udpSampleTimeout))
+		for {
+			n, _, err := conn.ReadFrom(response)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			if isResponse(response[:n]) {
+				delays = append(delays, time.Since(start))
+				break
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		lastErr = ctx.Err()
+	}
+	return delays, lastErr
+}
@@ -0,0 +1,114 @@
+package urltest_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/urltest"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+	result, err := urltest.Test(context.Background(), urltest.Options{
+		URL:            server.URL,
+		ExpectedStatus: []int{http.StatusTeapot},
+		ExpectedBody:   "world",
+		Samples:        3,
+	}, N.SystemDialer)
+	require.NoError(t, err)
+	require.NotZero(t, result.Delay)
+	require.Zero(t, result.Loss)
+	_, err = urltest.Test(context.Background(), urltest.Options{
+		URL:            server.URL,
+		ExpectedStatus: []int{http.StatusNoContent},
+	}, N.SystemDialer)
+	require.Error(t, err)
+	_, err = urltest.Test(context.Background(), urltest.Options{
+		URL:          server.URL,
+		ExpectedBody: "goodbye",
+	}, N.SystemDialer)
+	require.Error(t, err)
+}
+
+func TestDNS(t *testing.T) {
+	t.Parallel()
+	address := listenUDP(t, func(request []byte) []byte {
+		var message mDNS.Msg
+		require.NoError(t, message.Unpack(request))
+		response := new(mDNS.Msg)
+		response.SetReply(&message)
+		responseBytes, err := response.Pack()
+		require.NoError(t, err)
+		return responseBytes
+	})
+	result, err := urltest.Test(context.Background(), urltest.Options{
+		URL:     "dns://" + address + "/example.com",
+		Samples: 3,
+	}, N.SystemDialer)
+	require.NoError(t, err)
+	require.NotZero(t, result.Delay)
+	require.Zero(t, result.Loss)
+}
+
+func TestSTUN(t *testing.T) {
+	t.Parallel()
+	var requests int
+	address := listenUDP(t, func(request []byte) []byte {
+		requests++
+		if requests == 2 {
+			// drop the second request
+			return nil
+		}
+		response := append([]byte{}, request...)
+		binary.BigEndian.PutUint16(response, 0x0101)
+		return response
+	})
+	result, err := urltest.Test(context.Background(), urltest.Options{
+		URL:     "stun://" + address,
+		Samples: 3,
+	}, N.SystemDialer)
+	require.NoError(t, err)
+	require.NotZero(t, result.Delay)
+	require.Equal(t, uint8(33), result.Loss)
+}
+
+func TestUnknownMethod(t *testing.T) {
+	t.Parallel()
+	_, err := urltest.ParseURL("ftp://example.com")
+	require.Error(t, err)
+}
+
+func listenUDP(t *testing.T, handler func(request []byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	go func() {
+		buffer := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			response := handler(buffer[:n])
+			if response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
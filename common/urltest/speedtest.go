@@ -0,0 +1,227 @@
+package urltest
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+const (
+	DefaultSpeedTestDownloadURL = "https://speed.cloudflare.com/__down?bytes=100000000"
+	DefaultSpeedTestUploadURL   = "https://speed.cloudflare.com/__up"
+	DefaultSpeedTestStreams     = 4
+	DefaultSpeedTestDuration    = 10 * time.Second
+
+	speedTestChunkSize = 32 * 1024
+)
+
+type SpeedTestOptions struct {
+	DownloadURL string
+	UploadURL   string
+	// Streams is the number of parallel connections.
+	Streams int
+	// Duration caps the download and the upload phase each.
+	Duration        time.Duration
+	DisableDownload bool
+	DisableUpload   bool
+}
+
+type SpeedTestResult struct {
+	// Delay is the shortest time to the first download response, in
+	// milliseconds.
+	Delay uint16 `json:"delay"`
+	// Download and Upload are in bytes per second.
+	Download uint64 `json:"download"`
+	Upload   uint64 `json:"upload"`
+}
+
+// SpeedTest measures the throughput through detour. Each stream repeats its
+// request until the duration is over, so that small endpoints work too.
+func SpeedTest(ctx context.Context, options SpeedTestOptions, detour N.Dialer) (*SpeedTestResult, error) {
+	if options.DownloadURL == "" {
+		options.DownloadURL = DefaultSpeedTestDownloadURL
+	}
+	if options.UploadURL == "" {
+		options.UploadURL = DefaultSpeedTestUploadURL
+	}
+	if options.Streams <= 0 {
+		options.Streams = DefaultSpeedTestStreams
+	}
+	if options.Duration <= 0 {
+		options.Duration = DefaultSpeedTestDuration
+	}
+	test := &speedTest{
+		options: options,
+		detour:  detour,
+	}
+	var result SpeedTestResult
+	if !options.DisableDownload {
+		download, err := test.run(ctx, false)
+		if err != nil {
+			return nil, E.Cause(err, "download")
+		}
+		result.Download = download
+	}
+	if !options.DisableUpload {
+		upload, err := test.run(ctx, true)
+		if err != nil {
+			return nil, E.Cause(err, "upload")
+		}
+		result.Upload = upload
+	}
+	if delay := time.Duration(test.delay.Load()); delay > 0 {
+		result.Delay = max(uint16(delay/time.Millisecond), 1)
+	}
+	return &result, nil
+}
+
+type speedTest struct {
+	options SpeedTestOptions
+	detour  N.Dialer
+	delay   atomic.Int64
+}
+
+func (t *speedTest) run(ctx context.Context, upload bool) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.options.Duration)
+	defer cancel()
+	var (
+		transferred atomic.Uint64
+		wg          sync.WaitGroup
+		errAccess   sync.Mutex
+		errors      []error
+	)
+	start := time.Now()
+	for i := 0; i < t.options.Streams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := t.stream(ctx, upload, &transferred)
+			if err != nil {
+				errAccess.Lock()
+				errors = append(errors, err)
+				errAccess.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if transferred.Load() == 0 {
+		if len(errors) == 0 {
+			return 0, E.New("nothing transferred")
+		}
+		return 0, E.Errors(errors...)
+	}
+	return uint64(float64(transferred.Load()) / elapsed.Seconds()), nil
+}
+
+func (t *speedTest) stream(ctx context.Context, upload bool, transferred *atomic.Uint64) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := t.detour.DialContext(ctx, network, M.ParseSocksaddr(addr))
+				if err != nil || !upload {
+					return conn, err
+				}
+				// count the bytes written to the connection, not the ones
+				// buffered by the HTTP transport
+				return bufio.NewCounterConn(conn, nil, []N.CountFunc{func(n int64) {
+					transferred.Add(uint64(n))
+				}}), nil
+			},
+			TLSClientConfig: &tls.Config{
+				Time:    ntp.TimeFuncFromContext(ctx),
+				RootCAs: adapter.RootPoolFromContext(ctx),
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+	for ctx.Err() == nil {
+		var (
+			request *http.Request
+			err     error
+		)
+		if upload {
+			request, err = http.NewRequestWithContext(ctx, http.MethodPost, t.options.UploadURL, &zeroReader{ctx: ctx})
+			if request != nil {
+				request.Header.Set("Content-Type", "application/octet-stream")
+			}
+		} else {
+			request, err = http.NewRequestWithContext(ctx, http.MethodGet, t.options.DownloadURL, nil)
+		}
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		response, err := client.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if response.StatusCode >= 400 {
+			response.Body.Close()
+			return E.New("unexpected status: ", response.Status)
+		}
+		if !upload {
+			t.updateDelay(time.Since(start))
+			_, err = io.Copy(&countWriter{transferred}, response.Body)
+		} else {
+			_, err = io.Copy(io.Discard, response.Body)
+		}
+		response.Body.Close()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *speedTest) updateDelay(delay time.Duration) {
+	for {
+		current := t.delay.Load()
+		if current != 0 && current <= int64(delay) {
+			return
+		}
+		if t.delay.CompareAndSwap(current, int64(delay)) {
+			return
+		}
+	}
+}
+
+type countWriter struct {
+	transferred *atomic.Uint64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.transferred.Add(uint64(len(p)))
+	return len(p), nil
+}
+
+// zeroReader is an endless upload body, it ends when the test is over.
+type zeroReader struct {
+	ctx context.Context
+}
+
+func (r *zeroReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, io.EOF
+	}
+	if len(p) > speedTestChunkSize {
+		p = p[:speedTestChunkSize]
+	}
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,65 @@
+package urltest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpeedTest(t *testing.T) {
+	t.Parallel()
+	chunk := make([]byte, 64*1024)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 16; i++ {
+			_, err := w.Write(chunk)
+			if err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	result, err := urltest.SpeedTest(context.Background(), urltest.SpeedTestOptions{
+		DownloadURL: server.URL + "/down",
+		UploadURL:   server.URL + "/up",
+		Streams:     2,
+		Duration:    300 * time.Millisecond,
+	}, N.SystemDialer)
+	require.NoError(t, err)
+	require.NotZero(t, result.Delay)
+	require.NotZero(t, result.Download)
+	require.NotZero(t, result.Upload)
+	_, err = urltest.SpeedTest(context.Background(), urltest.SpeedTestOptions{
+		DownloadURL:   server.URL + "/missing",
+		DisableUpload: true,
+		Duration:      300 * time.Millisecond,
+	}, N.SystemDialer)
+	require.Error(t, err)
+}
+
+func TestDeleteHistoryKeepsSpeed(t *testing.T) {
+	t.Parallel()
+	storage := urltest.NewHistoryStorage()
+	urltest.DeleteHistory(storage, "proxy")
+	require.Nil(t, storage.LoadURLTestHistory("proxy"))
+	storage.StoreURLTestHistory("proxy", &adapter.URLTestHistory{Time: time.Now(), Delay: 100, Download: 1000, Upload: 500})
+	urltest.DeleteHistory(storage, "proxy")
+	history := storage.LoadURLTestHistory("proxy")
+	require.NotNil(t, history)
+	require.Zero(t, history.Delay)
+	require.Equal(t, uint64(1000), history.Download)
+	require.Equal(t, uint64(500), history.Upload)
+}
@@ -2,19 +2,12 @@ package urltest
 
 import (
 	"context"
-	"crypto/tls"
-	"net"
-	"net/http"
 	"net/url"
 	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
-	C "github.com/sagernet/sing-box/constant"
-	"github.com/sagernet/sing/common"
-	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/ntp"
 )
 
 var _ adapter.URLTestHistoryStorage = (*HistoryStorage)(nil)
@@ -75,59 +68,44 @@ func (s *HistoryStorage) Close() error {
 	return nil
 }
 
+// StoreHistory stores a latency test result, keeping the speed test result
+// of the last one.
+func StoreHistory(storage adapter.URLTestHistoryStorage, tag string, history *adapter.URLTestHistory) {
+	if lastHistory := storage.LoadURLTestHistory(tag); lastHistory != nil && history.Download == 0 && history.Upload == 0 {
+		history.Download = lastHistory.Download
+		history.Upload = lastHistory.Upload
+	}
+	storage.StoreURLTestHistory(tag, history)
+}
+
 func URLTest(ctx context.Context, link string, detour N.Dialer) (t uint16, err error) {
 	if link == "" {
-		link = "https://www.gstatic.com/generate_204"
+		link = defaultLink
 	}
 	linkURL, err := url.Parse(link)
 	if err != nil {
 		return
 	}
-	hostname := linkURL.Hostname()
-	port := linkURL.Port()
-	if port == "" {
-		switch linkURL.Scheme {
-		case "http":
-			port = "80"
-		case "https":
-			port = "443"
-		}
-	}
-
-	start := time.Now()
-	instance, err := detour.DialContext(ctx, "tcp", M.ParseSocksaddrHostPortStr(hostname, port))
-	if err != nil {
-		return
-	}
-	defer instance.Close()
-	if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](instance); isEarlyConn && earlyConn.NeedHandshake() {
-		start = time.Now()
-	}
-	req, err := http.NewRequest(http.MethodHead, link, nil)
+	delay, err := httpSample(ctx, linkURL, Options{}, detour)
 	if err != nil {
 		return
 	}
-	client := http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return instance, nil
-			},
-			TLSClientConfig: &tls.Config{
-				Time:    ntp.TimeFuncFromContext(ctx),
-				RootCAs: adapter.RootPoolFromContext(ctx),
-			},
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Timeout: C.TCPTimeout,
-	}
-	defer client.CloseIdleConnections()
-	resp, err := client.Do(req.WithContext(ctx))
-	if err != nil {
+	t = uint16(delay / time.Millisecond)
+	return
+}
+
+// DeleteHistory drops the latency test result of a failed test. The speed
+// test result is kept in a history with zero delay, which marks the
+// outbound unavailable.
+func DeleteHistory(storage adapter.URLTestHistoryStorage, tag string) {
+	lastHistory := storage.LoadURLTestHistory(tag)
+	if lastHistory == nil || lastHistory.Download == 0 && lastHistory.Upload == 0 {
+		storage.DeleteURLTestHistory(tag)
 		return
 	}
-	resp.Body.Close()
-	t = uint16(time.Since(start) / time.Millisecond)
-	return
+	storage.StoreURLTestHistory(tag, &adapter.URLTestHistory{
+		Loss:     100,
+		Download: lastHistory.Download,
+		Upload:   lastHistory.Upload,
+	})
 }
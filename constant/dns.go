@@ -28,6 +28,13 @@ const (
 	DNSTypeFakeIP      = "fakeip"
 	DNSTypeDHCP        = "dhcp"
 	DNSTypeTailscale   = "tailscale"
+	DNSTypeGroup       = "group"
+)
+
+const (
+	DNSGroupStrategyRace     = "race"
+	DNSGroupStrategyFallback = "fallback"
+	DNSGroupStrategyFastest  = "fastest"
 )
 
 const (
@@ -28,6 +28,10 @@ const (
 	TypeDERP         = "derp"
 	TypeResolved     = "resolved"
 	TypeSSMAPI       = "ssm-api"
+	TypeDHCP         = "dhcp"
+	TypeBridge       = "bridge"
+	TypePortal       = "portal"
+	TypeForward      = "forward"
 )
 
 const (
@@ -84,6 +88,12 @@ func ProxyDisplayName(proxyType string) string {
 		return "Hysteria2"
 	case TypeAnyTLS:
 		return "AnyTLS"
+	case TypeBridge:
+		return "Bridge"
+	case TypePortal:
+		return "Portal"
+	case TypeForward:
+		return "Forward"
 	case TypeSelector:
 		return "Selector"
 	case TypeURLTest:
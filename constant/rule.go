@@ -18,11 +18,21 @@ const (
 	RuleSetFormatBinary = "binary"
 )
 
+const (
+	RuleSetFormatClashDomain    = "clash-domain"
+	RuleSetFormatClashIPCIDR    = "clash-ipcidr"
+	RuleSetFormatClashClassical = "clash-classical"
+	RuleSetFormatSurgeList      = "surge-list"
+	RuleSetFormatHosts          = "hosts"
+	RuleSetFormatDnsmasq        = "dnsmasq"
+)
+
 const (
 	RuleSetVersion1 = 1 + iota
 	RuleSetVersion2
 	RuleSetVersion3
-	RuleSetVersionCurrent = RuleSetVersion3
+	RuleSetVersion4
+	RuleSetVersionCurrent = RuleSetVersion4
 )
 
 const (
@@ -42,18 +42,43 @@ func IsCacheHit(ctx context.Context) bool {
 
 var _ adapter.DNSClient = (*Client)(nil)
 
+const (
+	// staleTTL is the TTL of expired answers, as recommended by RFC 8767.
+	staleTTL = 30
+	// defaultServeStaleMaxAge is how long answers are served after expiry.
+	defaultServeStaleMaxAge = 24 * time.Hour
+	// defaultPrefetchMinHits is the hit count making an answer popular
+	// enough to be refreshed before expiry.
+	defaultPrefetchMinHits = 2
+	// maxNegativeTTL bounds the caching of negative answers, as RFC 2308.
+	maxNegativeTTL = 3600
+	// maxConcurrentRefresh bounds the background serve-stale and prefetch
+	// exchanges.
+	maxConcurrentRefresh = 16
+)
+
 type Client struct {
 	timeout            time.Duration
 	disableCache       bool
 	disableExpire      bool
 	independentCache   bool
 	clientSubnet       netip.Prefix
+	serveStale         bool
+	serveStaleMaxAge   time.Duration
+	prefetch           bool
+	prefetchMinHits    uint32
+	refreshAccess      chan struct{}
+	dnssec             bool
+	validator          *validator
 	rdrc               adapter.RDRCStore
 	initRDRCFunc       func() adapter.RDRCStore
+	cacheStore         adapter.DNSCacheStore
+	initCacheStoreFunc func() adapter.DNSCacheStore
+	transportManager   adapter.DNSTransportManager
 	logger             logger.ContextLogger
-	cache              freelru.Cache[dns.Question, *dns.Msg]
+	cache              freelru.Cache[dns.Question, *cacheEntry]
 	cacheLock          compatible.Map[dns.Question, chan struct{}]
-	transportCache     freelru.Cache[transportCacheKey, *dns.Msg]
+	transportCache     freelru.Cache[transportCacheKey, *cacheEntry]
 	transportCacheLock compatible.Map[dns.Question, chan struct{}]
 	cacheHitCallback   func() // 缓存命中回调
 }
@@ -65,32 +90,57 @@ type ClientOptions struct {
 	IndependentCache bool
 	CacheCapacity    uint32
 	ClientSubnet     netip.Prefix
-	RDRC             func() adapter.RDRCStore
-	Logger           logger.ContextLogger
+	ServeStale       bool
+	ServeStaleMaxAge time.Duration
+	Prefetch         bool
+	PrefetchMinHits  uint32
+	DNSSEC           bool
+	// DNSSECTrustAnchor is the DS or DNSKEY records trusted, root anchors
+	// are used if empty.
+	DNSSECTrustAnchor []dns.RR
+	RDRC              func() adapter.RDRCStore
+	CacheStore        func() adapter.DNSCacheStore
+	TransportManager  adapter.DNSTransportManager
+	Logger            logger.ContextLogger
 }
 
 func NewClient(options ClientOptions) *Client {
 	client := &Client{
-		timeout:          options.Timeout,
-		disableCache:     options.DisableCache,
-		disableExpire:    options.DisableExpire,
-		independentCache: options.IndependentCache,
-		clientSubnet:     options.ClientSubnet,
-		initRDRCFunc:     options.RDRC,
-		logger:           options.Logger,
+		timeout:            options.Timeout,
+		disableCache:       options.DisableCache,
+		disableExpire:      options.DisableExpire,
+		independentCache:   options.IndependentCache,
+		clientSubnet:       options.ClientSubnet,
+		serveStale:         options.ServeStale,
+		serveStaleMaxAge:   options.ServeStaleMaxAge,
+		prefetch:           options.Prefetch,
+		prefetchMinHits:    options.PrefetchMinHits,
+		refreshAccess:      make(chan struct{}, maxConcurrentRefresh),
+		dnssec:             options.DNSSEC,
+		validator:          newValidator(options.DNSSECTrustAnchor),
+		initRDRCFunc:       options.RDRC,
+		initCacheStoreFunc: options.CacheStore,
+		transportManager:   options.TransportManager,
+		logger:             options.Logger,
 	}
 	if client.timeout == 0 {
 		client.timeout = C.DNSTimeout
 	}
+	if client.serveStaleMaxAge == 0 {
+		client.serveStaleMaxAge = defaultServeStaleMaxAge
+	}
+	if client.prefetchMinHits == 0 {
+		client.prefetchMinHits = defaultPrefetchMinHits
+	}
 	cacheCapacity := options.CacheCapacity
 	if cacheCapacity < 1024 {
 		cacheCapacity = 1024
 	}
 	if !client.disableCache {
 		if !client.independentCache {
-			client.cache = common.Must1(freelru.NewSharded[dns.Question, *dns.Msg](cacheCapacity, maphash.NewHasher[dns.Question]().Hash32))
+			client.cache = common.Must1(freelru.NewSharded[dns.Question, *cacheEntry](cacheCapacity, maphash.NewHasher[dns.Question]().Hash32))
 		} else {
-			client.transportCache = common.Must1(freelru.NewSharded[transportCacheKey, *dns.Msg](cacheCapacity, maphash.NewHasher[transportCacheKey]().Hash32))
+			client.transportCache = common.Must1(freelru.NewSharded[transportCacheKey, *cacheEntry](cacheCapacity, maphash.NewHasher[transportCacheKey]().Hash32))
 		}
 	}
 	return client
@@ -105,6 +155,19 @@ func (c *Client) Start() {
 	if c.initRDRCFunc != nil {
 		c.rdrc = c.initRDRCFunc()
 	}
+	if c.initCacheStoreFunc != nil && !c.disableCache {
+		c.cacheStore = c.initCacheStoreFunc()
+		if c.cacheStore != nil {
+			c.loadCache()
+		}
+	}
+}
+
+func (c *Client) Close() error {
+	if c.cacheStore == nil {
+		return nil
+	}
+	return c.saveCache()
 }
 
 func extractNegativeTTL(response *dns.Msg) (uint32, bool) {
@@ -113,7 +176,10 @@ func extractNegativeTTL(response *dns.Msg) (uint32, bool) {
 			soaTTL := soa.Header().Ttl
 			soaMinimum := soa.Minttl
 			if soaTTL < soaMinimum {
-				return soaTTL, true
+				soaMinimum = soaTTL
+			}
+			if soaMinimum > maxNegativeTTL {
+				soaMinimum = maxNegativeTTL
 			}
 			return soaMinimum, true
 		}
@@ -174,7 +240,9 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 				}()
 			}
 		}
-		response, ttl := c.loadResponse(question, transport)
+		requestOpt := message.IsEdns0()
+		dnssecOK := requestOpt != nil && requestOpt.Do()
+		response, ttl := c.loadResponse(ctx, question, transport, c.serveStaleEnabled(options), c.dnssecEnabled(transport, options), dnssecOK)
 		if response != nil {
 			logCachedResponse(c.logger, ctx, response, ttl)
 			response.Id = message.Id
@@ -185,7 +253,11 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			return response, nil
 		}
 	}
+	return c.exchange(ctx, transport, message, options, responseChecker, disableCache)
+}
 
+func (c *Client) exchange(ctx context.Context, transport adapter.DNSTransport, message *dns.Msg, options adapter.DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool, disableCache bool) (*dns.Msg, error) {
+	question := message.Question[0]
 	messageId := message.Id
 	contextTransport, clientSubnetLoaded := transportTagFromContext(ctx)
 	if clientSubnetLoaded && transport.Tag() == contextTransport {
@@ -198,8 +270,13 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			return nil, ErrResponseRejectedCached
 		}
 	}
+	dnssec := c.dnssecEnabled(transport, options)
+	requestMessage := message
+	if dnssec {
+		requestMessage = withDNSSECOK(message)
+	}
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
-	response, err := transport.Exchange(ctx, message)
+	response, err := transport.Exchange(ctx, requestMessage)
 	cancel()
 	if err != nil {
 		var rcodeError RcodeError
@@ -209,6 +286,22 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 			return nil, err
 		}
 	}
+	if dnssec && (response.Rcode == dns.RcodeSuccess || response.Rcode == dns.RcodeNameError) {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		secure, err := c.validator.validate(ctx, transport, question, response)
+		cancel()
+		if err != nil {
+			if c.logger != nil {
+				c.logger.WarnContext(ctx, E.Cause(err, "bogus answer for ", FormatQuestion(question.String())))
+			}
+			response = FixedResponseStatus(message, dns.RcodeServerFailure)
+			if responseChecker != nil {
+				return response, ErrResponseRejected
+			}
+			return response, nil
+		}
+		response.AuthenticatedData = secure
+	}
 	/*if question.Qtype == dns.TypeA || question.Qtype == dns.TypeAAAA {
 		validResponse := response
 	loop:
@@ -304,7 +397,11 @@ func (c *Client) Exchange(ctx context.Context, transport adapter.DNSTransport, m
 		}
 	}
 	if !disableCache {
-		c.storeCache(transport, question, response, timeToLive)
+		c.storeCache(transport, question, response, timeToLive, options, responseChecker == nil, dnssec)
+	}
+	if requestOpt := message.IsEdns0(); dnssec && (requestOpt == nil || !requestOpt.Do()) {
+		// cached with DNSSEC records for requests asking them
+		stripDNSSEC(question, response)
 	}
 	response.Id = messageId
 	requestEDNSOpt := message.IsEdns0()
@@ -378,37 +475,37 @@ func (c *Client) LookupCache(domain string, strategy C.DomainStrategy) ([]netip.
 	}
 	dnsName := dns.Fqdn(domain)
 	if strategy == C.DomainStrategyIPv4Only {
-		addresses, err := c.questionCache(dns.Question{
+		addresses, err := c.questionCache(context.Background(), dns.Question{
 			Name:   dnsName,
 			Qtype:  dns.TypeA,
 			Qclass: dns.ClassINET,
-		}, nil)
+		}, nil, false, c.dnssec)
 		if err != ErrNotCached {
 			return addresses, true
 		}
 	} else if strategy == C.DomainStrategyIPv6Only {
-		addresses, err := c.questionCache(dns.Question{
+		addresses, err := c.questionCache(context.Background(), dns.Question{
 			Name:   dnsName,
 			Qtype:  dns.TypeAAAA,
 			Qclass: dns.ClassINET,
-		}, nil)
+		}, nil, false, c.dnssec)
 		if err != ErrNotCached {
 			return addresses, true
 		}
 	} else {
-		response4, _ := c.loadResponse(dns.Question{
+		response4, _ := c.loadResponse(context.Background(), dns.Question{
 			Name:   dnsName,
 			Qtype:  dns.TypeA,
 			Qclass: dns.ClassINET,
-		}, nil)
+		}, nil, false, c.dnssec, false)
 		if response4 == nil {
 			return nil, false
 		}
-		response6, _ := c.loadResponse(dns.Question{
+		response6, _ := c.loadResponse(context.Background(), dns.Question{
 			Name:   dnsName,
 			Qtype:  dns.TypeAAAA,
 			Qclass: dns.ClassINET,
-		}, nil)
+		}, nil, false, c.dnssec, false)
 		if response6 == nil {
 			return nil, false
 		}
@@ -422,7 +519,8 @@ func (c *Client) ExchangeCache(ctx context.Context, message *dns.Msg) (*dns.Msg,
 		return nil, false
 	}
 	question := message.Question[0]
-	response, ttl := c.loadResponse(question, nil)
+	requestOpt := message.IsEdns0()
+	response, ttl := c.loadResponse(ctx, question, nil, false, c.dnssec, requestOpt != nil && requestOpt.Do())
 	if response == nil {
 		return nil, false
 	}
@@ -439,31 +537,6 @@ func sortAddresses(response4 []netip.Addr, response6 []netip.Addr, strategy C.Do
 	}
 }
 
-func (c *Client) storeCache(transport adapter.DNSTransport, question dns.Question, message *dns.Msg, timeToLive uint32) {
-	if timeToLive == 0 {
-		return
-	}
-	if c.disableExpire {
-		if !c.independentCache {
-			c.cache.Add(question, message)
-		} else {
-			c.transportCache.Add(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			}, message)
-		}
-	} else {
-		if !c.independentCache {
-			c.cache.AddWithLifetime(question, message, time.Second*time.Duration(timeToLive))
-		} else {
-			c.transportCache.AddWithLifetime(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			}, message, time.Second*time.Duration(timeToLive))
-		}
-	}
-}
-
 func (c *Client) lookupToExchange(ctx context.Context, transport adapter.DNSTransport, name string, qType uint16, options adapter.DNSQueryOptions, responseChecker func(responseAddrs []netip.Addr) bool) ([]netip.Addr, error) {
 	question := dns.Question{
 		Name:   name,
@@ -472,7 +545,7 @@ func (c *Client) lookupToExchange(ctx context.Context, transport adapter.DNSTran
 	}
 	disableCache := c.disableCache || options.DisableCache
 	if !disableCache {
-		cachedAddresses, err := c.questionCache(question, transport)
+		cachedAddresses, err := c.questionCache(ctx, question, transport, c.serveStaleEnabled(options), c.dnssecEnabled(transport, options))
 		if err != ErrNotCached {
 			return cachedAddresses, err
 		}
@@ -493,8 +566,8 @@ func (c *Client) lookupToExchange(ctx context.Context, transport adapter.DNSTran
 	return MessageToAddresses(response), nil
 }
 
-func (c *Client) questionCache(question dns.Question, transport adapter.DNSTransport) ([]netip.Addr, error) {
-	response, _ := c.loadResponse(question, transport)
+func (c *Client) questionCache(ctx context.Context, question dns.Question, transport adapter.DNSTransport, serveStale bool, validated bool) ([]netip.Addr, error) {
+	response, _ := c.loadResponse(ctx, question, transport, serveStale, validated, false)
 	if response == nil {
 		return nil, ErrNotCached
 	}
@@ -504,80 +577,6 @@ func (c *Client) questionCache(question dns.Question, transport adapter.DNSTrans
 	return MessageToAddresses(response), nil
 }
 
-func (c *Client) loadResponse(question dns.Question, transport adapter.DNSTransport) (*dns.Msg, int) {
-	var (
-		response *dns.Msg
-		loaded   bool
-	)
-	if c.disableExpire {
-		if !c.independentCache {
-			response, loaded = c.cache.Get(question)
-		} else {
-			response, loaded = c.transportCache.Get(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			})
-		}
-		if !loaded {
-			return nil, 0
-		}
-		return response.Copy(), 0
-	} else {
-		var expireAt time.Time
-		if !c.independentCache {
-			response, expireAt, loaded = c.cache.GetWithLifetime(question)
-		} else {
-			response, expireAt, loaded = c.transportCache.GetWithLifetime(transportCacheKey{
-				Question:     question,
-				transportTag: transport.Tag(),
-			})
-		}
-		if !loaded {
-			return nil, 0
-		}
-		timeNow := time.Now()
-		if timeNow.After(expireAt) {
-			if !c.independentCache {
-				c.cache.Remove(question)
-			} else {
-				c.transportCache.Remove(transportCacheKey{
-					Question:     question,
-					transportTag: transport.Tag(),
-				})
-			}
-			return nil, 0
-		}
-		var originTTL int
-		for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
-			for _, record := range recordList {
-				if originTTL == 0 || record.Header().Ttl > 0 && int(record.Header().Ttl) < originTTL {
-					originTTL = int(record.Header().Ttl)
-				}
-			}
-		}
-		nowTTL := int(expireAt.Sub(timeNow).Seconds())
-		if nowTTL < 0 {
-			nowTTL = 0
-		}
-		response = response.Copy()
-		if originTTL > 0 {
-			duration := uint32(originTTL - nowTTL)
-			for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
-				for _, record := range recordList {
-					record.Header().Ttl = record.Header().Ttl - duration
-				}
-			}
-		} else {
-			for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
-				for _, record := range recordList {
-					record.Header().Ttl = uint32(nowTTL)
-				}
-			}
-		}
-		return response, nowTTL
-	}
-}
-
 func MessageToAddresses(response *dns.Msg) []netip.Addr {
 	if response == nil || response.Rcode != dns.RcodeSuccess {
 		return nil
@@ -0,0 +1,287 @@
+package dns
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/miekg/dns"
+)
+
+type cacheEntry struct {
+	message    *dns.Msg
+	timeToLive uint32
+	expire     time.Time
+	transport  adapter.DNSTransport
+	options    adapter.DNSQueryOptions
+	// refreshable entries may be served stale and prefetched, answers
+	// checked by address limit rules are not as refreshing skips the check.
+	refreshable bool
+	// validated entries are answered by DNSSEC validating exchanges and
+	// keep their DNSSEC records, only served to validating requests.
+	validated  bool
+	hits       atomic.Uint32
+	refreshing atomic.Bool
+}
+
+func (c *Client) serveStaleEnabled(options adapter.DNSQueryOptions) bool {
+	if options.ServeStale != nil {
+		return *options.ServeStale
+	}
+	return c.serveStale
+}
+
+func (c *Client) prefetchEnabled(options adapter.DNSQueryOptions) bool {
+	if options.Prefetch != nil {
+		return *options.Prefetch
+	}
+	return c.prefetch
+}
+
+func (c *Client) cacheKey(question dns.Question, transport adapter.DNSTransport) transportCacheKey {
+	return transportCacheKey{
+		Question:     question,
+		transportTag: transport.Tag(),
+	}
+}
+
+func (c *Client) peekEntry(question dns.Question, transport adapter.DNSTransport) (*cacheEntry, bool) {
+	if !c.independentCache {
+		return c.cache.Peek(question)
+	} else {
+		return c.transportCache.Peek(c.cacheKey(question, transport))
+	}
+}
+
+func (c *Client) storeEntry(question dns.Question, entry *cacheEntry, lifetime time.Duration) {
+	if !c.independentCache {
+		if lifetime == 0 {
+			c.cache.Add(question, entry)
+		} else {
+			c.cache.AddWithLifetime(question, entry, lifetime)
+		}
+	} else {
+		if lifetime == 0 {
+			c.transportCache.Add(c.cacheKey(question, entry.transport), entry)
+		} else {
+			c.transportCache.AddWithLifetime(c.cacheKey(question, entry.transport), entry, lifetime)
+		}
+	}
+}
+
+func (c *Client) storeCache(transport adapter.DNSTransport, question dns.Question, message *dns.Msg, timeToLive uint32, options adapter.DNSQueryOptions, refreshable bool, validated bool) {
+	if timeToLive == 0 {
+		return
+	}
+	entry := &cacheEntry{
+		message:     message.Copy(),
+		timeToLive:  timeToLive,
+		expire:      time.Now().Add(time.Second * time.Duration(timeToLive)),
+		transport:   transport,
+		options:     options,
+		refreshable: refreshable,
+		validated:   validated,
+	}
+	// keep the popularity of refreshed answers
+	if oldEntry, loaded := c.peekEntry(question, transport); loaded {
+		entry.hits.Store(oldEntry.hits.Load())
+	}
+	c.storeEntry(question, entry, c.entryLifetime(entry))
+}
+
+// entryLifetime is how long the entry stays in cache, expired entries are
+// kept for serve-stale. Zero means forever.
+func (c *Client) entryLifetime(entry *cacheEntry) time.Duration {
+	if c.disableExpire {
+		return 0
+	}
+	lifetime := time.Until(entry.expire)
+	if entry.refreshable && c.serveStaleEnabled(entry.options) {
+		lifetime += c.serveStaleMaxAge
+	}
+	return lifetime
+}
+
+// loadResponse loads the cached answer matching the validation state of
+// the request, DNSSEC records are removed unless dnssecOK.
+func (c *Client) loadResponse(ctx context.Context, question dns.Question, transport adapter.DNSTransport, serveStale bool, validated bool, dnssecOK bool) (*dns.Msg, int) {
+	var (
+		entry  *cacheEntry
+		loaded bool
+	)
+	if !c.independentCache {
+		entry, loaded = c.cache.Get(question)
+	} else {
+		entry, loaded = c.transportCache.Get(c.cacheKey(question, transport))
+	}
+	if !loaded || entry.validated != validated {
+		return nil, 0
+	}
+	if c.disableExpire {
+		return entry.cachedMessage(question, dnssecOK), 0
+	}
+	timeNow := time.Now()
+	if !timeNow.Before(entry.expire) {
+		if !serveStale || !entry.refreshable {
+			return nil, 0
+		}
+		c.refresh(ctx, question, entry)
+		response := entry.cachedMessage(question, dnssecOK)
+		setTimeToLive(response, staleTTL)
+		if c.logger != nil {
+			c.logger.DebugContext(ctx, "serve stale ", FormatQuestion(question.String()))
+		}
+		return response, staleTTL
+	}
+	nowTTL := int(entry.expire.Sub(timeNow).Seconds())
+	hits := entry.hits.Add(1)
+	if entry.refreshable && hits >= c.prefetchMinHits && c.prefetchEnabled(entry.options) && uint32(nowTTL)*10 <= entry.timeToLive {
+		c.refresh(ctx, question, entry)
+	}
+	response := entry.cachedMessage(question, dnssecOK)
+	if entry.timeToLive > 0 {
+		duration := entry.timeToLive - uint32(nowTTL)
+		for _, recordList := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+			for _, record := range recordList {
+				record.Header().Ttl = record.Header().Ttl - duration
+			}
+		}
+	} else {
+		setTimeToLive(response, uint32(nowTTL))
+	}
+	return response, nowTTL
+}
+
+func (e *cacheEntry) cachedMessage(question dns.Question, dnssecOK bool) *dns.Msg {
+	message := e.message.Copy()
+	if e.validated && !dnssecOK {
+		stripDNSSEC(question, message)
+	}
+	return message
+}
+
+func setTimeToLive(message *dns.Msg, timeToLive uint32) {
+	for _, recordList := range [][]dns.RR{message.Answer, message.Ns, message.Extra} {
+		for _, record := range recordList {
+			record.Header().Ttl = timeToLive
+		}
+	}
+}
+
+// refresh exchanges the question in background to replace a stale or
+// expiring entry.
+func (c *Client) refresh(ctx context.Context, question dns.Question, entry *cacheEntry) {
+	if entry.transport == nil || !entry.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	select {
+	case c.refreshAccess <- struct{}{}:
+	default:
+		// too many refreshes in flight, retry on a later hit
+		entry.refreshing.Store(false)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			<-c.refreshAccess
+			entry.refreshing.Store(false)
+		}()
+		message := &dns.Msg{
+			MsgHdr: dns.MsgHdr{
+				RecursionDesired: true,
+			},
+			Question: []dns.Question{question},
+		}
+		if c.clientSubnet.IsValid() {
+			message = SetClientSubnet(message, c.clientSubnet)
+		}
+		_, err := c.exchange(ctx, entry.transport, message, entry.options, nil, false)
+		if err != nil && c.logger != nil {
+			c.logger.DebugContext(ctx, "refresh ", FormatQuestion(question.String()), ": ", err)
+		}
+	}()
+}
+
+func (c *Client) loadCache() {
+	var loaded int
+	for _, savedEntry := range c.cacheStore.LoadDNSCache() {
+		if len(savedEntry.Message.Question) != 1 {
+			continue
+		}
+		entry := &cacheEntry{
+			message:    savedEntry.Message,
+			timeToLive: messageTimeToLive(savedEntry.Message),
+			expire:     savedEntry.Expire,
+			options:    savedEntry.Options,
+			validated:  savedEntry.Validated,
+		}
+		entry.hits.Store(savedEntry.Hits)
+		if c.transportManager != nil {
+			var transportLoaded bool
+			entry.transport, transportLoaded = c.transportManager.Transport(savedEntry.Transport)
+			entry.refreshable = savedEntry.Refreshable && transportLoaded
+		}
+		if c.independentCache && entry.transport == nil {
+			continue
+		}
+		lifetime := c.entryLifetime(entry)
+		if !c.disableExpire && lifetime <= 0 {
+			continue
+		}
+		c.storeEntry(savedEntry.Message.Question[0], entry, lifetime)
+		loaded++
+	}
+	if loaded > 0 && c.logger != nil {
+		c.logger.Debug("loaded ", loaded, " cached answers")
+	}
+}
+
+func (c *Client) saveCache() error {
+	var entries []*adapter.DNSCacheEntry
+	appendEntry := func(entry *cacheEntry) {
+		var transportTag string
+		if entry.transport != nil {
+			transportTag = entry.transport.Tag()
+		}
+		options := entry.options
+		options.Transport = nil
+		entries = append(entries, &adapter.DNSCacheEntry{
+			Transport:   transportTag,
+			Message:     entry.message,
+			Expire:      entry.expire,
+			Hits:        entry.hits.Load(),
+			Refreshable: entry.refreshable,
+			Validated:   entry.validated,
+			Options:     options,
+		})
+	}
+	if !c.independentCache {
+		for _, question := range c.cache.Keys() {
+			if entry, loaded := c.cache.Peek(question); loaded {
+				appendEntry(entry)
+			}
+		}
+	} else {
+		for _, key := range c.transportCache.Keys() {
+			if entry, loaded := c.transportCache.Peek(key); loaded {
+				appendEntry(entry)
+			}
+		}
+	}
+	return c.cacheStore.SaveDNSCache(entries)
+}
+
+func messageTimeToLive(message *dns.Msg) uint32 {
+	var timeToLive uint32
+	for _, recordList := range [][]dns.RR{message.Answer, message.Ns, message.Extra} {
+		for _, record := range recordList {
+			if timeToLive == 0 || record.Header().Ttl > 0 && record.Header().Ttl < timeToLive {
+				timeToLive = record.Header().Ttl
+			}
+		}
+	}
+	return timeToLive
+}
@@ -0,0 +1,591 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	dnssecUDPSize = 1232
+	// maxValidatorTTL bounds how long validated keys and delegations are
+	// cached, including proven absence of DS records.
+	maxValidatorTTL = 3600
+)
+
+// defaultTrustAnchors are the DS records of the root KSK-2017 and KSK-2024.
+var defaultTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+var ErrDNSSECBogus = E.New("DNSSEC validation failed")
+
+type delegationState uint8
+
+const (
+	// delegationNone means the name is not a zone cut.
+	delegationNone delegationState = iota
+	delegationSecure
+	delegationInsecure
+)
+
+type zoneState struct {
+	delegation delegationState
+	dsSet      []*dns.DS
+	keys       []*dns.DNSKEY
+}
+
+type validatorCacheKey struct {
+	transportTag string
+	name         string
+	qType        uint16
+}
+
+type validator struct {
+	anchors map[string][]*dns.DS
+	cache   freelru.Cache[validatorCacheKey, *zoneState]
+}
+
+func newValidator(trustAnchors []dns.RR) *validator {
+	if len(trustAnchors) == 0 {
+		for _, anchor := range defaultTrustAnchors {
+			trustAnchors = append(trustAnchors, common.Must1(dns.NewRR(anchor)))
+		}
+	}
+	anchors := make(map[string][]*dns.DS)
+	for _, anchor := range trustAnchors {
+		var ds *dns.DS
+		switch record := anchor.(type) {
+		case *dns.DS:
+			ds = record
+		case *dns.DNSKEY:
+			ds = record.ToDS(dns.SHA256)
+		}
+		if ds == nil {
+			continue
+		}
+		zone := dns.CanonicalName(ds.Hdr.Name)
+		anchors[zone] = append(anchors[zone], ds)
+	}
+	return &validator{
+		anchors: anchors,
+		cache:   common.Must1(freelru.NewSharded[validatorCacheKey, *zoneState](1024, maphash.NewHasher[validatorCacheKey]().Hash32)),
+	}
+}
+
+func (c *Client) dnssecEnabled(transport adapter.DNSTransport, options adapter.DNSQueryOptions) bool {
+	switch transport.Type() {
+	case C.DNSTypeHosts, C.DNSTypeFakeIP, C.DNSTypeLocal, C.DNSTypeTailscale, C.TypeResolved:
+		// answers not from the internet
+		return false
+	}
+	if options.DNSSEC != nil {
+		return *options.DNSSEC
+	}
+	return c.dnssec
+}
+
+// withDNSSECOK returns a copy of the message requesting DNSSEC records.
+func withDNSSECOK(message *dns.Msg) *dns.Msg {
+	message = message.Copy()
+	if opt := message.IsEdns0(); opt != nil {
+		opt.SetDo()
+		if opt.UDPSize() < dnssecUDPSize {
+			opt.SetUDPSize(dnssecUDPSize)
+		}
+	} else {
+		message.SetEdns0(dnssecUDPSize, true)
+	}
+	message.CheckingDisabled = true
+	return message
+}
+
+// stripDNSSEC removes the DNSSEC records not asked by the client.
+func stripDNSSEC(question dns.Question, response *dns.Msg) {
+	isDNSSECRecord := func(it dns.RR) bool {
+		recordType := it.Header().Rrtype
+		if recordType == question.Qtype {
+			return false
+		}
+		return recordType == dns.TypeRRSIG || recordType == dns.TypeNSEC || recordType == dns.TypeNSEC3
+	}
+	response.Answer = common.Filter(response.Answer, func(it dns.RR) bool {
+		return !isDNSSECRecord(it)
+	})
+	response.Ns = common.Filter(response.Ns, func(it dns.RR) bool {
+		return !isDNSSECRecord(it)
+	})
+	if opt := response.IsEdns0(); opt != nil {
+		opt.SetDo(false)
+	}
+}
+
+type rrset struct {
+	name       string
+	recordType uint16
+	records    []dns.RR
+	signatures []*dns.RRSIG
+}
+
+func splitRRsets(records []dns.RR) []*rrset {
+	var sets []*rrset
+	findSet := func(name string, recordType uint16) *rrset {
+		name = dns.CanonicalName(name)
+		for _, set := range sets {
+			if set.name == name && set.recordType == recordType {
+				return set
+			}
+		}
+		set := &rrset{name: name, recordType: recordType}
+		sets = append(sets, set)
+		return set
+	}
+	for _, record := range records {
+		if signature, isSignature := record.(*dns.RRSIG); isSignature {
+			set := findSet(signature.Hdr.Name, signature.TypeCovered)
+			set.signatures = append(set.signatures, signature)
+		} else {
+			set := findSet(record.Header().Name, record.Header().Rrtype)
+			set.records = append(set.records, record)
+		}
+	}
+	return common.Filter(sets, func(it *rrset) bool {
+		return len(it.records) > 0
+	})
+}
+
+// validate checks the response and reports if it is secure, the error is
+// non-nil for bogus answers.
+func (v *validator) validate(ctx context.Context, transport adapter.DNSTransport, question dns.Question, response *dns.Msg) (bool, error) {
+	answerSets := splitRRsets(response.Answer)
+	if len(answerSets) > 0 {
+		secure := true
+		for _, set := range answerSets {
+			if len(set.signatures) == 0 {
+				insecure, err := v.provenInsecure(ctx, transport, set.name)
+				if err != nil {
+					return false, err
+				}
+				if !insecure {
+					return false, E.New("missing signature for ", set.name, " ", dns.TypeToString[set.recordType])
+				}
+				secure = false
+				continue
+			}
+			setSecure, err := v.verifyRRset(ctx, transport, set)
+			if err != nil {
+				return false, err
+			}
+			secure = secure && setSecure
+		}
+		return secure, nil
+	}
+	authoritySets := splitRRsets(response.Ns)
+	if !common.Any(authoritySets, func(it *rrset) bool {
+		return len(it.signatures) > 0
+	}) {
+		insecure, err := v.provenInsecure(ctx, transport, question.Name)
+		if err != nil {
+			return false, err
+		}
+		if !insecure {
+			return false, E.New("missing signature for negative answer")
+		}
+		return false, nil
+	}
+	secureRecords, secure, err := v.verifyAuthority(ctx, transport, authoritySets)
+	if err != nil {
+		return false, err
+	}
+	if !secure {
+		return false, nil
+	}
+	err = checkDenial(question, response.Rcode, secureRecords)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyAuthority verifies the signed sets of the authority section and
+// returns their records, unsigned sets are dropped so that forged denial
+// records can not be mixed with a replayed signed one.
+func (v *validator) verifyAuthority(ctx context.Context, transport adapter.DNSTransport, authoritySets []*rrset) ([]dns.RR, bool, error) {
+	var secureRecords []dns.RR
+	for _, set := range authoritySets {
+		if len(set.signatures) == 0 {
+			continue
+		}
+		secure, err := v.verifyRRset(ctx, transport, set)
+		if err != nil {
+			return nil, false, err
+		}
+		if !secure {
+			return nil, false, nil
+		}
+		secureRecords = append(secureRecords, set.records...)
+	}
+	return secureRecords, true, nil
+}
+
+// verifyRRset verifies the signatures of the set with the keys of the
+// signer zone, the set is insecure if the signer zone is.
+func (v *validator) verifyRRset(ctx context.Context, transport adapter.DNSTransport, set *rrset) (bool, error) {
+	if len(set.signatures) == 0 {
+		return false, E.New("missing signature for ", set.name, " ", dns.TypeToString[set.recordType])
+	}
+	var lastErr error
+	for _, signature := range set.signatures {
+		signer := dns.CanonicalName(signature.SignerName)
+		if !dns.IsSubDomain(signer, set.name) || set.recordType == dns.TypeDS && signer == set.name {
+			lastErr = E.New("invalid signer ", signer, " for ", set.name)
+			continue
+		}
+		state, err := v.zoneKeys(ctx, transport, signer)
+		if err != nil {
+			return false, err
+		}
+		if state.delegation == delegationInsecure {
+			return false, nil
+		}
+		lastErr = verifySignature(set.records, signature, state.keys)
+		if lastErr == nil {
+			return true, nil
+		}
+	}
+	return false, E.Cause(lastErr, "verify ", set.name, " ", dns.TypeToString[set.recordType])
+}
+
+func verifySignature(records []dns.RR, signature *dns.RRSIG, keys []*dns.DNSKEY) error {
+	if !signature.ValidityPeriod(time.Now()) {
+		return E.New("signature expired or not yet valid")
+	}
+	for _, key := range keys {
+		if key.KeyTag() != signature.KeyTag || key.Algorithm != signature.Algorithm {
+			continue
+		}
+		if signature.Verify(key, records) == nil {
+			return nil
+		}
+	}
+	return E.New("no key verifies the signature")
+}
+
+// zoneKeys loads the DNSKEY set of the zone, authenticated by the DS set
+// of the parent zone or a trust anchor.
+func (v *validator) zoneKeys(ctx context.Context, transport adapter.DNSTransport, zone string) (*zoneState, error) {
+	cacheKey := validatorCacheKey{transport.Tag(), zone, dns.TypeDNSKEY}
+	if state, loaded := v.cache.Get(cacheKey); loaded {
+		return state, nil
+	}
+	dsSet, anchored := v.anchors[zone]
+	if !anchored {
+		if zone == "." {
+			return &zoneState{delegation: delegationInsecure}, nil
+		}
+		delegation, err := v.delegation(ctx, transport, zone)
+		if err != nil {
+			return nil, err
+		}
+		switch delegation.delegation {
+		case delegationInsecure:
+			return delegation, nil
+		case delegationNone:
+			return nil, E.New("missing DS for zone ", zone)
+		}
+		dsSet = delegation.dsSet
+	}
+	response, err := v.query(ctx, transport, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, E.Cause(err, "query DNSKEY for ", zone)
+	}
+	var (
+		keyRecords []dns.RR
+		keys       []*dns.DNSKEY
+		trusted    []*dns.DNSKEY
+		signatures []*dns.RRSIG
+	)
+	for _, record := range response.Answer {
+		if !strings.EqualFold(record.Header().Name, zone) {
+			continue
+		}
+		switch record := record.(type) {
+		case *dns.DNSKEY:
+			keyRecords = append(keyRecords, record)
+			keys = append(keys, record)
+			for _, ds := range dsSet {
+				if matchDS(record, ds) {
+					trusted = append(trusted, record)
+					break
+				}
+			}
+		case *dns.RRSIG:
+			if record.TypeCovered == dns.TypeDNSKEY {
+				signatures = append(signatures, record)
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, E.New("no DNSKEY of ", zone, " matches the DS set")
+	}
+	err = E.New("missing DNSKEY signature for ", zone)
+	for _, signature := range signatures {
+		err = verifySignature(keyRecords, signature, trusted)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, E.Cause(err, "verify DNSKEY for ", zone)
+	}
+	state := &zoneState{delegation: delegationSecure, dsSet: dsSet, keys: keys}
+	v.cache.AddWithLifetime(cacheKey, state, cacheLifetime(keyRecords))
+	return state, nil
+}
+
+func matchDS(key *dns.DNSKEY, ds *dns.DS) bool {
+	if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+		return false
+	}
+	keyDS := key.ToDS(ds.DigestType)
+	return keyDS != nil && strings.EqualFold(keyDS.Digest, ds.Digest)
+}
+
+// delegation looks up the DS set of the name in its parent zone.
+func (v *validator) delegation(ctx context.Context, transport adapter.DNSTransport, name string) (*zoneState, error) {
+	cacheKey := validatorCacheKey{transport.Tag(), name, dns.TypeDS}
+	if state, loaded := v.cache.Get(cacheKey); loaded {
+		return state, nil
+	}
+	response, err := v.query(ctx, transport, name, dns.TypeDS)
+	if err != nil {
+		return nil, E.Cause(err, "query DS for ", name)
+	}
+	state := &zoneState{}
+	var lifetimeRecords []dns.RR
+	answerSets := common.Filter(splitRRsets(response.Answer), func(it *rrset) bool {
+		return it.name == name && it.recordType == dns.TypeDS
+	})
+	if len(answerSets) > 0 {
+		set := answerSets[0]
+		lifetimeRecords = set.records
+		secure, err := v.verifyRRset(ctx, transport, set)
+		if err != nil {
+			return nil, err
+		}
+		if secure {
+			state.delegation = delegationSecure
+			for _, record := range set.records {
+				state.dsSet = append(state.dsSet, record.(*dns.DS))
+			}
+		} else {
+			state.delegation = delegationInsecure
+		}
+	} else {
+		lifetimeRecords = response.Ns
+		state.delegation, err = v.proveNoDS(ctx, transport, name, response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v.cache.AddWithLifetime(cacheKey, state, cacheLifetime(lifetimeRecords))
+	return state, nil
+}
+
+// proveNoDS checks the denial of DS records in the negative answer, the
+// name is an insecure delegation if it has NS records but no DS.
+func (v *validator) proveNoDS(ctx context.Context, transport adapter.DNSTransport, name string, response *dns.Msg) (delegationState, error) {
+	authoritySets := splitRRsets(response.Ns)
+	if !common.Any(authoritySets, func(it *rrset) bool {
+		return len(it.signatures) > 0
+	}) {
+		// answered by an unsigned zone, the delegation is above
+		return delegationNone, nil
+	}
+	secureRecords, secure, err := v.verifyAuthority(ctx, transport, authoritySets)
+	if err != nil {
+		return delegationNone, err
+	}
+	if !secure {
+		return delegationInsecure, nil
+	}
+	var hasDenial bool
+	for _, record := range secureRecords {
+		switch record := record.(type) {
+		case *dns.NSEC:
+			hasDenial = true
+			if strings.EqualFold(record.Hdr.Name, name) {
+				if hasType(record.TypeBitMap, dns.TypeDS) {
+					return delegationNone, E.New("DS of ", name, " denied by NSEC with DS")
+				}
+				if hasType(record.TypeBitMap, dns.TypeNS) && !hasType(record.TypeBitMap, dns.TypeSOA) {
+					return delegationInsecure, nil
+				}
+			}
+		case *dns.NSEC3:
+			hasDenial = true
+			if record.Match(name) {
+				if hasType(record.TypeBitMap, dns.TypeDS) {
+					return delegationNone, E.New("DS of ", name, " denied by NSEC3 with DS")
+				}
+				if hasType(record.TypeBitMap, dns.TypeNS) && !hasType(record.TypeBitMap, dns.TypeSOA) {
+					return delegationInsecure, nil
+				}
+			} else if record.Flags&1 == 1 && record.Cover(name) {
+				// opt-out spans only skip insecure delegations
+				return delegationInsecure, nil
+			}
+		}
+	}
+	if !hasDenial {
+		return delegationNone, E.New("missing denial of DS for ", name)
+	}
+	return delegationNone, nil
+}
+
+// provenInsecure walks up from the name to the closest zone cut and
+// reports if it is an insecure delegation.
+func (v *validator) provenInsecure(ctx context.Context, transport adapter.DNSTransport, name string) (bool, error) {
+	name = dns.CanonicalName(name)
+	for {
+		if _, anchored := v.anchors[name]; anchored {
+			return false, nil
+		}
+		if name == "." {
+			return true, nil
+		}
+		state, err := v.delegation(ctx, transport, name)
+		if err != nil {
+			return false, err
+		}
+		switch state.delegation {
+		case delegationSecure:
+			return false, nil
+		case delegationInsecure:
+			return true, nil
+		}
+		name = parentName(name)
+	}
+}
+
+func (v *validator) query(ctx context.Context, transport adapter.DNSTransport, name string, qType uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(name, qType)
+	response, err := transport.Exchange(ctx, withDNSSECOK(message))
+	if err != nil {
+		return nil, err
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, RcodeError(response.Rcode)
+	}
+	return response, nil
+}
+
+// checkDenial checks the NSEC or NSEC3 records proving the negative answer.
+// Wildcard denial is not checked.
+func checkDenial(question dns.Question, rcode int, authority []dns.RR) error {
+	name := dns.CanonicalName(question.Name)
+	var nsec3List []*dns.NSEC3
+	for _, record := range authority {
+		switch record := record.(type) {
+		case *dns.NSEC:
+			if rcode == dns.RcodeNameError {
+				if nsecCovers(record, name) {
+					return nil
+				}
+			} else {
+				if strings.EqualFold(record.Hdr.Name, name) && !hasType(record.TypeBitMap, question.Qtype) && !hasType(record.TypeBitMap, dns.TypeCNAME) {
+					return nil
+				}
+				// empty non-terminal
+				if nsecCovers(record, name) && dns.IsSubDomain(name, dns.CanonicalName(record.NextDomain)) {
+					return nil
+				}
+			}
+		case *dns.NSEC3:
+			nsec3List = append(nsec3List, record)
+			if rcode != dns.RcodeNameError && record.Match(name) && !hasType(record.TypeBitMap, question.Qtype) && !hasType(record.TypeBitMap, dns.TypeCNAME) {
+				return nil
+			}
+		}
+	}
+	if rcode == dns.RcodeNameError && nsec3ProvesNameError(name, nsec3List) {
+		return nil
+	}
+	return E.New("missing proof of non-existence for ", name)
+}
+
+// nsec3ProvesNameError checks the closest encloser proof of RFC 5155.
+func nsec3ProvesNameError(name string, nsec3List []*dns.NSEC3) bool {
+	labels := dns.SplitDomainName(name)
+	for i := 1; i <= len(labels); i++ {
+		encloser := dns.Fqdn(strings.Join(labels[i:], "."))
+		nextCloser := dns.Fqdn(strings.Join(labels[i-1:], "."))
+		if common.Any(nsec3List, func(it *dns.NSEC3) bool {
+			return it.Match(encloser)
+		}) && common.Any(nsec3List, func(it *dns.NSEC3) bool {
+			return it.Cover(nextCloser)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(bitmap []uint16, recordType uint16) bool {
+	return common.Contains(bitmap, recordType)
+}
+
+func nsecCovers(nsec *dns.NSEC, name string) bool {
+	owner := dns.CanonicalName(nsec.Hdr.Name)
+	next := dns.CanonicalName(nsec.NextDomain)
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+	// the last NSEC of the zone points back to the apex
+	return canonicalCompare(owner, name) < 0 || canonicalCompare(name, next) < 0
+}
+
+// canonicalCompare orders lower case names as RFC 4034 section 6.1.
+func canonicalCompare(a string, b string) int {
+	aLabels := dns.SplitDomainName(a)
+	bLabels := dns.SplitDomainName(b)
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		compare := strings.Compare(aLabels[len(aLabels)-i], bLabels[len(bLabels)-i])
+		if compare != 0 {
+			return compare
+		}
+	}
+	return len(aLabels) - len(bLabels)
+}
+
+func parentName(name string) string {
+	offset, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[offset:]
+}
+
+func cacheLifetime(records []dns.RR) time.Duration {
+	timeToLive := uint32(maxValidatorTTL)
+	for _, record := range records {
+		if record.Header().Ttl < timeToLive {
+			timeToLive = record.Header().Ttl
+		}
+	}
+	if timeToLive == 0 {
+		timeToLive = 1
+	}
+	return time.Duration(timeToLive) * time.Second
+}
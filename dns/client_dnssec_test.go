@@ -0,0 +1,193 @@
+package dns_test
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientDNSSEC(t *testing.T) {
+	t.Parallel()
+	transport := newSignedTransport(t)
+	client := dns.NewClient(dns.ClientOptions{
+		DNSSEC:            true,
+		DNSSECTrustAnchor: []mDNS.RR{transport.rootKey.ToDS(mDNS.SHA256)},
+		DisableCache:      true,
+	})
+	client.Start()
+	exchangeName := func(name string) *mDNS.Msg {
+		message := new(mDNS.Msg)
+		message.SetQuestion(name, mDNS.TypeA)
+		response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+		require.NoError(t, err)
+		return response
+	}
+	response := exchangeName("example.com.")
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+	require.Len(t, response.Answer, 1)
+
+	response = exchangeName("tampered.example.com.")
+	require.Equal(t, mDNS.RcodeServerFailure, response.Rcode)
+
+	response = exchangeName("insecure.net.")
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.False(t, response.AuthenticatedData)
+	require.Len(t, response.Answer, 1)
+}
+
+func TestClientDNSSECForgedDenial(t *testing.T) {
+	t.Parallel()
+	transport := newSignedTransport(t)
+	client := dns.NewClient(dns.ClientOptions{
+		DNSSEC:            true,
+		DNSSECTrustAnchor: []mDNS.RR{transport.rootKey.ToDS(mDNS.SHA256)},
+		DisableCache:      true,
+	})
+	client.Start()
+	exchangeName := func(name string) *mDNS.Msg {
+		message := new(mDNS.Msg)
+		message.SetQuestion(name, mDNS.TypeA)
+		response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+		require.NoError(t, err)
+		return response
+	}
+	response := exchangeName("nodata.example.com.")
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.True(t, response.AuthenticatedData)
+	require.Empty(t, response.Answer)
+
+	// unsigned NSEC next to a replayed signed SOA
+	response = exchangeName("forged.example.com.")
+	require.Equal(t, mDNS.RcodeServerFailure, response.Rcode)
+
+	// unsigned NSEC3 opt-out span claiming an insecure delegation
+	response = exchangeName("spoofed.example.com.")
+	require.Equal(t, mDNS.RcodeServerFailure, response.Rcode)
+
+	// unsigned DS set claiming a delegation
+	response = exchangeName("www.example.org.")
+	require.Equal(t, mDNS.RcodeServerFailure, response.Rcode)
+}
+
+func TestClientDNSSECCache(t *testing.T) {
+	t.Parallel()
+	transport := newSignedTransport(t)
+	client := dns.NewClient(dns.ClientOptions{
+		DNSSECTrustAnchor: []mDNS.RR{transport.rootKey.ToDS(mDNS.SHA256)},
+	})
+	client.Start()
+	exchangeName := func(dnssec bool) *mDNS.Msg {
+		message := new(mDNS.Msg)
+		message.SetQuestion("example.com.", mDNS.TypeA)
+		response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{DNSSEC: &dnssec}, nil)
+		require.NoError(t, err)
+		return response
+	}
+	require.False(t, exchangeName(false).AuthenticatedData)
+	// answers cached without validation are not served to validating rules
+	response := exchangeName(true)
+	require.True(t, response.AuthenticatedData)
+	require.Len(t, response.Answer, 1)
+	response = exchangeName(true)
+	require.True(t, response.AuthenticatedData)
+	require.Len(t, response.Answer, 1)
+	require.False(t, exchangeName(false).AuthenticatedData)
+}
+
+type signedTransport struct {
+	dns.TransportAdapter
+	rootKey *mDNS.DNSKEY
+	records map[mDNS.Question][]mDNS.RR
+	denial  map[mDNS.Question][]mDNS.RR
+}
+
+func newSignedTransport(t *testing.T) *signedTransport {
+	rootKey, rootSigner := generateKey(t, ".")
+	childKey, childSigner := generateKey(t, "example.com.")
+	sign := func(key *mDNS.DNSKEY, signer crypto.Signer, records ...mDNS.RR) []mDNS.RR {
+		signature := &mDNS.RRSIG{
+			KeyTag:     key.KeyTag(),
+			SignerName: key.Hdr.Name,
+			Algorithm:  key.Algorithm,
+			Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+			Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+		}
+		require.NoError(t, signature.Sign(signer, records))
+		return append(records, signature)
+	}
+	record := func(content string) mDNS.RR {
+		rr, err := mDNS.NewRR(content)
+		require.NoError(t, err)
+		return rr
+	}
+	soa := sign(childKey, childSigner, record("example.com. 300 IN SOA ns.example.com. admin.example.com. 1 3600 600 86400 300"))
+	tampered := sign(childKey, childSigner, record("tampered.example.com. 60 IN A 1.1.1.1"))
+	tampered[0].(*mDNS.A).A = net.IPv4(2, 2, 2, 2)
+	return &signedTransport{
+		TransportAdapter: dns.NewTransportAdapter("fake", "signed", nil),
+		rootKey:          rootKey,
+		records: map[mDNS.Question][]mDNS.RR{
+			question(".", mDNS.TypeDNSKEY):                sign(rootKey, rootSigner, rootKey),
+			question("example.com.", mDNS.TypeDS):         sign(rootKey, rootSigner, childKey.ToDS(mDNS.SHA256)),
+			question("example.com.", mDNS.TypeDNSKEY):     sign(childKey, childSigner, childKey),
+			question("example.com.", mDNS.TypeA):          sign(childKey, childSigner, record("example.com. 60 IN A 1.1.1.1")),
+			question("tampered.example.com.", mDNS.TypeA): tampered,
+			question("insecure.net.", mDNS.TypeA):         {record("insecure.net. 60 IN A 3.3.3.3")},
+			question("spoofed.example.com.", mDNS.TypeA):  {record("spoofed.example.com. 60 IN A 6.6.6.6")},
+			question("example.org.", mDNS.TypeDS):         {record("example.org. 3600 IN DS 12345 13 2 " + strings.Repeat("00", 32))},
+			question("www.example.org.", mDNS.TypeA):      {record("www.example.org. 60 IN A 7.7.7.7")},
+		},
+		denial: map[mDNS.Question][]mDNS.RR{
+			question("insecure.net.", mDNS.TypeDS): sign(rootKey, rootSigner, record("insecure.net. 3600 IN NSEC org. NS RRSIG NSEC")),
+			question("nodata.example.com.", mDNS.TypeA): slices.Concat(soa,
+				sign(childKey, childSigner, record("nodata.example.com. 300 IN NSEC z.example.com. TXT RRSIG NSEC"))),
+			question("forged.example.com.", mDNS.TypeA): slices.Concat(soa,
+				[]mDNS.RR{record("forged.example.com. 300 IN NSEC z.example.com. TXT RRSIG NSEC")}),
+			question("spoofed.example.com.", mDNS.TypeDS): slices.Concat(soa,
+				[]mDNS.RR{record("00000000000000000000000000000000.example.com. 300 IN NSEC3 1 1 0 - 00000000000000000000000000000000 A RRSIG")}),
+		},
+	}
+}
+
+func generateKey(t *testing.T, zone string) (*mDNS.DNSKEY, crypto.Signer) {
+	key := &mDNS.DNSKEY{
+		Hdr:       mDNS.RR_Header{Name: zone, Rrtype: mDNS.TypeDNSKEY, Class: mDNS.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: mDNS.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+	return key, privateKey.(crypto.Signer)
+}
+
+func question(name string, qType uint16) mDNS.Question {
+	return mDNS.Question{Name: name, Qtype: qType, Qclass: mDNS.ClassINET}
+}
+
+func (t *signedTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *signedTransport) Close() error {
+	return nil
+}
+
+func (t *signedTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.Answer = t.records[message.Question[0]]
+	response.Ns = t.denial[message.Question[0]]
+	return response.Copy(), nil
+}
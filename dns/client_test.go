@@ -0,0 +1,188 @@
+package dns_test
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientServeStale(t *testing.T) {
+	t.Parallel()
+	transport := &countingTransport{TransportAdapter: dns.NewTransportAdapter("fake", "fake", nil)}
+	client := dns.NewClient(dns.ClientOptions{ServeStale: true})
+	client.Start()
+	response := exchange(t, client, transport)
+	require.Equal(t, uint32(1), response.Answer[0].Header().Ttl)
+	time.Sleep(1100 * time.Millisecond)
+	response = exchange(t, client, transport)
+	require.Equal(t, uint32(30), response.Answer[0].Header().Ttl)
+	require.Equal(t, "1.0.0.1", dns.MessageToAddresses(response)[0].String())
+	require.Eventually(t, func() bool {
+		return transport.queries.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return dns.MessageToAddresses(exchange(t, client, transport))[0].String() == "1.0.0.2"
+	}, time.Second, 10*time.Millisecond)
+
+	transport = &countingTransport{TransportAdapter: dns.NewTransportAdapter("fake", "fake", nil)}
+	client = dns.NewClient(dns.ClientOptions{})
+	client.Start()
+	exchange(t, client, transport)
+	time.Sleep(1100 * time.Millisecond)
+	exchange(t, client, transport)
+	require.Equal(t, int32(2), transport.queries.Load())
+}
+
+func TestClientPrefetch(t *testing.T) {
+	t.Parallel()
+	transport := &countingTransport{TransportAdapter: dns.NewTransportAdapter("fake", "fake", nil)}
+	client := dns.NewClient(dns.ClientOptions{Prefetch: true})
+	client.Start()
+	exchange(t, client, transport)
+	exchange(t, client, transport)
+	require.Equal(t, int32(1), transport.queries.Load())
+	// the second hit makes the name popular
+	exchange(t, client, transport)
+	require.Eventually(t, func() bool {
+		return transport.queries.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClientStoreCache(t *testing.T) {
+	t.Parallel()
+	transport := &countingTransport{TransportAdapter: dns.NewTransportAdapter("fake", "fake", nil), timeToLive: 600}
+	store := new(memoryCacheStore)
+	options := dns.ClientOptions{
+		CacheStore: func() adapter.DNSCacheStore {
+			return store
+		},
+	}
+	client := dns.NewClient(options)
+	client.Start()
+	exchange(t, client, transport)
+	require.NoError(t, client.Close())
+	require.Len(t, store.entries, 1)
+	require.Equal(t, "fake", store.entries[0].Transport)
+
+	client = dns.NewClient(options)
+	client.Start()
+	response := exchange(t, client, transport)
+	require.Equal(t, "1.0.0.1", dns.MessageToAddresses(response)[0].String())
+	require.Equal(t, int32(1), transport.queries.Load())
+}
+
+func TestClientStoreCacheOptions(t *testing.T) {
+	t.Parallel()
+	transport := &countingTransport{TransportAdapter: dns.NewTransportAdapter("fake", "fake", nil)}
+	store := new(memoryCacheStore)
+	options := dns.ClientOptions{
+		CacheStore: func() adapter.DNSCacheStore {
+			return store
+		},
+		TransportManager: &staticTransportManager{transport: transport},
+	}
+	serveStale := true
+	queryOptions := adapter.DNSQueryOptions{ServeStale: &serveStale}
+	exchangeName := func(client *dns.Client, name string, responseChecker func(responseAddrs []netip.Addr) bool) *mDNS.Msg {
+		message := new(mDNS.Msg)
+		message.SetQuestion(name, mDNS.TypeA)
+		response, err := client.Exchange(context.Background(), transport, message, queryOptions, responseChecker)
+		require.NoError(t, err)
+		return response
+	}
+	acceptAll := func(responseAddrs []netip.Addr) bool {
+		return true
+	}
+	client := dns.NewClient(options)
+	client.Start()
+	exchangeName(client, "example.com.", nil)
+	exchangeName(client, "checked.example.com.", acceptAll)
+	require.NoError(t, client.Close())
+
+	client = dns.NewClient(options)
+	client.Start()
+	time.Sleep(1100 * time.Millisecond)
+	// the serve-stale option of the rule is restored
+	response := exchangeName(client, "example.com.", nil)
+	require.Equal(t, uint32(30), response.Answer[0].Header().Ttl)
+	// answers checked by address limit rules are never served stale
+	response = exchangeName(client, "checked.example.com.", acceptAll)
+	require.Equal(t, uint32(1), response.Answer[0].Header().Ttl)
+}
+
+type staticTransportManager struct {
+	adapter.DNSTransportManager
+	transport adapter.DNSTransport
+}
+
+func (m *staticTransportManager) Transport(tag string) (adapter.DNSTransport, bool) {
+	if tag != m.transport.Tag() {
+		return nil, false
+	}
+	return m.transport, true
+}
+
+type countingTransport struct {
+	dns.TransportAdapter
+	queries    atomic.Int32
+	timeToLive uint32
+}
+
+func (t *countingTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *countingTransport) Close() error {
+	return nil
+}
+
+func (t *countingTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	queries := t.queries.Add(1)
+	timeToLive := t.timeToLive
+	if timeToLive == 0 {
+		timeToLive = 1
+	}
+	return dns.FixedResponse(message.Id, message.Question[0], []netip.Addr{netip.AddrFrom4([4]byte{1, 0, 0, byte(queries)})}, timeToLive), nil
+}
+
+type memoryCacheStore struct {
+	entries []*adapter.DNSCacheEntry
+}
+
+func (s *memoryCacheStore) LoadDNSCache() []*adapter.DNSCacheEntry {
+	var entries []*adapter.DNSCacheEntry
+	for _, entry := range s.entries {
+		content, err := entry.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		var loaded adapter.DNSCacheEntry
+		err = loaded.UnmarshalBinary(content)
+		if err != nil {
+			panic(err)
+		}
+		entries = append(entries, &loaded)
+	}
+	return entries
+}
+
+func (s *memoryCacheStore) SaveDNSCache(entries []*adapter.DNSCacheEntry) error {
+	s.entries = entries
+	return nil
+}
+
+func exchange(t *testing.T, client *dns.Client, transport adapter.DNSTransport) *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", mDNS.TypeA)
+	response, err := client.Exchange(context.Background(), transport, message, adapter.DNSQueryOptions{}, nil)
+	require.NoError(t, err)
+	return response
+}
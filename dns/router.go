@@ -62,6 +62,14 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 		IndependentCache: options.DNSClientOptions.IndependentCache,
 		CacheCapacity:    options.DNSClientOptions.CacheCapacity,
 		ClientSubnet:     options.DNSClientOptions.ClientSubnet.Build(netip.Prefix{}),
+		ServeStale:       options.DNSClientOptions.ServeStale,
+		ServeStaleMaxAge: time.Duration(options.DNSClientOptions.ServeStaleMaxAge),
+		Prefetch:         options.DNSClientOptions.Prefetch,
+		PrefetchMinHits:  options.DNSClientOptions.PrefetchMinHits,
+		DNSSEC:           options.DNSClientOptions.DNSSEC,
+		DNSSECTrustAnchor: common.Map(options.DNSClientOptions.DNSSECTrustAnchor, func(it option.DNSTrustAnchor) mDNS.RR {
+			return it.Build()
+		}),
 		RDRC: func() adapter.RDRCStore {
 			cacheFile := service.FromContext[adapter.CacheFile](ctx)
 			if cacheFile == nil {
@@ -72,7 +80,18 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.DNSOp
 			}
 			return cacheFile
 		},
-		Logger: router.logger,
+		CacheStore: func() adapter.DNSCacheStore {
+			cacheFile := service.FromContext[adapter.CacheFile](ctx)
+			if cacheFile == nil {
+				return nil
+			}
+			if !cacheFile.StoreDNSCache() {
+				return nil
+			}
+			return cacheFile
+		},
+		TransportManager: router.transport,
+		Logger:           router.logger,
 	})
 	// 设置缓存命中回调
 	client.cacheHitCallback = func() {
@@ -127,6 +146,11 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	monitor.Start("close DNS client")
+	err = E.Append(err, r.client.Close(), func(err error) error {
+		return E.Cause(err, "save DNS cache")
+	})
+	monitor.Finish()
 	return err
 }
 
@@ -179,6 +203,15 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 				if action.ClientSubnet.IsValid() {
 					options.ClientSubnet = action.ClientSubnet
 				}
+				if action.ServeStale != nil {
+					options.ServeStale = action.ServeStale
+				}
+				if action.Prefetch != nil {
+					options.Prefetch = action.Prefetch
+				}
+				if action.DNSSEC != nil {
+					options.DNSSEC = action.DNSSEC
+				}
 				if legacyTransport, isLegacy := transport.(adapter.LegacyDNSTransport); isLegacy {
 					if options.Strategy == C.DomainStrategyAsIS {
 						options.Strategy = legacyTransport.LegacyStrategy()
@@ -201,6 +234,15 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, ruleIndex int,
 				if action.ClientSubnet.IsValid() {
 					options.ClientSubnet = action.ClientSubnet
 				}
+				if action.ServeStale != nil {
+					options.ServeStale = action.ServeStale
+				}
+				if action.Prefetch != nil {
+					options.Prefetch = action.Prefetch
+				}
+				if action.DNSSEC != nil {
+					options.DNSSEC = action.DNSSEC
+				}
 			case *R.RuleActionReject:
 				return nil, currentRule, currentRuleIndex
 			case *R.RuleActionPredefined:
@@ -487,13 +529,20 @@ func (r *Router) ResetNetwork() {
 	}
 }
 
-func (r *Router) GetDNSStats() (total, success, cached int64) {
-	total = r.totalQueries.Load()
-	success = r.successQueries.Load()
-	cached = r.cachedQueries.Load()
+func (r *Router) GetDNSStats() adapter.DNSStats {
+	stats := adapter.DNSStats{
+		Total:   r.totalQueries.Load(),
+		Success: r.successQueries.Load(),
+		Cached:  r.cachedQueries.Load(),
+	}
+	for _, transport := range r.transport.Transports() {
+		if groupTransport, isGroup := transport.(adapter.DNSGroupTransport); isGroup {
+			stats.Upstreams = append(stats.Upstreams, groupTransport.UpstreamStats()...)
+		}
+	}
 	// 调试日志
-	if total > 0 {
-		r.logger.Debug("DNS统计: total=", total, ", success=", success, ", cached=", cached)
+	if stats.Total > 0 {
+		r.logger.Debug("DNS统计: total=", stats.Total, ", success=", stats.Success, ", cached=", stats.Cached)
 	}
-	return total, success, cached
+	return stats
 }
@@ -0,0 +1,108 @@
+package group
+
+import (
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+
+	mDNS "github.com/miekg/dns"
+	"go4.org/netipx"
+)
+
+// fallbackFilter accepts answers whose addresses are all in the expected
+// region, answers from the primary servers that fail it are replaced with
+// the answer of the fallback servers.
+type fallbackFilter struct {
+	router       adapter.Router
+	ruleSetTags  []string
+	ipCIDR       []*netipx.IPSet
+	invert       bool
+	ruleSets     []adapter.RuleSet
+	callbacks    []*list.Element[adapter.RuleSetUpdateCallback]
+	access       sync.RWMutex
+	ruleSetIPSet []*netipx.IPSet
+}
+
+func newFallbackFilter(router adapter.Router, options option.DNSFallbackFilterOptions) *fallbackFilter {
+	filter := &fallbackFilter{
+		router:      router,
+		ruleSetTags: options.RuleSet,
+		invert:      options.Invert,
+	}
+	if len(options.IPCIDR) > 0 {
+		var builder netipx.IPSetBuilder
+		for _, prefix := range options.IPCIDR {
+			builder.AddPrefix(prefix)
+		}
+		filter.ipCIDR = append(filter.ipCIDR, common.Must1(builder.IPSet()))
+	}
+	return filter
+}
+
+func (f *fallbackFilter) start() error {
+	for _, tag := range f.ruleSetTags {
+		ruleSet, loaded := f.router.RuleSet(tag)
+		if !loaded {
+			return E.New("rule-set not found: ", tag)
+		}
+		ruleSet.IncRef()
+		f.ruleSets = append(f.ruleSets, ruleSet)
+		f.callbacks = append(f.callbacks, ruleSet.RegisterCallback(func(adapter.RuleSet) {
+			f.updateIPSet()
+		}))
+	}
+	return nil
+}
+
+func (f *fallbackFilter) updateIPSet() {
+	var ipSets []*netipx.IPSet
+	for _, ruleSet := range f.ruleSets {
+		ipSets = append(ipSets, ruleSet.ExtractIPSet()...)
+	}
+	f.access.Lock()
+	f.ruleSetIPSet = ipSets
+	f.access.Unlock()
+}
+
+func (f *fallbackFilter) close() {
+	for i, ruleSet := range f.ruleSets {
+		ruleSet.UnregisterCallback(f.callbacks[i])
+		ruleSet.DecRef()
+	}
+	f.ruleSets = nil
+	f.callbacks = nil
+}
+
+func (f *fallbackFilter) accept(response *mDNS.Msg) bool {
+	if response.Rcode != mDNS.RcodeSuccess {
+		return true
+	}
+	f.access.RLock()
+	defer f.access.RUnlock()
+	for _, address := range dns.MessageToAddresses(response) {
+		var matched bool
+		for _, ipSet := range f.ipCIDR {
+			if ipSet.Contains(address) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			for _, ipSet := range f.ruleSetIPSet {
+				if ipSet.Contains(address) {
+					matched = true
+					break
+				}
+			}
+		}
+		if matched == f.invert {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package group
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const defaultTimeout = 5 * time.Second
+
+func RegisterTransport(registry *dns.TransportRegistry) {
+	dns.RegisterTransport[option.GroupDNSServerOptions](registry, C.DNSTypeGroup, NewTransport)
+}
+
+var _ adapter.DNSGroupTransport = (*Transport)(nil)
+
+type Transport struct {
+	dns.TransportAdapter
+	logger           logger.ContextLogger
+	transportManager adapter.DNSTransportManager
+	strategy         string
+	timeout          time.Duration
+	serverTags       []string
+	fallbackTags     []string
+	servers          []*upstream
+	fallback         []*upstream
+	filter           *fallbackFilter
+}
+
+func NewTransport(ctx context.Context, logger log.ContextLogger, tag string, options option.GroupDNSServerOptions) (adapter.DNSTransport, error) {
+	if len(options.Servers) == 0 {
+		return nil, E.New("missing servers")
+	}
+	switch options.Strategy {
+	case "":
+		options.Strategy = C.DNSGroupStrategyRace
+	case C.DNSGroupStrategyRace, C.DNSGroupStrategyFallback, C.DNSGroupStrategyFastest:
+	default:
+		return nil, E.New("unknown strategy: ", options.Strategy)
+	}
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	var filter *fallbackFilter
+	if options.FallbackFilter != nil {
+		if len(options.Fallback) == 0 {
+			return nil, E.New("fallback_filter requires fallback servers")
+		}
+		filter = newFallbackFilter(service.FromContext[adapter.Router](ctx), *options.FallbackFilter)
+	}
+	var dependencies []string
+	dependencies = append(dependencies, options.Servers...)
+	dependencies = append(dependencies, options.Fallback...)
+	return &Transport{
+		TransportAdapter: dns.NewTransportAdapter(C.DNSTypeGroup, tag, common.Uniq(dependencies)),
+		logger:           logger,
+		transportManager: service.FromContext[adapter.DNSTransportManager](ctx),
+		strategy:         options.Strategy,
+		timeout:          timeout,
+		serverTags:       options.Servers,
+		fallbackTags:     options.Fallback,
+		filter:           filter,
+	}, nil
+}
+
+func (t *Transport) Start(stage adapter.StartStage) error {
+	switch stage {
+	case adapter.StartStateStart:
+		var err error
+		t.servers, err = t.loadUpstreams(t.serverTags)
+		if err != nil {
+			return err
+		}
+		t.fallback, err = t.loadUpstreams(t.fallbackTags)
+		if err != nil {
+			return err
+		}
+		if t.filter != nil {
+			return t.filter.start()
+		}
+	case adapter.StartStatePostStart:
+		if t.filter != nil {
+			t.filter.updateIPSet()
+		}
+	}
+	return nil
+}
+
+func (t *Transport) loadUpstreams(tags []string) ([]*upstream, error) {
+	upstreams := make([]*upstream, 0, len(tags))
+	for _, tag := range tags {
+		transport, loaded := t.transportManager.Transport(tag)
+		if !loaded {
+			return nil, E.New("DNS server not found: ", tag)
+		}
+		upstreams = append(upstreams, &upstream{transport: transport})
+	}
+	return upstreams, nil
+}
+
+func (t *Transport) Close() error {
+	if t.filter != nil {
+		t.filter.close()
+	}
+	return nil
+}
+
+func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(t.fallback) == 0 {
+		return t.exchange(ctx, t.servers, message)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fallbackDone := make(chan struct{})
+	var (
+		fallbackResponse *mDNS.Msg
+		fallbackErr      error
+	)
+	go func() {
+		fallbackResponse, fallbackErr = t.exchange(ctx, t.fallback, message.Copy())
+		close(fallbackDone)
+	}()
+	response, err := t.exchange(ctx, t.servers, message)
+	if err == nil {
+		if t.filter == nil || t.filter.accept(response) {
+			return response, nil
+		}
+		t.logger.DebugContext(ctx, "discard answer for ", dns.FormatQuestion(message.Question[0].String()), " not matching the fallback filter")
+	}
+	<-fallbackDone
+	if fallbackErr != nil {
+		return nil, E.Errors(err, fallbackErr)
+	}
+	return fallbackResponse, nil
+}
+
+func (t *Transport) exchange(ctx context.Context, upstreams []*upstream, message *mDNS.Msg) (*mDNS.Msg, error) {
+	switch t.strategy {
+	case C.DNSGroupStrategyFallback:
+		return t.exchangeSequential(ctx, upstreams, message)
+	case C.DNSGroupStrategyFastest:
+		return t.exchangeSequential(ctx, sortByLatency(upstreams), message)
+	default:
+		return t.exchangeRace(ctx, upstreams, message)
+	}
+}
+
+func (t *Transport) exchangeSequential(ctx context.Context, upstreams []*upstream, message *mDNS.Msg) (*mDNS.Msg, error) {
+	var errors []error
+	for _, server := range upstreams {
+		response, err := server.exchange(ctx, message, t.timeout)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		t.logger.DebugContext(ctx, "exchange failed with ", server.transport.Tag(), ": ", err)
+		errors = append(errors, err)
+	}
+	return nil, E.Errors(errors...)
+}
+
+type exchangeResult struct {
+	response *mDNS.Msg
+	err      error
+}
+
+func (t *Transport) exchangeRace(ctx context.Context, upstreams []*upstream, message *mDNS.Msg) (*mDNS.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan exchangeResult, len(upstreams))
+	for _, server := range upstreams {
+		go func() {
+			response, err := server.exchange(ctx, message.Copy(), t.timeout)
+			results <- exchangeResult{response, err}
+		}()
+	}
+	var errors []error
+	for range upstreams {
+		result := <-results
+		if result.err == nil {
+			return result.response, nil
+		}
+		errors = append(errors, result.err)
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (t *Transport) UpstreamStats() []adapter.DNSUpstreamStats {
+	var stats []adapter.DNSUpstreamStats
+	for _, server := range append(t.servers, t.fallback...) {
+		serverStats := server.stats()
+		serverStats.Group = t.Tag()
+		stats = append(stats, serverStats)
+	}
+	return stats
+}
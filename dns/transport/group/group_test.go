@@ -0,0 +1,150 @@
+package group_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/dns"
+	"github.com/sagernet/sing-box/dns/transport/group"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupRace(t *testing.T) {
+	t.Parallel()
+	transport := startGroup(t, option.GroupDNSServerOptions{
+		Servers:  []string{"slow", "fast", "broken"},
+		Strategy: C.DNSGroupStrategyRace,
+	})
+	require.Equal(t, "2.2.2.2", exchange(t, transport))
+}
+
+func TestGroupFallback(t *testing.T) {
+	t.Parallel()
+	transport := startGroup(t, option.GroupDNSServerOptions{
+		Servers:  []string{"broken", "slow"},
+		Strategy: C.DNSGroupStrategyFallback,
+	})
+	require.Equal(t, "1.1.1.1", exchange(t, transport))
+	stats := transport.(adapter.DNSGroupTransport).UpstreamStats()
+	require.Len(t, stats, 2)
+	require.Equal(t, "broken", stats[0].Tag)
+	require.Equal(t, int64(1), stats[0].Failures)
+	require.Equal(t, "slow", stats[1].Tag)
+	require.Equal(t, int64(0), stats[1].Failures)
+	require.True(t, stats[1].Healthy)
+}
+
+func TestGroupFastest(t *testing.T) {
+	t.Parallel()
+	transport := startGroup(t, option.GroupDNSServerOptions{
+		Servers:  []string{"slow", "fast"},
+		Strategy: C.DNSGroupStrategyFastest,
+	})
+	// both upstreams are measured first
+	require.Equal(t, "1.1.1.1", exchange(t, transport))
+	require.Equal(t, "2.2.2.2", exchange(t, transport))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "2.2.2.2", exchange(t, transport))
+	}
+}
+
+func TestGroupFallbackFilter(t *testing.T) {
+	t.Parallel()
+	transport := startGroup(t, option.GroupDNSServerOptions{
+		Servers:  []string{"fast"},
+		Fallback: []string{"slow"},
+		FallbackFilter: &option.DNSFallbackFilterOptions{
+			IPCIDR: []netip.Prefix{netip.MustParsePrefix("2.0.0.0/8")},
+		},
+	})
+	require.Equal(t, "2.2.2.2", exchange(t, transport))
+	transport = startGroup(t, option.GroupDNSServerOptions{
+		Servers:  []string{"fast"},
+		Fallback: []string{"slow"},
+		FallbackFilter: &option.DNSFallbackFilterOptions{
+			IPCIDR: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		},
+		Timeout: badoption.Duration(time.Second),
+	})
+	require.Equal(t, "1.1.1.1", exchange(t, transport))
+}
+
+type fakeOptions struct {
+	delay   time.Duration
+	address netip.Addr
+	err     error
+}
+
+type fakeTransport struct {
+	dns.TransportAdapter
+	fakeOptions
+}
+
+func (t *fakeTransport) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (t *fakeTransport) Close() error {
+	return nil
+}
+
+func (t *fakeTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(t.delay):
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return dns.FixedResponse(message.Id, message.Question[0], []netip.Addr{t.address}, 60), nil
+}
+
+func startGroup(t *testing.T, options option.GroupDNSServerOptions) adapter.DNSTransport {
+	registry := dns.NewTransportRegistry()
+	group.RegisterTransport(registry)
+	dns.RegisterTransport[fakeOptions](registry, "fake", func(ctx context.Context, logger log.ContextLogger, tag string, options fakeOptions) (adapter.DNSTransport, error) {
+		return &fakeTransport{dns.NewTransportAdapter("fake", tag, nil), options}, nil
+	})
+	logger := log.NewNOPFactory().NewLogger("dns")
+	manager := dns.NewTransportManager(logger, registry, nil, "")
+	ctx := service.ContextWith[adapter.DNSTransportManager](context.Background(), manager)
+	upstreams := map[string]fakeOptions{
+		"slow":   {delay: 100 * time.Millisecond, address: netip.MustParseAddr("1.1.1.1")},
+		"fast":   {address: netip.MustParseAddr("2.2.2.2")},
+		"broken": {err: E.New("broken")},
+	}
+	for tag, upstreamOptions := range upstreams {
+		require.NoError(t, manager.Create(ctx, logger, tag, "fake", &upstreamOptions))
+	}
+	require.NoError(t, manager.Create(ctx, logger, "group", C.DNSTypeGroup, &options))
+	for _, stage := range adapter.ListStartStages {
+		require.NoError(t, manager.Start(stage))
+	}
+	t.Cleanup(func() {
+		manager.Close()
+	})
+	transport, _ := manager.Transport("group")
+	return transport
+}
+
+func exchange(t *testing.T, transport adapter.DNSTransport) string {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", mDNS.TypeA)
+	response, err := transport.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	addresses := dns.MessageToAddresses(response)
+	require.Len(t, addresses, 1)
+	return addresses[0].String()
+}
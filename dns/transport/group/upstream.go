@@ -0,0 +1,111 @@
+package group
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/dns"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	// latencyWeight is the weight of the newest sample in the moving average.
+	latencyWeight = 0.3
+	// unhealthyFailures is the count of consecutive failures after which
+	// an upstream is reported unhealthy.
+	unhealthyFailures = 3
+)
+
+type upstream struct {
+	transport adapter.DNSTransport
+	access    sync.Mutex
+	queries   int64
+	failures  int64
+	latency   time.Duration
+	inRow     int
+}
+
+func (u *upstream) exchange(ctx context.Context, message *mDNS.Msg, timeout time.Duration) (*mDNS.Msg, error) {
+	exchangeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	response, err := u.transport.Exchange(exchangeCtx, message)
+	if err == nil {
+		switch response.Rcode {
+		case mDNS.RcodeServerFailure, mDNS.RcodeRefused:
+			err = dns.RcodeError(response.Rcode)
+		}
+	}
+	if ctx.Err() != nil {
+		// canceled by the caller, e.g. another upstream won the race
+		return nil, ctx.Err()
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		// failures count as slow as the timeout so that fastest prefers other upstreams
+		u.record(timeout, false)
+		return nil, err
+	}
+	u.record(elapsed, true)
+	return response, nil
+}
+
+func (u *upstream) record(latency time.Duration, success bool) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.queries++
+	if success {
+		u.inRow = 0
+	} else {
+		u.failures++
+		u.inRow++
+	}
+	if u.queries == 1 {
+		u.latency = latency
+	} else {
+		u.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(u.latency))
+	}
+}
+
+func (u *upstream) stats() adapter.DNSUpstreamStats {
+	u.access.Lock()
+	defer u.access.Unlock()
+	return adapter.DNSUpstreamStats{
+		Tag:      u.transport.Tag(),
+		Queries:  u.queries,
+		Failures: u.failures,
+		Latency:  u.latency,
+		Healthy:  u.inRow < unhealthyFailures,
+	}
+}
+
+// sortByLatency orders upstreams by their average latency, upstreams that
+// have not been measured yet come first.
+func sortByLatency(upstreams []*upstream) []*upstream {
+	type sortItem struct {
+		upstream *upstream
+		queries  int64
+		latency  time.Duration
+	}
+	items := make([]sortItem, 0, len(upstreams))
+	for _, server := range upstreams {
+		server.access.Lock()
+		items = append(items, sortItem{server, server.queries, server.latency})
+		server.access.Unlock()
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if (items[i].queries == 0) != (items[j].queries == 0) {
+			return items[i].queries == 0
+		}
+		return items[i].latency < items[j].latency
+	})
+	sorted := make([]*upstream, 0, len(items))
+	for _, item := range items {
+		sorted = append(sorted, item.upstream)
+	}
+	return sorted
+}
@@ -18,18 +18,25 @@ import (
 	"github.com/sagernet/sing/service/filemanager"
 )
 
+const processStatisticsSaveInterval = time.Minute
+
 var (
 	bucketSelected = []byte("selected")
 	bucketExpand   = []byte("group_expand")
 	bucketMode     = []byte("clash_mode")
 	bucketRuleSet  = []byte("rule_set")
+	bucketProcess  = []byte("process_statistics")
+	bucketDHCP     = []byte("dhcp_lease")
 
 	bucketNameList = []string{
 		string(bucketSelected),
 		string(bucketExpand),
 		string(bucketMode),
 		string(bucketRuleSet),
+		string(bucketProcess),
+		string(bucketDHCP),
 		string(bucketRDRC),
+		string(bucketDNS),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -43,6 +50,10 @@ type CacheFile struct {
 	cacheID           []byte
 	storeFakeIP       bool
 	storeRDRC         bool
+	storeDNS          bool
+	storeProcess      bool
+	processLoader     func() []*adapter.ProcessStatistics
+	processSaveDone   chan struct{}
 	rdrcTimeout       time.Duration
 	DB                *bbolt.DB
 	saveMetadataTimer *time.Timer
@@ -85,6 +96,8 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 		cacheID:      cacheIDBytes,
 		storeFakeIP:  options.StoreFakeIP,
 		storeRDRC:    options.StoreRDRC,
+		storeDNS:     options.StoreDNS,
+		storeProcess: options.StoreProcessStatistics,
 		rdrcTimeout:  rdrcTimeout,
 		saveDomain:   make(map[netip.Addr]string),
 		saveAddress4: make(map[string]netip.Addr),
@@ -166,7 +179,15 @@ func (c *CacheFile) Close() error {
 	if c.DB == nil {
 		return nil
 	}
-	return c.DB.Close()
+	var err error
+	if c.processLoader != nil {
+		close(c.processSaveDone)
+		err = c.saveProcessStatistics(c.processLoader())
+		if err != nil {
+			err = E.Cause(err, "save process statistics")
+		}
+	}
+	return E.Errors(err, c.DB.Close())
 }
 
 func (c *CacheFile) StoreFakeIP() bool {
@@ -316,3 +337,134 @@ func (c *CacheFile) SaveRuleSet(tag string, set *adapter.SavedBinary) error {
 		return bucket.Put([]byte(tag), setBinary)
 	})
 }
+
+func (c *CacheFile) StoreProcessStatistics() bool {
+	return c.storeProcess
+}
+
+func (c *CacheFile) LoadProcessStatistics() []*adapter.ProcessStatistics {
+	var statisticsList []*adapter.ProcessStatistics
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketProcess)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			statistics := &adapter.ProcessStatistics{Name: string(k)}
+			if statistics.UnmarshalBinary(v) == nil {
+				statisticsList = append(statisticsList, statistics)
+			}
+			return nil
+		})
+	})
+	return statisticsList
+}
+
+func (c *CacheFile) TrackProcessStatistics(loader func() []*adapter.ProcessStatistics) {
+	c.processLoader = loader
+	c.processSaveDone = make(chan struct{})
+	go c.loopSaveProcessStatistics()
+}
+
+func (c *CacheFile) loopSaveProcessStatistics() {
+	ticker := time.NewTicker(processStatisticsSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = c.saveProcessStatistics(c.processLoader())
+		case <-c.processSaveDone:
+			return
+		}
+	}
+}
+
+func (c *CacheFile) saveProcessStatistics(statisticsList []*adapter.ProcessStatistics) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketProcess)
+		if bucket != nil {
+			err := c.deleteBucket(t, bucketProcess)
+			if err != nil {
+				return err
+			}
+		}
+		bucket, err := c.createBucket(t, bucketProcess)
+		if err != nil {
+			return err
+		}
+		for _, statistics := range statisticsList {
+			content, err := statistics.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(statistics.Name), content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *CacheFile) LoadDHCPLeases(tag string) []*adapter.DHCPLease {
+	var leases []*adapter.DHCPLease
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketDHCP)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(tag))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var lease adapter.DHCPLease
+			if lease.UnmarshalBinary(v) == nil {
+				leases = append(leases, &lease)
+			}
+			return nil
+		})
+	})
+	return leases
+}
+
+func (c *CacheFile) SaveDHCPLeases(tag string, leases []*adapter.DHCPLease) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketDHCP)
+		if err != nil {
+			return err
+		}
+		if bucket.Bucket([]byte(tag)) != nil {
+			err = bucket.DeleteBucket([]byte(tag))
+			if err != nil {
+				return err
+			}
+		}
+		bucket, err = bucket.CreateBucket([]byte(tag))
+		if err != nil {
+			return err
+		}
+		for _, lease := range leases {
+			content, err := lease.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = bucket.Put(lease.MAC, content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *CacheFile) deleteBucket(t *bbolt.Tx, key []byte) error {
+	if c.cacheID == nil {
+		return t.DeleteBucket(key)
+	}
+	bucket := t.Bucket(c.cacheID)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.DeleteBucket(key)
+}
@@ -0,0 +1,41 @@
+package cachefile
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStatisticsSavedOnClose(t *testing.T) {
+	t.Parallel()
+	options := option.CacheFileOptions{
+		Path:                   filepath.Join(t.TempDir(), "cache.db"),
+		StoreProcessStatistics: true,
+	}
+	cacheFile := New(context.Background(), options)
+	require.NoError(t, cacheFile.Start(adapter.StartStateInitialize))
+	require.Empty(t, cacheFile.LoadProcessStatistics())
+	statistics := &adapter.ProcessStatistics{
+		Name:        "org.mozilla.firefox",
+		Upload:      100,
+		Download:    200,
+		Connections: 3,
+		Outbounds:   []string{"proxy"},
+		LastSeen:    time.Unix(1700000000, 0),
+	}
+	cacheFile.TrackProcessStatistics(func() []*adapter.ProcessStatistics {
+		return []*adapter.ProcessStatistics{statistics}
+	})
+	require.NoError(t, cacheFile.Close())
+
+	cacheFile = New(context.Background(), options)
+	require.NoError(t, cacheFile.Start(adapter.StartStateInitialize))
+	defer cacheFile.Close()
+	require.Equal(t, []*adapter.ProcessStatistics{statistics}, cacheFile.LoadProcessStatistics())
+}
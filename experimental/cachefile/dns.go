@@ -0,0 +1,59 @@
+package cachefile
+
+import (
+	"encoding/binary"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var bucketDNS = []byte("dns_cache")
+
+func (c *CacheFile) StoreDNSCache() bool {
+	return c.storeDNS
+}
+
+func (c *CacheFile) LoadDNSCache() []*adapter.DNSCacheEntry {
+	var entries []*adapter.DNSCacheEntry
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketDNS)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var entry adapter.DNSCacheEntry
+			if entry.UnmarshalBinary(v) == nil {
+				entries = append(entries, &entry)
+			}
+			return nil
+		})
+	})
+	return entries
+}
+
+func (c *CacheFile) SaveDNSCache(entries []*adapter.DNSCacheEntry) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketDNS)
+		if bucket != nil {
+			err := c.deleteBucket(t, bucketDNS)
+			if err != nil {
+				return err
+			}
+		}
+		bucket, err := c.createBucket(t, bucketDNS)
+		if err != nil {
+			return err
+		}
+		for i, entry := range entries {
+			content, err := entry.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = bucket.Put(binary.BigEndian.AppendUint32(nil, uint32(i)), content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
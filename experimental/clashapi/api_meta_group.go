@@ -108,10 +108,10 @@ func getGroupDelay(server *Server) func(w http.ResponseWriter, r *http.Request)
 					t, err := urltest.URLTest(ctx, url, p)
 					if err != nil {
 						server.logger.Debug("outbound ", tag, " unavailable: ", err)
-						server.urlTestHistory.DeleteURLTestHistory(realTag)
+						urltest.DeleteHistory(server.urlTestHistory, realTag)
 					} else {
 						server.logger.Debug("outbound ", tag, " available: ", t, "ms")
-						server.urlTestHistory.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
+						urltest.StoreHistory(server.urlTestHistory, realTag, &adapter.URLTestHistory{
 							Time:  time.Now(),
 							Delay: t,
 						})
@@ -0,0 +1,46 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func processRouter(trafficManager *trafficontrol.Manager) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getProcesses(trafficManager))
+	return r
+}
+
+type processStatistics struct {
+	Name              string   `json:"name"`
+	Upload            int64    `json:"upload"`
+	Download          int64    `json:"download"`
+	Connections       int64    `json:"connections"`
+	ActiveConnections int64    `json:"activeConnections"`
+	Outbounds         []string `json:"outbounds"`
+	LastSeen          int64    `json:"lastSeen"`
+}
+
+func getProcesses(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, render.M{
+			"processes": common.Map(trafficManager.ProcessStatistics(), func(it *adapter.ProcessStatistics) processStatistics {
+				return processStatistics{
+					Name:              it.Name,
+					Upload:            it.Upload,
+					Download:          it.Download,
+					Connections:       it.Connections,
+					ActiveConnections: it.ActiveConnections,
+					Outbounds:         it.Outbounds,
+					LastSeen:          it.LastSeen.Unix(),
+				}
+			}),
+		})
+	}
+}
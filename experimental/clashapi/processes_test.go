@@ -0,0 +1,50 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProcesses(t *testing.T) {
+	t.Parallel()
+	trafficManager := trafficontrol.NewManager()
+	lastSeen := time.Unix(1700000000, 0)
+	trafficManager.LoadProcessStatistics([]*adapter.ProcessStatistics{
+		{Name: "/usr/bin/curl", Upload: 1, Download: 2, Connections: 1, Outbounds: []string{"direct"}, LastSeen: lastSeen},
+		{Name: "org.mozilla.firefox", Upload: 100, Download: 200, Connections: 3, Outbounds: []string{"proxy"}, LastSeen: lastSeen},
+	})
+
+	recorder := httptest.NewRecorder()
+	processRouter(trafficManager).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Processes []processStatistics `json:"processes"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, []processStatistics{
+		{
+			Name:        "org.mozilla.firefox",
+			Upload:      100,
+			Download:    200,
+			Connections: 3,
+			Outbounds:   []string{"proxy"},
+			LastSeen:    lastSeen.Unix(),
+		},
+		{
+			Name:        "/usr/bin/curl",
+			Upload:      1,
+			Download:    2,
+			Connections: 1,
+			Outbounds:   []string{"direct"},
+			LastSeen:    lastSeen.Unix(),
+		},
+	}, response.Processes)
+}
@@ -29,6 +29,7 @@ func proxyRouter(server *Server, router adapter.Router) http.Handler {
 		r.Use(parseProxyName, findProxyByName(server))
 		r.Get("/", getProxy(server))
 		r.Get("/delay", getProxyDelay(server))
+		r.Get("/speedtest", getProxySpeedTest(server))
 		r.Put("/", updateProxy)
 	})
 	return r
@@ -71,7 +72,8 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 	info.Put("name", detour.Tag())
 	info.Put("udp", common.Contains(detour.Network(), N.NetworkUDP))
 	delayHistory := server.urlTestHistory.LoadURLTestHistory(adapter.OutboundTag(detour))
-	if delayHistory != nil {
+	// histories with zero delay only hold UDP or speed test results
+	if delayHistory != nil && delayHistory.Delay > 0 {
 		info.Put("history", []*adapter.URLTestHistory{delayHistory})
 	} else {
 		info.Put("history", []*adapter.URLTestHistory{})
@@ -206,9 +208,9 @@ func getProxyDelay(server *Server) func(w http.ResponseWriter, r *http.Request)
 		defer func() {
 			realTag := group.RealTag(proxy)
 			if err != nil {
-				server.urlTestHistory.DeleteURLTestHistory(realTag)
+				urltest.DeleteHistory(server.urlTestHistory, realTag)
 			} else {
-				server.urlTestHistory.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
+				urltest.StoreHistory(server.urlTestHistory, realTag, &adapter.URLTestHistory{
 					Time:  time.Now(),
 					Delay: delay,
 				})
@@ -232,3 +234,69 @@ func getProxyDelay(server *Server) func(w http.ResponseWriter, r *http.Request)
 		})
 	}
 }
+
+const (
+	maxSpeedTestStreams  = 16
+	maxSpeedTestDuration = time.Minute
+)
+
+// getProxySpeedTest measures the throughput of the proxy. The result is
+// stored with the URL test history if store is set.
+func getProxySpeedTest(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		options := urltest.SpeedTestOptions{
+			DownloadURL:     query.Get("download_url"),
+			UploadURL:       query.Get("upload_url"),
+			DisableDownload: query.Get("download") == "false",
+			DisableUpload:   query.Get("upload") == "false",
+		}
+		if streams := query.Get("streams"); streams != "" {
+			value, err := strconv.Atoi(streams)
+			if err != nil || value <= 0 || value > maxSpeedTestStreams {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+			options.Streams = value
+		}
+		if duration := query.Get("duration"); duration != "" {
+			value, err := strconv.ParseInt(duration, 10, 32)
+			if err != nil || value <= 0 || time.Duration(value)*time.Millisecond > maxSpeedTestDuration {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+			options.Duration = time.Duration(value) * time.Millisecond
+		}
+
+		proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
+		result, err := urltest.SpeedTest(r.Context(), options, proxy)
+		if err != nil {
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		if query.Get("store") == "true" {
+			realTag := group.RealTag(proxy)
+			var history adapter.URLTestHistory
+			if lastHistory := server.urlTestHistory.LoadURLTestHistory(realTag); lastHistory != nil {
+				history = *lastHistory
+			} else {
+				// without a latency result, a zero delay would mark the proxy unavailable
+				history.Time = time.Now()
+				history.Delay = result.Delay
+			}
+			if !options.DisableDownload {
+				history.Download = result.Download
+			}
+			if !options.DisableUpload {
+				history.Upload = result.Upload
+			}
+			if history.Delay > 0 {
+				server.urlTestHistory.StoreURLTestHistory(realTag, &history)
+			}
+		}
+		render.JSON(w, r, result)
+	}
+}
@@ -122,6 +122,7 @@ func NewServer(ctx context.Context, logFactory log.ObservableFactory, options op
 		r.Mount("/proxies", proxyRouter(s, s.router))
 		r.Mount("/rules", ruleRouter(s.router))
 		r.Mount("/connections", connectionRouter(s.router, trafficManager))
+		r.Mount("/processes", processRouter(trafficManager))
 		r.Mount("/providers/proxies", proxyProviderRouter())
 		r.Mount("/providers/rules", ruleProviderRouter())
 		r.Mount("/script", scriptRouter())
@@ -156,6 +157,10 @@ func (s *Server) Start(stage adapter.StartStage) error {
 			}) {
 				s.mode = mode
 			}
+			if cacheFile.StoreProcessStatistics() {
+				s.trafficManager.LoadProcessStatistics(cacheFile.LoadProcessStatistics())
+				cacheFile.TrackProcessStatistics(s.trafficManager.ProcessStatistics)
+			}
 		}
 	case adapter.StartStateStarted:
 		if s.externalController {
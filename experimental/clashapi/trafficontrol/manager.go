@@ -30,6 +30,8 @@ type Manager struct {
 	connections             compatible.Map[uuid.UUID, Tracker]
 	closedConnectionsAccess sync.Mutex
 	closedConnections       list.List[TrackerMetadata]
+	processAccess           sync.Mutex
+	processes               map[string]*processStatistics
 	// process     *process.Process
 	memory uint64
 }
@@ -46,6 +48,9 @@ func (m *Manager) Leave(c Tracker) {
 	metadata := c.Metadata()
 	_, loaded := m.connections.LoadAndDelete(metadata.ID)
 	if loaded {
+		if metadata.process != nil {
+			metadata.process.activeConnections.Add(-1)
+		}
 		metadata.ClosedAt = time.Now()
 		m.closedConnectionsAccess.Lock()
 		defer m.closedConnectionsAccess.Unlock()
@@ -151,6 +156,7 @@ func (m *Manager) ResetStatistic() {
 	m.directDownloadTotal.Store(0)
 	m.proxyUploadTotal.Store(0)
 	m.proxyDownloadTotal.Store(0)
+	m.resetProcessStatistics()
 }
 
 type Snapshot struct {
@@ -0,0 +1,140 @@
+package trafficontrol
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+)
+
+type processStatistics struct {
+	name              string
+	upload            atomic.Int64
+	download          atomic.Int64
+	connections       atomic.Int64
+	activeConnections atomic.Int64
+	lastSeen          atomic.Int64
+	access            sync.Mutex
+	outbounds         []string
+}
+
+// trackProcess accounts a new connection to the application owning it and
+// returns nil when the owner is unknown.
+func (m *Manager) trackProcess(info *process.Info, outbound string) *processStatistics {
+	name := process.StatisticsName(info)
+	if name == "" {
+		return nil
+	}
+	m.processAccess.Lock()
+	statistics, loaded := m.processes[name]
+	if !loaded {
+		statistics = &processStatistics{name: name}
+		if m.processes == nil {
+			m.processes = make(map[string]*processStatistics)
+		}
+		m.processes[name] = statistics
+	}
+	m.processAccess.Unlock()
+	statistics.connections.Add(1)
+	statistics.activeConnections.Add(1)
+	statistics.lastSeen.Store(time.Now().Unix())
+	if outbound != "" {
+		statistics.access.Lock()
+		if !slices.Contains(statistics.outbounds, outbound) {
+			statistics.outbounds = append(statistics.outbounds, outbound)
+		}
+		statistics.access.Unlock()
+	}
+	return statistics
+}
+
+func (s *processStatistics) pushUploaded(size int64) {
+	if s != nil {
+		s.upload.Add(size)
+	}
+}
+
+func (s *processStatistics) pushDownloaded(size int64) {
+	if s != nil {
+		s.download.Add(size)
+	}
+}
+
+// ProcessStatistics returns the per-application traffic table, sorted by
+// total traffic.
+func (m *Manager) ProcessStatistics() []*adapter.ProcessStatistics {
+	m.processAccess.Lock()
+	statisticsList := make([]*adapter.ProcessStatistics, 0, len(m.processes))
+	for _, statistics := range m.processes {
+		statistics.access.Lock()
+		outbounds := append([]string{}, statistics.outbounds...)
+		statistics.access.Unlock()
+		statisticsList = append(statisticsList, &adapter.ProcessStatistics{
+			Name:              statistics.name,
+			Upload:            statistics.upload.Load(),
+			Download:          statistics.download.Load(),
+			Connections:       statistics.connections.Load(),
+			ActiveConnections: statistics.activeConnections.Load(),
+			Outbounds:         outbounds,
+			LastSeen:          time.Unix(statistics.lastSeen.Load(), 0),
+		})
+	}
+	m.processAccess.Unlock()
+	slices.SortFunc(statisticsList, func(x, y *adapter.ProcessStatistics) int {
+		xTraffic := x.Upload + x.Download
+		yTraffic := y.Upload + y.Download
+		if xTraffic > yTraffic {
+			return -1
+		} else if xTraffic < yTraffic {
+			return 1
+		}
+		return 0
+	})
+	return statisticsList
+}
+
+// LoadProcessStatistics restores totals saved in the cache file.
+func (m *Manager) LoadProcessStatistics(statisticsList []*adapter.ProcessStatistics) {
+	m.processAccess.Lock()
+	defer m.processAccess.Unlock()
+	if m.processes == nil {
+		m.processes = make(map[string]*processStatistics)
+	}
+	for _, saved := range statisticsList {
+		statistics, loaded := m.processes[saved.Name]
+		if !loaded {
+			statistics = &processStatistics{name: saved.Name}
+			m.processes[saved.Name] = statistics
+		}
+		statistics.upload.Add(saved.Upload)
+		statistics.download.Add(saved.Download)
+		statistics.connections.Add(saved.Connections)
+		if statistics.lastSeen.Load() < saved.LastSeen.Unix() {
+			statistics.lastSeen.Store(saved.LastSeen.Unix())
+		}
+		statistics.access.Lock()
+		for _, outbound := range saved.Outbounds {
+			if !slices.Contains(statistics.outbounds, outbound) {
+				statistics.outbounds = append(statistics.outbounds, outbound)
+			}
+		}
+		statistics.access.Unlock()
+	}
+}
+
+func (m *Manager) resetProcessStatistics() {
+	m.processAccess.Lock()
+	defer m.processAccess.Unlock()
+	for name, statistics := range m.processes {
+		if statistics.activeConnections.Load() > 0 {
+			statistics.upload.Store(0)
+			statistics.download.Store(0)
+			statistics.connections.Store(statistics.activeConnections.Load())
+		} else {
+			delete(m.processes, name)
+		}
+	}
+}
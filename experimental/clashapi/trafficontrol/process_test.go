@@ -0,0 +1,138 @@
+package trafficontrol
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStatistics(t *testing.T) {
+	t.Parallel()
+	manager := NewManager()
+	outboundManager := &testOutboundManager{outbounds: map[string]adapter.Outbound{
+		"direct": &testOutbound{tag: "direct", outboundType: "direct"},
+		"proxy":  &testOutbound{tag: "proxy", outboundType: "vless"},
+	}}
+	browser := &process.Info{PackageName: "org.mozilla.firefox", UserId: 10100}
+
+	// reads from the inbound connection are uploads
+	conn := newTestTracker(manager, outboundManager, browser, "proxy")
+	_, err := conn.Read(make([]byte, 100))
+	require.NoError(t, err)
+	_, err = conn.Write(make([]byte, 50))
+	require.NoError(t, err)
+	other := newTestTracker(manager, outboundManager, browser, "direct")
+	_, err = other.Read(make([]byte, 10))
+	require.NoError(t, err)
+	other.Close()
+	// connections of unknown owners are not accounted
+	unknown := newTestTracker(manager, outboundManager, nil, "direct")
+	_, err = unknown.Write(make([]byte, 1000))
+	require.NoError(t, err)
+	unknown.Close()
+	shell := newTestTracker(manager, outboundManager, &process.Info{ProcessPath: "/bin/sh", UserId: 0}, "direct")
+	_, err = shell.Write(make([]byte, 1))
+	require.NoError(t, err)
+	shell.Close()
+
+	statisticsList := manager.ProcessStatistics()
+	require.Len(t, statisticsList, 2)
+	require.Equal(t, "org.mozilla.firefox", statisticsList[0].Name)
+	require.Equal(t, int64(110), statisticsList[0].Upload)
+	require.Equal(t, int64(50), statisticsList[0].Download)
+	require.Equal(t, int64(2), statisticsList[0].Connections)
+	require.Equal(t, int64(1), statisticsList[0].ActiveConnections)
+	require.Equal(t, []string{"proxy", "direct"}, statisticsList[0].Outbounds)
+	require.Equal(t, "/bin/sh", statisticsList[1].Name)
+
+	// a reset keeps only the applications with open connections
+	manager.ResetStatistic()
+	statisticsList = manager.ProcessStatistics()
+	require.Len(t, statisticsList, 1)
+	require.Equal(t, int64(0), statisticsList[0].Upload+statisticsList[0].Download)
+	require.Equal(t, int64(1), statisticsList[0].Connections)
+	conn.Close()
+	require.Equal(t, int64(0), manager.ProcessStatistics()[0].ActiveConnections)
+}
+
+func TestProcessStatisticsLoad(t *testing.T) {
+	t.Parallel()
+	manager := NewManager()
+	lastSeen := time.Unix(time.Now().Unix()-3600, 0)
+	manager.LoadProcessStatistics([]*adapter.ProcessStatistics{
+		{Name: "uid:1000", Upload: 10, Download: 20, Connections: 3, Outbounds: []string{"proxy"}, LastSeen: lastSeen},
+	})
+	manager.trackProcess(&process.Info{UserId: 1000}, "direct").pushUploaded(5)
+
+	statisticsList := manager.ProcessStatistics()
+	require.Len(t, statisticsList, 1)
+	require.True(t, statisticsList[0].LastSeen.After(lastSeen))
+	require.Equal(t, &adapter.ProcessStatistics{
+		Name:              "uid:1000",
+		Upload:            15,
+		Download:          20,
+		Connections:       4,
+		ActiveConnections: 1,
+		Outbounds:         []string{"proxy", "direct"},
+		LastSeen:          statisticsList[0].LastSeen,
+	}, statisticsList[0])
+
+	saved, err := statisticsList[0].MarshalBinary()
+	require.NoError(t, err)
+	restored := &adapter.ProcessStatistics{Name: "uid:1000"}
+	require.NoError(t, restored.UnmarshalBinary(saved))
+	restored.ActiveConnections = 1
+	require.Equal(t, statisticsList[0], restored)
+}
+
+func newTestTracker(manager *Manager, outboundManager adapter.OutboundManager, info *process.Info, outbound string) *TCPConn {
+	detour, _ := outboundManager.Outbound(outbound)
+	return NewTCPTracker(&testConn{}, manager, adapter.InboundContext{ProcessInfo: info}, outboundManager, nil, detour)
+}
+
+type testOutboundManager struct {
+	adapter.OutboundManager
+	outbounds map[string]adapter.Outbound
+}
+
+func (m *testOutboundManager) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := m.outbounds[tag]
+	return outbound, loaded
+}
+
+type testOutbound struct {
+	adapter.Outbound
+	tag          string
+	outboundType string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Type() string {
+	return o.outboundType
+}
+
+// testConn discards writes and reads zeros.
+type testConn struct {
+	net.Conn
+}
+
+func (c *testConn) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
+func (c *testConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
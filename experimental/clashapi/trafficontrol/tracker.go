@@ -26,6 +26,7 @@ type TrackerMetadata struct {
 	Rule         adapter.Rule
 	Outbound     string
 	OutboundType string
+	process      *processStatistics
 }
 
 func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
@@ -35,6 +36,9 @@ func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
 	} else {
 		inbound = t.Metadata.InboundType
 	}
+	if t.Metadata.InboundMapping != "" {
+		inbound += "/" + t.Metadata.InboundMapping
+	}
 	var domain string
 	if t.Metadata.Domain != "" {
 		domain = t.Metadata.Domain
@@ -58,6 +62,16 @@ func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
 			processPath = F.ToString(processPath, " (", t.Metadata.ProcessInfo.UserId, ")")
 		}
 	}
+	var remoteDestination string
+	if t.Metadata.RemoteDestination != nil {
+		if serverAddr := t.Metadata.RemoteDestination.Load(); serverAddr.IsValid() {
+			remoteDestination = serverAddr.String()
+		}
+	}
+	var sourceMAC string
+	if t.Metadata.SourceMACAddress != nil {
+		sourceMAC = t.Metadata.SourceMACAddress.String()
+	}
 	var rule string
 	if t.Rule != nil {
 		rule = F.ToString(t.Rule, " => ", t.Rule.Action())
@@ -67,15 +81,18 @@ func (t TrackerMetadata) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
 		"id": t.ID,
 		"metadata": map[string]any{
-			"network":         t.Metadata.Network,
-			"type":            inbound,
-			"sourceIP":        t.Metadata.Source.Addr,
-			"destinationIP":   t.Metadata.Destination.Addr,
-			"sourcePort":      F.ToString(t.Metadata.Source.Port),
-			"destinationPort": F.ToString(t.Metadata.Destination.Port),
-			"host":            domain,
-			"dnsMode":         "normal",
-			"processPath":     processPath,
+			"network":           t.Metadata.Network,
+			"type":              inbound,
+			"sourceIP":          t.Metadata.Source.Addr,
+			"destinationIP":     t.Metadata.Destination.Addr,
+			"sourcePort":        F.ToString(t.Metadata.Source.Port),
+			"destinationPort":   F.ToString(t.Metadata.Destination.Port),
+			"host":              domain,
+			"dnsMode":           "normal",
+			"processPath":       processPath,
+			"remoteDestination": remoteDestination,
+			"sourceHostname":    t.Metadata.SourceHostname,
+			"sourceMAC":         sourceMAC,
 		},
 		"upload":      t.Upload.Load(),
 		"download":    t.Download.Load(),
@@ -145,11 +162,13 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata adapter.InboundCont
 		}
 		next = group.Now()
 	}
+	processStatistics := manager.trackProcess(metadata.ProcessInfo, outbound)
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
 	tracker := &TCPConn{
 		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			processStatistics.pushUploaded(n)
 			if outboundType == "direct" {
 				manager.PushDirectUploaded(n)
 			} else {
@@ -157,6 +176,7 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata adapter.InboundCont
 			}
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			processStatistics.pushDownloaded(n)
 			if outboundType == "direct" {
 				manager.PushDirectDownloaded(n)
 			} else {
@@ -173,6 +193,7 @@ func NewTCPTracker(conn net.Conn, manager *Manager, metadata adapter.InboundCont
 			Rule:         matchRule,
 			Outbound:     outbound,
 			OutboundType: outboundType,
+			process:      processStatistics,
 		},
 		manager: manager,
 	}
@@ -234,11 +255,13 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata adapter.Inbound
 		}
 		next = group.Now()
 	}
+	processStatistics := manager.trackProcess(metadata.ProcessInfo, outbound)
 	upload := new(atomic.Int64)
 	download := new(atomic.Int64)
 	trackerConn := &UDPConn{
 		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
 			upload.Add(n)
+			processStatistics.pushUploaded(n)
 			if outboundType == "direct" {
 				manager.PushDirectUploaded(n)
 			} else {
@@ -246,6 +269,7 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata adapter.Inbound
 			}
 		}}, []N.CountFunc{func(n int64) {
 			download.Add(n)
+			processStatistics.pushDownloaded(n)
 			if outboundType == "direct" {
 				manager.PushDirectDownloaded(n)
 			} else {
@@ -262,6 +286,7 @@ func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata adapter.Inbound
 			Rule:         matchRule,
 			Outbound:     outbound,
 			OutboundType: outboundType,
+			process:      processStatistics,
 		},
 		manager: manager,
 	}
@@ -34,12 +34,16 @@ func (s *CommandServer) handleSetClashMode(conn net.Conn) error {
 	if err != nil {
 		return err
 	}
-	service := s.service
+	return writeError(conn, s.setClashMode(newMode))
+}
+
+func (s *CommandServer) setClashMode(newMode string) error {
+	service := s.service.Load()
 	if service == nil {
-		return writeError(conn, E.New("service not ready"))
+		return E.New("service not ready")
 	}
 	service.clashServer.(*clashapi.Server).SetMode(newMode)
-	return writeError(conn, nil)
+	return nil
 }
 
 func (c *CommandClient) handleModeConn(conn net.Conn) {
@@ -57,22 +61,28 @@ func (c *CommandClient) handleModeConn(conn net.Conn) {
 
 func (s *CommandServer) handleModeConn(conn net.Conn) error {
 	ctx := connKeepAlive(conn)
-	for s.service == nil {
+	boxService := s.service.Load()
+	for boxService == nil {
 		select {
 		case <-time.After(time.Second):
+			boxService = s.service.Load()
 			continue
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
-	err := writeClashModeList(conn, s.service.clashServer)
+	err := writeClashModeList(conn, boxService.clashServer)
 	if err != nil {
 		return err
 	}
 	for {
 		select {
 		case <-s.modeUpdate:
-			err = varbin.Write(conn, binary.BigEndian, s.service.clashServer.Mode())
+			boxService = s.service.Load()
+			if boxService == nil {
+				continue
+			}
+			err = varbin.Write(conn, binary.BigEndian, boxService.clashServer.Mode())
 			if err != nil {
 				return err
 			}
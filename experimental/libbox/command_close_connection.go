@@ -41,14 +41,18 @@ func (s *CommandServer) handleCloseConnection(conn net.Conn) error {
 	if err != nil {
 		return E.Cause(err, "read connection id")
 	}
-	service := s.service
+	return writeError(conn, s.closeConnection(connId))
+}
+
+func (s *CommandServer) closeConnection(connId string) error {
+	service := s.service.Load()
 	if service == nil {
-		return writeError(conn, E.New("service not ready"))
+		return E.New("service not ready")
 	}
 	targetConn := service.clashServer.(*clashapi.Server).TrafficManager().Connection(uuid.FromStringOrNil(connId))
 	if targetConn == nil {
-		return writeError(conn, E.New("connection already closed"))
+		return E.New("connection already closed")
 	}
 	targetConn.Close()
-	return writeError(conn, nil)
+	return nil
 }
@@ -47,7 +47,7 @@ func (s *CommandServer) handleConnectionsConn(conn net.Conn) error {
 	ctx := connKeepAlive(conn)
 	var trafficManager *trafficontrol.Manager
 	for {
-		service := s.service
+		service := s.service.Load()
 		if service != nil {
 			trafficManager = service.clashServer.(*clashapi.Server).TrafficManager()
 			break
@@ -34,7 +34,7 @@ func (c *CommandClient) GetDeprecatedNotes() (DeprecatedNoteIterator, error) {
 }
 
 func (s *CommandServer) handleGetDeprecatedNotes(conn net.Conn) error {
-	boxService := s.service
+	boxService := s.service.Load()
 	if boxService == nil {
 		return writeError(conn, E.New("service not ready"))
 	}
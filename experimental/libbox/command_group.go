@@ -39,7 +39,7 @@ func (s *CommandServer) handleGroupConn(conn net.Conn) error {
 	ctx := connKeepAlive(conn)
 	writer := bufio.NewWriter(conn)
 	for {
-		service := s.service
+		service := s.service.Load()
 		if service != nil {
 			err = writeGroups(writer, service)
 			if err != nil {
@@ -107,6 +107,10 @@ func readGroups(reader io.Reader) (OutboundGroupIterator, error) {
 }
 
 func writeGroups(writer io.Writer, boxService *BoxService) error {
+	return varbin.Write(writer, binary.BigEndian, readOutboundGroups(boxService))
+}
+
+func readOutboundGroups(boxService *BoxService) []OutboundGroup {
 	historyStorage := service.PtrFromContext[urltest.HistoryStorage](boxService.ctx)
 	cacheFile := service.FromContext[adapter.CacheFile](boxService.ctx)
 	outbounds := boxService.instance.Outbound().Outbounds()
@@ -138,7 +142,7 @@ func writeGroups(writer io.Writer, boxService *BoxService) error {
 			var item OutboundGroupItem
 			item.Tag = itemTag
 			item.Type = itemOutbound.Type()
-			if history := historyStorage.LoadURLTestHistory(adapter.OutboundTag(itemOutbound)); history != nil {
+			if history := historyStorage.LoadURLTestHistory(adapter.OutboundTag(itemOutbound)); history != nil && history.Delay > 0 {
 				item.URLTestTime = history.Time.Unix()
 				item.URLTestDelay = int32(history.Delay)
 			}
@@ -149,7 +153,7 @@ func writeGroups(writer io.Writer, boxService *BoxService) error {
 		}
 		groups = append(groups, outboundGroup)
 	}
-	return varbin.Write(writer, binary.BigEndian, groups)
+	return groups
 }
 
 func (c *CommandClient) SetGroupExpand(groupTag string, isExpand bool) error {
@@ -183,7 +187,7 @@ func (s *CommandServer) handleSetGroupExpand(conn net.Conn) error {
 	if err != nil {
 		return err
 	}
-	serviceNow := s.service
+	serviceNow := s.service.Load()
 	if serviceNow == nil {
 		return writeError(conn, E.New("service not ready"))
 	}
@@ -46,7 +46,10 @@ func (c *CommandClient) ServiceClose() error {
 }
 
 func (s *CommandServer) handleServiceClose(conn net.Conn) error {
-	rErr := s.service.Close()
+	var rErr error
+	if boxService := s.service.Load(); boxService != nil {
+		rErr = boxService.Close()
+	}
 	s.handler.PostServiceClose()
 	err := binary.Write(conn, binary.BigEndian, rErr != nil)
 	if err != nil {
@@ -0,0 +1,87 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+type ProcessStatistics struct {
+	Name              string
+	Upload            int64
+	Download          int64
+	Connections       int64
+	ActiveConnections int64
+	OutboundList      []string
+	LastSeen          int64
+}
+
+func (s *ProcessStatistics) Outbounds() StringIterator {
+	return newIterator(s.OutboundList)
+}
+
+type ProcessStatisticsIterator interface {
+	Next() *ProcessStatistics
+	HasNext() bool
+}
+
+func (c *CommandClient) GetProcessStatistics() (ProcessStatisticsIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandGetProcessStatistics))
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	var statisticsList []ProcessStatistics
+	err = varbin.Read(conn, binary.BigEndian, &statisticsList)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(common.Map(statisticsList, func(it ProcessStatistics) *ProcessStatistics { return &it })), nil
+}
+
+func (s *CommandServer) handleGetProcessStatistics(conn net.Conn) error {
+	statisticsList, err := s.readProcessStatistics()
+	if err != nil {
+		return writeError(conn, err)
+	}
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return varbin.Write(conn, binary.BigEndian, statisticsList)
+}
+
+func (s *CommandServer) readProcessStatistics() ([]ProcessStatistics, error) {
+	service := s.service.Load()
+	if service == nil {
+		return nil, E.New("service not ready")
+	}
+	clashServer, isClashServer := service.clashServer.(*clashapi.Server)
+	if !isClashServer {
+		return nil, E.New("clash api not enabled")
+	}
+	return common.Map(clashServer.TrafficManager().ProcessStatistics(), func(it *adapter.ProcessStatistics) ProcessStatistics {
+		return ProcessStatistics{
+			Name:              it.Name,
+			Upload:            it.Upload,
+			Download:          it.Download,
+			Connections:       it.Connections,
+			ActiveConnections: it.ActiveConnections,
+			OutboundList:      it.Outbounds,
+			LastSeen:          it.LastSeen.Unix(),
+		}
+	}), nil
+}
@@ -0,0 +1,465 @@
+package libbox
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	_ "embed"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/conntrack"
+	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/debug"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// RemoteCommandServerOptions configures the remote control listener, which
+// exposes the command server as line-delimited JSON-RPC 2.0 over TCP or TLS.
+// The protocol is described by command_remote_schema.json and can also be
+// fetched with the rpc.discover method.
+type RemoteCommandServerOptions struct {
+	Listen          string
+	Token           string
+	CertificatePath string
+	KeyPath         string
+	ClientCAPath    string
+}
+
+//go:embed command_remote_schema.json
+var remoteCommandSchema []byte
+
+const (
+	remoteErrorParse          = -32700
+	remoteErrorInvalidRequest = -32600
+	remoteErrorMethodNotFound = -32601
+	remoteErrorInvalidParams  = -32602
+	remoteErrorFailed         = -32000
+	remoteErrorUnauthorized   = -32001
+)
+
+const (
+	// unauthenticated connections may only send this much before the auth
+	// call completes, within remoteAuthTimeout
+	remoteMaxUnauthenticatedSize = 4096
+	remoteAuthTimeout            = 10 * time.Second
+
+	remoteMinStatusInterval = 100 * time.Millisecond
+)
+
+type remoteRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type remoteResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type remoteErrorResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   remoteError     `json:"error"`
+}
+
+type remoteNotification struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type remoteError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *remoteError) Error() string {
+	return e.Message
+}
+
+func newRemoteError(code int, message ...any) *remoteError {
+	return &remoteError{code, E.New(message...).Error()}
+}
+
+func (s *CommandServer) StartRemote(options *RemoteCommandServerOptions) error {
+	if options.Token == "" && options.ClientCAPath == "" {
+		return E.New("remote command server requires a token or a client CA")
+	}
+	if options.ClientCAPath != "" && options.CertificatePath == "" {
+		return E.New("client CA requires TLS certificate")
+	}
+	listen := options.Listen
+	if listen == "" {
+		listen = "127.0.0.1:8965"
+	}
+	if options.Token != "" && options.CertificatePath == "" && !isLoopbackListen(listen) {
+		return E.New("token authentication on non-loopback address requires TLS certificate")
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return E.Cause(err, "listen ", listen)
+	}
+	if options.CertificatePath != "" {
+		tlsConfig, err := newRemoteTLSConfig(options)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s.remoteListener = listener
+	s.remoteToken = options.Token
+	go s.loopRemoteConnection(listener)
+	return nil
+}
+
+func isLoopbackListen(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	address, err := netip.ParseAddr(host)
+	return err == nil && address.IsLoopback()
+}
+
+func newRemoteTLSConfig(options *RemoteCommandServerOptions) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(options.CertificatePath, options.KeyPath)
+	if err != nil {
+		return nil, E.Cause(err, "load TLS key pair")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if options.ClientCAPath != "" {
+		content, err := os.ReadFile(options.ClientCAPath)
+		if err != nil {
+			return nil, E.Cause(err, "read client CA")
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return nil, E.New("invalid client CA: ", options.ClientCAPath)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+func (s *CommandServer) loopRemoteConnection(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			hErr := s.handleRemoteConnection(conn)
+			if hErr != nil && !E.IsClosedOrCanceled(hErr) {
+				if debug.Enabled {
+					log.Warn("command-server: process remote connection: ", hErr)
+				}
+			}
+		}()
+	}
+}
+
+type remoteConnection struct {
+	server           *CommandServer
+	ctx              context.Context
+	encoder          *json.Encoder
+	access           sync.Mutex
+	authenticated    bool
+	logSubscribed    bool
+	statusSubscribed bool
+}
+
+func (s *CommandServer) handleRemoteConnection(conn net.Conn) error {
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remoteConn := &remoteConnection{
+		server:        s,
+		ctx:           ctx,
+		encoder:       json.NewEncoder(conn),
+		authenticated: s.remoteToken == "",
+	}
+	reader := &io.LimitedReader{R: conn, N: math.MaxInt64}
+	if !remoteConn.authenticated {
+		reader.N = remoteMaxUnauthenticatedSize
+		conn.SetReadDeadline(time.Now().Add(remoteAuthTimeout))
+	}
+	decoder := json.NewDecoder(reader)
+	for {
+		var request remoteRequest
+		err := decoder.Decode(&request)
+		if err != nil {
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				return remoteConn.writeError(nil, newRemoteError(remoteErrorParse, "parse error: ", err))
+			}
+			return err
+		}
+		authenticated := remoteConn.authenticated
+		err = remoteConn.handleRequest(&request)
+		if err != nil {
+			return err
+		}
+		if !authenticated && remoteConn.authenticated {
+			reader.N = math.MaxInt64
+			conn.SetReadDeadline(time.Time{})
+		}
+	}
+}
+
+func (c *remoteConnection) handleRequest(request *remoteRequest) error {
+	if request.Version != "2.0" || request.Method == "" {
+		return c.writeError(request.ID, newRemoteError(remoteErrorInvalidRequest, "invalid request"))
+	}
+	var (
+		result any
+		err    error
+	)
+	if request.Method == "auth" {
+		result, err = c.auth(request.Params)
+	} else if !c.authenticated {
+		err = newRemoteError(remoteErrorUnauthorized, "unauthorized")
+	} else {
+		result, err = c.call(request.Method, request.Params)
+	}
+	if request.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return c.writeError(request.ID, err)
+	}
+	return c.write(remoteResponse{Version: "2.0", ID: request.ID, Result: result})
+}
+
+func (c *remoteConnection) auth(rawParams json.RawMessage) (any, error) {
+	var params struct {
+		Token string `json:"token"`
+	}
+	err := unmarshalRemoteParams(rawParams, &params)
+	if err != nil {
+		return nil, err
+	}
+	if c.server.remoteToken != "" && subtle.ConstantTimeCompare([]byte(params.Token), []byte(c.server.remoteToken)) != 1 {
+		return nil, newRemoteError(remoteErrorUnauthorized, "invalid token")
+	}
+	c.authenticated = true
+	return true, nil
+}
+
+func (c *remoteConnection) call(method string, rawParams json.RawMessage) (any, error) {
+	s := c.server
+	switch method {
+	case "rpc.discover":
+		return json.RawMessage(remoteCommandSchema), nil
+	case "status":
+		return s.readStatus(), nil
+	case "groups":
+		service := s.service.Load()
+		if service == nil {
+			return []OutboundGroup{}, nil
+		}
+		return readOutboundGroups(service), nil
+	case "select_outbound":
+		var params struct {
+			Group    string `json:"group"`
+			Outbound string `json:"outbound"`
+		}
+		err := unmarshalRemoteParams(rawParams, &params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.selectOutbound(params.Group, params.Outbound)
+	case "url_test":
+		var params struct {
+			Group string `json:"group"`
+		}
+		err := unmarshalRemoteParams(rawParams, &params)
+		if err != nil {
+			return nil, err
+		}
+		service := s.service.Load()
+		if service == nil {
+			return nil, E.New("service not ready")
+		}
+		return nil, s.urlTest(service, params.Group)
+	case "connections":
+		return s.readConnections()
+	case "processes":
+		return s.readProcessStatistics()
+	case "dns_upstreams":
+		return s.readDNSUpstreams()
+	case "close_connection":
+		var params struct {
+			ID string `json:"id"`
+		}
+		err := unmarshalRemoteParams(rawParams, &params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.closeConnection(params.ID)
+	case "close_connections":
+		conntrack.Close()
+		return nil, nil
+	case "clash_mode":
+		service := s.service.Load()
+		if service == nil {
+			return nil, E.New("service not ready")
+		}
+		return map[string]any{
+			"modes":   service.clashServer.ModeList(),
+			"current": service.clashServer.Mode(),
+		}, nil
+	case "set_clash_mode":
+		var params struct {
+			Mode string `json:"mode"`
+		}
+		err := unmarshalRemoteParams(rawParams, &params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.setClashMode(params.Mode)
+	case "reload":
+		return nil, s.handler.ServiceReload()
+	case "subscribe_log":
+		if c.logSubscribed {
+			return nil, E.New("already subscribed")
+		}
+		subscription, done, err := s.observer.Subscribe()
+		if err != nil {
+			return nil, err
+		}
+		c.logSubscribed = true
+		go c.loopLog(subscription, done)
+		return true, nil
+	case "subscribe_status":
+		var params struct {
+			Interval int64 `json:"interval"`
+		}
+		err := unmarshalRemoteParams(rawParams, &params)
+		if err != nil {
+			return nil, err
+		}
+		if c.statusSubscribed {
+			return nil, E.New("already subscribed")
+		}
+		interval := time.Duration(params.Interval) * time.Millisecond
+		if params.Interval <= 0 {
+			interval = time.Second
+		} else if interval < remoteMinStatusInterval {
+			interval = remoteMinStatusInterval
+		}
+		c.statusSubscribed = true
+		go c.loopStatus(interval)
+		return true, nil
+	default:
+		return nil, newRemoteError(remoteErrorMethodNotFound, "method not found: ", method)
+	}
+}
+
+func (s *CommandServer) readConnections() ([]Connection, error) {
+	service := s.service.Load()
+	if service == nil {
+		return nil, E.New("service not ready")
+	}
+	trafficManager := service.clashServer.(*clashapi.Server).TrafficManager()
+	connections := make(map[uuid.UUID]*Connection)
+	var outConnections []Connection
+	for _, connection := range trafficManager.Connections() {
+		outConnections = append(outConnections, newConnection(connections, connection, false))
+	}
+	for _, connection := range trafficManager.ClosedConnections() {
+		outConnections = append(outConnections, newConnection(connections, connection, true))
+	}
+	return outConnections, nil
+}
+
+func (c *remoteConnection) loopLog(subscription <-chan string, done <-chan struct{}) {
+	defer c.server.observer.UnSubscribe(subscription)
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-done:
+			return
+		case logLine := <-subscription:
+			err := c.write(remoteNotification{Version: "2.0", Method: "log", Params: []string{logLine}})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *remoteConnection) loopStatus(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	status := c.server.readStatus()
+	uploadTotal := status.UplinkTotal
+	downloadTotal := status.DownlinkTotal
+	for {
+		err := c.write(remoteNotification{Version: "2.0", Method: "status", Params: status})
+		if err != nil {
+			return
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		status = c.server.readStatus()
+		status.Uplink = status.UplinkTotal - uploadTotal
+		status.Downlink = status.DownlinkTotal - downloadTotal
+		uploadTotal = status.UplinkTotal
+		downloadTotal = status.DownlinkTotal
+	}
+}
+
+func (c *remoteConnection) write(message any) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return c.encoder.Encode(message)
+}
+
+func (c *remoteConnection) writeError(id json.RawMessage, err error) error {
+	rpcError, isRPCError := err.(*remoteError)
+	if !isRPCError {
+		rpcError = &remoteError{remoteErrorFailed, err.Error()}
+	}
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return c.write(remoteErrorResponse{Version: "2.0", ID: id, Error: *rpcError})
+}
+
+func unmarshalRemoteParams(rawParams json.RawMessage, params any) error {
+	if len(rawParams) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(rawParams, params)
+	if err != nil {
+		return newRemoteError(remoteErrorInvalidParams, "invalid params: ", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package libbox
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteListenRequiresTLS(t *testing.T) {
+	t.Parallel()
+	server := NewCommandServer(nil, 0)
+	err := server.StartRemote(&RemoteCommandServerOptions{
+		Listen: "0.0.0.0:0",
+		Token:  "secret",
+	})
+	require.ErrorContains(t, err, "requires TLS")
+	err = server.StartRemote(&RemoteCommandServerOptions{
+		Listen: "127.0.0.1:0",
+		Token:  "secret",
+	})
+	require.NoError(t, err)
+	server.remoteListener.Close()
+}
+
+func TestRemoteAuth(t *testing.T) {
+	t.Parallel()
+	client := newTestRemoteClient(t, "secret")
+	response := client.call(t, "status", nil)
+	require.Equal(t, float64(remoteErrorUnauthorized), response["error"].(map[string]any)["code"])
+	response = client.call(t, "auth", map[string]any{"token": "wrong"})
+	require.Equal(t, float64(remoteErrorUnauthorized), response["error"].(map[string]any)["code"])
+	response = client.call(t, "auth", map[string]any{"token": "secret"})
+	require.Equal(t, true, response["result"])
+	response = client.call(t, "status", nil)
+	require.Contains(t, response, "result")
+
+	// requests past the pre-auth limit are fine once authenticated
+	response = client.call(t, "select_outbound", map[string]any{"group": strings.Repeat("a", remoteMaxUnauthenticatedSize*2)})
+	require.Contains(t, response, "error")
+	require.NotEqual(t, float64(remoteErrorUnauthorized), response["error"].(map[string]any)["code"])
+}
+
+func TestRemoteUnauthenticatedLimit(t *testing.T) {
+	t.Parallel()
+	client := newTestRemoteClient(t, "secret")
+	go client.conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"auth","params":{"token":"` + strings.Repeat("a", remoteMaxUnauthenticatedSize)))
+	require.ErrorIs(t, <-client.done, io.ErrUnexpectedEOF)
+}
+
+func TestRemoteSubscribeStatusOnce(t *testing.T) {
+	t.Parallel()
+	client := newTestRemoteClient(t, "")
+	response := client.call(t, "subscribe_status", map[string]any{"interval": 1})
+	require.Equal(t, true, response["result"])
+	response = client.call(t, "subscribe_status", nil)
+	require.Equal(t, "already subscribed", response["error"].(map[string]any)["message"])
+}
+
+type testRemoteClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+	done    chan error
+}
+
+func newTestRemoteClient(t *testing.T, token string) *testRemoteClient {
+	server := NewCommandServer(nil, 0)
+	server.remoteToken = token
+	serverConn, clientConn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.handleRemoteConnection(serverConn)
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+	})
+	return &testRemoteClient{
+		conn:    clientConn,
+		scanner: bufio.NewScanner(clientConn),
+		done:    done,
+	}
+}
+
+// call sends a request and returns its response, skipping notifications.
+func (c *testRemoteClient) call(t *testing.T, method string, params any) map[string]any {
+	c.nextID++
+	request, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	require.NoError(t, err)
+	go c.conn.Write(append(request, '\n'))
+	for c.scanner.Scan() {
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(c.scanner.Bytes(), &response))
+		if _, isNotification := response["method"]; !isNotification {
+			require.Equal(t, float64(c.nextID), response["id"])
+			return response
+		}
+	}
+	require.NoError(t, c.scanner.Err())
+	t.Fatal("connection closed")
+	return nil
+}
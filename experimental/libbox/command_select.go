@@ -39,20 +39,24 @@ func (s *CommandServer) handleSelectOutbound(conn net.Conn) error {
 	if err != nil {
 		return err
 	}
-	service := s.service
+	return writeError(conn, s.selectOutbound(groupTag, outboundTag))
+}
+
+func (s *CommandServer) selectOutbound(groupTag string, outboundTag string) error {
+	service := s.service.Load()
 	if service == nil {
-		return writeError(conn, E.New("service not ready"))
+		return E.New("service not ready")
 	}
 	outboundGroup, isLoaded := service.instance.Outbound().Outbound(groupTag)
 	if !isLoaded {
-		return writeError(conn, E.New("selector not found: ", groupTag))
+		return E.New("selector not found: ", groupTag)
 	}
 	selector, isSelector := outboundGroup.(*group.Selector)
 	if !isSelector {
-		return writeError(conn, E.New("outbound is not a selector: ", groupTag))
+		return E.New("outbound is not a selector: ", groupTag)
 	}
 	if !selector.SelectOutbound(outboundTag) {
-		return writeError(conn, E.New("outbound not found in selector: ", outboundTag))
+		return E.New("outbound not found in selector: ", outboundTag)
 	}
-	return writeError(conn, nil)
+	return nil
 }
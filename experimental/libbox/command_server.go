@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/common/urltest"
 	"github.com/sagernet/sing-box/experimental/clashapi"
@@ -22,12 +23,15 @@ type CommandServer struct {
 	listener net.Listener
 	handler  CommandServerHandler
 
+	remoteListener net.Listener
+	remoteToken    string
+
 	access     sync.Mutex
 	savedLines list.List[string]
 	maxLines   int
 	subscriber *observable.Subscriber[string]
 	observer   *observable.Observer[string]
-	service    *BoxService
+	service    atomic.Pointer[BoxService]
 
 	// These channels only work with a single client. if multi-client support is needed, replace with Subscriber/Observer
 	urlTestUpdate chan struct{}
@@ -62,7 +66,7 @@ func (s *CommandServer) SetService(newService *BoxService) {
 		service.PtrFromContext[urltest.HistoryStorage](newService.ctx).SetHook(s.urlTestUpdate)
 		newService.clashServer.(*clashapi.Server).SetModeUpdateHook(s.modeUpdate)
 	}
-	s.service = newService
+	s.service.Store(newService)
 	s.notifyURLTestUpdate()
 }
 
@@ -115,6 +119,7 @@ func (s *CommandServer) listenTCP() error {
 func (s *CommandServer) Close() error {
 	return common.Close(
 		s.listener,
+		s.remoteListener,
 		s.observer,
 	)
 }
@@ -176,6 +181,8 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleCloseConnection(conn)
 	case CommandGetDeprecatedNotes:
 		return s.handleGetDeprecatedNotes(conn)
+	case CommandGetProcessStatistics:
+		return s.handleGetProcessStatistics(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
@@ -6,11 +6,14 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/conntrack"
 	"github.com/sagernet/sing-box/common/tracker"
 	"github.com/sagernet/sing-box/experimental/clashapi"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/memory"
+	"github.com/sagernet/sing/service"
 )
 
 type StatusMessage struct {
@@ -49,9 +52,9 @@ func (s *CommandServer) readStatus() StatusMessage {
 	message.Goroutines = int32(runtime.NumGoroutine())
 	message.ConnectionsOut = int32(conntrack.Count())
 
-	if s.service != nil {
+	if boxService := s.service.Load(); boxService != nil {
 		message.TrafficAvailable = true
-		clashServer := s.service.clashServer.(*clashapi.Server)
+		clashServer := boxService.clashServer.(*clashapi.Server)
 		trafficManager := clashServer.TrafficManager()
 		message.UplinkTotal, message.DownlinkTotal = trafficManager.Total()
 		message.DirectUplinkTotal, message.DirectDownlinkTotal = trafficManager.DirectTotal()
@@ -60,7 +63,8 @@ func (s *CommandServer) readStatus() StatusMessage {
 
 		// 获取DNS统计
 		if dnsRouter := clashServer.DNSRouter(); dnsRouter != nil {
-			message.DNSTotalQueries, message.DNSSuccessQueries, message.DNSCachedQueries = dnsRouter.GetDNSStats()
+			dnsStats := dnsRouter.GetDNSStats()
+			message.DNSTotalQueries, message.DNSSuccessQueries, message.DNSCachedQueries = dnsStats.Total, dnsStats.Success, dnsStats.Cached
 		}
 
 		// 检查当前节点状态
@@ -70,17 +74,45 @@ func (s *CommandServer) readStatus() StatusMessage {
 	return message
 }
 
+type DNSUpstream struct {
+	Group    string
+	Tag      string
+	Queries  int64
+	Failures int64
+	Latency  int32
+	Healthy  bool
+}
+
+func (s *CommandServer) readDNSUpstreams() ([]DNSUpstream, error) {
+	boxService := s.service.Load()
+	if boxService == nil {
+		return nil, E.New("service not ready")
+	}
+	dnsStats := service.FromContext[adapter.DNSRouter](boxService.ctx).GetDNSStats()
+	return common.Map(dnsStats.Upstreams, func(it adapter.DNSUpstreamStats) DNSUpstream {
+		return DNSUpstream{
+			Group:    it.Group,
+			Tag:      it.Tag,
+			Queries:  it.Queries,
+			Failures: it.Failures,
+			Latency:  int32(it.Latency.Milliseconds()),
+			Healthy:  it.Healthy,
+		}
+	}), nil
+}
+
 // checkOutboundStatus 检查当前outbound的连接状态
 // 返回: status (0=未知, 1=正常, 2=失败), delay (毫秒)
 func (s *CommandServer) checkOutboundStatus() (int32, int32) {
-	if s.service == nil || s.service.instance == nil {
+	boxService := s.service.Load()
+	if boxService == nil || boxService.instance == nil {
 		return 0, 0
 	}
 
 	// 🔥 优先检查 proxy-main 的状态（这是主要的代理出站）
 	// 如果 proxy-main 不存在，才回退到默认 outbound
 	var outboundTag string
-	outboundManager := s.service.instance.Outbound()
+	outboundManager := boxService.instance.Outbound()
 
 	// 尝试获取 proxy-main
 	if proxyMain, exists := outboundManager.Outbound("proxy-main"); exists && proxyMain != nil {
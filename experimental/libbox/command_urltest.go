@@ -37,17 +37,21 @@ func (s *CommandServer) handleURLTest(conn net.Conn) error {
 	if err != nil {
 		return err
 	}
-	serviceNow := s.service
+	serviceNow := s.service.Load()
 	if serviceNow == nil {
 		return nil
 	}
+	return writeError(conn, s.urlTest(serviceNow, groupTag))
+}
+
+func (s *CommandServer) urlTest(serviceNow *BoxService, groupTag string) error {
 	abstractOutboundGroup, isLoaded := serviceNow.instance.Outbound().Outbound(groupTag)
 	if !isLoaded {
-		return writeError(conn, E.New("outbound group not found: ", groupTag))
+		return E.New("outbound group not found: ", groupTag)
 	}
 	outboundGroup, isOutboundGroup := abstractOutboundGroup.(adapter.OutboundGroup)
 	if !isOutboundGroup {
-		return writeError(conn, E.New("outbound is not a group: ", groupTag))
+		return E.New("outbound is not a group: ", groupTag)
 	}
 	urlTest, isURLTest := abstractOutboundGroup.(*group.URLTest)
 	if isURLTest {
@@ -71,9 +75,9 @@ func (s *CommandServer) handleURLTest(conn net.Conn) error {
 			b.Go(outboundTag, func() (any, error) {
 				t, err := urltest.URLTest(serviceNow.ctx, "", outboundToTest)
 				if err != nil {
-					historyStorage.DeleteURLTestHistory(outboundTag)
+					urltest.DeleteHistory(historyStorage, outboundTag)
 				} else {
-					historyStorage.StoreURLTestHistory(outboundTag, &adapter.URLTestHistory{
+					urltest.StoreHistory(historyStorage, outboundTag, &adapter.URLTestHistory{
 						Time:  time.Now(),
 						Delay: t,
 					})
@@ -82,5 +86,5 @@ func (s *CommandServer) handleURLTest(conn net.Conn) error {
 			})
 		}
 	}
-	return writeError(conn, nil)
+	return nil
 }
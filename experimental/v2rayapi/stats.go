@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -31,6 +32,7 @@ type StatsService struct {
 	inbounds  map[string]bool
 	outbounds map[string]bool
 	users     map[string]bool
+	processes bool
 	access    sync.Mutex
 	counters  map[string]*atomic.Int64
 }
@@ -56,6 +58,7 @@ func NewStatsService(options option.V2RayStatsServiceOptions) *StatsService {
 		inbounds:  inbounds,
 		outbounds: outbounds,
 		users:     users,
+		processes: options.Processes,
 		counters:  make(map[string]*atomic.Int64),
 	}
 }
@@ -69,13 +72,23 @@ func (s *StatsService) RoutedConnection(ctx context.Context, conn net.Conn, meta
 	countInbound := inbound != "" && s.inbounds[inbound]
 	countOutbound := outbound != "" && s.outbounds[outbound]
 	countUser := user != "" && s.users[user]
-	if !countInbound && !countOutbound && !countUser {
+	var processName string
+	if s.processes {
+		processName = process.StatisticsName(metadata.ProcessInfo)
+	}
+	countProcess := processName != ""
+	if !countInbound && !countOutbound && !countUser && !countProcess {
 		return conn
 	}
 	s.access.Lock()
 	if countInbound {
 		readCounter = append(readCounter, s.loadOrCreateCounter("inbound>>>"+inbound+">>>traffic>>>uplink"))
 		writeCounter = append(writeCounter, s.loadOrCreateCounter("inbound>>>"+inbound+">>>traffic>>>downlink"))
+		if metadata.InboundMapping != "" {
+			mapping := inbound + "/" + metadata.InboundMapping
+			readCounter = append(readCounter, s.loadOrCreateCounter("inbound>>>"+mapping+">>>traffic>>>uplink"))
+			writeCounter = append(writeCounter, s.loadOrCreateCounter("inbound>>>"+mapping+">>>traffic>>>downlink"))
+		}
 	}
 	if countOutbound {
 		readCounter = append(readCounter, s.loadOrCreateCounter("outbound>>>"+outbound+">>>traffic>>>uplink"))
@@ -85,6 +98,10 @@ func (s *StatsService) RoutedConnection(ctx context.Context, conn net.Conn, meta
 		readCounter = append(readCounter, s.loadOrCreateCounter("user>>>"+user+">>>traffic>>>uplink"))
 		writeCounter = append(writeCounter, s.loadOrCreateCounter("user>>>"+user+">>>traffic>>>downlink"))
 	}
+	if countProcess {
+		readCounter = append(readCounter, s.loadOrCreateCounter("process>>>"+processName+">>>traffic>>>uplink"))
+		writeCounter = append(writeCounter, s.loadOrCreateCounter("process>>>"+processName+">>>traffic>>>downlink"))
+	}
 	s.access.Unlock()
 	return bufio.NewInt64CounterConn(conn, readCounter, writeCounter)
 }
@@ -98,13 +115,23 @@ func (s *StatsService) RoutedPacketConnection(ctx context.Context, conn N.Packet
 	countInbound := inbound != "" && s.inbounds[inbound]
 	countOutbound := outbound != "" && s.outbounds[outbound]
 	countUser := user != "" && s.users[user]
-	if !countInbound && !countOutbound && !countUser {
+	var processName string
+	if s.processes {
+		processName = process.StatisticsName(metadata.ProcessInfo)
+	}
+	countProcess := processName != ""
+	if !countInbound && !countOutbound && !countUser && !countProcess {
 		return conn
 	}
 	s.access.Lock()
 	if countInbound {
 		readCounter = append(readCounter, s.loadOrCreateCounter("inbound>>>"+inbound+">>>traffic>>>uplink"))
 		writeCounter = append(writeCounter, s.loadOrCreateCounter("inbound>>>"+inbound+">>>traffic>>>downlink"))
+		if metadata.InboundMapping != "" {
+			mapping := inbound + "/" + metadata.InboundMapping
+			readCounter = append(readCounter, s.loadOrCreateCounter("inbound>>>"+mapping+">>>traffic>>>uplink"))
+			writeCounter = append(writeCounter, s.loadOrCreateCounter("inbound>>>"+mapping+">>>traffic>>>downlink"))
+		}
 	}
 	if countOutbound {
 		readCounter = append(readCounter, s.loadOrCreateCounter("outbound>>>"+outbound+">>>traffic>>>uplink"))
@@ -114,6 +141,10 @@ func (s *StatsService) RoutedPacketConnection(ctx context.Context, conn N.Packet
 		readCounter = append(readCounter, s.loadOrCreateCounter("user>>>"+user+">>>traffic>>>uplink"))
 		writeCounter = append(writeCounter, s.loadOrCreateCounter("user>>>"+user+">>>traffic>>>downlink"))
 	}
+	if countProcess {
+		readCounter = append(readCounter, s.loadOrCreateCounter("process>>>"+processName+">>>traffic>>>uplink"))
+		writeCounter = append(writeCounter, s.loadOrCreateCounter("process>>>"+processName+">>>traffic>>>downlink"))
+	}
 	s.access.Unlock()
 	return bufio.NewInt64CounterPacketConn(conn, readCounter, nil, writeCounter, nil)
 }
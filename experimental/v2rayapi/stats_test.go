@@ -0,0 +1,69 @@
+package v2rayapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsServiceProcess(t *testing.T) {
+	t.Parallel()
+	service := NewStatsService(option.V2RayStatsServiceOptions{
+		Enabled:   true,
+		Processes: true,
+	})
+	detour := &testOutbound{tag: "proxy"}
+	conn := service.RoutedConnection(context.Background(), &testConn{}, adapter.InboundContext{
+		ProcessInfo: &process.Info{PackageName: "org.mozilla.firefox", UserId: 10100},
+	}, nil, detour)
+	_, err := conn.Read(make([]byte, 100))
+	require.NoError(t, err)
+	_, err = conn.Write(make([]byte, 50))
+	require.NoError(t, err)
+	// connections of unknown owners are not counted
+	unknown := service.RoutedConnection(context.Background(), &testConn{}, adapter.InboundContext{}, nil, detour)
+	require.IsType(t, &testConn{}, unknown)
+
+	response, err := service.QueryStats(context.Background(), &QueryStatsRequest{Patterns: []string{"process>>>"}})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*Stat{
+		{Name: "process>>>org.mozilla.firefox>>>traffic>>>uplink", Value: 100},
+		{Name: "process>>>org.mozilla.firefox>>>traffic>>>downlink", Value: 50},
+	}, response.Stat)
+
+	// disabled unless processes is set
+	service = NewStatsService(option.V2RayStatsServiceOptions{Enabled: true})
+	conn = service.RoutedConnection(context.Background(), &testConn{}, adapter.InboundContext{
+		ProcessInfo: &process.Info{ProcessPath: "/usr/bin/curl"},
+	}, nil, detour)
+	require.IsType(t, &testConn{}, conn)
+}
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+// testConn discards writes and reads zeros.
+type testConn struct {
+	net.Conn
+}
+
+func (c *testConn) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
+func (c *testConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -3,10 +3,16 @@
 package include
 
 import (
+	"github.com/sagernet/sing-box/adapter/service"
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/dns/transport/dhcp"
+	dhcpService "github.com/sagernet/sing-box/service/dhcp"
 )
 
 func registerDHCPTransport(registry *dns.TransportRegistry) {
 	dhcp.RegisterTransport(registry)
 }
+
+func registerDHCPService(registry *service.Registry) {
+	dhcpService.RegisterService(registry)
+}
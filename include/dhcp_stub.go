@@ -6,6 +6,7 @@ import (
 	"context"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/service"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/log"
@@ -18,3 +19,9 @@ func registerDHCPTransport(registry *dns.TransportRegistry) {
 		return nil, E.New(`DHCP is not included in this build, rebuild with -tags with_dhcp`)
 	})
 }
+
+func registerDHCPService(registry *service.Registry) {
+	service.Register[option.DHCPServiceOptions](registry, C.TypeDHCP, func(ctx context.Context, logger log.ContextLogger, tag string, options option.DHCPServiceOptions) (adapter.Service, error) {
+		return nil, E.New(`DHCP is not included in this build, rebuild with -tags with_dhcp`)
+	})
+}
@@ -0,0 +1,25 @@
+package option
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+type DHCPServiceOptions struct {
+	Interface    string                         `json:"interface"`
+	ListenPort   uint16                         `json:"listen_port,omitempty"`
+	Address      *badoption.Prefix              `json:"address,omitempty"`
+	Pool         badoption.Listable[string]     `json:"pool,omitempty"`
+	LeaseTime    badoption.Duration             `json:"lease_time,omitempty"`
+	Router       badoption.Listable[netip.Addr] `json:"router,omitempty"`
+	DNS          badoption.Listable[netip.Addr] `json:"dns,omitempty"`
+	Domain       string                         `json:"domain,omitempty"`
+	StaticLeases []DHCPStaticLease              `json:"static_leases,omitempty"`
+}
+
+type DHCPStaticLease struct {
+	MAC      string     `json:"mac"`
+	Address  netip.Addr `json:"address"`
+	Hostname string     `json:"hostname,omitempty"`
+}
@@ -103,12 +103,18 @@ func rewriteRcodeAction(rcodeMap map[string]int, ruleAction *DNSRuleAction) {
 }
 
 type DNSClientOptions struct {
-	Strategy         DomainStrategy        `json:"strategy,omitempty"`
-	DisableCache     bool                  `json:"disable_cache,omitempty"`
-	DisableExpire    bool                  `json:"disable_expire,omitempty"`
-	IndependentCache bool                  `json:"independent_cache,omitempty"`
-	CacheCapacity    uint32                `json:"cache_capacity,omitempty"`
-	ClientSubnet     *badoption.Prefixable `json:"client_subnet,omitempty"`
+	Strategy          DomainStrategy                     `json:"strategy,omitempty"`
+	DisableCache      bool                               `json:"disable_cache,omitempty"`
+	DisableExpire     bool                               `json:"disable_expire,omitempty"`
+	IndependentCache  bool                               `json:"independent_cache,omitempty"`
+	CacheCapacity     uint32                             `json:"cache_capacity,omitempty"`
+	ClientSubnet      *badoption.Prefixable              `json:"client_subnet,omitempty"`
+	ServeStale        bool                               `json:"serve_stale,omitempty"`
+	ServeStaleMaxAge  badoption.Duration                 `json:"serve_stale_max_age,omitempty"`
+	Prefetch          bool                               `json:"prefetch,omitempty"`
+	PrefetchMinHits   uint32                             `json:"prefetch_min_hits,omitempty"`
+	DNSSEC            bool                               `json:"dnssec,omitempty"`
+	DNSSECTrustAnchor badoption.Listable[DNSTrustAnchor] `json:"dnssec_trust_anchor,omitempty"`
 }
 
 type LegacyDNSFakeIPOptions struct {
@@ -341,11 +347,11 @@ func (o DNSServerAddressOptions) ServerIsDomain() bool {
 }
 
 func (o *DNSServerAddressOptions) TakeServerOptions() ServerOptions {
-	return ServerOptions(*o)
+	return ServerOptions{Server: o.Server, ServerPort: o.ServerPort}
 }
 
 func (o *DNSServerAddressOptions) ReplaceServerOptions(options ServerOptions) {
-	*o = DNSServerAddressOptions(options)
+	*o = DNSServerAddressOptions{Server: options.Server, ServerPort: options.ServerPort}
 }
 
 type LegacyDNSServerOptions struct {
@@ -400,3 +406,17 @@ type DHCPDNSServerOptions struct {
 	LocalDNSServerOptions
 	Interface string `json:"interface,omitempty"`
 }
+
+type GroupDNSServerOptions struct {
+	Servers        badoption.Listable[string] `json:"servers"`
+	Strategy       string                     `json:"strategy,omitempty"`
+	Timeout        badoption.Duration         `json:"timeout,omitempty"`
+	Fallback       badoption.Listable[string] `json:"fallback,omitempty"`
+	FallbackFilter *DNSFallbackFilterOptions  `json:"fallback_filter,omitempty"`
+}
+
+type DNSFallbackFilterOptions struct {
+	RuleSet badoption.Listable[string]       `json:"rule_set,omitempty"`
+	IPCIDR  badoption.Listable[netip.Prefix] `json:"ip_cidr,omitempty"`
+	Invert  bool                             `json:"invert,omitempty"`
+}
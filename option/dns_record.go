@@ -100,3 +100,20 @@ func (o *DNSRecordOptions) unmarshalBase64(binary []byte) error {
 func (o DNSRecordOptions) Build() dns.RR {
 	return o.RR
 }
+
+type DNSTrustAnchor struct {
+	DNSRecordOptions
+}
+
+func (a *DNSTrustAnchor) UnmarshalJSON(data []byte) error {
+	err := a.DNSRecordOptions.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	switch a.RR.(type) {
+	case *dns.DS, *dns.DNSKEY:
+		return nil
+	default:
+		return E.New("trust anchor must be a DS or DNSKEY record")
+	}
+}
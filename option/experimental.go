@@ -16,6 +16,9 @@ type CacheFileOptions struct {
 	StoreFakeIP bool               `json:"store_fakeip,omitempty"`
 	StoreRDRC   bool               `json:"store_rdrc,omitempty"`
 	RDRCTimeout badoption.Duration `json:"rdrc_timeout,omitempty"`
+	StoreDNS    bool               `json:"store_dns,omitempty"`
+
+	StoreProcessStatistics bool `json:"store_process_statistics,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -51,4 +54,5 @@ type V2RayStatsServiceOptions struct {
 	Inbounds  []string `json:"inbounds,omitempty"`
 	Outbounds []string `json:"outbounds,omitempty"`
 	Users     []string `json:"users,omitempty"`
+	Processes bool     `json:"processes,omitempty"`
 }
@@ -0,0 +1,84 @@
+package option
+
+import (
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+type ForwardInboundOptions struct {
+	ListenOptions
+	Mappings []ForwardMapping `json:"mappings"`
+}
+
+type ForwardMapping struct {
+	Name                string      `json:"name,omitempty"`
+	Network             NetworkList `json:"network,omitempty"`
+	ListenPort          PortRange   `json:"listen_port"`
+	Destination         string      `json:"destination"`
+	DestinationPort     PortRange   `json:"destination_port"`
+	Outbound            string      `json:"outbound,omitempty"`
+	AcceptProxyProtocol bool        `json:"accept_proxy_protocol,omitempty"`
+	ProxyProtocol       uint8       `json:"proxy_protocol,omitempty"`
+}
+
+// PortRange is a single port or an inclusive range written as "start:end"
+// or "start-end".
+type PortRange struct {
+	Start uint16
+	End   uint16
+}
+
+func (r PortRange) Len() int {
+	return int(r.End) - int(r.Start) + 1
+}
+
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(int(r.Start))
+	}
+	return strconv.Itoa(int(r.Start)) + ":" + strconv.Itoa(int(r.End))
+}
+
+func (r PortRange) MarshalJSON() ([]byte, error) {
+	if r.Start == r.End {
+		return json.Marshal(r.Start)
+	}
+	return json.Marshal(r.String())
+}
+
+func (r *PortRange) UnmarshalJSON(content []byte) error {
+	var port uint16
+	err := json.Unmarshal(content, &port)
+	if err == nil {
+		*r = PortRange{port, port}
+		return nil
+	}
+	var rangeString string
+	err = json.Unmarshal(content, &rangeString)
+	if err != nil {
+		return err
+	}
+	startString, endString, isRange := strings.Cut(rangeString, ":")
+	if !isRange {
+		startString, endString, isRange = strings.Cut(rangeString, "-")
+	}
+	if !isRange {
+		endString = startString
+	}
+	start, err := strconv.ParseUint(startString, 10, 16)
+	if err != nil {
+		return E.Cause(err, "parse port range: ", rangeString)
+	}
+	end, err := strconv.ParseUint(endString, 10, 16)
+	if err != nil {
+		return E.Cause(err, "parse port range: ", rangeString)
+	}
+	if start == 0 || end < start {
+		return E.New("invalid port range: ", rangeString)
+	}
+	*r = PortRange{uint16(start), uint16(end)}
+	return nil
+}
@@ -2,19 +2,37 @@ package option
 
 import "github.com/sagernet/sing/common/json/badoption"
 
+// GroupFilterOptions selects group members from all outbounds besides the
+// ones listed in outbounds. Selection is enabled by use_all, include or
+// include_type, and follows outbounds added or removed at runtime. Other
+// groups are never selected this way.
+type GroupFilterOptions struct {
+	UseAll      bool                       `json:"use_all,omitempty"`
+	Include     badoption.Listable[string] `json:"include,omitempty"`
+	Exclude     badoption.Listable[string] `json:"exclude,omitempty"`
+	IncludeType badoption.Listable[string] `json:"include_type,omitempty"`
+}
+
 type SelectorOutboundOptions struct {
 	Outbounds                 []string `json:"outbounds"`
 	Default                   string   `json:"default,omitempty"`
 	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
+	GroupFilterOptions
 }
 
 type URLTestOutboundOptions struct {
-	Outbounds                 []string           `json:"outbounds"`
-	URL                       string             `json:"url,omitempty"`
-	Interval                  badoption.Duration `json:"interval,omitempty"`
-	Tolerance                 uint16             `json:"tolerance,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	Outbounds                 []string                `json:"outbounds"`
+	URL                       string                  `json:"url,omitempty"`
+	ExpectedStatus            badoption.Listable[int] `json:"expected_status,omitempty"`
+	ExpectedBody              string                  `json:"expected_body,omitempty"`
+	UDPURL                    string                  `json:"udp_url,omitempty"`
+	Samples                   int                     `json:"samples,omitempty"`
+	Strategy                  string                  `json:"strategy,omitempty"`
+	Interval                  badoption.Duration      `json:"interval,omitempty"`
+	Tolerance                 uint16                  `json:"tolerance,omitempty"`
+	IdleTimeout               badoption.Duration      `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool                    `json:"interrupt_exist_connections,omitempty"`
+	GroupFilterOptions
 }
 
 // 🔥 Failover 故障转移出站配置（基于真实连接检测）
@@ -24,4 +42,5 @@ type FailoverOutboundOptions struct {
 	RecoveryInterval          badoption.Duration `json:"recovery_interval,omitempty"`
 	RecoveryURL               string             `json:"recovery_url,omitempty"`
 	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	GroupFilterOptions
 }
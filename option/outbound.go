@@ -5,6 +5,7 @@ import (
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/deprecated"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -142,16 +143,35 @@ type ServerOptionsWrapper interface {
 }
 
 type ServerOptions struct {
-	Server     string `json:"server"`
-	ServerPort uint16 `json:"server_port"`
+	Server         string                     `json:"server"`
+	ServerPort     uint16                     `json:"server_port"`
+	Servers        badoption.Listable[string] `json:"servers,omitempty"`
+	ServerStrategy string                     `json:"server_strategy,omitempty"`
+	ServerCooldown badoption.Duration         `json:"server_cooldown,omitempty"`
 }
 
 func (o ServerOptions) Build() M.Socksaddr {
 	return M.ParseSocksaddrHostPort(o.Server, o.ServerPort)
 }
 
+// BuildAll returns server followed by the alternative addresses in servers,
+// each of which may carry its own port.
+func (o ServerOptions) BuildAll() []M.Socksaddr {
+	serverAddrs := []M.Socksaddr{o.Build()}
+	for _, server := range o.Servers {
+		serverAddr := M.ParseSocksaddr(server)
+		if serverAddr.Port == 0 {
+			serverAddr.Port = o.ServerPort
+		}
+		serverAddrs = append(serverAddrs, serverAddr)
+	}
+	return serverAddrs
+}
+
 func (o ServerOptions) ServerIsDomain() bool {
-	return M.IsDomainName(o.Server)
+	return M.IsDomainName(o.Server) || common.Any(o.Servers, func(it string) bool {
+		return M.ParseSocksaddr(it).IsFqdn()
+	})
 }
 
 func (o *ServerOptions) TakeServerOptions() ServerOptions {
@@ -1,10 +1,29 @@
 package option
 
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
 type RedirectInboundOptions struct {
 	ListenOptions
 }
 
 type TProxyInboundOptions struct {
 	ListenOptions
-	Network NetworkList `json:"network,omitempty"`
+	Network      NetworkList                `json:"network,omitempty"`
+	AutoFirewall *TProxyAutoFirewallOptions `json:"auto_firewall,omitempty"`
+}
+
+type TProxyAutoFirewallOptions struct {
+	Enabled          bool                             `json:"enabled,omitempty"`
+	Backend          string                           `json:"backend,omitempty"`
+	Interface        string                           `json:"interface,omitempty"`
+	Mark             FwMark                           `json:"mark,omitempty"`
+	RouteTable       uint32                           `json:"route_table,omitempty"`
+	BypassMark       FwMark                           `json:"bypass_mark,omitempty"`
+	BypassAddress    badoption.Listable[netip.Prefix] `json:"bypass_address,omitempty"`
+	BypassAddressSet badoption.Listable[string]       `json:"bypass_address_set,omitempty"`
+	DNSRedirectPort  uint16                           `json:"dns_redirect_port,omitempty"`
 }
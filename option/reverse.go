@@ -0,0 +1,17 @@
+package option
+
+import "github.com/sagernet/sing/common/json/badoption"
+
+type BridgeInboundOptions struct {
+	DialerOptions
+	Domain      string `json:"domain"`
+	Token       string `json:"token"`
+	Connections int    `json:"connections,omitempty"`
+}
+
+type PortalOutboundOptions struct {
+	Domain   string                     `json:"domain"`
+	Tokens   badoption.Listable[string] `json:"tokens"`
+	Protocol string                     `json:"protocol,omitempty"`
+	Padding  bool                       `json:"padding,omitempty"`
+}
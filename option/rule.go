@@ -84,8 +84,12 @@ type RawDefaultRule struct {
 	SourceIPIsPrivate        bool                              `json:"source_ip_is_private,omitempty"`
 	IPCIDR                   badoption.Listable[string]        `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                              `json:"ip_is_private,omitempty"`
+	IPASN                    badoption.Listable[ASN]           `json:"ip_asn,omitempty"`
+	SourceIPASN              badoption.Listable[ASN]           `json:"source_ip_asn,omitempty"`
 	SourcePort               badoption.Listable[uint16]        `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]        `json:"source_port_range,omitempty"`
+	SourceMACAddress         badoption.Listable[string]        `json:"source_mac_address,omitempty"`
+	SourceHostname           badoption.Listable[string]        `json:"source_hostname,omitempty"`
 	Port                     badoption.Listable[uint16]        `json:"port,omitempty"`
 	PortRange                badoption.Listable[string]        `json:"port_range,omitempty"`
 	ProcessName              badoption.Listable[string]        `json:"process_name,omitempty"`
@@ -100,6 +104,7 @@ type RawDefaultRule struct {
 	NetworkIsConstrained     bool                              `json:"network_is_constrained,omitempty"`
 	WIFISSID                 badoption.Listable[string]        `json:"wifi_ssid,omitempty"`
 	WIFIBSSID                badoption.Listable[string]        `json:"wifi_bssid,omitempty"`
+	Schedule                 *RuleSchedule                     `json:"schedule,omitempty"`
 	RuleSet                  badoption.Listable[string]        `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool                              `json:"rule_set_ip_cidr_match_source,omitempty"`
 	Invert                   bool                              `json:"invert,omitempty"`
@@ -108,6 +113,13 @@ type RawDefaultRule struct {
 	Deprecated_RulesetIPCIDRMatchSource bool `json:"rule_set_ipcidr_match_source,omitempty"`
 }
 
+type RuleSchedule struct {
+	Weekday                   badoption.Listable[string] `json:"weekday,omitempty"`
+	Time                      badoption.Listable[string] `json:"time,omitempty"`
+	TimeZone                  string                     `json:"time_zone,omitempty"`
+	InterruptExistConnections bool                       `json:"interrupt_exist_connections,omitempty"`
+}
+
 type DefaultRule struct {
 	RawDefaultRule
 	RuleAction
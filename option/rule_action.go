@@ -183,6 +183,9 @@ type DNSRouteActionOptions struct {
 	DisableCache bool                  `json:"disable_cache,omitempty"`
 	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
 	ClientSubnet *badoption.Prefixable `json:"client_subnet,omitempty"`
+	ServeStale   *bool                 `json:"serve_stale,omitempty"`
+	Prefetch     *bool                 `json:"prefetch,omitempty"`
+	DNSSEC       *bool                 `json:"dnssec,omitempty"`
 }
 
 type _DNSRouteOptionsActionOptions struct {
@@ -190,6 +193,9 @@ type _DNSRouteOptionsActionOptions struct {
 	DisableCache bool                  `json:"disable_cache,omitempty"`
 	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
 	ClientSubnet *badoption.Prefixable `json:"client_subnet,omitempty"`
+	ServeStale   *bool                 `json:"serve_stale,omitempty"`
+	Prefetch     *bool                 `json:"prefetch,omitempty"`
+	DNSSEC       *bool                 `json:"dnssec,omitempty"`
 }
 
 type DNSRouteOptionsActionOptions _DNSRouteOptionsActionOptions
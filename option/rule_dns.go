@@ -83,11 +83,15 @@ type RawDefaultDNSRule struct {
 	GeoIP                    badoption.Listable[string]        `json:"geoip,omitempty"`
 	IPCIDR                   badoption.Listable[string]        `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                              `json:"ip_is_private,omitempty"`
+	IPASN                    badoption.Listable[ASN]           `json:"ip_asn,omitempty"`
+	SourceIPASN              badoption.Listable[ASN]           `json:"source_ip_asn,omitempty"`
 	IPAcceptAny              bool                              `json:"ip_accept_any,omitempty"`
 	SourceIPCIDR             badoption.Listable[string]        `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool                              `json:"source_ip_is_private,omitempty"`
 	SourcePort               badoption.Listable[uint16]        `json:"source_port,omitempty"`
 	SourcePortRange          badoption.Listable[string]        `json:"source_port_range,omitempty"`
+	SourceMACAddress         badoption.Listable[string]        `json:"source_mac_address,omitempty"`
+	SourceHostname           badoption.Listable[string]        `json:"source_hostname,omitempty"`
 	Port                     badoption.Listable[uint16]        `json:"port,omitempty"`
 	PortRange                badoption.Listable[string]        `json:"port_range,omitempty"`
 	ProcessName              badoption.Listable[string]        `json:"process_name,omitempty"`
@@ -103,6 +107,7 @@ type RawDefaultDNSRule struct {
 	NetworkIsConstrained     bool                              `json:"network_is_constrained,omitempty"`
 	WIFISSID                 badoption.Listable[string]        `json:"wifi_ssid,omitempty"`
 	WIFIBSSID                badoption.Listable[string]        `json:"wifi_bssid,omitempty"`
+	Schedule                 *RuleSchedule                     `json:"schedule,omitempty"`
 	RuleSet                  badoption.Listable[string]        `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool                              `json:"rule_set_ip_cidr_match_source,omitempty"`
 	RuleSetIPCIDRAcceptEmpty bool                              `json:"rule_set_ip_cidr_accept_empty,omitempty"`
@@ -92,7 +92,9 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 		switch r.Format {
 		case "":
 			return E.New("missing format")
-		case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+		case C.RuleSetFormatSource, C.RuleSetFormatBinary,
+			C.RuleSetFormatClashDomain, C.RuleSetFormatClashIPCIDR, C.RuleSetFormatClashClassical,
+			C.RuleSetFormatSurgeList, C.RuleSetFormatHosts, C.RuleSetFormatDnsmasq:
 		default:
 			return E.New("unknown rule-set format: " + r.Format)
 		}
@@ -121,9 +123,12 @@ type LocalRuleSet struct {
 }
 
 type RemoteRuleSet struct {
-	URL            string             `json:"url"`
-	DownloadDetour string             `json:"download_detour,omitempty"`
-	UpdateInterval badoption.Duration `json:"update_interval,omitempty"`
+	URL            string                     `json:"url"`
+	DownloadDetour string                     `json:"download_detour,omitempty"`
+	UpdateInterval badoption.Duration         `json:"update_interval,omitempty"`
+	SHA256         string                     `json:"sha256,omitempty"`
+	PublicKey      badoption.Listable[string] `json:"public_key,omitempty"`
+	SignatureURL   string                     `json:"signature_url,omitempty"`
 }
 
 type _HeadlessRule struct {
@@ -190,8 +195,11 @@ type DefaultHeadlessRule struct {
 	DomainRegex          badoption.Listable[string]        `json:"domain_regex,omitempty"`
 	SourceIPCIDR         badoption.Listable[string]        `json:"source_ip_cidr,omitempty"`
 	IPCIDR               badoption.Listable[string]        `json:"ip_cidr,omitempty"`
+	ASN                  badoption.Listable[ASN]           `json:"asn,omitempty"`
 	SourcePort           badoption.Listable[uint16]        `json:"source_port,omitempty"`
 	SourcePortRange      badoption.Listable[string]        `json:"source_port_range,omitempty"`
+	SourceMACAddress     badoption.Listable[string]        `json:"source_mac_address,omitempty"`
+	SourceHostname       badoption.Listable[string]        `json:"source_hostname,omitempty"`
 	Port                 badoption.Listable[uint16]        `json:"port,omitempty"`
 	PortRange            badoption.Listable[string]        `json:"port_range,omitempty"`
 	ProcessName          badoption.Listable[string]        `json:"process_name,omitempty"`
@@ -208,6 +216,7 @@ type DefaultHeadlessRule struct {
 	DomainMatcher *domain.Matcher `json:"-"`
 	SourceIPSet   *netipx.IPSet   `json:"-"`
 	IPSet         *netipx.IPSet   `json:"-"`
+	ASNIPSet      *netipx.IPSet   `json:"-"`
 
 	AdGuardDomain        badoption.Listable[string] `json:"-"`
 	AdGuardDomainMatcher *domain.AdGuardMatcher     `json:"-"`
@@ -240,7 +249,7 @@ type PlainRuleSetCompat _PlainRuleSetCompat
 func (r PlainRuleSetCompat) MarshalJSON() ([]byte, error) {
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
 		v = r.Options
 	default:
 		return nil, E.New("unknown rule-set version: ", r.Version)
@@ -255,7 +264,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
 		v = &r.Options
 	case 0:
 		return E.New("missing rule-set version")
@@ -272,7 +281,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 
 func (r PlainRuleSetCompat) Upgrade() (PlainRuleSet, error) {
 	switch r.Version {
-	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3:
+	case C.RuleSetVersion1, C.RuleSetVersion2, C.RuleSetVersion3, C.RuleSetVersion4:
 	default:
 		return PlainRuleSet{}, E.New("unknown rule-set version: " + F.ToString(r.Version))
 	}
@@ -2,6 +2,23 @@ package option
 
 import "github.com/sagernet/sing/common/json/badoption"
 
+type SSHInboundOptions struct {
+	ListenOptions
+	Users              []SSHUser                                 `json:"users,omitempty"`
+	HostKey            badoption.Listable[string]                `json:"host_key,omitempty"`
+	HostKeyPath        string                                    `json:"host_key_path,omitempty"`
+	ServerVersion      string                                    `json:"server_version,omitempty"`
+	AllowTCPIPForward  bool                                      `json:"allow_tcpip_forward,omitempty"`
+	TCPIPForwardListen badoption.Listable[*badoption.Prefixable] `json:"tcpip_forward_listen,omitempty"`
+}
+
+type SSHUser struct {
+	Name               string                     `json:"name,omitempty"`
+	Password           string                     `json:"password,omitempty"`
+	PublicKey          badoption.Listable[string] `json:"public_key,omitempty"`
+	AuthorizedKeysPath string                     `json:"authorized_keys_path,omitempty"`
+}
+
 type SSHOutboundOptions struct {
 	DialerOptions
 	ServerOptions
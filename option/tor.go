@@ -1,5 +1,17 @@
 package option
 
+import "github.com/sagernet/sing/common/json/badoption"
+
+type TorInboundOptions struct {
+	ExecutablePath string                     `json:"executable_path,omitempty"`
+	ExtraArgs      []string                   `json:"extra_args,omitempty"`
+	DataDirectory  string                     `json:"data_directory,omitempty"`
+	Options        map[string]string          `json:"torrc,omitempty"`
+	Ports          badoption.Listable[uint16] `json:"ports,omitempty"`
+	MaxStreams     int                        `json:"max_streams,omitempty"`
+	Detour         string                     `json:"detour,omitempty"`
+}
+
 type TorOutboundOptions struct {
 	DialerOptions
 	ExecutablePath string            `json:"executable_path,omitempty"`
@@ -1,6 +1,7 @@
 package option
 
 import (
+	"strconv"
 	"strings"
 
 	C "github.com/sagernet/sing-box/constant"
@@ -194,3 +195,32 @@ func (t *InterfaceType) UnmarshalJSON(content []byte) error {
 	*t = InterfaceType(interfaceType)
 	return nil
 }
+
+type ASN uint32
+
+func (a ASN) String() string {
+	return F.ToString("AS", uint32(a))
+}
+
+func (a ASN) MarshalJSON() ([]byte, error) {
+	return json.Marshal(uint32(a))
+}
+
+func (a *ASN) UnmarshalJSON(bytes []byte) error {
+	var valueNumber uint32
+	err := json.Unmarshal(bytes, &valueNumber)
+	if err == nil {
+		*a = ASN(valueNumber)
+		return nil
+	}
+	var valueString string
+	err = json.Unmarshal(bytes, &valueString)
+	if err == nil {
+		valueNumber, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(valueString), "AS"), 10, 32)
+		if err == nil {
+			*a = ASN(valueNumber)
+			return nil
+		}
+	}
+	return E.New("invalid AS number: ", string(bytes))
+}
@@ -1,8 +1,12 @@
 package option
 
 import (
+	"strconv"
+	"strings"
+
 	C "github.com/sagernet/sing-box/constant"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
 	"github.com/sagernet/sing/common/json/badoption"
@@ -15,6 +19,7 @@ type _V2RayTransportOptions struct {
 	QUICOptions        V2RayQUICOptions        `json:"-"`
 	GRPCOptions        V2RayGRPCOptions        `json:"-"`
 	HTTPUpgradeOptions V2RayHTTPUpgradeOptions `json:"-"`
+	XHTTPOptions       V2RayXHTTPOptions       `json:"-"`
 }
 
 type V2RayTransportOptions _V2RayTransportOptions
@@ -32,6 +37,8 @@ func (o V2RayTransportOptions) MarshalJSON() ([]byte, error) {
 		v = o.GRPCOptions
 	case C.V2RayTransportTypeHTTPUpgrade:
 		v = o.HTTPUpgradeOptions
+	case C.V2RayTransportTypeXHTTP:
+		v = o.XHTTPOptions
 	case "":
 		return nil, E.New("missing transport type")
 	default:
@@ -57,6 +64,8 @@ func (o *V2RayTransportOptions) UnmarshalJSON(bytes []byte) error {
 		v = &o.GRPCOptions
 	case C.V2RayTransportTypeHTTPUpgrade:
 		v = &o.HTTPUpgradeOptions
+	case C.V2RayTransportTypeXHTTP:
+		v = &o.XHTTPOptions
 	default:
 		return E.New("unknown transport type: " + o.Type)
 	}
@@ -98,3 +107,61 @@ type V2RayHTTPUpgradeOptions struct {
 	Path    string               `json:"path,omitempty"`
 	Headers badoption.HTTPHeader `json:"headers,omitempty"`
 }
+
+type V2RayXHTTPOptions struct {
+	Host                 string               `json:"host,omitempty"`
+	Path                 string               `json:"path,omitempty"`
+	Mode                 string               `json:"mode,omitempty"`
+	Headers              badoption.HTTPHeader `json:"headers,omitempty"`
+	XPaddingBytes        *XHTTPRange          `json:"x_padding_bytes,omitempty"`
+	NoGRPCHeader         bool                 `json:"no_grpc_header,omitempty"`
+	NoSSEHeader          bool                 `json:"no_sse_header,omitempty"`
+	ScMaxEachPostBytes   *XHTTPRange          `json:"sc_max_each_post_bytes,omitempty"`
+	ScMaxBufferedPosts   int                  `json:"sc_max_buffered_posts,omitempty"`
+	ScMinPostsIntervalMs *XHTTPRange          `json:"sc_min_posts_interval_ms,omitempty"`
+}
+
+type XHTTPRange struct {
+	From int32
+	To   int32
+}
+
+func (r XHTTPRange) MarshalJSON() ([]byte, error) {
+	if r.From == r.To {
+		return json.Marshal(r.From)
+	}
+	return json.Marshal(F.ToString(r.From, "-", r.To))
+}
+
+func (r *XHTTPRange) UnmarshalJSON(bytes []byte) error {
+	var valueNumber int32
+	err := json.Unmarshal(bytes, &valueNumber)
+	if err == nil {
+		r.From = valueNumber
+		r.To = valueNumber
+		return nil
+	}
+	var valueString string
+	err = json.Unmarshal(bytes, &valueString)
+	if err != nil {
+		return err
+	}
+	fromString, toString, isRange := strings.Cut(valueString, "-")
+	if !isRange {
+		toString = fromString
+	}
+	from, err := strconv.ParseInt(strings.TrimSpace(fromString), 10, 32)
+	if err != nil {
+		return E.Cause(err, "parse range: ", valueString)
+	}
+	to, err := strconv.ParseInt(strings.TrimSpace(toString), 10, 32)
+	if err != nil {
+		return E.Cause(err, "parse range: ", valueString)
+	}
+	if from < 0 || to < from {
+		return E.New("invalid range: ", valueString)
+	}
+	r.From = int32(from)
+	r.To = int32(to)
+	return nil
+}
@@ -16,9 +16,22 @@ type WireGuardEndpointOptions struct {
 	Peers      []WireGuardPeer                  `json:"peers,omitempty"`
 	UDPTimeout badoption.Duration               `json:"udp_timeout,omitempty"`
 	Workers    int                              `json:"workers,omitempty"`
+	Amnezia    *WireGuardAmneziaOptions         `json:"amnezia,omitempty"`
 	DialerOptions
 }
 
+type WireGuardAmneziaOptions struct {
+	JunkPacketCount            int    `json:"jc,omitempty"`
+	JunkPacketMinSize          int    `json:"jmin,omitempty"`
+	JunkPacketMaxSize          int    `json:"jmax,omitempty"`
+	InitPacketJunkSize         int    `json:"s1,omitempty"`
+	ResponsePacketJunkSize     int    `json:"s2,omitempty"`
+	InitPacketMagicHeader      uint32 `json:"h1,omitempty"`
+	ResponsePacketMagicHeader  uint32 `json:"h2,omitempty"`
+	UnderloadPacketMagicHeader uint32 `json:"h3,omitempty"`
+	TransportPacketMagicHeader uint32 `json:"h4,omitempty"`
+}
+
 type WireGuardPeer struct {
 	Address                     string                           `json:"address,omitempty"`
 	Port                        uint16                           `json:"port,omitempty"`
@@ -58,11 +58,11 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	}
 	outbound.tlsConfig = tlsConfig
 
-	outboundDialer, err := dialer.NewWithOptions(dialer.Options{
+	outboundDialer, err := dialer.NewServerWithOptions(dialer.Options{
 		Context:        ctx,
 		Options:        options.DialerOptions,
 		RemoteIsDomain: options.ServerIsDomain(),
-	})
+	}, options.ServerOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,210 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/listener"
+	"github.com/sagernet/sing-box/common/proxyproto"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/udpnat2"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.ForwardInboundOptions](registry, C.TypeForward, NewInbound)
+}
+
+var _ adapter.Inbound = (*Inbound)(nil)
+
+// Inbound forwards every port of its mappings to a fixed destination.
+type Inbound struct {
+	inbound.Adapter
+	ports []*forwardPort
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ForwardInboundOptions) (adapter.Inbound, error) {
+	if len(options.Mappings) == 0 {
+		return nil, E.New("missing mappings")
+	}
+	if options.ListenPort != 0 {
+		return nil, E.New("listen_port must be set in mappings")
+	}
+	options.UDPFragmentDefault = true
+	var udpTimeout time.Duration
+	if options.UDPTimeout != 0 {
+		udpTimeout = time.Duration(options.UDPTimeout)
+	} else {
+		udpTimeout = C.UDPTimeout
+	}
+	forwardInbound := &Inbound{
+		Adapter: inbound.NewAdapter(C.TypeForward, tag),
+	}
+	for i, mapping := range options.Mappings {
+		if mapping.ListenPort.Start == 0 {
+			return nil, E.New("mapping[", i, "]: missing listen_port")
+		}
+		if mapping.Destination == "" {
+			return nil, E.New("mapping[", i, "]: missing destination")
+		}
+		if mapping.DestinationPort.Start == 0 {
+			return nil, E.New("mapping[", i, "]: missing destination_port")
+		}
+		if mapping.DestinationPort.Len() != 1 && mapping.DestinationPort.Len() != mapping.ListenPort.Len() {
+			return nil, E.New("mapping[", i, "]: destination_port must be a single port or a range of the same size as listen_port")
+		}
+		switch mapping.ProxyProtocol {
+		case 0, proxyproto.Version1, proxyproto.Version2:
+		default:
+			return nil, E.New("mapping[", i, "]: unknown proxy_protocol version: ", mapping.ProxyProtocol)
+		}
+		for offset := 0; offset < mapping.ListenPort.Len(); offset++ {
+			destinationPort := mapping.DestinationPort.Start
+			if mapping.DestinationPort.Len() > 1 {
+				destinationPort += uint16(offset)
+			}
+			port := &forwardPort{
+				ctx:                 ctx,
+				router:              router,
+				logger:              logger,
+				tag:                 tag,
+				name:                mapping.Name,
+				outbound:            mapping.Outbound,
+				destination:         M.ParseSocksaddrHostPort(mapping.Destination, destinationPort),
+				acceptProxyProtocol: mapping.AcceptProxyProtocol,
+				proxyProtocol:       mapping.ProxyProtocol,
+			}
+			listenOptions := options.ListenOptions
+			listenOptions.ListenPort = mapping.ListenPort.Start + uint16(offset)
+			port.udpNat = udpnat.New(port, port.preparePacketConnection, udpTimeout, false)
+			port.listener = listener.New(listener.Options{
+				Context:           ctx,
+				Logger:            logger,
+				Network:           mapping.Network.Build(),
+				Listen:            listenOptions,
+				ConnectionHandler: port,
+				PacketHandler:     port,
+			})
+			forwardInbound.ports = append(forwardInbound.ports, port)
+		}
+	}
+	return forwardInbound, nil
+}
+
+func (i *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	for _, port := range i.ports {
+		err := port.listener.Start()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Inbound) Close() error {
+	return common.Close(common.Map(i.ports, func(it *forwardPort) any {
+		return it.listener
+	})...)
+}
+
+type forwardPort struct {
+	ctx                 context.Context
+	router              adapter.ConnectionRouterEx
+	logger              log.ContextLogger
+	tag                 string
+	name                string
+	outbound            string
+	destination         M.Socksaddr
+	acceptProxyProtocol bool
+	proxyProtocol       uint8
+	listener            *listener.Listener
+	udpNat              *udpnat.Service
+}
+
+func (p *forwardPort) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	metadata.Inbound = p.tag
+	metadata.InboundType = C.TypeForward
+	//nolint:staticcheck
+	metadata.InboundDetour = p.listener.ListenOptions().Detour
+	//nolint:staticcheck
+	metadata.InboundOptions = p.listener.ListenOptions().InboundOptions
+	metadata.InboundMapping = p.name
+	metadata.RouteOutbound = p.outbound
+	if p.acceptProxyProtocol {
+		conn.SetReadDeadline(time.Now().Add(C.TCPTimeout))
+		header, err := proxyproto.ReadHeader(conn)
+		if err != nil {
+			N.CloseOnHandshakeFailure(conn, onClose, err)
+			p.logger.ErrorContext(ctx, E.Cause(err, "read PROXY protocol header from ", metadata.Source))
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+		if header.Source.IsValid() {
+			metadata.Source = header.Source
+			p.logger.InfoContext(ctx, "inbound PROXY protocol connection from ", metadata.Source)
+		}
+	}
+	if p.proxyProtocol != 0 {
+		buffer := buf.New()
+		err := proxyproto.WriteHeader(buffer, p.proxyProtocol, metadata.Source, metadata.OriginDestination)
+		if err != nil {
+			buffer.Release()
+			N.CloseOnHandshakeFailure(conn, onClose, err)
+			p.logger.ErrorContext(ctx, E.Cause(err, "write PROXY protocol header"))
+			return
+		}
+		// the header is the first payload relayed to the destination
+		conn = bufio.NewCachedConn(conn, buffer)
+	}
+	metadata.Destination = p.destination
+	p.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+	p.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (p *forwardPort) NewPacketEx(buffer *buf.Buffer, source M.Socksaddr) {
+	p.udpNat.NewPacket([][]byte{buffer.Bytes()}, source, p.listener.UDPAddr(), nil)
+}
+
+func (p *forwardPort) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	p.logger.InfoContext(ctx, "inbound packet connection from ", source)
+	var metadata adapter.InboundContext
+	metadata.Inbound = p.tag
+	metadata.InboundType = C.TypeForward
+	//nolint:staticcheck
+	metadata.InboundDetour = p.listener.ListenOptions().Detour
+	//nolint:staticcheck
+	metadata.InboundOptions = p.listener.ListenOptions().InboundOptions
+	metadata.InboundMapping = p.name
+	metadata.RouteOutbound = p.outbound
+	metadata.Source = source
+	metadata.Destination = p.destination
+	p.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
+	conn = bufio.NewDestinationNATPacketConn(bufio.NewNetPacketConn(conn), p.listener.UDPAddr(), p.destination)
+	p.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (p *forwardPort) preparePacketConnection(source M.Socksaddr, destination M.Socksaddr, userData any) (bool, context.Context, N.PacketWriter, N.CloseHandlerFunc) {
+	return true, log.ContextWithNewID(p.ctx), &forwardPacketWriter{p.listener.PacketWriter(), source}, nil
+}
+
+type forwardPacketWriter struct {
+	writer N.PacketWriter
+	source M.Socksaddr
+}
+
+func (w *forwardPacketWriter) WritePacket(buffer *buf.Buffer, addr M.Socksaddr) error {
+	return w.writer.WritePacket(buffer, w.source)
+}
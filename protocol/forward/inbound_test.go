@@ -0,0 +1,113 @@
+package forward
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/proxyproto"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortRange(t *testing.T) {
+	t.Parallel()
+	forwardInbound := newTestInbound(t, nil, option.ForwardMapping{
+		ListenPort:      option.PortRange{Start: 10000, End: 10002},
+		Destination:     "10.0.0.1",
+		DestinationPort: option.PortRange{Start: 20000, End: 20002},
+	}, option.ForwardMapping{
+		ListenPort:      option.PortRange{Start: 10010, End: 10011},
+		Destination:     "10.0.0.2",
+		DestinationPort: option.PortRange{Start: 80, End: 80},
+	})
+	var mappings []string
+	for _, port := range forwardInbound.ports {
+		mappings = append(mappings, F.ToString(port.listener.ListenOptions().ListenPort, " => ", port.destination))
+	}
+	require.Equal(t, []string{
+		"10000 => 10.0.0.1:20000",
+		"10001 => 10.0.0.1:20001",
+		"10002 => 10.0.0.1:20002",
+		"10010 => 10.0.0.2:80",
+		"10011 => 10.0.0.2:80",
+	}, mappings)
+
+	_, err := NewInbound(context.Background(), nil, log.NewNOPFactory().NewLogger("forward"), "forward", option.ForwardInboundOptions{
+		Mappings: []option.ForwardMapping{{
+			ListenPort:      option.PortRange{Start: 10000, End: 10002},
+			Destination:     "10.0.0.1",
+			DestinationPort: option.PortRange{Start: 20000, End: 20001},
+		}},
+	})
+	require.Error(t, err)
+}
+
+func TestProxyProtocol(t *testing.T) {
+	t.Parallel()
+	router := &testRouter{connections: make(chan routedConnection, 1)}
+	forwardInbound := newTestInbound(t, router, option.ForwardMapping{
+		Name:                "web",
+		ListenPort:          option.PortRange{Start: 10000, End: 10000},
+		Destination:         "10.0.0.1",
+		DestinationPort:     option.PortRange{Start: 443, End: 443},
+		Outbound:            "direct",
+		AcceptProxyProtocol: true,
+		ProxyProtocol:       proxyproto.Version2,
+	})
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	go func() {
+		clientConn.Write([]byte("PROXY TCP4 192.168.1.2 10.0.0.1 12345 443\r\npayload"))
+	}()
+	forwardInbound.ports[0].NewConnectionEx(context.Background(), serverConn, adapter.InboundContext{
+		Source:            M.ParseSocksaddr("127.0.0.1:40000"),
+		OriginDestination: M.ParseSocksaddr("10.0.0.1:10000"),
+	}, nil)
+	routed := <-router.connections
+	defer routed.conn.Close()
+	require.Equal(t, "192.168.1.2:12345", routed.metadata.Source.String())
+	require.Equal(t, "10.0.0.1:443", routed.metadata.Destination.String())
+	require.Equal(t, "web", routed.metadata.InboundMapping)
+	require.Equal(t, "direct", routed.metadata.RouteOutbound)
+	require.Empty(t, routed.metadata.User)
+
+	// the destination receives a v2 header carrying the client address
+	header, err := proxyproto.ReadHeader(routed.conn)
+	require.NoError(t, err)
+	require.Equal(t, uint8(proxyproto.Version2), header.Version)
+	require.Equal(t, "192.168.1.2:12345", header.Source.String())
+	payload := make([]byte, len("payload"))
+	_, err = io.ReadFull(routed.conn, payload)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(payload))
+}
+
+func newTestInbound(t *testing.T, router adapter.Router, mappings ...option.ForwardMapping) *Inbound {
+	forwardInbound, err := NewInbound(context.Background(), router, log.NewNOPFactory().NewLogger("forward"), "forward", option.ForwardInboundOptions{
+		Mappings: mappings,
+	})
+	require.NoError(t, err)
+	return forwardInbound.(*Inbound)
+}
+
+type routedConnection struct {
+	conn     net.Conn
+	metadata adapter.InboundContext
+}
+
+type testRouter struct {
+	adapter.Router
+	connections chan routedConnection
+}
+
+func (r *testRouter) RouteConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	r.connections <- routedConnection{conn, metadata}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -38,13 +39,11 @@ type Failover struct {
 	outboundManager              adapter.OutboundManager
 	connection                   adapter.ConnectionManager
 	logger                       log.ContextLogger
-	tags                         []string
+	filter                       *memberFilter
 	maxFailures                  int
 	recoveryInterval             time.Duration
 	recoveryURL                  string
-	outbounds                    []adapter.Outbound
-	selected                     atomic.Int32
-	consecutiveFailures          []atomic.Int32
+	members                      common.TypedValue[*failoverMembers]
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
 	access                       sync.Mutex
@@ -55,9 +54,25 @@ type Failover struct {
 	wg                           sync.WaitGroup // 🔥 新增：等待 goroutine 退出
 }
 
+// failoverMembers 成员、当前节点下标及连续失败计数，整体原子替换，
+// 下标始终对应同一份成员列表
+type failoverMembers struct {
+	*groupMembers
+	selected            atomic.Int32
+	consecutiveFailures []atomic.Int32
+}
+
+func newFailoverMembers(members *groupMembers) *failoverMembers {
+	return &failoverMembers{
+		groupMembers:        members,
+		consecutiveFailures: make([]atomic.Int32, len(members.outbounds)),
+	}
+}
+
 func NewFailover(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FailoverOutboundOptions) (adapter.Outbound, error) {
-	if len(options.Outbounds) == 0 {
-		return nil, E.New("missing outbounds")
+	filter, err := newMemberFilter(ctx, tag, options.Outbounds, options.GroupFilterOptions)
+	if err != nil {
+		return nil, err
 	}
 
 	maxFailures := options.MaxFailures
@@ -80,11 +95,10 @@ func NewFailover(ctx context.Context, router adapter.Router, logger log.ContextL
 		outboundManager:              service.FromContext[adapter.OutboundManager](ctx),
 		connection:                   service.FromContext[adapter.ConnectionManager](ctx),
 		logger:                       logger,
-		tags:                         options.Outbounds,
+		filter:                       filter,
 		maxFailures:                  maxFailures,
 		recoveryInterval:             recoveryInterval,
 		recoveryURL:                  options.RecoveryURL,
-		consecutiveFailures:          make([]atomic.Int32, len(options.Outbounds)),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
 		close:                        make(chan struct{}),
@@ -100,27 +114,61 @@ func (f *Failover) Network() []string {
 }
 
 func (f *Failover) Start() error {
-	f.outbounds = make([]adapter.Outbound, 0, len(f.tags))
-	for i, tag := range f.tags {
-		detour, loaded := f.outboundManager.Outbound(tag)
-		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
-		}
-		f.outbounds = append(f.outbounds, detour)
+	members, err := f.filter.load(true)
+	if err != nil {
+		return err
 	}
-
-	f.selected.Store(0)
+	f.members.Store(newFailoverMembers(members))
 	f.started = true
 
+	// 🔥 动态成员：跟随出站的增删更新
+	f.filter.watch(f.updateMembers)
+
 	// 🔥 启动主节点恢复检测（使用 WaitGroup 跟踪）
 	f.recoveryTicker = time.NewTicker(f.recoveryInterval)
 	f.wg.Add(1)
 	go f.recoveryCheckLoop()
 
-	f.logger.Info("failover started with ", len(f.outbounds), " outbounds, primary: ", f.tags[0])
+	if len(members.tags) > 0 {
+		f.logger.Info("failover started with ", len(members.tags), " outbounds, primary: ", members.tags[0])
+	} else {
+		f.logger.Info("failover started without outbounds")
+	}
 	return nil
 }
 
+// 成员变化时按 tag 保留当前节点和失败计数，当前节点被移除时回到主节点
+func (f *Failover) updateMembers() {
+	members, _ := f.filter.load(false)
+	newMembers := newFailoverMembers(members)
+
+	f.access.Lock()
+	oldMembers := f.members.Load()
+	var selectedTag string
+	if idx := int(oldMembers.selected.Load()); idx < len(oldMembers.tags) {
+		selectedTag = oldMembers.tags[idx]
+	}
+	for i, tag := range oldMembers.tags {
+		if newIdx := common.Index(newMembers.tags, func(it string) bool { return it == tag }); newIdx >= 0 {
+			newMembers.consecutiveFailures[newIdx].Store(oldMembers.consecutiveFailures[i].Load())
+		}
+	}
+	newIdx := common.Index(newMembers.tags, func(it string) bool { return it == selectedTag })
+	if newIdx >= 0 {
+		newMembers.selected.Store(int32(newIdx))
+	}
+	f.members.Store(newMembers)
+	f.access.Unlock()
+	if newIdx >= 0 {
+		return
+	}
+
+	if selectedTag != "" {
+		f.logger.Info("selected outbound ", selectedTag, " removed, switching to primary")
+		f.interruptGroup.Interrupt(f.interruptExternalConnections)
+	}
+}
+
 func (f *Failover) Close() error {
 	f.closeOnce.Do(func() {
 		// 🔥 1. 取消 context，通知所有操作停止
@@ -128,6 +176,8 @@ func (f *Failover) Close() error {
 			f.cancel()
 		}
 
+		f.filter.unwatch()
+
 		// 🔥 2. 关闭 close channel
 		close(f.close)
 
@@ -158,48 +208,64 @@ func (f *Failover) Close() error {
 func (f *Failover) Now() string {
 	selected := f.getSelected()
 	if selected == nil {
-		return f.tags[0]
+		members := f.members.Load()
+		if members == nil || len(members.tags) == 0 {
+			return ""
+		}
+		return members.tags[0]
 	}
 	return selected.Tag()
 }
 
 func (f *Failover) All() []string {
-	return f.tags
+	members := f.members.Load()
+	if members == nil {
+		return nil
+	}
+	return members.tags
 }
 
 func (f *Failover) getSelected() adapter.Outbound {
-	idx := int(f.selected.Load())
-	if idx >= 0 && idx < len(f.outbounds) {
-		return f.outbounds[idx]
+	members := f.members.Load()
+	if members == nil {
+		return nil
+	}
+	idx := int(members.selected.Load())
+	if idx >= 0 && idx < len(members.outbounds) {
+		return members.outbounds[idx]
 	}
 	return nil
 }
 
 // 🔥 核心方法：真实连接时检测失败
 func (f *Failover) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	idx := int(f.selected.Load())
-	if idx >= len(f.outbounds) {
+	members := f.members.Load()
+	if members == nil {
+		return nil, E.New("no available outbound")
+	}
+	idx := int(members.selected.Load())
+	if idx >= len(members.outbounds) {
 		return nil, E.New("no available outbound")
 	}
-	selected := f.outbounds[idx]
+	selected := members.outbounds[idx]
 
 	conn, err := selected.DialContext(ctx, network, destination)
 	if err != nil {
 		// 连接失败，增加连续失败计数
-		failures := f.consecutiveFailures[idx].Add(1)
+		failures := members.consecutiveFailures[idx].Add(1)
 		f.logger.Warn("outbound ", selected.Tag(), " dial failed (", failures, "/", f.maxFailures, "): ", err)
 
 		if int(failures) >= f.maxFailures {
 			// 达到阈值，切换到下一个节点
-			f.switchToNext(idx)
+			f.switchToNext(members, idx)
 
 			// 用新节点重试本次连接
-			newIdx := int(f.selected.Load())
-			if newIdx != idx && newIdx < len(f.outbounds) {
-				newSelected := f.outbounds[newIdx]
+			newIdx := int(members.selected.Load())
+			if newIdx != idx && newIdx < len(members.outbounds) {
+				newSelected := members.outbounds[newIdx]
 				conn, err = newSelected.DialContext(ctx, network, destination)
 				if err == nil {
-					f.consecutiveFailures[newIdx].Store(0)
+					members.consecutiveFailures[newIdx].Store(0)
 					return f.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 				}
 			}
@@ -208,31 +274,35 @@ func (f *Failover) DialContext(ctx context.Context, network string, destination
 	}
 
 	// 连接成功，重置当前节点的失败计数
-	f.consecutiveFailures[idx].Store(0)
+	members.consecutiveFailures[idx].Store(0)
 	return f.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 }
 
 func (f *Failover) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	idx := int(f.selected.Load())
-	if idx >= len(f.outbounds) {
+	members := f.members.Load()
+	if members == nil {
+		return nil, E.New("no available outbound")
+	}
+	idx := int(members.selected.Load())
+	if idx >= len(members.outbounds) {
 		return nil, E.New("no available outbound")
 	}
-	selected := f.outbounds[idx]
+	selected := members.outbounds[idx]
 
 	conn, err := selected.ListenPacket(ctx, destination)
 	if err != nil {
-		failures := f.consecutiveFailures[idx].Add(1)
+		failures := members.consecutiveFailures[idx].Add(1)
 		f.logger.Warn("outbound ", selected.Tag(), " listen packet failed (", failures, "/", f.maxFailures, "): ", err)
 
 		if int(failures) >= f.maxFailures {
-			f.switchToNext(idx)
+			f.switchToNext(members, idx)
 
-			newIdx := int(f.selected.Load())
-			if newIdx != idx && newIdx < len(f.outbounds) {
-				newSelected := f.outbounds[newIdx]
+			newIdx := int(members.selected.Load())
+			if newIdx != idx && newIdx < len(members.outbounds) {
+				newSelected := members.outbounds[newIdx]
 				conn, err = newSelected.ListenPacket(ctx, destination)
 				if err == nil {
-					f.consecutiveFailures[newIdx].Store(0)
+					members.consecutiveFailures[newIdx].Store(0)
 					return f.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 				}
 			}
@@ -240,27 +310,32 @@ func (f *Failover) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 		return nil, err
 	}
 
-	f.consecutiveFailures[idx].Store(0)
+	members.consecutiveFailures[idx].Store(0)
 	return f.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 }
 
 // 切换到下一个可用节点
-func (f *Failover) switchToNext(currentIdx int) {
+func (f *Failover) switchToNext(members *failoverMembers, currentIdx int) {
 	f.access.Lock()
 	defer f.access.Unlock()
 
-	fromNode := f.tags[currentIdx]
+	// 成员已更新，由 updateMembers 负责重新选择
+	if f.members.Load() != members {
+		return
+	}
+
+	fromNode := members.tags[currentIdx]
 
-	for i := 1; i < len(f.outbounds); i++ {
-		nextIdx := (currentIdx + i) % len(f.outbounds)
+	for i := 1; i < len(members.outbounds); i++ {
+		nextIdx := (currentIdx + i) % len(members.outbounds)
 
 		// 跳过连续失败次数已达阈值的节点
-		if int(f.consecutiveFailures[nextIdx].Load()) >= f.maxFailures {
+		if int(members.consecutiveFailures[nextIdx].Load()) >= f.maxFailures {
 			continue
 		}
 
-		toNode := f.tags[nextIdx]
-		f.selected.Store(int32(nextIdx))
+		toNode := members.tags[nextIdx]
+		members.selected.Store(int32(nextIdx))
 		f.logger.Warn("🔄 switched from ", fromNode, " to ", toNode)
 		f.interruptGroup.Interrupt(f.interruptExternalConnections)
 
@@ -277,10 +352,10 @@ func (f *Failover) switchToNext(currentIdx int) {
 
 	// 所有节点都失败了，重置所有计数，回到第一个节点重试
 	f.logger.Error("all outbounds failed, resetting and retry from primary")
-	for i := range f.consecutiveFailures {
-		f.consecutiveFailures[i].Store(0)
+	for i := range members.consecutiveFailures {
+		members.consecutiveFailures[i].Store(0)
 	}
-	f.selected.Store(0)
+	members.selected.Store(0)
 	f.interruptGroup.Interrupt(f.interruptExternalConnections)
 
 	// 🔥 通知 iOS 前端所有节点都失败了
@@ -321,17 +396,17 @@ func (f *Failover) checkPrimaryRecovery() {
 		return
 	}
 
-	currentIdx := int(f.selected.Load())
-	if currentIdx == 0 {
-		return // 已经在使用主节点
-	}
-
 	// 🔥 检查 outbounds 是否有效
-	if len(f.outbounds) == 0 {
+	members := f.members.Load()
+	if members == nil || len(members.outbounds) == 0 {
 		return
 	}
 
-	primary := f.outbounds[0]
+	if members.selected.Load() == 0 {
+		return // 已经在使用主节点
+	}
+
+	primary := members.outbounds[0]
 
 	// 🔥 使用更短的超时时间（3秒），避免长时间挂起
 	var err error
@@ -357,8 +432,12 @@ func (f *Failover) checkPrimaryRecovery() {
 
 	if err == nil {
 		f.access.Lock()
-		f.selected.Store(0)
-		f.consecutiveFailures[0].Store(0)
+		if f.members.Load() != members {
+			f.access.Unlock()
+			return
+		}
+		members.selected.Store(0)
+		members.consecutiveFailures[0].Store(0)
 		f.access.Unlock()
 
 		f.logger.Info("✅ primary outbound ", primary.Tag(), " recovered, switching back")
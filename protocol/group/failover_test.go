@@ -0,0 +1,106 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover(t *testing.T) {
+	t.Parallel()
+	ctx, managers := newTestManagers(t)
+	for _, tag := range []string{"primary", "backup-1", "backup-2"} {
+		require.NoError(t, managers.createOutbound(ctx, tag))
+	}
+	failover := newTestFailover(t, ctx, option.FailoverOutboundOptions{
+		Outbounds:   []string{"primary", "backup-1"},
+		MaxFailures: 2,
+		GroupFilterOptions: option.GroupFilterOptions{
+			Include: []string{"^backup-"},
+		},
+	})
+	require.Equal(t, []string{"primary", "backup-1", "backup-2"}, failover.All())
+	require.Equal(t, "primary", failover.Now())
+
+	primary, _ := managers.outbound.Outbound("primary")
+	primary.(*testOutbound).failed.Store(true)
+	destination := M.ParseSocksaddr("1.1.1.1:443")
+	_, err := failover.DialContext(ctx, N.NetworkTCP, destination)
+	require.Error(t, err)
+	require.Equal(t, "primary", failover.Now())
+	// the second failure switches over and retries on the backup
+	conn, err := failover.DialContext(ctx, N.NetworkTCP, destination)
+	require.NoError(t, err)
+	conn.Close()
+	require.Equal(t, "backup-1", failover.Now())
+
+	// the selected member is kept by tag when members change
+	require.NoError(t, managers.createOutbound(ctx, "backup-0"))
+	require.Equal(t, []string{"primary", "backup-1", "backup-2", "backup-0"}, failover.All())
+	require.Equal(t, "backup-1", failover.Now())
+
+	// and the group goes back to the primary once it is removed
+	require.NoError(t, managers.outbound.Remove("backup-1"))
+	require.Equal(t, []string{"primary", "backup-2", "backup-0"}, failover.All())
+	require.Equal(t, "primary", failover.Now())
+}
+
+func TestFailoverMemberSwap(t *testing.T) {
+	t.Parallel()
+	ctx, managers := newTestManagers(t)
+	for _, tag := range []string{"a", "b", "c"} {
+		require.NoError(t, managers.createOutbound(ctx, tag))
+	}
+	failover := newTestFailover(t, ctx, option.FailoverOutboundOptions{
+		MaxFailures:        1,
+		GroupFilterOptions: option.GroupFilterOptions{UseAll: true},
+	})
+	for _, tag := range []string{"a", "b"} {
+		detour, _ := managers.outbound.Outbound(tag)
+		detour.(*testOutbound).failed.Store(true)
+	}
+
+	// dials never use an index of another member list
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			managers.outbound.Remove("b")
+			managers.createOutbound(ctx, "b")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			conn, err := failover.DialContext(ctx, N.NetworkTCP, M.ParseSocksaddr("1.1.1.1:443"))
+			if err == nil {
+				conn.Close()
+			}
+			members := failover.members.Load()
+			if int(members.selected.Load()) >= len(members.outbounds) {
+				t.Error("selected index out of range")
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func newTestFailover(t *testing.T, ctx context.Context, options option.FailoverOutboundOptions) *Failover {
+	detour, err := NewFailover(ctx, nil, log.NewNOPFactory().NewLogger("failover"), "failover", options)
+	require.NoError(t, err)
+	failover := detour.(*Failover)
+	require.NoError(t, failover.Start())
+	t.Cleanup(func() {
+		failover.Close()
+	})
+	return failover
+}
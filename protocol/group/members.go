@@ -0,0 +1,153 @@
+package group
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+)
+
+// memberFilter resolves the members of a group: the listed outbounds first,
+// then every outbound and endpoint matched by the filters.
+type memberFilter struct {
+	outbound    adapter.OutboundManager
+	endpoint    adapter.EndpointManager
+	tag         string
+	tags        []string
+	useAll      bool
+	include     []*regexp.Regexp
+	exclude     []*regexp.Regexp
+	includeType []string
+
+	outboundCallback *list.Element[adapter.OutboundUpdateCallback]
+	endpointCallback *list.Element[adapter.OutboundUpdateCallback]
+}
+
+type groupMembers struct {
+	tags      []string
+	outbounds []adapter.Outbound
+}
+
+func newMemberFilter(ctx context.Context, tag string, tags []string, options option.GroupFilterOptions) (*memberFilter, error) {
+	filter := &memberFilter{
+		outbound:    service.FromContext[adapter.OutboundManager](ctx),
+		endpoint:    service.FromContext[adapter.EndpointManager](ctx),
+		tag:         tag,
+		tags:        tags,
+		useAll:      options.UseAll,
+		includeType: options.IncludeType,
+	}
+	for i, expression := range options.Include {
+		include, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, E.Cause(err, "parse include[", i, "]")
+		}
+		filter.include = append(filter.include, include)
+	}
+	for i, expression := range options.Exclude {
+		exclude, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, E.Cause(err, "parse exclude[", i, "]")
+		}
+		filter.exclude = append(filter.exclude, exclude)
+	}
+	if len(filter.tags) == 0 && !filter.dynamic() {
+		return nil, E.New("missing outbounds")
+	}
+	return filter, nil
+}
+
+func (f *memberFilter) dynamic() bool {
+	return f.useAll || len(f.include) > 0 || len(f.includeType) > 0
+}
+
+// load returns the current members. Listed outbounds that do not exist are
+// an error if strict, and skipped otherwise, as after a runtime removal.
+func (f *memberFilter) load(strict bool) (*groupMembers, error) {
+	members := &groupMembers{}
+	for i, tag := range f.tags {
+		detour, loaded := f.outbound.Outbound(tag)
+		if !loaded {
+			if strict {
+				return nil, E.New("outbound ", i, " not found: ", tag)
+			}
+			continue
+		}
+		members.add(detour)
+	}
+	if !f.dynamic() {
+		return members, nil
+	}
+	candidates := common.Map(f.outbound.Outbounds(), func(it adapter.Outbound) adapter.Outbound { return it })
+	if f.endpoint != nil {
+		candidates = append(candidates, common.Map(f.endpoint.Endpoints(), func(it adapter.Endpoint) adapter.Outbound { return it })...)
+	}
+	for _, detour := range candidates {
+		if f.match(detour) && !common.Contains(members.tags, detour.Tag()) {
+			members.add(detour)
+		}
+	}
+	return members, nil
+}
+
+// watch calls update whenever an outbound or endpoint is created or removed,
+// if the members depend on them.
+func (f *memberFilter) watch(update adapter.OutboundUpdateCallback) {
+	if !f.dynamic() {
+		return
+	}
+	f.outboundCallback = f.outbound.RegisterCallback(update)
+	if f.endpoint != nil {
+		f.endpointCallback = f.endpoint.RegisterCallback(update)
+	}
+}
+
+func (f *memberFilter) unwatch() {
+	if f.outboundCallback != nil {
+		f.outbound.UnregisterCallback(f.outboundCallback)
+		f.outboundCallback = nil
+	}
+	if f.endpointCallback != nil {
+		f.endpoint.UnregisterCallback(f.endpointCallback)
+		f.endpointCallback = nil
+	}
+}
+
+func (f *memberFilter) match(detour adapter.Outbound) bool {
+	if detour.Tag() == f.tag {
+		return false
+	}
+	if _, isGroup := detour.(adapter.OutboundGroup); isGroup {
+		return false
+	}
+	if len(f.includeType) > 0 && !common.Contains(f.includeType, detour.Type()) {
+		return false
+	}
+	if len(f.include) > 0 && !common.Any(f.include, func(it *regexp.Regexp) bool {
+		return it.MatchString(detour.Tag())
+	}) {
+		return false
+	}
+	return !common.Any(f.exclude, func(it *regexp.Regexp) bool {
+		return it.MatchString(detour.Tag())
+	})
+}
+
+func (m *groupMembers) add(detour adapter.Outbound) {
+	m.tags = append(m.tags, detour.Tag())
+	m.outbounds = append(m.outbounds, detour)
+}
+
+func (m *groupMembers) outbound(tag string) (adapter.Outbound, bool) {
+	for i, memberTag := range m.tags {
+		if memberTag == tag {
+			return m.outbounds[i], true
+		}
+	}
+	return nil, false
+}
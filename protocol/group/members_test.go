@@ -0,0 +1,143 @@
+package group
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/endpoint"
+	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembersFollowEndpoints(t *testing.T) {
+	t.Parallel()
+	ctx, managers := newTestManagers(t)
+	require.NoError(t, managers.createOutbound(ctx, "proxy-a"))
+	selector, err := NewSelector(ctx, nil, log.NewNOPFactory().NewLogger("selector"), "selector", option.SelectorOutboundOptions{
+		GroupFilterOptions: option.GroupFilterOptions{Include: []string{"^proxy-"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, selector.(*Selector).Start())
+	defer selector.(*Selector).Close()
+	require.Equal(t, []string{"proxy-a"}, selector.(*Selector).All())
+
+	require.NoError(t, managers.createEndpoint(ctx, "proxy-wg"))
+	require.Equal(t, []string{"proxy-a", "proxy-wg"}, selector.(*Selector).All())
+	require.NoError(t, managers.endpoint.Remove("proxy-wg"))
+	require.Equal(t, []string{"proxy-a"}, selector.(*Selector).All())
+
+	// callbacks are gone after close
+	selector.(*Selector).Close()
+	require.NoError(t, managers.createEndpoint(ctx, "proxy-wg"))
+	require.Equal(t, []string{"proxy-a"}, selector.(*Selector).All())
+}
+
+func TestMemberFilter(t *testing.T) {
+	t.Parallel()
+	ctx, managers := newTestManagers(t)
+	for _, tag := range []string{"hk-1", "hk-2", "us-1", "hk-direct"} {
+		require.NoError(t, managers.createOutbound(ctx, tag))
+	}
+	filter, err := newMemberFilter(ctx, "group", []string{"us-1"}, option.GroupFilterOptions{
+		Include: []string{"^hk-"},
+		Exclude: []string{"direct"},
+	})
+	require.NoError(t, err)
+	members, err := filter.load(true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"us-1", "hk-1", "hk-2"}, members.tags)
+
+	filter, err = newMemberFilter(ctx, "group", []string{"missing"}, option.GroupFilterOptions{})
+	require.NoError(t, err)
+	_, err = filter.load(true)
+	require.Error(t, err)
+	members, err = filter.load(false)
+	require.NoError(t, err)
+	require.Empty(t, members.tags)
+
+	_, err = newMemberFilter(ctx, "group", nil, option.GroupFilterOptions{})
+	require.ErrorContains(t, err, "missing outbounds")
+}
+
+type testManagers struct {
+	outbound *outbound.Manager
+	endpoint *endpoint.Manager
+}
+
+func newTestManagers(t *testing.T) (context.Context, *testManagers) {
+	logger := log.NewNOPFactory().NewLogger("manager")
+	endpointManager := endpoint.NewManager(logger, &testEndpointRegistry{})
+	outboundManager := outbound.NewManager(logger, &testOutboundRegistry{}, endpointManager, "")
+	ctx := service.ContextWith[adapter.OutboundManager](context.Background(), outboundManager)
+	ctx = service.ContextWith[adapter.EndpointManager](ctx, endpointManager)
+	return ctx, &testManagers{outboundManager, endpointManager}
+}
+
+func (m *testManagers) createOutbound(ctx context.Context, tag string) error {
+	return m.outbound.Create(ctx, nil, log.NewNOPFactory().NewLogger("outbound"), tag, "test", nil)
+}
+
+func (m *testManagers) createEndpoint(ctx context.Context, tag string) error {
+	return m.endpoint.Create(ctx, nil, log.NewNOPFactory().NewLogger("endpoint"), tag, "test", nil)
+}
+
+type testOutboundRegistry struct {
+	adapter.OutboundRegistry
+}
+
+func (r *testOutboundRegistry) CreateOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, outboundType string, options any) (adapter.Outbound, error) {
+	return newTestOutbound(tag), nil
+}
+
+type testEndpointRegistry struct {
+	adapter.EndpointRegistry
+}
+
+func (r *testEndpointRegistry) Create(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, endpointType string, options any) (adapter.Endpoint, error) {
+	return &testEndpoint{newTestOutbound(tag)}, nil
+}
+
+// testOutbound dials a pipe, or fails while failed is set.
+type testOutbound struct {
+	outbound.Adapter
+	failed atomic.Bool
+}
+
+func newTestOutbound(tag string) *testOutbound {
+	return &testOutbound{Adapter: outbound.NewAdapter("test", tag, []string{N.NetworkTCP, N.NetworkUDP}, nil)}
+}
+
+func (o *testOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if o.failed.Load() {
+		return nil, E.New("outbound ", o.Tag(), " failed")
+	}
+	conn, peer := net.Pipe()
+	peer.Close()
+	return conn, nil
+}
+
+func (o *testOutbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, E.New("not implemented")
+}
+
+type testEndpoint struct {
+	*testOutbound
+}
+
+func (e *testEndpoint) Start(stage adapter.StartStage) error {
+	return nil
+}
+
+func (e *testEndpoint) Close() error {
+	return nil
+}
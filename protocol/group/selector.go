@@ -34,30 +34,30 @@ type Selector struct {
 	outbound                     adapter.OutboundManager
 	connection                   adapter.ConnectionManager
 	logger                       logger.ContextLogger
-	tags                         []string
+	filter                       *memberFilter
 	defaultTag                   string
-	outbounds                    map[string]adapter.Outbound
+	members                      common.TypedValue[*groupMembers]
 	selected                     common.TypedValue[adapter.Outbound]
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
 }
 
 func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SelectorOutboundOptions) (adapter.Outbound, error) {
+	filter, err := newMemberFilter(ctx, tag, options.Outbounds, options.GroupFilterOptions)
+	if err != nil {
+		return nil, err
+	}
 	outbound := &Selector{
 		Adapter:                      outbound.NewAdapter(C.TypeSelector, tag, nil, options.Outbounds),
 		ctx:                          ctx,
 		outbound:                     service.FromContext[adapter.OutboundManager](ctx),
 		connection:                   service.FromContext[adapter.ConnectionManager](ctx),
 		logger:                       logger,
-		tags:                         options.Outbounds,
+		filter:                       filter,
 		defaultTag:                   options.Default,
-		outbounds:                    make(map[string]adapter.Outbound),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
-	if len(outbound.tags) == 0 {
-		return nil, E.New("missing tags")
-	}
 	return outbound, nil
 }
 
@@ -70,20 +70,19 @@ func (s *Selector) Network() []string {
 }
 
 func (s *Selector) Start() error {
-	for i, tag := range s.tags {
-		detour, loaded := s.outbound.Outbound(tag)
-		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
-		}
-		s.outbounds[tag] = detour
+	members, err := s.filter.load(true)
+	if err != nil {
+		return err
 	}
+	s.members.Store(members)
+	s.filter.watch(s.updateMembers)
 
 	if s.Tag() != "" {
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
 			selected := cacheFile.LoadSelected(s.Tag())
 			if selected != "" {
-				detour, loaded := s.outbounds[selected]
+				detour, loaded := members.outbound(selected)
 				if loaded {
 					s.selected.Store(detour)
 					return nil
@@ -93,7 +92,7 @@ func (s *Selector) Start() error {
 	}
 
 	if s.defaultTag != "" {
-		detour, loaded := s.outbounds[s.defaultTag]
+		detour, loaded := members.outbound(s.defaultTag)
 		if !loaded {
 			return E.New("default outbound not found: ", s.defaultTag)
 		}
@@ -101,24 +100,70 @@ func (s *Selector) Start() error {
 		return nil
 	}
 
-	s.selected.Store(s.outbounds[s.tags[0]])
+	if len(members.outbounds) > 0 {
+		s.selected.Store(members.outbounds[0])
+	}
+	return nil
+}
+
+func (s *Selector) Close() error {
+	s.filter.unwatch()
 	return nil
 }
 
+// updateMembers keeps the selected outbound if it is still a member, and
+// falls back to the default or first member otherwise.
+func (s *Selector) updateMembers() {
+	members, _ := s.filter.load(false)
+	s.members.Store(members)
+	selected := s.selected.Load()
+	if selected != nil {
+		detour, loaded := members.outbound(selected.Tag())
+		if loaded && detour == selected {
+			return
+		}
+	}
+	var detour adapter.Outbound
+	if defaultOutbound, loaded := members.outbound(s.defaultTag); loaded {
+		detour = defaultOutbound
+	} else if len(members.outbounds) > 0 {
+		detour = members.outbounds[0]
+	}
+	if s.selected.Swap(detour) == detour {
+		return
+	}
+	if detour != nil {
+		s.logger.Info("selected outbound removed, switched to ", detour.Tag())
+	}
+	s.interruptGroup.Interrupt(s.interruptExternalConnections)
+}
+
 func (s *Selector) Now() string {
 	selected := s.selected.Load()
 	if selected == nil {
-		return s.tags[0]
+		members := s.members.Load()
+		if members == nil || len(members.tags) == 0 {
+			return ""
+		}
+		return members.tags[0]
 	}
 	return selected.Tag()
 }
 
 func (s *Selector) All() []string {
-	return s.tags
+	members := s.members.Load()
+	if members == nil {
+		return nil
+	}
+	return members.tags
 }
 
 func (s *Selector) SelectOutbound(tag string) bool {
-	detour, loaded := s.outbounds[tag]
+	members := s.members.Load()
+	if members == nil {
+		return false
+	}
+	detour, loaded := members.outbound(tag)
 	if !loaded {
 		return false
 	}
@@ -139,7 +184,11 @@ func (s *Selector) SelectOutbound(tag string) bool {
 }
 
 func (s *Selector) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	conn, err := s.selected.Load().DialContext(ctx, network, destination)
+	selected := s.selected.Load()
+	if selected == nil {
+		return nil, E.New("missing selected outbound")
+	}
+	conn, err := selected.DialContext(ctx, network, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +196,11 @@ func (s *Selector) DialContext(ctx context.Context, network string, destination
 }
 
 func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	conn, err := s.selected.Load().ListenPacket(ctx, destination)
+	selected := s.selected.Load()
+	if selected == nil {
+		return nil, E.New("missing selected outbound")
+	}
+	conn, err := selected.ListenPacket(ctx, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +210,10 @@ func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 func (s *Selector) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
 	selected := s.selected.Load()
+	if selected == nil {
+		N.CloseOnHandshakeFailure(conn, onClose, E.New("missing selected outbound"))
+		return
+	}
 	if outboundHandler, isHandler := selected.(adapter.ConnectionHandlerEx); isHandler {
 		outboundHandler.NewConnectionEx(ctx, conn, metadata, onClose)
 	} else {
@@ -167,6 +224,10 @@ func (s *Selector) NewConnectionEx(ctx context.Context, conn net.Conn, metadata
 func (s *Selector) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
 	selected := s.selected.Load()
+	if selected == nil {
+		N.CloseOnHandshakeFailure(conn, onClose, E.New("missing selected outbound"))
+		return
+	}
 	if outboundHandler, isHandler := selected.(adapter.PacketConnectionHandlerEx); isHandler {
 		outboundHandler.NewPacketConnectionEx(ctx, conn, metadata, onClose)
 	} else {
@@ -24,6 +24,15 @@ import (
 	"github.com/sagernet/sing/service/pause"
 )
 
+const (
+	urlTestStrategyLatency   = "latency"
+	urlTestStrategyBandwidth = "bandwidth"
+
+	// a node must be this much faster, in percent, to replace the selected
+	// one by bandwidth
+	bandwidthTolerance = 10
+)
+
 func RegisterURLTest(registry *outbound.Registry) {
 	outbound.Register[option.URLTestOutboundOptions](registry, C.TypeURLTest, NewURLTest)
 }
@@ -37,8 +46,11 @@ type URLTest struct {
 	outbound                     adapter.OutboundManager
 	connection                   adapter.ConnectionManager
 	logger                       log.ContextLogger
-	tags                         []string
-	link                         string
+	filter                       *memberFilter
+	members                      common.TypedValue[*groupMembers]
+	testOptions                  urltest.Options
+	udpLink                      string
+	strategy                     string
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
@@ -47,6 +59,31 @@ type URLTest struct {
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (adapter.Outbound, error) {
+	filter, err := newMemberFilter(ctx, tag, options.Outbounds, options.GroupFilterOptions)
+	if err != nil {
+		return nil, err
+	}
+	_, err = urltest.ParseURL(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "parse url")
+	}
+	if options.UDPURL != "" {
+		_, err = urltest.ParseURL(options.UDPURL)
+		if err != nil {
+			return nil, E.Cause(err, "parse udp_url")
+		}
+	}
+	switch options.Strategy {
+	case "", urlTestStrategyLatency, urlTestStrategyBandwidth:
+	default:
+		return nil, E.New("unknown strategy: ", options.Strategy)
+	}
+	testOptions := urltest.Options{
+		URL:            options.URL,
+		ExpectedStatus: options.ExpectedStatus,
+		ExpectedBody:   options.ExpectedBody,
+		Samples:        options.Samples,
+	}
 	outbound := &URLTest{
 		Adapter:                      outbound.NewAdapter(C.TypeURLTest, tag, []string{N.NetworkTCP, N.NetworkUDP}, options.Outbounds),
 		ctx:                          ctx,
@@ -54,58 +91,67 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 		outbound:                     service.FromContext[adapter.OutboundManager](ctx),
 		connection:                   service.FromContext[adapter.ConnectionManager](ctx),
 		logger:                       logger,
-		tags:                         options.Outbounds,
-		link:                         options.URL,
+		filter:                       filter,
+		testOptions:                  testOptions,
+		udpLink:                      options.UDPURL,
+		strategy:                     options.Strategy,
 		interval:                     time.Duration(options.Interval),
 		tolerance:                    options.Tolerance,
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
-	if len(outbound.tags) == 0 {
-		return nil, E.New("missing tags")
-	}
 	return outbound, nil
 }
 
 func (s *URLTest) Start() error {
-	outbounds := make([]adapter.Outbound, 0, len(s.tags))
-	for i, tag := range s.tags {
-		detour, loaded := s.outbound.Outbound(tag)
-		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
-		}
-		outbounds = append(outbounds, detour)
+	members, err := s.filter.load(true)
+	if err != nil {
+		return err
 	}
-	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, members.outbounds, s.testOptions, s.udpLink, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections)
 	if err != nil {
 		return err
 	}
+	group.strategy = s.strategy
+	s.members.Store(members)
 	s.group = group
+	s.filter.watch(s.updateMembers)
 	return nil
 }
 
+func (s *URLTest) updateMembers() {
+	members, _ := s.filter.load(false)
+	s.members.Store(members)
+	s.group.SetOutbounds(members.outbounds)
+}
+
 func (s *URLTest) PostStart() error {
 	s.group.PostStart()
 	return nil
 }
 
 func (s *URLTest) Close() error {
+	s.filter.unwatch()
 	return common.Close(
 		common.PtrOrNil(s.group),
 	)
 }
 
 func (s *URLTest) Now() string {
-	if s.group.selectedOutboundTCP != nil {
-		return s.group.selectedOutboundTCP.Tag()
-	} else if s.group.selectedOutboundUDP != nil {
-		return s.group.selectedOutboundUDP.Tag()
+	if selected := s.group.selectedOutboundTCP.Load(); selected != nil {
+		return selected.Tag()
+	} else if selected = s.group.selectedOutboundUDP.Load(); selected != nil {
+		return selected.Tag()
 	}
 	return ""
 }
 
 func (s *URLTest) All() []string {
-	return s.tags
+	members := s.members.Load()
+	if members == nil {
+		return nil
+	}
+	return members.tags
 }
 
 func (s *URLTest) URLTest(ctx context.Context) (map[string]uint16, error) {
@@ -121,9 +167,9 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 	var outbound adapter.Outbound
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
-		outbound = s.group.selectedOutboundTCP
+		outbound = s.group.selectedOutboundTCP.Load()
 	case N.NetworkUDP:
-		outbound = s.group.selectedOutboundUDP
+		outbound = s.group.selectedOutboundUDP.Load()
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
@@ -138,13 +184,13 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 		return s.group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	urltest.DeleteHistory(s.group.history, outbound.Tag())
 	return nil, err
 }
 
 func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	s.group.Touch()
-	outbound := s.group.selectedOutboundUDP
+	outbound := s.group.selectedOutboundUDP.Load()
 	if outbound == nil {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
@@ -156,7 +202,7 @@ func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (ne
 		return s.group.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	urltest.DeleteHistory(s.group.history, outbound.Tag())
 	return nil, err
 }
 
@@ -177,15 +223,18 @@ type URLTestGroup struct {
 	pause                        pause.Manager
 	pauseCallback                *list.Element[pause.Callback]
 	logger                       log.Logger
-	outbounds                    []adapter.Outbound
-	link                         string
+	outbounds                    common.TypedValue[[]adapter.Outbound]
+	testOptions                  urltest.Options
+	udpLink                      string
+	strategy                     string
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
 	checking                     atomic.Bool
-	selectedOutboundTCP          adapter.Outbound
-	selectedOutboundUDP          adapter.Outbound
+	selectedOutboundTCP          common.TypedValue[adapter.Outbound]
+	selectedOutboundUDP          common.TypedValue[adapter.Outbound]
+	selectAccess                 sync.Mutex
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
 	access                       sync.Mutex
@@ -195,7 +244,7 @@ type URLTestGroup struct {
 	lastActive                   common.TypedValue[time.Time]
 }
 
-func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*URLTestGroup, error) {
+func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, testOptions urltest.Options, udpLink string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -216,12 +265,12 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 	} else {
 		history = urltest.NewHistoryStorage()
 	}
-	return &URLTestGroup{
+	group := &URLTestGroup{
 		ctx:                          ctx,
 		outbound:                     outboundManager,
 		logger:                       logger,
-		outbounds:                    outbounds,
-		link:                         link,
+		testOptions:                  testOptions,
+		udpLink:                      udpLink,
 		interval:                     interval,
 		tolerance:                    tolerance,
 		idleTimeout:                  idleTimeout,
@@ -230,7 +279,34 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 		pause:                        service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
-	}, nil
+	}
+	group.outbounds.Store(outbounds)
+	return group, nil
+}
+
+// SetOutbounds replaces the outbounds of the group, and selects again if a
+// selected outbound was removed.
+func (g *URLTestGroup) SetOutbounds(outbounds []adapter.Outbound) {
+	g.selectAccess.Lock()
+	g.outbounds.Store(outbounds)
+	var removed bool
+	for _, selected := range []*common.TypedValue[adapter.Outbound]{&g.selectedOutboundTCP, &g.selectedOutboundUDP} {
+		if outbound := selected.Load(); outbound != nil && !common.Contains(outbounds, outbound) {
+			selected.Store(nil)
+			removed = true
+		}
+	}
+	g.selectAccess.Unlock()
+	if removed {
+		g.interruptGroup.Interrupt(g.interruptExternalConnections)
+	}
+	g.performUpdateCheck()
+	g.access.Lock()
+	started := g.started
+	g.access.Unlock()
+	if started {
+		go g.CheckOutbounds(false)
+	}
 }
 
 func (g *URLTestGroup) PostStart() {
@@ -242,11 +318,11 @@ func (g *URLTestGroup) PostStart() {
 }
 
 func (g *URLTestGroup) Touch() {
+	g.access.Lock()
+	defer g.access.Unlock()
 	if !g.started {
 		return
 	}
-	g.access.Lock()
-	defer g.access.Unlock()
 	if g.ticker != nil {
 		g.lastActive.Store(time.Now())
 		return
@@ -269,39 +345,35 @@ func (g *URLTestGroup) Close() error {
 }
 
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
+	if g.strategy == urlTestStrategyBandwidth {
+		if outbound := g.selectBandwidth(network); outbound != nil {
+			return outbound, true
+		}
+	}
 	var minDelay uint16
 	var minOutbound adapter.Outbound
-	switch network {
-	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil {
-				minOutbound = g.selectedOutboundTCP
-				minDelay = history.Delay
-			}
-		}
-	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil {
-				minOutbound = g.selectedOutboundUDP
-				minDelay = history.Delay
-			}
+	outbounds := g.outbounds.Load()
+	if selected := g.selected(network); selected != nil && common.Contains(outbounds, selected) {
+		if delay := g.delay(RealTag(selected), network); delay > 0 {
+			minOutbound = selected
+			minDelay = delay
 		}
 	}
-	for _, detour := range g.outbounds {
+	for _, detour := range outbounds {
 		if !common.Contains(detour.Network(), network) {
 			continue
 		}
-		history := g.history.LoadURLTestHistory(RealTag(detour))
-		if history == nil {
+		delay := g.delay(RealTag(detour), network)
+		if delay == 0 {
 			continue
 		}
-		if minDelay == 0 || minDelay > history.Delay+g.tolerance {
-			minDelay = history.Delay
+		if minDelay == 0 || minDelay > delay+g.tolerance {
+			minDelay = delay
 			minOutbound = detour
 		}
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
+		for _, detour := range outbounds {
 			if !common.Contains(detour.Network(), network) {
 				continue
 			}
@@ -312,6 +384,69 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 	return minOutbound, true
 }
 
+// selectBandwidth returns the available outbound with the highest download
+// speed, or nil if no available outbound has a speed test result.
+func (g *URLTestGroup) selectBandwidth(network string) adapter.Outbound {
+	var (
+		maxDownload uint64
+		maxOutbound adapter.Outbound
+	)
+	for _, detour := range g.outbounds.Load() {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		download := g.download(RealTag(detour), network)
+		if download > maxDownload {
+			maxDownload = download
+			maxOutbound = detour
+		}
+	}
+	if maxOutbound == nil {
+		return nil
+	}
+	if selected := g.selected(network); selected != nil && common.Contains(g.outbounds.Load(), selected) {
+		if download := g.download(RealTag(selected), network); download*(100+bandwidthTolerance)/100 >= maxDownload {
+			return selected
+		}
+	}
+	return maxOutbound
+}
+
+func (g *URLTestGroup) selected(network string) adapter.Outbound {
+	switch network {
+	case N.NetworkTCP:
+		return g.selectedOutboundTCP.Load()
+	case N.NetworkUDP:
+		return g.selectedOutboundUDP.Load()
+	default:
+		return nil
+	}
+}
+
+func (g *URLTestGroup) download(tag string, network string) uint64 {
+	history := g.history.LoadURLTestHistory(tag)
+	if g.historyDelay(history, network) == 0 {
+		return 0
+	}
+	return history.Download
+}
+
+// delay returns the tested delay of the outbound for network, or zero if it
+// is unavailable. UDP uses the result of the UDP test if there is one.
+func (g *URLTestGroup) delay(tag string, network string) uint16 {
+	return g.historyDelay(g.history.LoadURLTestHistory(tag), network)
+}
+
+func (g *URLTestGroup) historyDelay(history *adapter.URLTestHistory, network string) uint16 {
+	if history == nil {
+		return 0
+	}
+	if network == N.NetworkUDP && g.udpLink != "" {
+		return history.UDPDelay
+	}
+	return history.Delay
+}
+
 func (g *URLTestGroup) loopCheck() {
 	if time.Since(g.lastActive.Load()) > g.interval {
 		g.lastActive.Store(time.Now())
@@ -353,7 +488,7 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 	b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
 	checked := make(map[string]bool)
 	var resultAccess sync.Mutex
-	for _, detour := range g.outbounds {
+	for _, detour := range g.outbounds.Load() {
 		tag := detour.Tag()
 		realTag := RealTag(detour)
 		if checked[realTag] {
@@ -369,21 +504,16 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 			continue
 		}
 		b.Go(realTag, func() (any, error) {
-			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
-			defer cancel()
-			t, err := urltest.URLTest(testCtx, g.link, p)
+			history, err := g.test(tag, p)
 			if err != nil {
-				g.logger.Debug("outbound ", tag, " unavailable: ", err)
-				g.history.DeleteURLTestHistory(realTag)
+				urltest.DeleteHistory(g.history, realTag)
 			} else {
-				g.logger.Debug("outbound ", tag, " available: ", t, "ms")
-				g.history.StoreURLTestHistory(realTag, &adapter.URLTestHistory{
-					Time:  time.Now(),
-					Delay: t,
-				})
-				resultAccess.Lock()
-				result[tag] = t
-				resultAccess.Unlock()
+				urltest.StoreHistory(g.history, realTag, history)
+				if history.Delay > 0 {
+					resultAccess.Lock()
+					result[tag] = history.Delay
+					resultAccess.Unlock()
+				}
 			}
 			return nil, nil
 		})
@@ -393,20 +523,64 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 	return result, nil
 }
 
+// test runs the TCP test, and the UDP test if configured. It fails only if
+// every test fails.
+func (g *URLTestGroup) test(tag string, detour adapter.Outbound) (*adapter.URLTestHistory, error) {
+	history := &adapter.URLTestHistory{Time: time.Now()}
+	testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
+	defer cancel()
+	result, err := urltest.Test(testCtx, g.testOptions, detour)
+	if err != nil {
+		g.logger.Debug("outbound ", tag, " unavailable: ", err)
+		history.Loss = 100
+	} else {
+		g.logger.Debug("outbound ", tag, " available: ", result.Delay, "ms")
+		history.Delay = result.Delay
+		history.Jitter = result.Jitter
+		history.Loss = result.Loss
+	}
+	if g.udpLink == "" || !common.Contains(detour.Network(), N.NetworkUDP) {
+		return history, err
+	}
+	udpOptions := urltest.Options{
+		URL:     g.udpLink,
+		Samples: g.testOptions.Samples,
+	}
+	udpCtx, udpCancel := context.WithTimeout(g.ctx, C.TCPTimeout)
+	defer udpCancel()
+	udpResult, udpErr := urltest.Test(udpCtx, udpOptions, detour)
+	if udpErr != nil {
+		g.logger.Debug("outbound ", tag, " unavailable for UDP: ", udpErr)
+		history.UDPLoss = 100
+		return history, err
+	}
+	g.logger.Debug("outbound ", tag, " available for UDP: ", udpResult.Delay, "ms")
+	history.UDPDelay = udpResult.Delay
+	history.UDPJitter = udpResult.Jitter
+	history.UDPLoss = udpResult.Loss
+	return history, nil
+}
+
 func (g *URLTestGroup) performUpdateCheck() {
+	g.selectAccess.Lock()
 	var updated bool
-	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil && (g.selectedOutboundTCP == nil || (exists && outbound != g.selectedOutboundTCP)) {
-		if g.selectedOutboundTCP != nil {
-			updated = true
+	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil {
+		if selected := g.selectedOutboundTCP.Load(); selected == nil || (exists && outbound != selected) {
+			if selected != nil {
+				updated = true
+			}
+			g.selectedOutboundTCP.Store(outbound)
 		}
-		g.selectedOutboundTCP = outbound
 	}
-	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil && (g.selectedOutboundUDP == nil || (exists && outbound != g.selectedOutboundUDP)) {
-		if g.selectedOutboundUDP != nil {
-			updated = true
+	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil {
+		if selected := g.selectedOutboundUDP.Load(); selected == nil || (exists && outbound != selected) {
+			if selected != nil {
+				updated = true
+			}
+			g.selectedOutboundUDP.Store(outbound)
 		}
-		g.selectedOutboundUDP = outbound
 	}
+	g.selectAccess.Unlock()
 	if updated {
 		g.interruptGroup.Interrupt(g.interruptExternalConnections)
 	}
@@ -0,0 +1,63 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/log"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLTestGroupSetOutbounds(t *testing.T) {
+	t.Parallel()
+	outboundA, outboundB, outboundC := newTestOutbound("a"), newTestOutbound("b"), newTestOutbound("c")
+	group, err := NewURLTestGroup(context.Background(), nil, log.NewNOPFactory().NewLogger("urltest"), []adapter.Outbound{outboundA, outboundB}, urltest.Options{}, "", 0, 0, 0, false)
+	require.NoError(t, err)
+	for tag, delay := range map[string]uint16{"a": 100, "b": 200, "c": 50} {
+		urltest.StoreHistory(group.history, tag, &adapter.URLTestHistory{Time: time.Now(), Delay: delay})
+	}
+	group.performUpdateCheck()
+	require.Equal(t, outboundA, group.selectedOutboundTCP.Load())
+
+	// removing the selected outbound selects again
+	group.SetOutbounds([]adapter.Outbound{outboundB})
+	require.Equal(t, outboundB, group.selectedOutboundTCP.Load())
+	require.Equal(t, outboundB, group.selectedOutboundUDP.Load())
+	group.SetOutbounds([]adapter.Outbound{outboundB, outboundC})
+	require.Equal(t, outboundC, group.selectedOutboundTCP.Load())
+
+	// membership updates race with connections
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				if (i+j)%2 == 0 {
+					group.SetOutbounds([]adapter.Outbound{outboundA, outboundB})
+				} else {
+					group.SetOutbounds([]adapter.Outbound{outboundB, outboundC})
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if selected, _ := group.Select(N.NetworkTCP); selected == nil {
+					t.Error("no outbound selected")
+					return
+				}
+				group.performUpdateCheck()
+			}
+		}()
+	}
+	wg.Wait()
+	group.SetOutbounds([]adapter.Outbound{outboundA, outboundB})
+	require.Equal(t, outboundA, group.selectedOutboundTCP.Load())
+}
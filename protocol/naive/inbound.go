@@ -23,6 +23,8 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	sHttp "github.com/sagernet/sing/protocol/http"
+
+	"golang.org/x/net/http2"
 )
 
 var ConfigureHTTP3ListenerFunc func(listener *listener.Listener, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error)
@@ -88,6 +90,9 @@ func (n *Inbound) Start(stage adapter.StartStage) error {
 		if err != nil {
 			return E.Cause(err, "create TLS config")
 		}
+		if common.Contains(n.network, N.NetworkTCP) && len(n.tlsConfig.NextProtos()) == 0 {
+			n.tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+		}
 		tlsConfig, err = n.tlsConfig.Config()
 		if err != nil {
 			return err
@@ -0,0 +1,247 @@
+package naive
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/http2"
+)
+
+var ConfigureHTTP3ClientFunc func(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) (RoundTripper, error)
+
+type RoundTripper interface {
+	http.RoundTripper
+	io.Closer
+}
+
+func RegisterOutbound(registry *outbound.Registry) {
+	outbound.Register[option.NaiveOutboundOptions](registry, C.TypeNaive, NewOutbound)
+}
+
+var _ adapter.InterfaceUpdateListener = (*Outbound)(nil)
+
+type Outbound struct {
+	outbound.Adapter
+	ctx           context.Context
+	logger        logger.ContextLogger
+	serverAddr    M.Socksaddr
+	authorization string
+	headers       http.Header
+	transport     RoundTripper
+}
+
+func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.NaiveOutboundOptions) (adapter.Outbound, error) {
+	if options.TLS == nil || !options.TLS.Enabled {
+		return nil, E.New("TLS is required for naive client")
+	}
+	outboundDialer, err := dialer.NewServer(ctx, options.DialerOptions, options.ServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	outbound := &Outbound{
+		Adapter:    outbound.NewAdapterWithDialerOptions(C.TypeNaive, tag, []string{N.NetworkTCP}, options.DialerOptions),
+		ctx:        ctx,
+		logger:     logger,
+		serverAddr: options.ServerOptions.Build(),
+		headers:    options.ExtraHeaders.Build(),
+	}
+	if outbound.serverAddr.Port == 0 {
+		outbound.serverAddr.Port = 443
+	}
+	if options.Username != "" {
+		outbound.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(options.Username+":"+options.Password))
+	}
+	tlsOptions := common.PtrValueOrDefault(options.TLS)
+	if options.QUIC {
+		if ConfigureHTTP3ClientFunc == nil {
+			return nil, C.ErrQUICNotIncluded
+		}
+		tlsConfig, err := tls.NewClient(ctx, options.Server, tlsOptions)
+		if err != nil {
+			return nil, err
+		}
+		outbound.transport, err = ConfigureHTTP3ClientFunc(ctx, outboundDialer, outbound.serverAddr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if tlsOptions.Reality == nil && tlsOptions.UTLS == nil {
+			tlsOptions.UTLS = defaultUTLSOptions()
+		}
+		tlsConfig, err := tls.NewClient(ctx, options.Server, tlsOptions)
+		if err != nil {
+			return nil, err
+		}
+		if len(tlsConfig.NextProtos()) == 0 {
+			tlsConfig.SetNextProtos([]string{http2.NextProtoTLS})
+		}
+		outbound.transport = newHTTP2Client(outboundDialer, outbound.serverAddr, tlsConfig)
+	}
+	return outbound, nil
+}
+
+func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkTCP {
+		return nil, os.ErrInvalid
+	}
+	h.logger.InfoContext(ctx, "outbound connection to ", destination)
+	pipeReader, pipeWriter := io.Pipe()
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL: &url.URL{
+			Host: destination.String(),
+		},
+		Host:   destination.String(),
+		Header: h.headers.Clone(),
+		Body:   pipeReader,
+	}
+	if h.authorization != "" {
+		request.Header.Set("Proxy-Authorization", h.authorization)
+	}
+	request.Header.Set("Padding", generateNaivePaddingHeader())
+	// the request lives as long as the tunnel, so it must not inherit the dial timeout
+	request = request.WithContext(h.ctx)
+	type roundTripResult struct {
+		response *http.Response
+		err      error
+	}
+	done := make(chan roundTripResult, 1)
+	go func() {
+		response, err := h.transport.RoundTrip(request)
+		done <- roundTripResult{response, err}
+	}()
+	var result roundTripResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		pipeWriter.CloseWithError(ctx.Err())
+		go func() {
+			result := <-done
+			if result.response != nil {
+				result.response.Body.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		pipeWriter.Close()
+		return nil, result.err
+	}
+	if result.response.StatusCode != http.StatusOK {
+		pipeWriter.Close()
+		result.response.Body.Close()
+		return nil, E.New("naive: unexpected status: ", result.response.Status)
+	}
+	conn := &naiveH2Conn{
+		reader:  result.response.Body,
+		writer:  pipeWriter,
+		flusher: nopFlusher{},
+		rAddr:   h.serverAddr,
+	}
+	if result.response.Header.Get("Padding") == "" {
+		conn.readPadding = kFirstPaddings
+		conn.writePadding = kFirstPaddings
+	}
+	return conn, nil
+}
+
+func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (h *Outbound) InterfaceUpdated() {
+	h.transport.Close()
+}
+
+func (h *Outbound) Close() error {
+	return h.transport.Close()
+}
+
+type nopFlusher struct{}
+
+func (nopFlusher) Flush() {}
+
+type http2Client struct {
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	tlsConfig  tls.Config
+	transport  *http2.Transport
+	connAccess sync.Mutex
+	conn       *http2.ClientConn
+}
+
+func newHTTP2Client(dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) *http2Client {
+	return &http2Client{
+		dialer:     dialer,
+		serverAddr: serverAddr,
+		tlsConfig:  tlsConfig,
+		transport: &http2.Transport{
+			ReadIdleTimeout: C.TCPKeepAliveInterval,
+			PingTimeout:     C.TCPTimeout,
+		},
+	}
+}
+
+// offer returns a connection shared by all streams, since CONNECT requests
+// would otherwise be pooled by their destination authority.
+func (c *http2Client) offer(ctx context.Context) (*http2.ClientConn, error) {
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	if c.conn != nil && c.conn.CanTakeNewRequest() {
+		return c.conn, nil
+	}
+	conn, err := c.dialer.DialContext(ctx, N.NetworkTCP, c.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := tls.ClientHandshake(ctx, conn, c.tlsConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	clientConn, err := c.transport.NewClientConn(tlsConn)
+	if err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	c.conn = clientConn
+	return clientConn, nil
+}
+
+func (c *http2Client) RoundTrip(request *http.Request) (*http.Response, error) {
+	clientConn, err := c.offer(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	return clientConn.RoundTrip(request)
+}
+
+func (c *http2Client) Close() error {
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build with_utls
+
+package naive
+
+import "github.com/sagernet/sing-box/option"
+
+// naive clients are expected to look like Chrome on the wire.
+func defaultUTLSOptions() *option.OutboundUTLSOptions {
+	return &option.OutboundUTLSOptions{
+		Enabled:     true,
+		Fingerprint: "chrome",
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !with_utls
+
+package naive
+
+import "github.com/sagernet/sing-box/option"
+
+func defaultUTLSOptions() *option.OutboundUTLSOptions {
+	return nil
+}
@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-quic"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type http3Client struct {
+	ctx        context.Context
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	tlsConfig  tls.Config
+	transport  *http3.Transport
+	connAccess sync.Mutex
+	quicConn   quic.EarlyConnection
+	clientConn *http3.ClientConn
+}
+
+func (c *http3Client) offer() (*http3.ClientConn, error) {
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	if c.quicConn != nil && !common.Done(c.quicConn.Context()) {
+		return c.clientConn, nil
+	}
+	udpConn, err := c.dialer.DialContext(c.ctx, N.NetworkUDP, c.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	quicConn, err := qtls.DialEarly(c.ctx, bufio.NewUnbindPacketConn(udpConn), udpConn.RemoteAddr(), c.tlsConfig, &quic.Config{
+		KeepAlivePeriod: C.TCPKeepAliveInterval,
+	})
+	if err != nil {
+		udpConn.Close()
+		return nil, err
+	}
+	c.quicConn = quicConn
+	c.clientConn = c.transport.NewClientConn(quicConn)
+	return c.clientConn, nil
+}
+
+func (c *http3Client) RoundTrip(request *http.Request) (*http.Response, error) {
+	clientConn, err := c.offer()
+	if err != nil {
+		return nil, err
+	}
+	return clientConn.RoundTrip(request)
+}
+
+func (c *http3Client) Close() error {
+	c.connAccess.Lock()
+	defer c.connAccess.Unlock()
+	if c.quicConn == nil {
+		return nil
+	}
+	err := c.quicConn.CloseWithError(0, "")
+	c.quicConn = nil
+	c.clientConn = nil
+	return err
+}
@@ -0,0 +1,26 @@
+package quic
+
+import (
+	"context"
+
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/protocol/naive"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func init() {
+	naive.ConfigureHTTP3ClientFunc = func(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) (naive.RoundTripper, error) {
+		if len(tlsConfig.NextProtos()) == 0 {
+			tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
+		}
+		return &http3Client{
+			ctx:        ctx,
+			dialer:     dialer,
+			serverAddr: serverAddr,
+			tlsConfig:  tlsConfig,
+			transport:  &http3.Transport{},
+		}, nil
+	}
+}
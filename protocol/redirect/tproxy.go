@@ -16,6 +16,7 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/udpnat2"
@@ -32,6 +33,7 @@ type TProxy struct {
 	logger   log.ContextLogger
 	listener *listener.Listener
 	udpNat   *udpnat.Service
+	firewall *tproxyFirewall
 }
 
 func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TProxyInboundOptions) (adapter.Inbound, error) {
@@ -57,6 +59,16 @@ func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLog
 		OOBPacketHandler:  tproxy,
 		TProxy:            true,
 	})
+	if options.AutoFirewall != nil && options.AutoFirewall.Enabled {
+		if options.ListenPort == 0 {
+			return nil, E.New("auto_firewall requires listen_port")
+		}
+		firewall, err := newTProxyFirewall(ctx, router, logger, options.ListenPort, *options.AutoFirewall)
+		if err != nil {
+			return nil, E.Cause(err, "auto_firewall")
+		}
+		tproxy.firewall = firewall
+	}
 	return tproxy, nil
 }
 
@@ -64,11 +76,24 @@ func (t *TProxy) Start(stage adapter.StartStage) error {
 	if stage != adapter.StartStateStart {
 		return nil
 	}
-	return t.listener.Start()
+	err := t.listener.Start()
+	if err != nil {
+		return err
+	}
+	if t.firewall != nil {
+		err = t.firewall.Start()
+		if err != nil {
+			return E.Cause(err, "auto_firewall")
+		}
+	}
+	return nil
 }
 
 func (t *TProxy) Close() error {
-	return t.listener.Close()
+	return common.Close(
+		common.PtrOrNil(t.firewall),
+		t.listener,
+	)
 }
 
 func (t *TProxy) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
@@ -0,0 +1,200 @@
+//go:build linux
+
+package redirect
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+
+	"go4.org/netipx"
+)
+
+const (
+	firewallBackendNFTables = "nftables"
+	firewallBackendIPTables = "iptables"
+
+	defaultTProxyMark       = 0x2d0
+	defaultTProxyRouteTable = 0x2d0
+	defaultTProxyBypassMark = 2158
+)
+
+// tproxyFirewall installs the packet marking and policy routing required to
+// divert traffic into a tproxy inbound.
+type tproxyFirewall struct {
+	logger           log.ContextLogger
+	backend          string
+	interfaceName    string
+	tproxyPort       uint16
+	dnsPort          uint16
+	mark             uint32
+	routeTable       uint32
+	bypassMark       uint32
+	bypassAddress    []netip.Prefix
+	ruleSets         []adapter.RuleSet
+	ruleSetCallbacks []*list.Element[adapter.RuleSetUpdateCallback]
+	access           sync.Mutex
+	iptables         *TProxyIPTables
+	started          bool
+	nftablesEnabled  bool
+	routes           []*netlink.Route
+}
+
+func newTProxyFirewall(ctx context.Context, router adapter.Router, logger log.ContextLogger, tproxyPort uint16, options option.TProxyAutoFirewallOptions) (*tproxyFirewall, error) {
+	firewall := &tproxyFirewall{
+		logger:        logger,
+		backend:       options.Backend,
+		interfaceName: options.Interface,
+		tproxyPort:    tproxyPort,
+		dnsPort:       options.DNSRedirectPort,
+		mark:          uint32(options.Mark),
+		routeTable:    options.RouteTable,
+		bypassMark:    uint32(options.BypassMark),
+		bypassAddress: options.BypassAddress,
+	}
+	switch firewall.backend {
+	case "", firewallBackendNFTables, firewallBackendIPTables:
+	default:
+		return nil, E.New("unknown auto_firewall backend: ", firewall.backend)
+	}
+	if firewall.mark == 0 {
+		firewall.mark = defaultTProxyMark
+	}
+	if firewall.routeTable == 0 {
+		firewall.routeTable = defaultTProxyRouteTable
+	}
+	// outgoing connections are only exempted from the output chain when
+	// they carry bypass_mark, which is what route.default_mark sets
+	var defaultMark uint32
+	networkManager := service.FromContext[adapter.NetworkManager](ctx)
+	if networkManager != nil {
+		defaultMark = networkManager.DefaultOptions().RoutingMark
+	}
+	if firewall.bypassMark == 0 {
+		if defaultMark != 0 {
+			firewall.bypassMark = defaultMark
+		} else {
+			firewall.bypassMark = defaultTProxyBypassMark
+		}
+	}
+	if firewall.mark == firewall.bypassMark {
+		return nil, E.New("mark and bypass_mark must be different")
+	}
+	if defaultMark != firewall.bypassMark {
+		return nil, E.New("route.default_mark must be set to bypass_mark (", firewall.bypassMark, ")")
+	}
+	for _, tag := range options.BypassAddressSet {
+		ruleSet, loaded := router.RuleSet(tag)
+		if !loaded {
+			return nil, E.New("parse bypass_address_set: rule-set not found: ", tag)
+		}
+		firewall.ruleSets = append(firewall.ruleSets, ruleSet)
+	}
+	return firewall, nil
+}
+
+func (f *tproxyFirewall) Start() error {
+	for _, ruleSet := range f.ruleSets {
+		if len(ruleSet.ExtractIPSet()) == 0 {
+			f.logger.Warn("bypass_address_set: no destination IP CIDR rules found in rule-set: ", ruleSet.Name())
+		}
+		ruleSet.IncRef()
+	}
+	f.access.Lock()
+	defer f.access.Unlock()
+	f.started = true
+	backend := f.backend
+	if backend == "" {
+		if nftablesAvailable() {
+			backend = firewallBackendNFTables
+		} else {
+			f.logger.Warn("nftables unavailable, falling back to iptables without IPv6 support")
+			backend = firewallBackendIPTables
+		}
+	}
+	switch backend {
+	case firewallBackendNFTables:
+		err := f.setupNFTables()
+		if err != nil {
+			f.cleanupNFTables()
+			return E.Cause(err, "setup nftables")
+		}
+		for _, ruleSet := range f.ruleSets {
+			f.ruleSetCallbacks = append(f.ruleSetCallbacks, ruleSet.RegisterCallback(f.updateBypassAddressSet))
+		}
+	case firewallBackendIPTables:
+		f.iptables = NewTProxyIPTables(f.logger)
+		if !f.iptables.Available() {
+			return E.New("iptables command not found")
+		}
+		f.iptables.SetMark(f.mark, f.routeTable)
+		bypass := common.Map(common.Filter(f.bypassAddress, func(it netip.Prefix) bool {
+			return it.Addr().Is4()
+		}), netip.Prefix.String)
+		for _, ipSet := range f.bypassAddressSet() {
+			for _, prefix := range ipSet.Prefixes() {
+				if prefix.Addr().Is4() {
+					bypass = append(bypass, prefix.String())
+				}
+			}
+		}
+		interfaceName := f.interfaceName
+		if interfaceName == "" {
+			interfaceName = "lo"
+		}
+		err := f.iptables.Setup(interfaceName, bypass, f.tproxyPort, f.dnsPort > 0, f.dnsPort, f.bypassMark)
+		if err != nil {
+			return E.Cause(err, "setup iptables")
+		}
+	}
+	f.logger.Info("auto firewall configured with ", backend)
+	return nil
+}
+
+func (f *tproxyFirewall) bypassAddressSet() []*netipx.IPSet {
+	return common.FlatMap(f.ruleSets, adapter.RuleSet.ExtractIPSet)
+}
+
+func (f *tproxyFirewall) updateBypassAddressSet(it adapter.RuleSet) {
+	f.access.Lock()
+	defer f.access.Unlock()
+	if !f.nftablesEnabled {
+		return
+	}
+	err := f.updateNFTables()
+	if err != nil {
+		f.logger.Error(E.Cause(err, "update bypass_address_set"))
+	} else {
+		f.logger.Info("updated bypass_address_set")
+	}
+}
+
+func (f *tproxyFirewall) Close() error {
+	f.access.Lock()
+	defer f.access.Unlock()
+	for index, element := range f.ruleSetCallbacks {
+		f.ruleSets[index].UnregisterCallback(element)
+	}
+	f.ruleSetCallbacks = nil
+	if f.started {
+		for _, ruleSet := range f.ruleSets {
+			ruleSet.DecRef()
+		}
+		f.started = false
+	}
+	f.cleanupNFTables()
+	if f.iptables != nil {
+		f.iptables.Cleanup()
+		f.iptables = nil
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package redirect
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/nftables"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirewallBypassMark(t *testing.T) {
+	t.Parallel()
+	logger := log.NewNOPFactory().NewLogger("tproxy")
+
+	// bypass_mark follows route.default_mark when unset
+	firewall, err := newTProxyFirewall(testNetworkContext(1234), nil, logger, 7890, option.TProxyAutoFirewallOptions{})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1234), firewall.bypassMark)
+
+	firewall, err = newTProxyFirewall(testNetworkContext(defaultTProxyBypassMark), nil, logger, 7890, option.TProxyAutoFirewallOptions{
+		BypassMark: defaultTProxyBypassMark,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(defaultTProxyBypassMark), firewall.bypassMark)
+
+	// outgoing connections would loop back into the tproxy
+	_, err = newTProxyFirewall(testNetworkContext(0), nil, logger, 7890, option.TProxyAutoFirewallOptions{})
+	require.ErrorContains(t, err, "route.default_mark")
+	_, err = newTProxyFirewall(testNetworkContext(1234), nil, logger, 7890, option.TProxyAutoFirewallOptions{
+		BypassMark: 4321,
+	})
+	require.ErrorContains(t, err, "route.default_mark")
+
+	_, err = newTProxyFirewall(testNetworkContext(defaultTProxyMark), nil, logger, 7890, option.TProxyAutoFirewallOptions{})
+	require.ErrorContains(t, err, "must be different")
+}
+
+func TestNFTablesSetElements(t *testing.T) {
+	t.Parallel()
+	elements, err := nftablesBuildSetElements(nftables.TableFamilyIPv4, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("255.255.255.255/32"),
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []nftables.SetElement{
+		{Key: []byte{10, 0, 0, 0}},
+		{Key: []byte{11, 0, 0, 0}, IntervalEnd: true},
+		// the last address has no end element
+		{Key: []byte{255, 255, 255, 255}},
+	}, elements)
+
+	elements = make([]nftables.SetElement, nftablesSetChunkSize*2+1)
+	for i := range elements {
+		elements[i].IntervalEnd = i%2 == 1
+	}
+	// shift the pairs so a chunk boundary would fall before an end element
+	elements = append([]nftables.SetElement{{}}, elements...)
+	chunks := nftablesChunkSetElements(elements)
+	var total int
+	for _, chunk := range chunks {
+		require.LessOrEqual(t, len(chunk), nftablesSetChunkSize)
+		require.False(t, chunk[0].IntervalEnd)
+		total += len(chunk)
+	}
+	require.Equal(t, len(elements), total)
+}
+
+func testNetworkContext(defaultMark uint32) context.Context {
+	return service.ContextWith[adapter.NetworkManager](context.Background(), &testNetworkManager{
+		options: adapter.NetworkOptions{RoutingMark: defaultMark},
+	})
+}
+
+type testNetworkManager struct {
+	adapter.NetworkManager
+	options adapter.NetworkOptions
+}
+
+func (m *testNetworkManager) DefaultOptions() adapter.NetworkOptions {
+	return m.options
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package redirect
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type tproxyFirewall struct{}
+
+func newTProxyFirewall(ctx context.Context, router adapter.Router, logger log.ContextLogger, tproxyPort uint16, options option.TProxyAutoFirewallOptions) (*tproxyFirewall, error) {
+	return nil, E.New("auto_firewall is only supported on Linux")
+}
+
+func (f *tproxyFirewall) Start() error {
+	return nil
+}
+
+func (f *tproxyFirewall) Close() error {
+	return nil
+}
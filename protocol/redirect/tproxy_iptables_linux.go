@@ -25,15 +25,35 @@ type TProxyIPTables struct {
 	bypass        []string
 	dnsRedirect   bool
 	routingMark   uint32
+	fwmark        string
+	routeTable    string
 	enabled       bool
 }
 
 func NewTProxyIPTables(logger log.ContextLogger) *TProxyIPTables {
 	return &TProxyIPTables{
-		logger: logger,
+		logger:     logger,
+		fwmark:     PROXY_FWMARK,
+		routeTable: PROXY_ROUTE_TABLE,
 	}
 }
 
+// SetMark overrides the default tproxy mark and policy routing table.
+func (t *TProxyIPTables) SetMark(mark uint32, routeTable uint32) {
+	if mark != 0 {
+		t.fwmark = fmt.Sprintf("%#x", mark)
+	}
+	if routeTable != 0 {
+		t.routeTable = fmt.Sprintf("%d", routeTable)
+	}
+}
+
+// Available reports whether the iptables command can be used.
+func (t *TProxyIPTables) Available() bool {
+	_, err := exec.LookPath("iptables")
+	return err == nil
+}
+
 func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort uint16, dnsRedirect bool, dnsPort uint16, routingMark uint32) error {
 	if runtime.GOOS != "linux" {
 		return fmt.Errorf("auto_iptables only supports Linux")
@@ -57,9 +77,19 @@ func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort
 		t.routingMark = 2158
 	}
 
+	// remove chains left over by a previous run that did not exit cleanly
+	if _, err := t.execCmdWithOutput("iptables -t mangle -L sing_box_divert"); err == nil {
+		t.logger.Warn("[IPTABLES] removing stale tproxy chains")
+		t.enabled = true
+		t.Cleanup()
+		t.interfaceName = interfaceName
+		t.tproxyPort = tproxyPort
+		t.dnsPort = dnsPort
+	}
+
 	// add route
-	t.execCmd(fmt.Sprintf("ip -f inet rule add fwmark %s lookup %s", PROXY_FWMARK, PROXY_ROUTE_TABLE))
-	t.execCmd(fmt.Sprintf("ip -f inet route add local default dev %s table %s", interfaceName, PROXY_ROUTE_TABLE))
+	t.execCmd(fmt.Sprintf("ip -f inet rule add fwmark %s lookup %s", t.fwmark, t.routeTable))
+	t.execCmd(fmt.Sprintf("ip -f inet route add local default dev %s table %s", interfaceName, t.routeTable))
 
 	// set FORWARD
 	if interfaceName != "lo" {
@@ -73,7 +103,7 @@ func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort
 	// set sing-box divert
 	t.execCmd("iptables -t mangle -N sing_box_divert")
 	t.execCmd("iptables -t mangle -F sing_box_divert")
-	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_divert -j MARK --set-mark %s", PROXY_FWMARK))
+	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_divert -j MARK --set-mark %s", t.fwmark))
 	t.execCmd("iptables -t mangle -A sing_box_divert -j ACCEPT")
 
 	// set pre routing
@@ -88,8 +118,8 @@ func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort
 	t.addLocalnetworkToChain("sing_box_prerouting")
 	t.execCmd("iptables -t mangle -A sing_box_prerouting -p tcp -m socket -j sing_box_divert")
 	t.execCmd("iptables -t mangle -A sing_box_prerouting -p udp -m socket -j sing_box_divert")
-	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_prerouting -p tcp -j TPROXY --on-port %d --tproxy-mark %s/%s", tproxyPort, PROXY_FWMARK, PROXY_FWMARK))
-	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_prerouting -p udp -j TPROXY --on-port %d --tproxy-mark %s/%s", tproxyPort, PROXY_FWMARK, PROXY_FWMARK))
+	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_prerouting -p tcp -j TPROXY --on-port %d --tproxy-mark %s/%s", tproxyPort, t.fwmark, t.fwmark))
+	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_prerouting -p udp -j TPROXY --on-port %d --tproxy-mark %s/%s", tproxyPort, t.fwmark, t.fwmark))
 	t.execCmd("iptables -t mangle -A PREROUTING -j sing_box_prerouting")
 
 	if t.dnsRedirect && t.dnsPort > 0 {
@@ -113,8 +143,8 @@ func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort
 	t.execCmd("iptables -t mangle -A sing_box_output -m addrtype --dst-type LOCAL -j RETURN")
 	t.execCmd("iptables -t mangle -A sing_box_output -m addrtype --dst-type BROADCAST -j RETURN")
 	t.addLocalnetworkToChain("sing_box_output")
-	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_output -p tcp -j MARK --set-mark %s", PROXY_FWMARK))
-	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_output -p udp -j MARK --set-mark %s", PROXY_FWMARK))
+	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_output -p tcp -j MARK --set-mark %s", t.fwmark))
+	t.execCmd(fmt.Sprintf("iptables -t mangle -A sing_box_output -p udp -j MARK --set-mark %s", t.fwmark))
 	t.execCmd(fmt.Sprintf("iptables -t mangle -I OUTPUT -o %s -j sing_box_output", interfaceName))
 
 	// set dns output
@@ -147,8 +177,8 @@ func (t *TProxyIPTables) Cleanup() {
 	}
 
 	// clean route
-	t.execCmd(fmt.Sprintf("ip -f inet rule del fwmark %s lookup %s", PROXY_FWMARK, PROXY_ROUTE_TABLE))
-	t.execCmd(fmt.Sprintf("ip -f inet route del local default dev %s table %s", t.interfaceName, PROXY_ROUTE_TABLE))
+	t.execCmd(fmt.Sprintf("ip -f inet rule del fwmark %s lookup %s", t.fwmark, t.routeTable))
+	t.execCmd(fmt.Sprintf("ip -f inet route del local default dev %s table %s", t.interfaceName, t.routeTable))
 
 	// clean FORWARD
 	if t.interfaceName != "lo" {
@@ -17,3 +17,9 @@ func (t *TProxyIPTables) Setup(interfaceName string, bypass []string, tproxyPort
 }
 
 func (t *TProxyIPTables) Cleanup() {}
+
+func (t *TProxyIPTables) SetMark(mark uint32, routeTable uint32) {}
+
+func (t *TProxyIPTables) Available() bool {
+	return false
+}
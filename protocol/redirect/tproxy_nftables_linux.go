@@ -0,0 +1,527 @@
+//go:build linux
+
+package redirect
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/nftables"
+	"github.com/sagernet/nftables/binaryutil"
+	"github.com/sagernet/nftables/expr"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"go4.org/netipx"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	nftablesTableName = "sing-box-tproxy"
+	nftablesSetIDIPv4 = 1
+	nftablesSetIDIPv6 = 2
+	nftablesSetIPv4   = "inet4_bypass_address_set"
+	nftablesSetIPv6   = "inet6_bypass_address_set"
+
+	// set elements are split across messages to stay below the netlink
+	// attribute size limit, all within the same transaction
+	nftablesSetChunkSize = 1000
+)
+
+var privateAddresses = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+func nftablesAvailable() bool {
+	nft, err := nftables.New()
+	if err != nil {
+		return false
+	}
+	defer nft.CloseLasting()
+	_, err = nft.ListTablesOfFamily(nftables.TableFamilyIPv4)
+	return err == nil
+}
+
+func (f *tproxyFirewall) setupNFTables() error {
+	nft, err := nftables.New()
+	if err != nil {
+		return err
+	}
+	defer nft.CloseLasting()
+
+	table, err := nft.ListTableOfFamily(nftablesTableName, nftables.TableFamilyINet)
+	if err == nil && table != nil {
+		f.logger.Warn("removing stale nftables table ", nftablesTableName)
+		nft.DelTable(table)
+		err = nft.Flush()
+		if err != nil {
+			return E.Cause(err, "remove stale table")
+		}
+	}
+
+	table = nft.AddTable(&nftables.Table{
+		Name:   nftablesTableName,
+		Family: nftables.TableFamilyINet,
+	})
+	err = f.nftablesCreateBypassSet(nft, table, nftablesSetIDIPv4, nftablesSetIPv4, nftables.TableFamilyIPv4, false)
+	if err != nil {
+		return err
+	}
+	err = f.nftablesCreateBypassSet(nft, table, nftablesSetIDIPv6, nftablesSetIPv6, nftables.TableFamilyIPv6, false)
+	if err != nil {
+		return err
+	}
+
+	chainPreRouting := nft.AddChain(&nftables.Chain{
+		Name:     "prerouting",
+		Table:    table,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityMangle,
+		Type:     nftables.ChainTypeFilter,
+	})
+	err = f.nftablesCreateExcludeRules(nft, table, chainPreRouting)
+	if err != nil {
+		return err
+	}
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chainPreRouting,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.IPPROTO_TCP}},
+			&expr.Socket{Key: expr.SocketKeyTransparent, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{1}},
+			&expr.Immediate{Register: 1, Data: binaryutil.NativeEndian.PutUint32(f.mark)},
+			&expr.Meta{Key: expr.MetaKeyMARK, Register: 1, SourceRegister: true},
+			&expr.Counter{},
+			&expr.Verdict{Kind: expr.VerdictAccept},
+		},
+	})
+	for _, family := range []nftables.TableFamily{nftables.TableFamilyIPv4, nftables.TableFamilyIPv6} {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chainPreRouting,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{byte(family)}},
+				&expr.Immediate{Register: 1, Data: binaryutil.BigEndian.PutUint16(f.tproxyPort)},
+				&expr.TProxy{Family: byte(family), TableFamily: byte(nftables.TableFamilyINet), RegPort: 1},
+				&expr.Immediate{Register: 1, Data: binaryutil.NativeEndian.PutUint32(f.mark)},
+				&expr.Meta{Key: expr.MetaKeyMARK, Register: 1, SourceRegister: true},
+				&expr.Counter{},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+	}
+
+	chainOutput := nft.AddChain(&nftables.Chain{
+		Name:     "output",
+		Table:    table,
+		Hooknum:  nftables.ChainHookOutput,
+		Priority: nftables.ChainPriorityMangle,
+		Type:     nftables.ChainTypeRoute,
+	})
+	err = f.nftablesCreateExcludeRules(nft, table, chainOutput)
+	if err != nil {
+		return err
+	}
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chainOutput,
+		Exprs: []expr.Any{
+			&expr.Immediate{Register: 1, Data: binaryutil.NativeEndian.PutUint32(f.mark)},
+			&expr.Meta{Key: expr.MetaKeyMARK, Register: 1, SourceRegister: true},
+			&expr.Counter{},
+		},
+	})
+
+	if f.dnsPort > 0 {
+		chainDNSPreRouting := nft.AddChain(&nftables.Chain{
+			Name:     "dns_prerouting",
+			Table:    table,
+			Hooknum:  nftables.ChainHookPrerouting,
+			Priority: nftables.ChainPriorityNATDest,
+			Type:     nftables.ChainTypeNAT,
+		})
+		err = f.nftablesCreateDNSRedirect(nft, table, chainDNSPreRouting)
+		if err != nil {
+			return err
+		}
+		chainDNSOutput := nft.AddChain(&nftables.Chain{
+			Name:     "dns_output",
+			Table:    table,
+			Hooknum:  nftables.ChainHookOutput,
+			Priority: nftables.ChainPriorityNATDest,
+			Type:     nftables.ChainTypeNAT,
+		})
+		err = f.nftablesCreateDNSRedirect(nft, table, chainDNSOutput)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the table, chains, rules and bypass sets are committed in one transaction
+	err = nft.Flush()
+	if err != nil {
+		return err
+	}
+	f.nftablesEnabled = true
+	return f.setupRoute()
+}
+
+func (f *tproxyFirewall) updateNFTables() error {
+	nft, err := nftables.New()
+	if err != nil {
+		return err
+	}
+	defer nft.CloseLasting()
+	table, err := nft.ListTableOfFamily(nftablesTableName, nftables.TableFamilyINet)
+	if err != nil {
+		return err
+	}
+	err = f.nftablesCreateBypassSet(nft, table, nftablesSetIDIPv4, nftablesSetIPv4, nftables.TableFamilyIPv4, true)
+	if err != nil {
+		return err
+	}
+	err = f.nftablesCreateBypassSet(nft, table, nftablesSetIDIPv6, nftablesSetIPv6, nftables.TableFamilyIPv6, true)
+	if err != nil {
+		return err
+	}
+	// flushing and refilling the sets is atomic, so no packet sees them empty
+	return nft.Flush()
+}
+
+func (f *tproxyFirewall) cleanupNFTables() {
+	if !f.nftablesEnabled {
+		return
+	}
+	f.nftablesEnabled = false
+	f.cleanupRoute()
+	nft, err := nftables.New()
+	if err != nil {
+		return
+	}
+	defer nft.CloseLasting()
+	nft.DelTable(&nftables.Table{
+		Name:   nftablesTableName,
+		Family: nftables.TableFamilyINet,
+	})
+	_ = nft.Flush()
+}
+
+// nftablesCreateBypassSet queues the set with the static bypass ranges and
+// the rule-set ranges.
+func (f *tproxyFirewall) nftablesCreateBypassSet(
+	nft *nftables.Conn, table *nftables.Table,
+	id uint32, name string, family nftables.TableFamily, update bool,
+) error {
+	elements, err := nftablesBuildSetElements(family, append(privateAddresses, f.bypassAddress...), f.bypassAddressSet())
+	if err != nil {
+		return err
+	}
+	var keyType nftables.SetDatatype
+	if family == nftables.TableFamilyIPv4 {
+		keyType = nftables.TypeIPAddr
+	} else {
+		keyType = nftables.TypeIP6Addr
+	}
+	set := &nftables.Set{
+		Table:    table,
+		ID:       id,
+		Name:     name,
+		Interval: true,
+		KeyType:  keyType,
+	}
+	if update {
+		nft.FlushSet(set)
+	} else {
+		err = nft.AddSet(set, nil)
+		if err != nil {
+			return err
+		}
+	}
+	for _, chunk := range nftablesChunkSetElements(elements) {
+		err = nft.SetAddElements(set, chunk)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nftablesChunkSetElements splits the elements into chunks of at most
+// nftablesSetChunkSize, never separating an interval start from its end.
+func nftablesChunkSetElements(elements []nftables.SetElement) [][]nftables.SetElement {
+	var chunks [][]nftables.SetElement
+	for len(elements) > 0 {
+		size := min(len(elements), nftablesSetChunkSize)
+		if size < len(elements) && elements[size].IntervalEnd {
+			size--
+		}
+		chunks = append(chunks, elements[:size])
+		elements = elements[size:]
+	}
+	return chunks
+}
+
+func nftablesBuildSetElements(family nftables.TableFamily, prefixList []netip.Prefix, setList []*netipx.IPSet) ([]nftables.SetElement, error) {
+	var builder netipx.IPSetBuilder
+	for _, prefix := range prefixList {
+		builder.AddPrefix(prefix)
+	}
+	for _, set := range setList {
+		builder.AddSet(set)
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	var elements []nftables.SetElement
+	for _, ipRange := range ipSet.Ranges() {
+		if (family == nftables.TableFamilyIPv4) != ipRange.From().Is4() {
+			continue
+		}
+		elements = append(elements, nftables.SetElement{
+			Key: ipRange.From().AsSlice(),
+		})
+		endAddr := ipRange.To().Next()
+		if endAddr.IsValid() {
+			elements = append(elements, nftables.SetElement{
+				Key:         endAddr.AsSlice(),
+				IntervalEnd: true,
+			})
+		}
+	}
+	return elements, nil
+}
+
+// nftablesCreateExcludeRules returns early for traffic that should not be
+// proxied: sing-box's own connections, non TCP/UDP packets, DNS handled by
+// the redirect chains, local destinations and bypassed addresses.
+func (f *tproxyFirewall) nftablesCreateExcludeRules(nft *nftables.Conn, table *nftables.Table, chain *nftables.Chain) error {
+	if chain.Hooknum == nftables.ChainHookOutput {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyMARK, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(f.bypassMark)},
+				&expr.Verdict{Kind: expr.VerdictReturn},
+			},
+		})
+	} else if f.interfaceName != "" {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: nftablesIfname(f.interfaceName)},
+				&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: nftablesIfname("lo")},
+				&expr.Verdict{Kind: expr.VerdictReturn},
+			},
+		})
+	}
+	ipProto, err := nftablesCreateIPProtoSet(nft, table)
+	if err != nil {
+		return err
+	}
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Lookup{SourceRegister: 1, SetID: ipProto.ID, SetName: ipProto.Name, Invert: true},
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		},
+	})
+	if f.dnsPort > 0 {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: []expr.Any{
+				&expr.Payload{OperationType: expr.PayloadLoad, DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(53)},
+				&expr.Verdict{Kind: expr.VerdictReturn},
+			},
+		})
+	}
+	for _, addressType := range []uint32{unix.RTN_LOCAL, unix.RTN_BROADCAST} {
+		nft.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: []expr.Any{
+				&expr.Fib{Register: 1, FlagDADDR: true, ResultADDRTYPE: true},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(addressType)},
+				&expr.Verdict{Kind: expr.VerdictReturn},
+			},
+		})
+	}
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: append(
+			nftablesDestinationIPSetExprs(nftablesSetIDIPv4, nftablesSetIPv4, nftables.TableFamilyIPv4),
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		),
+	})
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: append(
+			nftablesDestinationIPSetExprs(nftablesSetIDIPv6, nftablesSetIPv6, nftables.TableFamilyIPv6),
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		),
+	})
+	return nil
+}
+
+func (f *tproxyFirewall) nftablesCreateDNSRedirect(nft *nftables.Conn, table *nftables.Table, chain *nftables.Chain) error {
+	var exprs []expr.Any
+	if chain.Hooknum == nftables.ChainHookOutput {
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyMARK, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(f.bypassMark)},
+			// requests to loopback resolvers can not be redirected
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: nftablesIfname("lo")},
+		)
+	} else if f.interfaceName != "" {
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: nftablesIfname(f.interfaceName)},
+		)
+	}
+	ipProto, err := nftablesCreateIPProtoSet(nft, table)
+	if err != nil {
+		return err
+	}
+	nft.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: append(exprs,
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Lookup{SourceRegister: 1, SetID: ipProto.ID, SetName: ipProto.Name},
+			&expr.Payload{OperationType: expr.PayloadLoad, DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(53)},
+			&expr.Counter{},
+			&expr.Immediate{Register: 1, Data: binaryutil.BigEndian.PutUint16(f.dnsPort)},
+			&expr.Redir{RegisterProtoMin: 1, Flags: unix.NF_NAT_RANGE_PROTO_SPECIFIED},
+		),
+	})
+	return nil
+}
+
+func nftablesCreateIPProtoSet(nft *nftables.Conn, table *nftables.Table) (*nftables.Set, error) {
+	ipProto := &nftables.Set{
+		Table:     table,
+		Anonymous: true,
+		Constant:  true,
+		KeyType:   nftables.TypeInetProto,
+	}
+	err := nft.AddSet(ipProto, []nftables.SetElement{
+		{Key: []byte{unix.IPPROTO_TCP}},
+		{Key: []byte{unix.IPPROTO_UDP}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ipProto, nil
+}
+
+func nftablesDestinationIPSetExprs(id uint32, name string, family nftables.TableFamily) []expr.Any {
+	exprs := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{byte(family)}},
+	}
+	if family == nftables.TableFamilyIPv4 {
+		exprs = append(exprs, &expr.Payload{OperationType: expr.PayloadLoad, DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 16, Len: 4})
+	} else {
+		exprs = append(exprs, &expr.Payload{OperationType: expr.PayloadLoad, DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 24, Len: 16})
+	}
+	return append(exprs, &expr.Lookup{SourceRegister: 1, SetID: id, SetName: name})
+}
+
+func nftablesIfname(name string) []byte {
+	b := make([]byte, 16)
+	copy(b, name+"\x00")
+	return b
+}
+
+// setupRoute sends marked packets to the local stack, where the tproxy
+// listener picks them up.
+func (f *tproxyFirewall) setupRoute() error {
+	loopback, err := netlink.LinkByName("lo")
+	if err != nil {
+		return E.Cause(err, "find loopback interface")
+	}
+	f.cleanupRoute()
+	for _, family := range []int{unix.AF_INET, unix.AF_INET6} {
+		err = netlink.RuleAdd(f.routeRule(family))
+		if err != nil {
+			return E.Cause(err, "add ip rule")
+		}
+		route := &netlink.Route{
+			LinkIndex: loopback.Attrs().Index,
+			Dst:       defaultRoute(family),
+			Table:     int(f.routeTable),
+			Type:      unix.RTN_LOCAL,
+			Scope:     netlink.SCOPE_HOST,
+			Family:    family,
+		}
+		err = netlink.RouteReplace(route)
+		if err != nil {
+			return E.Cause(err, "add local route")
+		}
+		f.routes = append(f.routes, route)
+	}
+	return nil
+}
+
+func (f *tproxyFirewall) cleanupRoute() {
+	for _, family := range []int{unix.AF_INET, unix.AF_INET6} {
+		// also removes rules left over by a previous run
+		for netlink.RuleDel(f.routeRule(family)) == nil {
+		}
+	}
+	for _, route := range f.routes {
+		_ = netlink.RouteDel(route)
+	}
+	f.routes = nil
+}
+
+func (f *tproxyFirewall) routeRule(family int) *netlink.Rule {
+	rule := netlink.NewRule()
+	rule.Family = family
+	rule.Mark = f.mark
+	rule.MarkSet = true
+	rule.Table = int(f.routeTable)
+	return rule
+}
+
+func defaultRoute(family int) *net.IPNet {
+	if family == unix.AF_INET {
+		return &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	}
+	return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+}
@@ -0,0 +1,191 @@
+package reverse
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-mux"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	bridgeRetryInitial = time.Second
+	bridgeRetryMax     = time.Minute
+)
+
+func RegisterBridge(registry *inbound.Registry) {
+	inbound.Register[option.BridgeInboundOptions](registry, C.TypeBridge, NewBridge)
+}
+
+var _ adapter.Inbound = (*Bridge)(nil)
+
+// Bridge keeps tunnels open to a portal and routes the connections the
+// portal sends back through them as if they were accepted locally.
+type Bridge struct {
+	inbound.Adapter
+	ctx         context.Context
+	cancel      context.CancelFunc
+	router      adapter.ConnectionRouterEx
+	logger      logger.ContextLogger
+	dialer      N.Dialer
+	destination M.Socksaddr
+	token       string
+	connections int
+	service     *mux.Service
+	done        sync.WaitGroup
+}
+
+func NewBridge(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.BridgeInboundOptions) (adapter.Inbound, error) {
+	if options.Domain == "" {
+		return nil, E.New("missing domain")
+	}
+	if options.Token == "" {
+		return nil, E.New("missing token")
+	}
+	if options.Detour == "" {
+		return nil, E.New("missing detour")
+	}
+	outboundDialer, err := dialer.New(ctx, options.DialerOptions, true)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	bridge := &Bridge{
+		Adapter:     inbound.NewAdapter(C.TypeBridge, tag),
+		ctx:         ctx,
+		cancel:      cancel,
+		router:      router,
+		logger:      logger,
+		dialer:      outboundDialer,
+		destination: M.Socksaddr{Fqdn: options.Domain},
+		token:       options.Token,
+		connections: options.Connections,
+	}
+	if bridge.connections <= 0 {
+		bridge.connections = 1
+	}
+	bridge.service, err = mux.NewService(mux.ServiceOptions{
+		NewStreamContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return log.ContextWithNewID(ctx)
+		},
+		Logger:    logger,
+		HandlerEx: (*bridgeHandler)(bridge),
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return bridge, nil
+}
+
+func (b *Bridge) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStatePostStart {
+		return nil
+	}
+	for i := 0; i < b.connections; i++ {
+		b.done.Add(1)
+		go b.loopTunnel()
+	}
+	return nil
+}
+
+func (b *Bridge) Close() error {
+	b.cancel()
+	b.done.Wait()
+	return nil
+}
+
+func (b *Bridge) loopTunnel() {
+	defer b.done.Done()
+	retryDelay := bridgeRetryInitial
+	for {
+		established, err := b.serveTunnel()
+		if b.ctx.Err() != nil {
+			return
+		}
+		if established {
+			retryDelay = bridgeRetryInitial
+		}
+		b.logger.Error(E.Cause(err, "tunnel to ", b.destination), ", retry in ", retryDelay)
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		if !established {
+			retryDelay *= 2
+			if retryDelay > bridgeRetryMax {
+				retryDelay = bridgeRetryMax
+			}
+		}
+	}
+}
+
+func (b *Bridge) serveTunnel() (bool, error) {
+	ctx := log.ContextWithNewID(b.ctx)
+	dialCtx, cancel := context.WithTimeout(ctx, C.TCPTimeout)
+	conn, err := b.dialer.DialContext(dialCtx, N.NetworkTCP, b.destination)
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	stopClose := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stopClose()
+	conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	err = writeRequest(conn, b.token)
+	if err == nil {
+		err = readResponse(conn)
+	}
+	if err != nil {
+		conn.Close()
+		return false, E.Cause(err, "handshake")
+	}
+	conn.SetDeadline(time.Time{})
+	b.logger.InfoContext(ctx, "tunnel established to ", b.destination)
+	metadata := adapter.InboundContext{
+		Inbound:     b.Tag(),
+		InboundType: C.TypeBridge,
+	}
+	// blocks until the multiplex session ends, errors are logged by the service
+	b.service.NewConnectionEx(adapter.WithContext(ctx, &metadata), conn, M.Socksaddr{}, M.Socksaddr{}, nil)
+	conn.Close()
+	return true, E.New("tunnel closed")
+}
+
+type bridgeHandler Bridge
+
+func (h *bridgeHandler) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	if destination == pingDestination {
+		// write the stream response so the portal sees a healthy tunnel
+		_, err := conn.Write(nil)
+		conn.Close()
+		if onClose != nil {
+			onClose(err)
+		}
+		return
+	}
+	metadata := *adapter.ContextFrom(ctx)
+	metadata.Source = source
+	metadata.Destination = destination
+	h.router.RouteConnectionEx(ctx, conn, metadata, onClose)
+}
+
+func (h *bridgeHandler) NewPacketConnectionEx(ctx context.Context, conn N.PacketConn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	metadata := *adapter.ContextFrom(ctx)
+	metadata.Source = source
+	metadata.Destination = destination
+	h.router.RoutePacketConnectionEx(ctx, conn, metadata, onClose)
+}
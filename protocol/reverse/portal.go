@@ -0,0 +1,285 @@
+package reverse
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/outbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-mux"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+const portalPingInterval = time.Minute
+
+func RegisterPortal(registry *outbound.Registry) {
+	outbound.Register[option.PortalOutboundOptions](registry, C.TypePortal, NewPortal)
+}
+
+var (
+	_ adapter.Outbound            = (*Portal)(nil)
+	_ adapter.ConnectionHandlerEx = (*Portal)(nil)
+)
+
+// Portal accepts tunnels from bridges, which arrive as connections routed to
+// the portal with its domain as destination, and sends every other
+// connection routed to it back through one of those tunnels.
+type Portal struct {
+	outbound.Adapter
+	ctx        context.Context
+	logger     logger.ContextLogger
+	connection adapter.ConnectionManager
+	domain     string
+	tokens     []string
+	protocol   string
+	padding    bool
+	access     sync.Mutex
+	tunnels    []*portalTunnel
+	index      atomic.Uint32
+}
+
+func NewPortal(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.PortalOutboundOptions) (adapter.Outbound, error) {
+	if options.Domain == "" {
+		return nil, E.New("missing domain")
+	}
+	if len(options.Tokens) == 0 {
+		return nil, E.New("missing tokens")
+	}
+	if common.Contains(options.Tokens, "") {
+		return nil, E.New("empty token")
+	}
+	switch options.Protocol {
+	case "", "h2mux", "smux", "yamux":
+	default:
+		return nil, E.New("unknown protocol: ", options.Protocol)
+	}
+	return &Portal{
+		Adapter:    outbound.NewAdapter(C.TypePortal, tag, []string{N.NetworkTCP, N.NetworkUDP}, nil),
+		ctx:        ctx,
+		logger:     logger,
+		connection: service.FromContext[adapter.ConnectionManager](ctx),
+		domain:     options.Domain,
+		tokens:     options.Tokens,
+		protocol:   options.Protocol,
+		padding:    options.Padding,
+	}, nil
+}
+
+func (p *Portal) Close() error {
+	p.access.Lock()
+	tunnels := p.tunnels
+	p.tunnels = nil
+	p.access.Unlock()
+	for _, tunnel := range tunnels {
+		tunnel.close(os.ErrClosed)
+	}
+	return nil
+}
+
+func (p *Portal) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	var lastErr error
+	for _, tunnel := range p.selectTunnels() {
+		conn, err := tunnel.client.DialContext(ctx, network, destination)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		p.removeTunnel(tunnel, err)
+	}
+	if lastErr == nil {
+		return nil, E.New("no available tunnel")
+	}
+	return nil, lastErr
+}
+
+func (p *Portal) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	var lastErr error
+	for _, tunnel := range p.selectTunnels() {
+		conn, err := tunnel.client.ListenPacket(ctx, destination)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		p.removeTunnel(tunnel, err)
+	}
+	if lastErr == nil {
+		return nil, E.New("no available tunnel")
+	}
+	return nil, lastErr
+}
+
+func (p *Portal) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	if metadata.Destination.Fqdn != p.domain {
+		p.connection.NewConnection(ctx, p, conn, metadata, onClose)
+		return
+	}
+	err := p.acceptTunnel(ctx, conn, metadata, onClose)
+	if err != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, err)
+		p.logger.ErrorContext(ctx, E.Cause(err, "accept tunnel from ", metadata.Source))
+	}
+}
+
+func (p *Portal) acceptTunnel(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) error {
+	conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	token, err := readRequest(conn)
+	if err != nil {
+		return E.Cause(err, "read request")
+	}
+	if !p.authenticate(token) {
+		writeResponse(conn, statusUnauthorized)
+		return E.New("unauthorized")
+	}
+	err = writeResponse(conn, statusSuccess)
+	if err != nil {
+		return E.Cause(err, "write response")
+	}
+	conn.SetDeadline(time.Time{})
+	tunnel := &portalTunnel{
+		conn:    conn,
+		onClose: onClose,
+		closed:  make(chan struct{}),
+	}
+	tunnel.client, err = mux.NewClient(mux.Options{
+		Dialer:         &tunnelDialer{conn: conn},
+		Logger:         p.logger,
+		Protocol:       p.protocol,
+		MaxConnections: 1,
+		Padding:        p.padding,
+	})
+	if err != nil {
+		return err
+	}
+	// set up the multiplex session before announcing the tunnel
+	err = tunnel.ping(ctx)
+	if err != nil {
+		tunnel.client.Close()
+		return E.Cause(err, "ping")
+	}
+	p.access.Lock()
+	p.tunnels = append(p.tunnels, tunnel)
+	p.access.Unlock()
+	p.logger.InfoContext(ctx, "tunnel accepted from ", metadata.Source)
+	go p.loopPing(ctx, tunnel)
+	return nil
+}
+
+func (p *Portal) authenticate(token string) bool {
+	return common.Any(p.tokens, func(it string) bool {
+		return subtle.ConstantTimeCompare([]byte(it), []byte(token)) == 1
+	})
+}
+
+func (p *Portal) loopPing(ctx context.Context, tunnel *portalTunnel) {
+	ticker := time.NewTicker(portalPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.removeTunnel(tunnel, p.ctx.Err())
+			return
+		case <-tunnel.closed:
+			return
+		case <-ticker.C:
+		}
+		err := tunnel.ping(ctx)
+		if err != nil {
+			p.logger.ErrorContext(ctx, E.Cause(err, "ping tunnel"))
+			p.removeTunnel(tunnel, err)
+			return
+		}
+	}
+}
+
+// selectTunnels returns the live tunnels in round-robin order.
+func (p *Portal) selectTunnels() []*portalTunnel {
+	p.access.Lock()
+	defer p.access.Unlock()
+	if len(p.tunnels) == 0 {
+		return nil
+	}
+	start := int(p.index.Add(1)) % len(p.tunnels)
+	tunnels := make([]*portalTunnel, 0, len(p.tunnels))
+	tunnels = append(tunnels, p.tunnels[start:]...)
+	return append(tunnels, p.tunnels[:start]...)
+}
+
+func (p *Portal) removeTunnel(tunnel *portalTunnel, err error) {
+	p.access.Lock()
+	p.tunnels = common.Filter(p.tunnels, func(it *portalTunnel) bool {
+		return it != tunnel
+	})
+	p.access.Unlock()
+	tunnel.close(err)
+}
+
+type portalTunnel struct {
+	conn      net.Conn
+	client    *mux.Client
+	onClose   N.CloseHandlerFunc
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (t *portalTunnel) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, C.TCPTimeout)
+	defer cancel()
+	conn, err := t.client.DialContext(ctx, N.NetworkTCP, pingDestination)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	_, err = conn.Write(nil)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Read(make([]byte, 1))
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (t *portalTunnel) close(err error) {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		t.client.Close()
+		t.conn.Close()
+		if t.onClose != nil {
+			t.onClose(err)
+		}
+	})
+}
+
+// tunnelDialer hands the accepted tunnel to the multiplex client once, so a
+// broken session is never replaced behind the portal's back.
+type tunnelDialer struct {
+	conn net.Conn
+	used atomic.Bool
+}
+
+func (d *tunnelDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if d.used.Swap(true) {
+		return nil, E.New("tunnel closed")
+	}
+	return d.conn, nil
+}
+
+func (d *tunnelDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
@@ -0,0 +1,79 @@
+package reverse
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// A bridge opens a tunnel by dialing the portal domain and sending a
+// handshake request. Once the portal accepts it, the portal acts as the
+// multiplex client on the tunnel and the bridge as the multiplex server,
+// so connections flow back from the portal to the bridge.
+
+const (
+	Version0 = 0
+
+	statusSuccess      = 0
+	statusUnauthorized = 1
+)
+
+// pingDestination is opened by the portal to set up the multiplex session and
+// to check tunnel liveness; bridges answer it without routing.
+var pingDestination = M.Socksaddr{Fqdn: "sp.reverse.ping"}
+
+func writeRequest(writer io.Writer, token string) error {
+	buffer := buf.NewSize(3 + len(token))
+	defer buffer.Release()
+	buffer.WriteByte(Version0)
+	binary.Write(buffer, binary.BigEndian, uint16(len(token)))
+	buffer.WriteString(token)
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func readRequest(reader io.Reader) (string, error) {
+	var version uint8
+	err := binary.Read(reader, binary.BigEndian, &version)
+	if err != nil {
+		return "", err
+	}
+	if version != Version0 {
+		return "", E.New("unknown version: ", version)
+	}
+	var tokenLen uint16
+	err = binary.Read(reader, binary.BigEndian, &tokenLen)
+	if err != nil {
+		return "", err
+	}
+	token := make([]byte, tokenLen)
+	_, err = io.ReadFull(reader, token)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+func writeResponse(writer io.Writer, status uint8) error {
+	_, err := writer.Write([]byte{status})
+	return err
+}
+
+func readResponse(reader io.Reader) error {
+	var status uint8
+	err := binary.Read(reader, binary.BigEndian, &status)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case statusSuccess:
+		return nil
+	case statusUnauthorized:
+		return E.New("unauthorized")
+	default:
+		return E.New("unknown status: ", status)
+	}
+}
@@ -0,0 +1,151 @@
+package reverse
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDomain = "reverse.test"
+
+func TestReverse(t *testing.T) {
+	t.Parallel()
+	portal := newTestPortal(t)
+	router := &testRouter{connections: make(chan routedConnection, 1)}
+	bridge := newTestBridge(t, portal, router, "secret")
+	require.NoError(t, bridge.Start(adapter.StartStatePostStart))
+	require.Eventually(t, func() bool {
+		return len(portal.selectTunnels()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	conn, err := portal.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("10.0.0.1:80"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	routed := <-router.connections
+	defer routed.conn.Close()
+	require.Equal(t, "bridge", routed.metadata.Inbound)
+	require.Equal(t, "10.0.0.1:80", routed.metadata.Destination.String())
+	message := make([]byte, 5)
+	_, err = io.ReadFull(routed.conn, message)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(message))
+
+	// the tunnel is removed once the bridge goes away
+	require.NoError(t, bridge.Close())
+	require.Eventually(t, func() bool {
+		conn, err := portal.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("10.0.0.1:80"))
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil && len(portal.selectTunnels()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReverseUnauthorized(t *testing.T) {
+	t.Parallel()
+	portal := newTestPortal(t)
+	bridge := newTestBridge(t, portal, &testRouter{}, "wrong")
+	established, err := bridge.serveTunnel()
+	require.False(t, established)
+	require.ErrorContains(t, err, "unauthorized")
+	require.Empty(t, portal.selectTunnels())
+}
+
+func TestPortalMissingTokens(t *testing.T) {
+	t.Parallel()
+	_, err := NewPortal(context.Background(), nil, log.NewNOPFactory().NewLogger("portal"), "portal", option.PortalOutboundOptions{
+		Domain: testDomain,
+	})
+	require.Error(t, err)
+}
+
+func newTestPortal(t *testing.T) *Portal {
+	portal, err := NewPortal(context.Background(), nil, log.NewNOPFactory().NewLogger("portal"), "portal", option.PortalOutboundOptions{
+		Domain:   testDomain,
+		Tokens:   []string{"secret"},
+		Protocol: "smux",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		portal.(*Portal).Close()
+	})
+	return portal.(*Portal)
+}
+
+func newTestBridge(t *testing.T, portal *Portal, router adapter.Router, token string) *Bridge {
+	ctx := service.ContextWith[adapter.OutboundManager](context.Background(), &testOutboundManager{})
+	bridge, err := NewBridge(ctx, router, log.NewNOPFactory().NewLogger("bridge"), "bridge", option.BridgeInboundOptions{
+		DialerOptions: option.DialerOptions{Detour: "portal"},
+		Domain:        testDomain,
+		Token:         token,
+	})
+	require.NoError(t, err)
+	bridge.(*Bridge).dialer = &portalDialer{portal}
+	t.Cleanup(func() {
+		bridge.Close()
+	})
+	return bridge.(*Bridge)
+}
+
+// portalDialer hands a loopback connection to the portal as if it was routed
+// there with the portal domain as destination.
+type portalDialer struct {
+	portal *Portal
+}
+
+func (d *portalDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	serverConn, err := listener.Accept()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go d.portal.NewConnectionEx(context.Background(), serverConn, adapter.InboundContext{
+		Source:      M.SocksaddrFromNet(conn.LocalAddr()),
+		Destination: destination,
+	}, nil)
+	return conn, nil
+}
+
+func (d *portalDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, net.ErrClosed
+}
+
+type testOutboundManager struct {
+	adapter.OutboundManager
+}
+
+type routedConnection struct {
+	conn     net.Conn
+	metadata adapter.InboundContext
+}
+
+type testRouter struct {
+	adapter.Router
+	connections chan routedConnection
+}
+
+func (r *testRouter) RouteConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	r.connections <- routedConnection{conn, metadata}
+}
@@ -0,0 +1,536 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/pem"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/listener"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.SSHInboundOptions](registry, C.TypeSSH, NewInbound)
+}
+
+var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+
+type Inbound struct {
+	inbound.Adapter
+	ctx                 context.Context
+	router              adapter.ConnectionRouterEx
+	logger              log.ContextLogger
+	listener            *listener.Listener
+	passwords           map[string]string
+	publicKeys          map[string][]ssh.PublicKey
+	authorizedKeysPaths map[string]string
+	allowTCPIPForward   bool
+	forwardListen       []netip.Prefix
+	serverConfig        *ssh.ServerConfig
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHInboundOptions) (adapter.Inbound, error) {
+	inbound := &Inbound{
+		Adapter:             inbound.NewAdapter(C.TypeSSH, tag),
+		ctx:                 ctx,
+		router:              router,
+		logger:              logger,
+		passwords:           make(map[string]string),
+		publicKeys:          make(map[string][]ssh.PublicKey),
+		authorizedKeysPaths: make(map[string]string),
+		allowTCPIPForward:   options.AllowTCPIPForward,
+	}
+	for index, user := range options.Users {
+		if user.Name == "" {
+			return nil, E.New("missing name for user[", index, "]")
+		}
+		if user.Password == "" && len(user.PublicKey) == 0 && user.AuthorizedKeysPath == "" {
+			return nil, E.New("missing password, public key or authorized keys for user ", user.Name)
+		}
+		if user.Password != "" {
+			inbound.passwords[user.Name] = user.Password
+		}
+		for _, publicKey := range user.PublicKey {
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+			if err != nil {
+				return nil, E.Cause(err, "parse public key for user ", user.Name)
+			}
+			inbound.publicKeys[user.Name] = append(inbound.publicKeys[user.Name], key)
+		}
+		if user.AuthorizedKeysPath != "" {
+			authorizedKeysPath := filemanager.BasePath(ctx, os.ExpandEnv(user.AuthorizedKeysPath))
+			_, err := readAuthorizedKeys(authorizedKeysPath)
+			if err != nil {
+				return nil, E.Cause(err, "user ", user.Name)
+			}
+			inbound.authorizedKeysPaths[user.Name] = authorizedKeysPath
+		}
+	}
+	if len(options.Users) == 0 {
+		return nil, E.New("missing users")
+	}
+	for _, prefix := range options.TCPIPForwardListen {
+		inbound.forwardListen = append(inbound.forwardListen, prefix.Build(netip.Prefix{}))
+	}
+	hostKey, err := loadHostKey(ctx, logger, options)
+	if err != nil {
+		return nil, err
+	}
+	serverConfig := &ssh.ServerConfig{
+		ServerVersion: options.ServerVersion,
+	}
+	if serverConfig.ServerVersion == "" {
+		serverConfig.ServerVersion = randomVersion()
+	}
+	if len(inbound.passwords) > 0 {
+		serverConfig.PasswordCallback = inbound.passwordCallback
+	}
+	if len(inbound.publicKeys) > 0 || len(inbound.authorizedKeysPaths) > 0 {
+		serverConfig.PublicKeyCallback = inbound.publicKeyCallback
+	}
+	serverConfig.AddHostKey(hostKey)
+	inbound.serverConfig = serverConfig
+	inbound.listener = listener.New(listener.Options{
+		Context:           ctx,
+		Logger:            logger,
+		Network:           []string{N.NetworkTCP},
+		Listen:            options.ListenOptions,
+		ConnectionHandler: inbound,
+	})
+	return inbound, nil
+}
+
+func loadHostKey(ctx context.Context, logger log.ContextLogger, options option.SSHInboundOptions) (ssh.Signer, error) {
+	if len(options.HostKey) > 0 {
+		signer, err := ssh.ParsePrivateKey([]byte(strings.Join(options.HostKey, "\n")))
+		if err != nil {
+			return nil, E.Cause(err, "parse host key")
+		}
+		return signer, nil
+	}
+	if options.HostKeyPath == "" {
+		logger.Warn("host_key or host_key_path not set, using a temporary host key")
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.NewSignerFromKey(privateKey)
+	}
+	hostKeyPath := filemanager.BasePath(ctx, os.ExpandEnv(options.HostKeyPath))
+	content, err := os.ReadFile(hostKeyPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, E.Cause(err, "read host key")
+		}
+		content, err = writeNewHostKey(hostKeyPath)
+		if err != nil {
+			return nil, E.Cause(err, "generate host key")
+		}
+		logger.Info("generated new host key at ", hostKeyPath)
+	}
+	signer, err := ssh.ParsePrivateKey(content)
+	if err != nil {
+		return nil, E.Cause(err, "parse host key")
+	}
+	return signer, nil
+}
+
+func writeNewHostKey(path string) ([]byte, error) {
+	privateKeyPEM, _, err := GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(path, privateKeyPEM, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return privateKeyPEM, nil
+}
+
+// GenerateKeyPair returns a new ed25519 private key in OpenSSH PEM format
+// and its public key in authorized_keys format.
+func GenerateKeyPair() (privateKeyPEM []byte, publicKey []byte, err error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		return
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return
+	}
+	privateKeyPEM = pem.EncodeToMemory(block)
+	publicKey = ssh.MarshalAuthorizedKey(signer.PublicKey())
+	return
+}
+
+func readAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, E.Cause(err, "read authorized keys")
+	}
+	var keys []ssh.PublicKey
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, E.Cause(err, "parse authorized keys")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (h *Inbound) passwordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	userPassword, loaded := h.passwords[conn.User()]
+	if !loaded || subtle.ConstantTimeCompare([]byte(userPassword), password) != 1 {
+		return nil, E.New("password rejected for ", conn.User())
+	}
+	return nil, nil
+}
+
+func (h *Inbound) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	publicKey := key.Marshal()
+	for _, userKey := range h.publicKeys[conn.User()] {
+		if bytes.Equal(publicKey, userKey.Marshal()) {
+			return nil, nil
+		}
+	}
+	if authorizedKeysPath, loaded := h.authorizedKeysPaths[conn.User()]; loaded {
+		// re-read on each attempt so that edits apply without a restart
+		authorizedKeys, err := readAuthorizedKeys(authorizedKeysPath)
+		if err != nil {
+			h.logger.Error(err)
+		}
+		for _, authorizedKey := range authorizedKeys {
+			if bytes.Equal(publicKey, authorizedKey.Marshal()) {
+				return nil, nil
+			}
+		}
+	}
+	return nil, E.New("public key rejected for ", conn.User())
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	return h.listener.Start()
+}
+
+func (h *Inbound) Close() error {
+	return h.listener.Close()
+}
+
+func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, h.serverConfig)
+	if err != nil {
+		N.CloseOnHandshakeFailure(conn, onClose, err)
+		h.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", metadata.Source))
+		return
+	}
+	session := &serverSession{
+		inbound:    h,
+		ctx:        ctx,
+		serverConn: serverConn,
+		metadata:   metadata,
+		forwards:   make(map[string]net.Listener),
+	}
+	h.logger.InfoContext(ctx, "[", serverConn.User(), "] ssh session established from ", metadata.Source)
+	go session.handleRequests(requests)
+	session.handleChannels(channels)
+	session.close()
+	conn.Close()
+	if onClose != nil {
+		onClose(nil)
+	}
+}
+
+type serverSession struct {
+	inbound      *Inbound
+	ctx          context.Context
+	serverConn   *ssh.ServerConn
+	metadata     adapter.InboundContext
+	forwardLock  sync.Mutex
+	forwards     map[string]net.Listener
+	forwardClose bool
+}
+
+type directTCPIPPayload struct {
+	Host       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+type tcpipForwardPayload struct {
+	Addr string
+	Port uint32
+}
+
+type tcpipForwardReply struct {
+	Port uint32
+}
+
+type forwardedTCPIPPayload struct {
+	Addr       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+func (s *serverSession) handleChannels(channels <-chan ssh.NewChannel) {
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type: "+newChannel.ChannelType())
+			continue
+		}
+		var payload directTCPIPPayload
+		err := ssh.Unmarshal(newChannel.ExtraData(), &payload)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, "invalid payload")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			s.inbound.logger.ErrorContext(s.ctx, E.Cause(err, "accept channel"))
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go s.newDirectTCPIP(channel, payload)
+	}
+}
+
+func (s *serverSession) newDirectTCPIP(channel ssh.Channel, payload directTCPIPPayload) {
+	ctx := log.ContextWithNewID(s.ctx)
+	metadata := s.metadata
+	metadata.Inbound = s.inbound.Tag()
+	metadata.InboundType = s.inbound.Type()
+	metadata.User = s.serverConn.User()
+	metadata.Destination = M.ParseSocksaddrHostPort(payload.Host, uint16(payload.Port))
+	s.inbound.logger.InfoContext(ctx, "[", metadata.User, "] inbound connection to ", metadata.Destination)
+	conn := &channelConn{
+		Channel:    channel,
+		localAddr:  s.serverConn.LocalAddr(),
+		remoteAddr: s.serverConn.RemoteAddr(),
+	}
+	s.inbound.router.RouteConnectionEx(ctx, conn, metadata, func(it error) {
+		conn.Close()
+	})
+}
+
+func (s *serverSession) handleRequests(requests <-chan *ssh.Request) {
+	for request := range requests {
+		switch request.Type {
+		case "tcpip-forward":
+			if !s.inbound.allowTCPIPForward {
+				request.Reply(false, nil)
+				continue
+			}
+			var payload tcpipForwardPayload
+			err := ssh.Unmarshal(request.Payload, &payload)
+			if err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			port, err := s.startForward(payload)
+			if err != nil {
+				s.inbound.logger.ErrorContext(s.ctx, E.Cause(err, "[", s.serverConn.User(), "] tcpip-forward"))
+				request.Reply(false, nil)
+				continue
+			}
+			if payload.Port == 0 {
+				request.Reply(true, ssh.Marshal(tcpipForwardReply{Port: port}))
+			} else {
+				request.Reply(true, nil)
+			}
+		case "cancel-tcpip-forward":
+			var payload tcpipForwardPayload
+			err := ssh.Unmarshal(request.Payload, &payload)
+			if err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(s.cancelForward(payload), nil)
+		default:
+			if request.WantReply {
+				request.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *serverSession) startForward(payload tcpipForwardPayload) (uint32, error) {
+	s.forwardLock.Lock()
+	defer s.forwardLock.Unlock()
+	if s.forwardClose {
+		return 0, net.ErrClosed
+	}
+	bindAddr, err := s.inbound.forwardAddr(payload.Addr)
+	if err != nil {
+		return 0, err
+	}
+	tcpListener, err := net.Listen(N.NetworkTCP, M.SocksaddrFrom(bindAddr, uint16(payload.Port)).String())
+	if err != nil {
+		return 0, err
+	}
+	listenPort := uint32(M.SocksaddrFromNet(tcpListener.Addr()).Port)
+	s.forwards[forwardKey(payload.Addr, listenPort)] = tcpListener
+	s.inbound.logger.InfoContext(s.ctx, "[", s.serverConn.User(), "] remote forward listening at ", tcpListener.Addr())
+	go s.loopForward(tcpListener, payload.Addr, listenPort)
+	return listenPort, nil
+}
+
+// forwardAddr resolves the address a remote forward asks to bind, which must
+// be a loopback address or in tcpip_forward_listen.
+func (h *Inbound) forwardAddr(addr string) (netip.Addr, error) {
+	var bindAddr netip.Addr
+	switch addr {
+	case "localhost":
+		bindAddr = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	case "":
+		bindAddr = netip.IPv4Unspecified()
+	default:
+		var err error
+		bindAddr, err = netip.ParseAddr(addr)
+		if err != nil {
+			return netip.Addr{}, E.New("bind address is not an IP address: ", addr)
+		}
+		bindAddr = bindAddr.Unmap()
+	}
+	if bindAddr.IsLoopback() {
+		return bindAddr, nil
+	}
+	for _, prefix := range h.forwardListen {
+		if prefix.Contains(bindAddr) {
+			return bindAddr, nil
+		}
+	}
+	return netip.Addr{}, E.New("bind address not allowed: ", addr)
+}
+
+func (s *serverSession) cancelForward(payload tcpipForwardPayload) bool {
+	s.forwardLock.Lock()
+	defer s.forwardLock.Unlock()
+	key := forwardKey(payload.Addr, payload.Port)
+	tcpListener, loaded := s.forwards[key]
+	if !loaded {
+		return false
+	}
+	delete(s.forwards, key)
+	tcpListener.Close()
+	return true
+}
+
+func (s *serverSession) loopForward(tcpListener net.Listener, bindAddr string, bindPort uint32) {
+	for {
+		conn, err := tcpListener.Accept()
+		if err != nil {
+			return
+		}
+		go s.newForwardedTCPIP(conn, bindAddr, bindPort)
+	}
+}
+
+func (s *serverSession) newForwardedTCPIP(conn net.Conn, bindAddr string, bindPort uint32) {
+	ctx := log.ContextWithNewID(s.ctx)
+	origin := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+	channel, requests, err := s.serverConn.OpenChannel("forwarded-tcpip", ssh.Marshal(forwardedTCPIPPayload{
+		Addr:       bindAddr,
+		Port:       bindPort,
+		OriginAddr: origin.AddrString(),
+		OriginPort: uint32(origin.Port),
+	}))
+	if err != nil {
+		conn.Close()
+		s.inbound.logger.ErrorContext(ctx, E.Cause(err, "open forwarded channel for ", origin))
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	s.inbound.logger.InfoContext(ctx, "[", s.serverConn.User(), "] remote forward connection from ", origin)
+	err = bufio.CopyConn(ctx, conn, &channelConn{
+		Channel:    channel,
+		localAddr:  s.serverConn.LocalAddr(),
+		remoteAddr: s.serverConn.RemoteAddr(),
+	})
+	if err != nil && !E.IsClosedOrCanceled(err) {
+		s.inbound.logger.DebugContext(ctx, E.Cause(err, "remote forward connection"))
+	}
+}
+
+func (s *serverSession) close() {
+	s.forwardLock.Lock()
+	defer s.forwardLock.Unlock()
+	s.forwardClose = true
+	for _, tcpListener := range s.forwards {
+		common.Close(tcpListener)
+	}
+	s.forwards = nil
+}
+
+func forwardKey(addr string, port uint32) string {
+	return net.JoinHostPort(addr, F.ToString(port))
+}
+
+type channelConn struct {
+	ssh.Channel
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (c *channelConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *channelConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *channelConn) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *channelConn) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *channelConn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *channelConn) NeedAdditionalReadDeadline() bool {
+	return true
+}
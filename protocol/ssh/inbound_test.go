@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestInboundPassword(t *testing.T) {
+	t.Parallel()
+	inbound := newTestInbound(t, option.SSHInboundOptions{
+		Users: []option.SSHUser{{Name: "alice", Password: "secret"}},
+	})
+	_, err := inbound.passwordCallback(testConnMetadata("alice"), []byte("secret"))
+	require.NoError(t, err)
+	_, err = inbound.passwordCallback(testConnMetadata("alice"), []byte("secre"))
+	require.Error(t, err)
+	_, err = inbound.passwordCallback(testConnMetadata("bob"), []byte("secret"))
+	require.Error(t, err)
+}
+
+func TestInboundAuthorizedKeys(t *testing.T) {
+	t.Parallel()
+	_, alicePublicKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+	_, bobPublicKey, err := GenerateKeyPair()
+	require.NoError(t, err)
+	authorizedKeysPath := filepath.Join(t.TempDir(), "authorized_keys")
+	require.NoError(t, os.WriteFile(authorizedKeysPath, alicePublicKey, 0o600))
+	inbound := newTestInbound(t, option.SSHInboundOptions{
+		Users: []option.SSHUser{
+			{Name: "alice", AuthorizedKeysPath: authorizedKeysPath},
+			{Name: "bob", PublicKey: []string{string(bobPublicKey)}},
+		},
+	})
+	aliceKey := parseTestPublicKey(t, alicePublicKey)
+	bobKey := parseTestPublicKey(t, bobPublicKey)
+	_, err = inbound.publicKeyCallback(testConnMetadata("alice"), aliceKey)
+	require.NoError(t, err)
+	_, err = inbound.publicKeyCallback(testConnMetadata("bob"), bobKey)
+	require.NoError(t, err)
+
+	// keys only authenticate the user they are configured for
+	_, err = inbound.publicKeyCallback(testConnMetadata("bob"), aliceKey)
+	require.Error(t, err)
+	_, err = inbound.publicKeyCallback(testConnMetadata("alice"), bobKey)
+	require.Error(t, err)
+	_, err = inbound.publicKeyCallback(testConnMetadata("root"), aliceKey)
+	require.Error(t, err)
+}
+
+func TestInboundForwardAddr(t *testing.T) {
+	t.Parallel()
+	inbound := newTestInbound(t, option.SSHInboundOptions{
+		Users: []option.SSHUser{{Name: "alice", Password: "secret"}},
+	})
+	for addr, expected := range map[string]string{
+		"localhost": "127.0.0.1",
+		"127.0.0.2": "127.0.0.2",
+		"::1":       "::1",
+	} {
+		bindAddr, err := inbound.forwardAddr(addr)
+		require.NoError(t, err)
+		require.Equal(t, expected, bindAddr.String())
+	}
+	for _, addr := range []string{"", "0.0.0.0", "::", "192.0.2.1", "example.com"} {
+		_, err := inbound.forwardAddr(addr)
+		require.Error(t, err, addr)
+	}
+
+	prefix := badoption.Prefixable(netip.MustParsePrefix("192.0.2.0/24"))
+	inbound = newTestInbound(t, option.SSHInboundOptions{
+		Users:              []option.SSHUser{{Name: "alice", Password: "secret"}},
+		TCPIPForwardListen: []*badoption.Prefixable{&prefix},
+	})
+	for _, addr := range []string{"localhost", "192.0.2.1"} {
+		_, err := inbound.forwardAddr(addr)
+		require.NoError(t, err, addr)
+	}
+	for _, addr := range []string{"", "198.51.100.1"} {
+		_, err := inbound.forwardAddr(addr)
+		require.Error(t, err, addr)
+	}
+}
+
+func TestChannelConnDeadline(t *testing.T) {
+	t.Parallel()
+	conn := &channelConn{}
+	require.ErrorIs(t, conn.SetDeadline(time.Now()), os.ErrInvalid)
+	require.ErrorIs(t, conn.SetReadDeadline(time.Now()), os.ErrInvalid)
+	require.ErrorIs(t, conn.SetWriteDeadline(time.Now()), os.ErrInvalid)
+	require.True(t, conn.NeedAdditionalReadDeadline())
+}
+
+func newTestInbound(t *testing.T, options option.SSHInboundOptions) *Inbound {
+	inbound, err := NewInbound(context.Background(), nil, log.NewNOPFactory().NewLogger("ssh"), "ssh", options)
+	require.NoError(t, err)
+	return inbound.(*Inbound)
+}
+
+func parseTestPublicKey(t *testing.T, content []byte) ssh.PublicKey {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(content)
+	require.NoError(t, err)
+	return publicKey
+}
+
+type testConnMetadata string
+
+func (m testConnMetadata) User() string {
+	return string(m)
+}
+
+func (m testConnMetadata) SessionID() []byte {
+	return nil
+}
+
+func (m testConnMetadata) ClientVersion() []byte {
+	return nil
+}
+
+func (m testConnMetadata) ServerVersion() []byte {
+	return nil
+}
+
+func (m testConnMetadata) RemoteAddr() net.Addr {
+	return nil
+}
+
+func (m testConnMetadata) LocalAddr() net.Addr {
+	return nil
+}
@@ -0,0 +1,212 @@
+package tor
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/common/rw"
+
+	"github.com/cretz/bine/control"
+	"github.com/cretz/bine/tor"
+	"github.com/cretz/bine/torutil/ed25519"
+)
+
+func RegisterInbound(registry *inbound.Registry) {
+	inbound.Register[option.TorInboundOptions](registry, C.TypeTor, NewInbound)
+}
+
+// onionKeyHeader is the header of hs_ed25519_secret_key files written by tor,
+// so that the key can be moved between sing-box and a HiddenServiceDir.
+var onionKeyHeader = []byte("== ed25519v1-secret: type0 ==\x00\x00\x00")
+
+type Inbound struct {
+	inbound.Adapter
+	ctx        context.Context
+	router     adapter.ConnectionRouterEx
+	logger     log.ContextLogger
+	startConf  *tor.StartConf
+	options    map[string]string
+	ports      []uint16
+	maxStreams int
+	detour     string
+	keyPath    string
+	events     *eventListener
+	instance   *tor.Tor
+	serviceID  string
+	listeners  []net.Listener
+}
+
+func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TorInboundOptions) (adapter.Inbound, error) {
+	startConf, err := newStartConf(options.ExecutablePath, options.ExtraArgs, options.DataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	inbound := &Inbound{
+		Adapter:    inbound.NewAdapter(C.TypeTor, tag),
+		ctx:        ctx,
+		router:     router,
+		logger:     logger,
+		startConf:  startConf,
+		options:    options.Options,
+		ports:      options.Ports,
+		maxStreams: options.MaxStreams,
+		detour:     options.Detour,
+	}
+	if len(inbound.ports) == 0 {
+		inbound.ports = []uint16{80}
+	}
+	if startConf.DataDir != "" {
+		inbound.keyPath = filepath.Join(startConf.DataDir, "onion_service", "hs_ed25519_secret_key")
+	} else {
+		logger.Warn("data_directory not set, the onion service address will change on every start")
+	}
+	return inbound, nil
+}
+
+func (h *Inbound) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	err := h.start()
+	if err != nil {
+		h.Close()
+	}
+	return err
+}
+
+func (h *Inbound) start() error {
+	onionKey, err := h.loadKey()
+	if err != nil {
+		return err
+	}
+	torInstance, events, err := startInstance(h.ctx, h.logger, h.startConf)
+	if err != nil {
+		return err
+	}
+	h.instance = torInstance
+	h.events = events
+	err = setConf(torInstance, h.options)
+	if err != nil {
+		return err
+	}
+	request := &control.AddOnionRequest{
+		MaxStreams: h.maxStreams,
+	}
+	if onionKey != nil {
+		request.Key = &control.ED25519Key{KeyPair: onionKey}
+	} else {
+		request.Key = control.GenKey(control.KeyAlgoED25519V3)
+	}
+	for _, port := range h.ports {
+		var tcpListener net.Listener
+		tcpListener, err = net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		h.listeners = append(h.listeners, tcpListener)
+		request.Ports = append(request.Ports, control.NewKeyVal(strconv.Itoa(int(port)), tcpListener.Addr().String()))
+	}
+	response, err := torInstance.Control.AddOnion(request)
+	if err != nil {
+		return E.Cause(err, "create onion service")
+	}
+	h.serviceID = response.ServiceID
+	if onionKey == nil {
+		if generatedKey, isED25519 := response.Key.(*control.ED25519Key); isED25519 && h.keyPath != "" {
+			err = h.saveKey(generatedKey.KeyPair)
+			if err != nil {
+				return E.Cause(err, "save onion service key")
+			}
+		}
+	}
+	for index, tcpListener := range h.listeners {
+		go h.loopAccept(tcpListener, h.ports[index])
+	}
+	err = torInstance.EnableNetwork(h.ctx, false)
+	if err != nil {
+		return err
+	}
+	h.logger.Info("onion service published at ", h.serviceID, ".onion")
+	return nil
+}
+
+func (h *Inbound) loadKey() (ed25519.KeyPair, error) {
+	if h.keyPath == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(h.keyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, E.Cause(err, "read onion service key")
+	}
+	if len(content) != len(onionKeyHeader)+64 || !bytes.HasPrefix(content, onionKeyHeader) {
+		return nil, E.New("invalid onion service key: ", h.keyPath)
+	}
+	return ed25519.PrivateKey(content[len(onionKeyHeader):]).KeyPair(), nil
+}
+
+func (h *Inbound) saveKey(keyPair ed25519.KeyPair) error {
+	err := rw.MkdirParent(h.keyPath)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(h.keyPath, append(append([]byte{}, onionKeyHeader...), keyPair.PrivateKey()...), 0o600)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(filepath.Dir(h.keyPath), "hostname"), []byte(h.serviceID+".onion\n"), 0o644)
+}
+
+func (h *Inbound) loopAccept(tcpListener net.Listener, port uint16) {
+	destination := M.Socksaddr{
+		Fqdn: h.serviceID + ".onion",
+		Port: port,
+	}
+	for {
+		conn, err := tcpListener.Accept()
+		if err != nil {
+			if !E.IsClosed(err) {
+				h.logger.Error("onion service listener closed: ", err)
+			}
+			return
+		}
+		ctx := log.ContextWithNewID(h.ctx)
+		var metadata adapter.InboundContext
+		metadata.Inbound = h.Tag()
+		metadata.InboundType = h.Type()
+		metadata.InboundDetour = h.detour
+		metadata.Source = M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+		metadata.OriginDestination = destination
+		metadata.Destination = destination
+		h.logger.InfoContext(ctx, "inbound connection to ", destination)
+		go h.router.RouteConnectionEx(ctx, conn, metadata, nil)
+	}
+}
+
+func (h *Inbound) Close() error {
+	if h.instance != nil && h.serviceID != "" {
+		h.instance.Control.DelOnion(h.serviceID)
+	}
+	listeners := []any{common.PtrOrNil(h.events)}
+	for _, tcpListener := range h.listeners {
+		listeners = append(listeners, tcpListener)
+	}
+	err := common.Close(append(listeners, common.PtrOrNil(h.instance))...)
+	h.listeners = nil
+	h.events = nil
+	return err
+}
@@ -0,0 +1,145 @@
+package tor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/rw"
+
+	"github.com/cretz/bine/control"
+	"github.com/cretz/bine/tor"
+)
+
+func newStartConf(executablePath string, extraArgs []string, dataDirectory string) (*tor.StartConf, error) {
+	var startConf tor.StartConf
+	startConf.DataDir = os.ExpandEnv(dataDirectory)
+	startConf.TempDataDirBase = os.TempDir()
+	if dataDirectory != "" {
+		dataDirAbs, _ := filepath.Abs(startConf.DataDir)
+		if geoIPPath := filepath.Join(dataDirAbs, "geoip"); rw.IsFile(geoIPPath) && !common.Contains(extraArgs, "--GeoIPFile") {
+			extraArgs = append(extraArgs, "--GeoIPFile", geoIPPath)
+		}
+		if geoIP6Path := filepath.Join(dataDirAbs, "geoip6"); rw.IsFile(geoIP6Path) && !common.Contains(extraArgs, "--GeoIPv6File") {
+			extraArgs = append(extraArgs, "--GeoIPv6File", geoIP6Path)
+		}
+	}
+	startConf.ExtraArgs = extraArgs
+	if executablePath != "" {
+		startConf.ExePath = executablePath
+		startConf.ProcessCreator = nil
+		startConf.UseEmbeddedControlConn = false
+	}
+	if startConf.DataDir != "" {
+		torrcFile := filepath.Join(startConf.DataDir, "torrc")
+		err := rw.MkdirParent(torrcFile)
+		if err != nil {
+			return nil, err
+		}
+		if !rw.IsFile(torrcFile) {
+			err := os.WriteFile(torrcFile, []byte(""), 0o600)
+			if err != nil {
+				return nil, err
+			}
+		}
+		startConf.TorrcFile = torrcFile
+	}
+	return &startConf, nil
+}
+
+var torLogEvents = []control.EventCode{
+	control.EventCodeLogDebug,
+	control.EventCodeLogErr,
+	control.EventCodeLogInfo,
+	control.EventCodeLogNotice,
+	control.EventCodeLogWarn,
+}
+
+func startInstance(ctx context.Context, logger logger.ContextLogger, startConf *tor.StartConf) (*tor.Tor, *eventListener, error) {
+	torInstance, err := tor.Start(ctx, startConf)
+	if err != nil {
+		return nil, nil, E.New(strings.ToLower(err.Error()))
+	}
+	events, err := listenEvents(logger, torInstance.Control)
+	if err != nil {
+		torInstance.Close()
+		return nil, nil, err
+	}
+	return torInstance, events, nil
+}
+
+// eventListener relays tor log events to the logger.
+type eventListener struct {
+	control *control.Conn
+	events  chan control.Event
+	done    chan struct{}
+}
+
+func listenEvents(logger logger.ContextLogger, conn *control.Conn) (*eventListener, error) {
+	listener := &eventListener{
+		control: conn,
+		events:  make(chan control.Event, 8),
+		done:    make(chan struct{}),
+	}
+	err := conn.AddEventListener(listener.events, torLogEvents...)
+	if err != nil {
+		return nil, err
+	}
+	go listener.loop(logger)
+	return listener, nil
+}
+
+// Close unregisters the channel from bine, which sends to it while reading
+// responses, and then stops the receive loop. The channel itself is never
+// closed, so a send racing with Close can not panic.
+func (l *eventListener) Close() error {
+	err := l.control.RemoveEventListener(l.events, torLogEvents...)
+	close(l.done)
+	return err
+}
+
+func setConf(torInstance *tor.Tor, options map[string]string, reservedKeys ...string) error {
+	for key, value := range options {
+		if common.Contains(reservedKeys, key) {
+			continue
+		}
+		err := torInstance.Control.SetConf(control.NewKeyVal(key, value))
+		if err != nil {
+			return E.Cause(err, "set ", key, "=", value)
+		}
+	}
+	return nil
+}
+
+func (l *eventListener) loop(logger logger.ContextLogger) {
+	for {
+		var rawEvent control.Event
+		select {
+		case rawEvent = <-l.events:
+		case <-l.done:
+			return
+		}
+		switch event := rawEvent.(type) {
+		case *control.LogEvent:
+			event.Raw = strings.ToLower(event.Raw)
+			switch event.Severity {
+			case control.EventCodeLogDebug, control.EventCodeLogInfo:
+				logger.Trace(event.Raw)
+			case control.EventCodeLogNotice:
+				if strings.Contains(event.Raw, "disablenetwork") || strings.Contains(event.Raw, "socks listener") {
+					logger.Trace(event.Raw)
+					continue
+				}
+				logger.Info(event.Raw)
+			case control.EventCodeLogWarn:
+				logger.Warn(event.Raw)
+			case control.EventCodeLogErr:
+				logger.Error(event.Raw)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tor
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/cretz/bine/control"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventListenerClose(t *testing.T) {
+	t.Parallel()
+	conn := newTestControlConn(t)
+	listener, err := listenEvents(log.NewNOPFactory().NewLogger("tor"), conn)
+	require.NoError(t, err)
+
+	// events are relayed while other requests are running
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if _, err := conn.SendRequest("GETINFO version"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	require.NoError(t, listener.Close())
+	wg.Wait()
+
+	// nothing is sent to the channel once closed
+	for len(listener.events) > 0 {
+		<-listener.events
+	}
+	_, err = conn.SendRequest("GETINFO version")
+	require.NoError(t, err)
+	require.Empty(t, listener.events)
+}
+
+// newTestControlConn returns a control connection to a fake tor that sends a
+// few log events before every reply.
+func newTestControlConn(t *testing.T) *control.Conn {
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		for {
+			_, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			_, err = serverConn.Write([]byte("650 NOTICE bootstrapped\r\n650 WARN clock skew\r\n250 OK\r\n"))
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return control.NewConn(textproto.NewConn(clientConn))
+}
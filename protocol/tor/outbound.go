@@ -4,8 +4,6 @@ import (
 	"context"
 	"net"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
@@ -19,7 +17,6 @@ import (
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/rw"
 	"github.com/sagernet/sing/protocol/socks"
 
 	"github.com/cretz/bine/control"
@@ -37,43 +34,15 @@ type Outbound struct {
 	proxy       *ProxyListener
 	startConf   *tor.StartConf
 	options     map[string]string
-	events      chan control.Event
+	events      *eventListener
 	instance    *tor.Tor
 	socksClient *socks.Client
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TorOutboundOptions) (adapter.Outbound, error) {
-	var startConf tor.StartConf
-	startConf.DataDir = os.ExpandEnv(options.DataDirectory)
-	startConf.TempDataDirBase = os.TempDir()
-	startConf.ExtraArgs = options.ExtraArgs
-	if options.DataDirectory != "" {
-		dataDirAbs, _ := filepath.Abs(startConf.DataDir)
-		if geoIPPath := filepath.Join(dataDirAbs, "geoip"); rw.IsFile(geoIPPath) && !common.Contains(options.ExtraArgs, "--GeoIPFile") {
-			options.ExtraArgs = append(options.ExtraArgs, "--GeoIPFile", geoIPPath)
-		}
-		if geoIP6Path := filepath.Join(dataDirAbs, "geoip6"); rw.IsFile(geoIP6Path) && !common.Contains(options.ExtraArgs, "--GeoIPv6File") {
-			options.ExtraArgs = append(options.ExtraArgs, "--GeoIPv6File", geoIP6Path)
-		}
-	}
-	if options.ExecutablePath != "" {
-		startConf.ExePath = options.ExecutablePath
-		startConf.ProcessCreator = nil
-		startConf.UseEmbeddedControlConn = false
-	}
-	if startConf.DataDir != "" {
-		torrcFile := filepath.Join(startConf.DataDir, "torrc")
-		err := rw.MkdirParent(torrcFile)
-		if err != nil {
-			return nil, err
-		}
-		if !rw.IsFile(torrcFile) {
-			err := os.WriteFile(torrcFile, []byte(""), 0o600)
-			if err != nil {
-				return nil, err
-			}
-		}
-		startConf.TorrcFile = torrcFile
+	startConf, err := newStartConf(options.ExecutablePath, options.ExtraArgs, options.DataDirectory)
+	if err != nil {
+		return nil, err
 	}
 	outboundDialer, err := dialer.New(ctx, options.DialerOptions, false)
 	if err != nil {
@@ -84,7 +53,7 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		ctx:       ctx,
 		logger:    logger,
 		proxy:     NewProxyListener(ctx, logger, outboundDialer),
-		startConf: &startConf,
+		startConf: startConf,
 		options:   options.Options,
 	}, nil
 }
@@ -97,26 +66,13 @@ func (t *Outbound) Start() error {
 	return err
 }
 
-var torLogEvents = []control.EventCode{
-	control.EventCodeLogDebug,
-	control.EventCodeLogErr,
-	control.EventCodeLogInfo,
-	control.EventCodeLogNotice,
-	control.EventCodeLogWarn,
-}
-
 func (t *Outbound) start() error {
-	torInstance, err := tor.Start(t.ctx, t.startConf)
-	if err != nil {
-		return E.New(strings.ToLower(err.Error()))
-	}
-	t.instance = torInstance
-	t.events = make(chan control.Event, 8)
-	err = torInstance.Control.AddEventListener(t.events, torLogEvents...)
+	torInstance, events, err := startInstance(t.ctx, t.logger, t.startConf)
 	if err != nil {
 		return err
 	}
-	go t.recvLoop()
+	t.instance = torInstance
+	t.events = events
 	err = t.proxy.Start()
 	if err != nil {
 		return err
@@ -136,19 +92,9 @@ func (t *Outbound) start() error {
 	if err != nil {
 		return err
 	}
-	if len(t.options) > 0 {
-		for key, value := range t.options {
-			switch key {
-			case "Socks5Proxy",
-				"Socks5ProxyUsername",
-				"Socks5ProxyPassword":
-				continue
-			}
-			err = torInstance.Control.SetConf(control.NewKeyVal(key, value))
-			if err != nil {
-				return E.Cause(err, "set ", key, "=", value)
-			}
-		}
+	err = setConf(torInstance, t.options, "Socks5Proxy", "Socks5ProxyUsername", "Socks5ProxyPassword")
+	if err != nil {
+		return err
 	}
 	err = torInstance.EnableNetwork(t.ctx, true)
 	if err != nil {
@@ -167,38 +113,13 @@ func (t *Outbound) start() error {
 	return nil
 }
 
-func (t *Outbound) recvLoop() {
-	for rawEvent := range t.events {
-		switch event := rawEvent.(type) {
-		case *control.LogEvent:
-			event.Raw = strings.ToLower(event.Raw)
-			switch event.Severity {
-			case control.EventCodeLogDebug, control.EventCodeLogInfo:
-				t.logger.Trace(event.Raw)
-			case control.EventCodeLogNotice:
-				if strings.Contains(event.Raw, "disablenetwork") || strings.Contains(event.Raw, "socks listener") {
-					t.logger.Trace(event.Raw)
-					continue
-				}
-				t.logger.Info(event.Raw)
-			case control.EventCodeLogWarn:
-				t.logger.Warn(event.Raw)
-			case control.EventCodeLogErr:
-				t.logger.Error(event.Raw)
-			}
-		}
-	}
-}
-
 func (t *Outbound) Close() error {
 	err := common.Close(
 		common.PtrOrNil(t.proxy),
+		common.PtrOrNil(t.events),
 		common.PtrOrNil(t.instance),
 	)
-	if t.events != nil {
-		close(t.events)
-		t.events = nil
-	}
+	t.events = nil
 	return err
 }
 
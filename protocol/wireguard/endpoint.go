@@ -100,6 +100,7 @@ func NewEndpoint(ctx context.Context, router adapter.Router, logger log.ContextL
 			}
 		}),
 		Workers: options.Workers,
+		Amnezia: newAmneziaOptions(options.Amnezia),
 	})
 	if err != nil {
 		return nil, err
@@ -210,3 +211,20 @@ func (w *Endpoint) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	}
 	return w.endpoint.ListenPacket(ctx, destination)
 }
+
+func newAmneziaOptions(options *option.WireGuardAmneziaOptions) *wireguard.AmneziaOptions {
+	if options == nil {
+		return nil
+	}
+	return &wireguard.AmneziaOptions{
+		JunkPacketCount:            options.JunkPacketCount,
+		JunkPacketMinSize:          options.JunkPacketMinSize,
+		JunkPacketMaxSize:          options.JunkPacketMaxSize,
+		InitPacketJunkSize:         options.InitPacketJunkSize,
+		ResponsePacketJunkSize:     options.ResponsePacketJunkSize,
+		InitPacketMagicHeader:      options.InitPacketMagicHeader,
+		ResponsePacketMagicHeader:  options.ResponsePacketMagicHeader,
+		UnderloadPacketMagicHeader: options.UnderloadPacketMagicHeader,
+		TransportPacketMagicHeader: options.TransportPacketMagicHeader,
+	}
+}
@@ -49,6 +49,11 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if options.Detour != "" && options.GSO {
 		return nil, E.New("gso is conflict with detour")
 	}
+	if hasServerList(options.ServerOptions) || common.Any(options.Peers, func(it option.LegacyWireGuardPeer) bool {
+		return hasServerList(it.ServerOptions)
+	}) {
+		return nil, E.New("servers, server_strategy and server_cooldown are not supported by wireguard")
+	}
 	outboundDialer, err := dialer.NewWithOptions(dialer.Options{
 		Context: ctx,
 		Options: options.DialerOptions,
@@ -110,6 +115,12 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	return outbound, nil
 }
 
+// hasServerList reports whether alternative servers are configured, which the
+// wireguard peer endpoints can not use.
+func hasServerList(options option.ServerOptions) bool {
+	return len(options.Servers) > 0 || options.ServerStrategy != "" || options.ServerCooldown != 0
+}
+
 func (o *Outbound) Start(stage adapter.StartStage) error {
 	switch stage {
 	case adapter.StartStateStart:
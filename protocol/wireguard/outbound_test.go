@@ -0,0 +1,29 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboundRejectServerList(t *testing.T) {
+	t.Parallel()
+	for _, serverOptions := range []option.ServerOptions{
+		{Server: "10.0.0.1", ServerPort: 51820, Servers: []string{"10.0.0.2"}},
+		{Server: "10.0.0.1", ServerPort: 51820, ServerStrategy: "random"},
+		{Server: "10.0.0.1", ServerPort: 51820, ServerCooldown: 1},
+	} {
+		_, err := NewOutbound(context.Background(), nil, log.NewNOPFactory().NewLogger("wireguard"), "wireguard", option.LegacyWireGuardOutboundOptions{
+			ServerOptions: serverOptions,
+		})
+		require.ErrorContains(t, err, "not supported by wireguard")
+		_, err = NewOutbound(context.Background(), nil, log.NewNOPFactory().NewLogger("wireguard"), "wireguard", option.LegacyWireGuardOutboundOptions{
+			Peers: []option.LegacyWireGuardPeer{{ServerOptions: serverOptions}},
+		})
+		require.ErrorContains(t, err, "not supported by wireguard")
+	}
+}
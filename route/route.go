@@ -101,11 +101,12 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	if selectedRule != nil {
 		switch action := selectedRule.Action().(type) {
 		case *R.RuleActionRoute:
+			outboundTag := routeOutbound(&metadata, action)
 			var loaded bool
-			selectedOutbound, loaded = r.outbound.Outbound(action.Outbound)
+			selectedOutbound, loaded = r.outbound.Outbound(outboundTag)
 			if !loaded {
 				buf.ReleaseMulti(buffers)
-				return E.New("outbound not found: ", action.Outbound)
+				return E.New("outbound not found: ", outboundTag)
 			}
 			if !common.Contains(selectedOutbound.Network(), N.NetworkTCP) {
 				buf.ReleaseMulti(buffers)
@@ -123,7 +124,11 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 		}
 	}
 	if selectedRule == nil {
-		defaultOutbound := r.outbound.Default()
+		defaultOutbound, err := r.defaultOutbound(&metadata)
+		if err != nil {
+			buf.ReleaseMulti(buffers)
+			return err
+		}
 		if !common.Contains(defaultOutbound.Network(), N.NetworkTCP) {
 			buf.ReleaseMulti(buffers)
 			return E.New("TCP is not supported by default outbound: ", defaultOutbound.Tag())
@@ -134,6 +139,14 @@ func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata ad
 	for _, buffer := range buffers {
 		conn = bufio.NewCachedConn(conn, buffer)
 	}
+	if selectedRule != nil {
+		if interruptGroup := R.ScheduleInterruptGroup(selectedRule); interruptGroup != nil {
+			conn = interruptGroup.NewConn(conn, true)
+		}
+	}
+	if len(r.trackers) > 0 {
+		metadata.RemoteDestination = new(common.TypedValue[M.Socksaddr])
+	}
 	for _, tracker := range r.trackers {
 		conn = tracker.RoutedConnection(ctx, conn, metadata, selectedRule, selectedOutbound)
 	}
@@ -216,11 +229,12 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 	if selectedRule != nil {
 		switch action := selectedRule.Action().(type) {
 		case *R.RuleActionRoute:
+			outboundTag := routeOutbound(&metadata, action)
 			var loaded bool
-			selectedOutbound, loaded = r.outbound.Outbound(action.Outbound)
+			selectedOutbound, loaded = r.outbound.Outbound(outboundTag)
 			if !loaded {
 				N.ReleaseMultiPacketBuffer(packetBuffers)
-				return E.New("outbound not found: ", action.Outbound)
+				return E.New("outbound not found: ", outboundTag)
 			}
 			if !common.Contains(selectedOutbound.Network(), N.NetworkUDP) {
 				N.ReleaseMultiPacketBuffer(packetBuffers)
@@ -234,7 +248,11 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		}
 	}
 	if selectedRule == nil || selectReturn {
-		defaultOutbound := r.outbound.Default()
+		defaultOutbound, err := r.defaultOutbound(&metadata)
+		if err != nil {
+			N.ReleaseMultiPacketBuffer(packetBuffers)
+			return err
+		}
 		if !common.Contains(defaultOutbound.Network(), N.NetworkUDP) {
 			N.ReleaseMultiPacketBuffer(packetBuffers)
 			return E.New("UDP is not supported by outbound: ", defaultOutbound.Tag())
@@ -245,6 +263,14 @@ func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, m
 		conn = bufio.NewCachedPacketConn(conn, buffer.Buffer, buffer.Destination)
 		N.PutPacketBuffer(buffer)
 	}
+	if selectedRule != nil {
+		if interruptGroup := R.ScheduleInterruptGroup(selectedRule); interruptGroup != nil {
+			conn = interruptGroup.NewSingPacketConn(conn, true)
+		}
+	}
+	if len(r.trackers) > 0 {
+		metadata.RemoteDestination = new(common.TypedValue[M.Socksaddr])
+	}
 	for _, tracker := range r.trackers {
 		conn = tracker.RoutedPacketConnection(ctx, conn, metadata, selectedRule, selectedOutbound)
 	}
@@ -274,6 +300,28 @@ func (r *Router) PreMatch(metadata adapter.InboundContext) error {
 	return rejectAction.Error(context.Background())
 }
 
+// defaultOutbound returns the outbound for connections that matched no rule,
+// which is the one fixed by the inbound if any.
+func (r *Router) defaultOutbound(metadata *adapter.InboundContext) (adapter.Outbound, error) {
+	if metadata.RouteOutbound != "" {
+		outbound, loaded := r.outbound.Outbound(metadata.RouteOutbound)
+		if !loaded {
+			return nil, E.New("outbound not found: ", metadata.RouteOutbound)
+		}
+		return outbound, nil
+	}
+	return r.outbound.Default(), nil
+}
+
+// routeOutbound returns the outbound of a route rule, which is replaced by
+// the one fixed by the inbound if any.
+func routeOutbound(metadata *adapter.InboundContext, action *R.RuleActionRoute) string {
+	if metadata.RouteOutbound != "" {
+		return metadata.RouteOutbound
+	}
+	return action.Outbound
+}
+
 func (r *Router) matchRule(
 	ctx context.Context, metadata *adapter.InboundContext, preMatch bool,
 	inputConn net.Conn, inputPacketConn N.PacketConn,
@@ -312,6 +360,18 @@ func (r *Router) matchRule(
 			metadata.ProcessInfo = processInfo
 		}
 	}
+	if r.neighborResolver != nil && metadata.SourceMACAddress == nil && metadata.Source.IsIP() {
+		sourceAddress := metadata.Source.Addr
+		metadata.SourceMACAddress, _ = r.neighborResolver.LookupMAC(sourceAddress)
+		metadata.SourceHostname, _ = r.neighborResolver.LookupHostname(sourceAddress, metadata.SourceMACAddress)
+		if metadata.SourceMACAddress != nil {
+			if metadata.SourceHostname != "" {
+				r.logger.DebugContext(ctx, "found neighbor: ", metadata.SourceMACAddress, ", hostname: ", metadata.SourceHostname)
+			} else {
+				r.logger.DebugContext(ctx, "found neighbor: ", metadata.SourceMACAddress)
+			}
+		}
+	}
 	if metadata.Destination.Addr.IsValid() && r.dnsTransport.FakeIP() != nil && r.dnsTransport.FakeIP().Store().Contains(metadata.Destination.Addr) {
 		domain, loaded := r.dnsTransport.FakeIP().Store().Lookup(metadata.Destination.Addr)
 		if !loaded {
@@ -2,10 +2,13 @@ package route
 
 import (
 	"context"
+	"net/netip"
 	"os"
 	"runtime"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-box/common/neighbor"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
@@ -16,6 +19,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/task"
 	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 	"github.com/sagernet/sing/service/pause"
 )
 
@@ -35,6 +39,12 @@ type Router struct {
 	ruleSets          []adapter.RuleSet
 	ruleSetMap        map[string]adapter.RuleSet
 	processSearcher   process.Searcher
+	needFindNeighbor  bool
+	dhcpLeaseFiles    []string
+	neighborResolver  *neighbor.Resolver
+	needASNReader     bool
+	asnDatabasePath   string
+	asnReader         *geoip.ASNReader
 	pauseManager      pause.Manager
 	trackers          []adapter.ConnectionTracker
 	platformInterface platform.Interface
@@ -43,7 +53,7 @@ type Router struct {
 }
 
 func NewRouter(ctx context.Context, logFactory log.Factory, options option.RouteOptions, dnsOptions option.DNSOptions) *Router {
-	return &Router{
+	router := &Router{
 		ctx:               ctx,
 		logger:            logFactory.NewLogger("router"),
 		inbound:           service.FromContext[adapter.InboundManager](ctx),
@@ -55,10 +65,17 @@ func NewRouter(ctx context.Context, logFactory log.Factory, options option.Route
 		rules:             make([]adapter.Rule, 0, len(options.Rules)),
 		ruleSetMap:        make(map[string]adapter.RuleSet),
 		needFindProcess:   hasRule(options.Rules, isProcessRule) || hasDNSRule(dnsOptions.Rules, isProcessDNSRule) || options.FindProcess,
+		needFindNeighbor:  hasRule(options.Rules, isNeighborRule) || hasDNSRule(dnsOptions.Rules, isNeighborDNSRule) || options.FindNeighbor,
+		dhcpLeaseFiles:    options.DHCPLeaseFiles,
+		needASNReader:     hasRule(options.Rules, isASNRule) || hasDNSRule(dnsOptions.Rules, isASNDNSRule),
 		pauseManager:      service.FromContext[pause.Manager](ctx),
 		platformInterface: service.FromContext[platform.Interface](ctx),
 		needWIFIState:     hasRule(options.Rules, isWIFIRule) || hasDNSRule(dnsOptions.Rules, isWIFIDNSRule),
 	}
+	if options.ASNDatabase != nil {
+		router.asnDatabasePath = filemanager.BasePath(ctx, options.ASNDatabase.Path)
+	}
+	return router
 }
 
 func (r *Router) Initialize(rules []option.Rule, ruleSets []option.RuleSet) error {
@@ -122,6 +139,42 @@ func (r *Router) Start(stage adapter.StartStage) error {
 			if metadata.ContainsWIFIRule {
 				r.needWIFIState = true
 			}
+			if metadata.ContainsNeighborRule {
+				r.needFindNeighbor = true
+			}
+			if metadata.ContainsASNRule {
+				r.needASNReader = true
+			}
+		}
+		if r.needASNReader {
+			if r.asnDatabasePath == "" {
+				return E.New("missing asn_database for ASN rules")
+			}
+			monitor.Start("initialize asn database")
+			reader, err := geoip.OpenASN(r.asnDatabasePath)
+			monitor.Finish()
+			if err != nil {
+				return E.Cause(err, "open asn database")
+			}
+			r.asnReader = reader
+		}
+		var leaseProviders []adapter.DHCPLeaseProvider
+		for _, boxService := range service.FromContext[adapter.ServiceManager](r.ctx).Services() {
+			if provider, isProvider := boxService.(adapter.DHCPLeaseProvider); isProvider {
+				leaseProviders = append(leaseProviders, provider)
+			}
+		}
+		// clients of the built-in DHCP server are always resolved, so that
+		// their hostnames are shown in the Clash API
+		if r.needFindNeighbor || len(leaseProviders) > 0 {
+			monitor.Start("initialize neighbor resolver")
+			resolver := neighbor.NewResolver(r.logger, r.dhcpLeaseFiles, leaseProviders)
+			err := resolver.Start()
+			monitor.Finish()
+			if err != nil {
+				r.logger.Warn(E.Cause(err, "create neighbor resolver"))
+			}
+			r.neighborResolver = resolver
 		}
 		if needFindProcess {
 			if r.platformInterface != nil {
@@ -180,6 +233,20 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	if r.neighborResolver != nil {
+		monitor.Start("close neighbor resolver")
+		err = E.Append(err, r.neighborResolver.Close(), func(err error) error {
+			return E.Cause(err, "close neighbor resolver")
+		})
+		monitor.Finish()
+	}
+	if r.asnReader != nil {
+		monitor.Start("close asn database")
+		err = E.Append(err, r.asnReader.Close(), func(err error) error {
+			return E.Cause(err, "close asn database")
+		})
+		monitor.Finish()
+	}
 	for i, ruleSet := range r.ruleSets {
 		monitor.Start("close rule-set[", i, "]")
 		err = E.Append(err, ruleSet.Close(), func(err error) error {
@@ -199,6 +266,13 @@ func (r *Router) NeedWIFIState() bool {
 	return r.needWIFIState
 }
 
+func (r *Router) LookupASN(addr netip.Addr) (uint32, bool) {
+	if r.asnReader == nil {
+		return 0, false
+	}
+	return r.asnReader.Lookup(addr)
+}
+
 func (r *Router) Rules() []adapter.Rule {
 	return r.rules
 }
@@ -116,6 +116,9 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 				DisableCache: action.RouteOptions.DisableCache,
 				RewriteTTL:   action.RouteOptions.RewriteTTL,
 				ClientSubnet: netip.Prefix(common.PtrValueOrDefault(action.RouteOptions.ClientSubnet)),
+				ServeStale:   action.RouteOptions.ServeStale,
+				Prefetch:     action.RouteOptions.Prefetch,
+				DNSSEC:       action.RouteOptions.DNSSEC,
 			},
 		}
 	case C.RuleActionTypeRouteOptions:
@@ -124,6 +127,9 @@ func NewDNSRuleAction(logger logger.ContextLogger, action option.DNSRuleAction)
 			DisableCache: action.RouteOptionsOptions.DisableCache,
 			RewriteTTL:   action.RouteOptionsOptions.RewriteTTL,
 			ClientSubnet: netip.Prefix(common.PtrValueOrDefault(action.RouteOptionsOptions.ClientSubnet)),
+			ServeStale:   action.RouteOptionsOptions.ServeStale,
+			Prefetch:     action.RouteOptionsOptions.Prefetch,
+			DNSSEC:       action.RouteOptionsOptions.DNSSEC,
 		}
 	case C.RuleActionTypeReject:
 		return &RuleActionReject{
@@ -244,6 +250,15 @@ func (r *RuleActionDNSRoute) String() string {
 	if r.ClientSubnet.IsValid() {
 		descriptions = append(descriptions, F.ToString("client-subnet=", r.ClientSubnet))
 	}
+	if r.ServeStale != nil {
+		descriptions = append(descriptions, F.ToString("serve-stale=", *r.ServeStale))
+	}
+	if r.Prefetch != nil {
+		descriptions = append(descriptions, F.ToString("prefetch=", *r.Prefetch))
+	}
+	if r.DNSSEC != nil {
+		descriptions = append(descriptions, F.ToString("dnssec=", *r.DNSSEC))
+	}
 	return F.ToString("route(", strings.Join(descriptions, ","), ")")
 }
 
@@ -252,6 +267,9 @@ type RuleActionDNSRouteOptions struct {
 	DisableCache bool
 	RewriteTTL   *uint32
 	ClientSubnet netip.Prefix
+	ServeStale   *bool
+	Prefetch     *bool
+	DNSSEC       *bool
 }
 
 func (r *RuleActionDNSRouteOptions) Type() string {
@@ -269,6 +287,15 @@ func (r *RuleActionDNSRouteOptions) String() string {
 	if r.ClientSubnet.IsValid() {
 		descriptions = append(descriptions, F.ToString("client-subnet=", r.ClientSubnet))
 	}
+	if r.ServeStale != nil {
+		descriptions = append(descriptions, F.ToString("serve-stale=", *r.ServeStale))
+	}
+	if r.Prefetch != nil {
+		descriptions = append(descriptions, F.ToString("prefetch=", *r.Prefetch))
+	}
+	if r.DNSSEC != nil {
+		descriptions = append(descriptions, F.ToString("dnssec=", *r.DNSSEC))
+	}
 	return F.ToString("route-options(", strings.Join(descriptions, ","), ")")
 }
 
@@ -0,0 +1,71 @@
+package rule
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+)
+
+var _ RuleItem = (*ASNItem)(nil)
+
+type ASNItem struct {
+	router   adapter.Router
+	asnList  []option.ASN
+	asnMap   map[uint32]bool
+	isSource bool
+}
+
+func NewASNItem(router adapter.Router, asnList []option.ASN, isSource bool) *ASNItem {
+	asnMap := make(map[uint32]bool, len(asnList))
+	for _, asn := range asnList {
+		asnMap[uint32(asn)] = true
+	}
+	return &ASNItem{
+		router:   router,
+		asnList:  asnList,
+		asnMap:   asnMap,
+		isSource: isSource,
+	}
+}
+
+func (r *ASNItem) Match(metadata *adapter.InboundContext) bool {
+	if r.isSource {
+		return r.match(metadata.Source.Addr)
+	}
+	if metadata.Destination.IsIP() {
+		return r.match(metadata.Destination.Addr)
+	}
+	for _, address := range metadata.DestinationAddresses {
+		if r.match(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ASNItem) match(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	asn, loaded := r.router.LookupASN(addr.Unmap())
+	return loaded && r.asnMap[asn]
+}
+
+func (r *ASNItem) String() string {
+	var description string
+	if r.isSource {
+		description = "source_ip_asn="
+	} else {
+		description = "ip_asn="
+	}
+	asnLen := len(r.asnList)
+	if asnLen == 1 {
+		description += r.asnList[0].String()
+	} else {
+		description += "[" + strings.Join(common.Map(r.asnList, option.ASN.String), " ") + "]"
+	}
+	return description
+}
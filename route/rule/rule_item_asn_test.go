@@ -0,0 +1,77 @@
+package rule
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+	"go4.org/netipx"
+)
+
+func TestASNItem(t *testing.T) {
+	t.Parallel()
+	router := &testASNRouter{asn: map[netip.Addr]uint32{
+		netip.MustParseAddr("1.1.1.1"):              13335,
+		netip.MustParseAddr("8.8.8.8"):              15169,
+		netip.MustParseAddr("2606:4700:4700::1111"): 13335,
+	}}
+	item := NewASNItem(router, []option.ASN{13335}, false)
+	require.True(t, item.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("1.1.1.1:443")}))
+	require.True(t, item.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("[::ffff:1.1.1.1]:443")}))
+	require.False(t, item.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("8.8.8.8:443")}))
+	require.True(t, item.Match(&adapter.InboundContext{
+		Destination:          M.ParseSocksaddr("one.one.one.one:443"),
+		DestinationAddresses: []netip.Addr{netip.MustParseAddr("8.8.8.8"), netip.MustParseAddr("2606:4700:4700::1111")},
+	}))
+	require.False(t, item.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("one.one.one.one:443")}))
+	require.Equal(t, "ip_asn=AS13335", item.String())
+
+	sourceItem := NewASNItem(router, []option.ASN{13335, 15169}, true)
+	require.True(t, sourceItem.Match(&adapter.InboundContext{Source: M.ParseSocksaddr("8.8.8.8:53")}))
+	require.False(t, sourceItem.Match(&adapter.InboundContext{Source: M.ParseSocksaddr("9.9.9.9:53")}))
+}
+
+func TestASNHeadlessRule(t *testing.T) {
+	t.Parallel()
+	router := &testASNRouter{asn: map[netip.Addr]uint32{
+		netip.MustParseAddr("1.1.1.1"): 13335,
+	}}
+	ctx := service.ContextWith[adapter.Router](context.Background(), router)
+	unexpanded := option.DefaultHeadlessRule{ASN: []option.ASN{13335}}
+	require.True(t, isASNHeadlessRule(unexpanded))
+	require.False(t, isIPCIDRHeadlessRule(unexpanded))
+	rule, err := NewDefaultHeadlessRule(ctx, unexpanded)
+	require.NoError(t, err)
+	require.True(t, rule.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("1.1.1.1:443")}))
+	require.Empty(t, extractIPSetFromRule(rule))
+
+	// rules compiled against a database carry the expanded prefixes instead
+	var builder netipx.IPSetBuilder
+	builder.AddPrefix(netip.MustParsePrefix("104.16.0.0/13"))
+	ipSet, err := builder.IPSet()
+	require.NoError(t, err)
+	expanded := option.DefaultHeadlessRule{ASN: []option.ASN{13335}, ASNIPSet: ipSet}
+	require.False(t, isASNHeadlessRule(expanded))
+	require.True(t, isIPCIDRHeadlessRule(expanded))
+	rule, err = NewDefaultHeadlessRule(ctx, expanded)
+	require.NoError(t, err)
+	require.True(t, rule.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("104.16.1.1:443")}))
+	require.False(t, rule.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("1.1.1.1:443")}))
+	require.Equal(t, []*netipx.IPSet{ipSet}, extractIPSetFromRule(rule))
+}
+
+type testASNRouter struct {
+	adapter.Router
+	asn map[netip.Addr]uint32
+}
+
+func (r *testASNRouter) LookupASN(addr netip.Addr) (uint32, bool) {
+	asn, loaded := r.asn[addr]
+	return asn, loaded
+}
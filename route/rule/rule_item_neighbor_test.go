@@ -0,0 +1,55 @@
+package rule
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceMACAddressItem(t *testing.T) {
+	t.Parallel()
+	item, err := NewSourceMACAddressItem([]string{"AA-BB-CC-DD-EE-01", "aa:bb:cc:dd:ee:02"})
+	require.NoError(t, err)
+	tablet, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+	other, _ := net.ParseMAC("aa:bb:cc:dd:ee:03")
+	require.True(t, item.Match(&adapter.InboundContext{SourceMACAddress: tablet}))
+	require.False(t, item.Match(&adapter.InboundContext{SourceMACAddress: other}))
+	require.False(t, item.Match(&adapter.InboundContext{}))
+	require.Equal(t, "source_mac_address=[AA-BB-CC-DD-EE-01 aa:bb:cc:dd:ee:02]", item.String())
+
+	_, err = NewSourceMACAddressItem([]string{"tablet"})
+	require.ErrorContains(t, err, "parse source_mac_address")
+}
+
+func TestSourceHostnameItem(t *testing.T) {
+	t.Parallel()
+	item := NewSourceHostnameItem([]string{"Kids-Tablet"})
+	require.True(t, item.Match(&adapter.InboundContext{SourceHostname: "kids-tablet"}))
+	require.False(t, item.Match(&adapter.InboundContext{SourceHostname: "laptop"}))
+	require.False(t, item.Match(&adapter.InboundContext{}))
+	require.Equal(t, "source_hostname=Kids-Tablet", item.String())
+}
+
+func TestNeighborHeadlessRule(t *testing.T) {
+	t.Parallel()
+	options := option.DefaultHeadlessRule{
+		SourceMACAddress: []string{"aa:bb:cc:dd:ee:01"},
+		SourceHostname:   []string{"kids-tablet"},
+	}
+	require.True(t, isNeighborHeadlessRule(options))
+	rule, err := NewDefaultHeadlessRule(context.Background(), options)
+	require.NoError(t, err)
+	tablet, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+	// items of different kinds must all match
+	require.True(t, rule.Match(&adapter.InboundContext{SourceMACAddress: tablet, SourceHostname: "kids-tablet"}))
+	require.False(t, rule.Match(&adapter.InboundContext{SourceMACAddress: tablet, SourceHostname: "laptop"}))
+	require.False(t, rule.Match(&adapter.InboundContext{SourceHostname: "kids-tablet"}))
+
+	_, err = NewDefaultHeadlessRule(context.Background(), option.DefaultHeadlessRule{SourceMACAddress: []string{"tablet"}})
+	require.Error(t, err)
+}
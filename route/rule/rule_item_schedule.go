@@ -0,0 +1,233 @@
+package rule
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/interrupt"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*ScheduleItem)(nil)
+
+type ScheduleItem struct {
+	options        option.RuleSchedule
+	location       *time.Location
+	weekdays       [7]bool
+	ranges         []scheduleRange
+	interruptGroup *interrupt.Group
+	access         sync.Mutex
+	timer          *time.Timer
+	active         bool
+}
+
+// scheduleRange is a daily window in minutes since midnight. A range whose
+// end is not after its start crosses midnight and belongs to the weekday it
+// starts on.
+type scheduleRange struct {
+	start int
+	end   int
+}
+
+func NewScheduleItem(options option.RuleSchedule, interruptExistConnections bool) (*ScheduleItem, error) {
+	item := &ScheduleItem{
+		options:  options,
+		location: time.Local,
+	}
+	if options.TimeZone != "" {
+		location, err := time.LoadLocation(options.TimeZone)
+		if err != nil {
+			return nil, E.Cause(err, "parse time_zone")
+		}
+		item.location = location
+	}
+	if len(options.Weekday) == 0 {
+		for i := range item.weekdays {
+			item.weekdays[i] = true
+		}
+	}
+	for _, weekdayName := range options.Weekday {
+		weekday, err := parseWeekday(weekdayName)
+		if err != nil {
+			return nil, err
+		}
+		item.weekdays[weekday] = true
+	}
+	for _, timeRange := range options.Time {
+		parsedRange, err := parseScheduleRange(timeRange)
+		if err != nil {
+			return nil, err
+		}
+		item.ranges = append(item.ranges, parsedRange)
+	}
+	if len(item.ranges) == 0 {
+		item.ranges = []scheduleRange{{0, 24 * 60}}
+	}
+	if interruptExistConnections {
+		item.interruptGroup = interrupt.NewGroup()
+	}
+	return item, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	lowerName := strings.ToLower(name)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		fullName := strings.ToLower(weekday.String())
+		if lowerName == fullName || lowerName == fullName[:3] {
+			return weekday, nil
+		}
+	}
+	return 0, E.New("invalid weekday: ", name)
+}
+
+func parseScheduleRange(timeRange string) (scheduleRange, error) {
+	startString, endString, found := strings.Cut(timeRange, "-")
+	if !found {
+		return scheduleRange{}, E.New("invalid time range: ", timeRange)
+	}
+	start, err := parseClock(startString)
+	if err != nil || start == 24*60 {
+		return scheduleRange{}, E.New("invalid time range: ", timeRange)
+	}
+	end, err := parseClock(endString)
+	if err != nil || start == end {
+		return scheduleRange{}, E.New("invalid time range: ", timeRange)
+	}
+	return scheduleRange{start, end}, nil
+}
+
+func parseClock(clock string) (int, error) {
+	hourString, minuteString, found := strings.Cut(strings.TrimSpace(clock), ":")
+	if !found {
+		return 0, E.New("missing minutes")
+	}
+	hour, err := strconv.Atoi(hourString)
+	if err != nil {
+		return 0, err
+	}
+	minute, err := strconv.Atoi(minuteString)
+	if err != nil {
+		return 0, err
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute != 0 {
+		return 0, E.New("clock out of range")
+	}
+	return hour*60 + minute, nil
+}
+
+func (r *ScheduleItem) Start() error {
+	if r.interruptGroup == nil {
+		return nil
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	r.active = r.matchTime(time.Now())
+	r.resetTimer()
+	return nil
+}
+
+func (r *ScheduleItem) resetTimer() {
+	now := time.Now()
+	r.timer = time.AfterFunc(now.Truncate(time.Minute).Add(time.Minute).Sub(now), r.checkWindow)
+}
+
+// checkWindow runs at every minute boundary and interrupts the connections
+// routed by the rule once its window closes.
+func (r *ScheduleItem) checkWindow() {
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.timer == nil {
+		return
+	}
+	active := r.matchTime(time.Now())
+	if r.active && !active {
+		r.interruptGroup.Interrupt(true)
+	}
+	r.active = active
+	r.resetTimer()
+}
+
+func (r *ScheduleItem) Close() error {
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	return nil
+}
+
+func (r *ScheduleItem) InterruptGroup() *interrupt.Group {
+	return r.interruptGroup
+}
+
+func (r *ScheduleItem) Match(metadata *adapter.InboundContext) bool {
+	return r.matchTime(time.Now())
+}
+
+func (r *ScheduleItem) matchTime(now time.Time) bool {
+	now = now.In(r.location)
+	minutes := now.Hour()*60 + now.Minute()
+	weekday := now.Weekday()
+	yesterday := (weekday + 6) % 7
+	for _, timeRange := range r.ranges {
+		if timeRange.start < timeRange.end {
+			if r.weekdays[weekday] && minutes >= timeRange.start && minutes < timeRange.end {
+				return true
+			}
+		} else {
+			if r.weekdays[weekday] && minutes >= timeRange.start || r.weekdays[yesterday] && minutes < timeRange.end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *ScheduleItem) String() string {
+	var description []string
+	if len(r.options.Weekday) > 0 {
+		description = append(description, "weekday=["+strings.Join(r.options.Weekday, " ")+"]")
+	}
+	if len(r.options.Time) > 0 {
+		description = append(description, "time=["+strings.Join(r.options.Time, " ")+"]")
+	}
+	if r.options.TimeZone != "" {
+		description = append(description, "time_zone="+r.options.TimeZone)
+	}
+	return F.ToString("schedule=(", strings.Join(description, " "), ")")
+}
+
+// ScheduleInterruptGroup returns the group that collects connections routed
+// by a rule with a schedule that interrupts existing connections. Only
+// schedules that every match depends on count, so or and inverted rules,
+// which may match by another branch, have none.
+func ScheduleInterruptGroup(rule adapter.HeadlessRule) *interrupt.Group {
+	switch typedRule := rule.(type) {
+	case *DefaultRule:
+		if typedRule.invert {
+			return nil
+		}
+		for _, item := range typedRule.allItems {
+			if scheduleItem, isSchedule := item.(*ScheduleItem); isSchedule && scheduleItem.interruptGroup != nil {
+				return scheduleItem.interruptGroup
+			}
+		}
+	case *LogicalRule:
+		if typedRule.mode != C.LogicalTypeAnd || typedRule.invert {
+			return nil
+		}
+		for _, subRule := range typedRule.rules {
+			if group := ScheduleInterruptGroup(subRule); group != nil {
+				return group
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleItem(t *testing.T) {
+	t.Parallel()
+	item, err := NewScheduleItem(option.RuleSchedule{
+		Weekday:  []string{"fri", "Saturday"},
+		Time:     []string{"09:00-18:00", "22:00-02:00"},
+		TimeZone: "UTC",
+	}, false)
+	require.NoError(t, err)
+	at := func(value string) time.Time {
+		parsed, parseErr := time.Parse(time.DateTime, value)
+		require.NoError(t, parseErr)
+		return parsed
+	}
+	// 2025-01-03 is a Friday
+	require.True(t, item.matchTime(at("2025-01-03 09:00:00")))
+	require.False(t, item.matchTime(at("2025-01-03 18:00:00")))
+	require.True(t, item.matchTime(at("2025-01-03 23:30:00")))
+	require.True(t, item.matchTime(at("2025-01-04 01:59:00")))
+	require.True(t, item.matchTime(at("2025-01-05 01:00:00")))
+	require.False(t, item.matchTime(at("2025-01-05 02:00:00")))
+	require.False(t, item.matchTime(at("2025-01-03 01:00:00")))
+	require.False(t, item.matchTime(at("2025-01-05 10:00:00")))
+
+	_, err = NewScheduleItem(option.RuleSchedule{Time: []string{"10:00-10:00"}}, false)
+	require.Error(t, err)
+	_, err = NewScheduleItem(option.RuleSchedule{Weekday: []string{"someday"}}, false)
+	require.Error(t, err)
+}
+
+func TestScheduleInterruptGroup(t *testing.T) {
+	t.Parallel()
+	item, err := NewScheduleItem(option.RuleSchedule{Time: []string{"09:00-18:00"}}, true)
+	require.NoError(t, err)
+	scheduleRule := &DefaultRule{abstractDefaultRule{allItems: []RuleItem{item}}}
+	otherRule := &DefaultRule{abstractDefaultRule{allItems: []RuleItem{NewNetworkItem([]string{"tcp"})}}}
+	require.Equal(t, item.interruptGroup, ScheduleInterruptGroup(scheduleRule))
+	require.Nil(t, ScheduleInterruptGroup(otherRule))
+	require.Nil(t, ScheduleInterruptGroup(&DefaultRule{abstractDefaultRule{allItems: []RuleItem{item}, invert: true}}))
+
+	// the schedule applies to every match of an and rule only
+	require.Equal(t, item.interruptGroup, ScheduleInterruptGroup(&LogicalRule{abstractLogicalRule{
+		rules: []adapter.HeadlessRule{otherRule, scheduleRule},
+		mode:  C.LogicalTypeAnd,
+	}}))
+	require.Nil(t, ScheduleInterruptGroup(&LogicalRule{abstractLogicalRule{
+		rules: []adapter.HeadlessRule{otherRule, scheduleRule},
+		mode:  C.LogicalTypeOr,
+	}}))
+	require.Nil(t, ScheduleInterruptGroup(&LogicalRule{abstractLogicalRule{
+		rules:  []adapter.HeadlessRule{scheduleRule},
+		mode:   C.LogicalTypeAnd,
+		invert: true,
+	}}))
+}
@@ -0,0 +1,37 @@
+package rule
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*SourceHostnameItem)(nil)
+
+type SourceHostnameItem struct {
+	hostnameList []string
+	hostnameMap  map[string]bool
+}
+
+func NewSourceHostnameItem(hostnameList []string) *SourceHostnameItem {
+	hostnameMap := make(map[string]bool)
+	for _, hostname := range hostnameList {
+		hostnameMap[strings.ToLower(hostname)] = true
+	}
+	return &SourceHostnameItem{
+		hostnameList,
+		hostnameMap,
+	}
+}
+
+func (r *SourceHostnameItem) Match(metadata *adapter.InboundContext) bool {
+	return metadata.SourceHostname != "" && r.hostnameMap[strings.ToLower(metadata.SourceHostname)]
+}
+
+func (r *SourceHostnameItem) String() string {
+	if len(r.hostnameList) == 1 {
+		return F.ToString("source_hostname=", r.hostnameList[0])
+	}
+	return F.ToString("source_hostname=[", strings.Join(r.hostnameList, " "), "]")
+}
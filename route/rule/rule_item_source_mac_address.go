@@ -0,0 +1,43 @@
+package rule
+
+import (
+	"net"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*SourceMACAddressItem)(nil)
+
+type SourceMACAddressItem struct {
+	addressList []string
+	addressMap  map[string]bool
+}
+
+func NewSourceMACAddressItem(addressList []string) (*SourceMACAddressItem, error) {
+	addressMap := make(map[string]bool)
+	for _, address := range addressList {
+		hardwareAddr, err := net.ParseMAC(address)
+		if err != nil {
+			return nil, E.Cause(err, "parse source_mac_address")
+		}
+		addressMap[hardwareAddr.String()] = true
+	}
+	return &SourceMACAddressItem{
+		addressList,
+		addressMap,
+	}, nil
+}
+
+func (r *SourceMACAddressItem) Match(metadata *adapter.InboundContext) bool {
+	return metadata.SourceMACAddress != nil && r.addressMap[metadata.SourceMACAddress.String()]
+}
+
+func (r *SourceMACAddressItem) String() string {
+	if len(r.addressList) == 1 {
+		return F.ToString("source_mac_address=", r.addressList[0])
+	}
+	return F.ToString("source_mac_address=[", strings.Join(r.addressList, " "), "]")
+}
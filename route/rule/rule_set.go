@@ -18,7 +18,7 @@ func NewRuleSet(ctx context.Context, logger logger.ContextLogger, options option
 	case C.RuleSetTypeInline, C.RuleSetTypeLocal, "":
 		return NewLocalRuleSet(ctx, logger, options)
 	case C.RuleSetTypeRemote:
-		return NewRemoteRuleSet(ctx, logger, options), nil
+		return NewRemoteRuleSet(ctx, logger, options)
 	default:
 		return nil, E.New("unknown rule-set type: ", options.Type)
 	}
@@ -66,6 +66,16 @@ func isWIFIHeadlessRule(rule option.DefaultHeadlessRule) bool {
 	return len(rule.WIFISSID) > 0 || len(rule.WIFIBSSID) > 0
 }
 
+func isASNHeadlessRule(rule option.DefaultHeadlessRule) bool {
+	return len(rule.ASN) > 0 && rule.ASNIPSet == nil
+}
+
+func isNeighborHeadlessRule(rule option.DefaultHeadlessRule) bool {
+	return len(rule.SourceMACAddress) > 0 || len(rule.SourceHostname) > 0
+}
+
 func isIPCIDRHeadlessRule(rule option.DefaultHeadlessRule) bool {
-	return len(rule.IPCIDR) > 0 || rule.IPSet != nil
+	// unexpanded asn items match against the database at runtime and carry
+	// no IP set
+	return len(rule.IPCIDR) > 0 || rule.IPSet != nil || rule.ASNIPSet != nil
 }
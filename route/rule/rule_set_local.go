@@ -10,6 +10,7 @@ import (
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -119,7 +120,19 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.fileFormat)
+		setFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		rules, err := convertor.ToOptions(s.fileFormat, setFile, s.logger)
+		setFile.Close()
+		if err != nil {
+			return err
+		}
+		ruleSet = option.PlainRuleSetCompat{
+			Version: C.RuleSetVersionCurrent,
+			Options: option.PlainRuleSet{Rules: rules},
+		}
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -140,6 +153,8 @@ func (s *LocalRuleSet) reloadRules(headlessRules []option.HeadlessRule) error {
 	var metadata adapter.RuleSetMetadata
 	metadata.ContainsProcessRule = hasHeadlessRule(headlessRules, isProcessHeadlessRule)
 	metadata.ContainsWIFIRule = hasHeadlessRule(headlessRules, isWIFIHeadlessRule)
+	metadata.ContainsNeighborRule = hasHeadlessRule(headlessRules, isNeighborHeadlessRule)
+	metadata.ContainsASNRule = hasHeadlessRule(headlessRules, isASNHeadlessRule)
 	metadata.ContainsIPCIDRRule = hasHeadlessRule(headlessRules, isIPCIDRHeadlessRule)
 	s.access.Lock()
 	s.rules = rules
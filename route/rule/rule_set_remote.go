@@ -3,7 +3,9 @@ package rule
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"io"
 	"net"
 	"net/http"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convertor"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -52,16 +55,37 @@ type RemoteRuleSet struct {
 	pauseManager   pause.Manager
 	callbacks      list.List[adapter.RuleSetUpdateCallback]
 	refs           atomic.Int32
+	sha256         []byte
+	publicKeys     []*srs.PublicKey
 }
 
-func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options option.RuleSet) *RemoteRuleSet {
-	ctx, cancel := context.WithCancel(ctx)
+func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options option.RuleSet) (*RemoteRuleSet, error) {
 	var updateInterval time.Duration
 	if options.RemoteOptions.UpdateInterval > 0 {
 		updateInterval = time.Duration(options.RemoteOptions.UpdateInterval)
 	} else {
 		updateInterval = 24 * time.Hour
 	}
+	var checksum []byte
+	if options.RemoteOptions.SHA256 != "" {
+		var err error
+		checksum, err = hex.DecodeString(options.RemoteOptions.SHA256)
+		if err != nil || len(checksum) != sha256.Size {
+			return nil, E.New("invalid sha256: ", options.RemoteOptions.SHA256)
+		}
+	}
+	publicKeys := make([]*srs.PublicKey, 0, len(options.RemoteOptions.PublicKey))
+	for i, keyContent := range options.RemoteOptions.PublicKey {
+		publicKey, err := srs.ParsePublicKey(keyContent)
+		if err != nil {
+			return nil, E.Cause(err, "parse public_key[", i, "]")
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	if len(publicKeys) > 0 && options.Format != C.RuleSetFormatBinary && options.RemoteOptions.SignatureURL == "" {
+		return nil, E.New("signature_url is required to verify rule-sets not in binary format")
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	return &RemoteRuleSet{
 		ctx:            ctx,
 		cancel:         cancel,
@@ -70,7 +94,9 @@ func NewRemoteRuleSet(ctx context.Context, logger logger.ContextLogger, options
 		options:        options,
 		updateInterval: updateInterval,
 		pauseManager:   service.FromContext[pause.Manager](ctx),
-	}
+		sha256:         checksum,
+		publicKeys:     publicKeys,
+	}, nil
 }
 
 func (s *RemoteRuleSet) Name() string {
@@ -96,12 +122,17 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext *adapter.
 	s.dialer = dialer
 	if s.cacheFile != nil {
 		if savedSet := s.cacheFile.LoadRuleSet(s.options.Tag); savedSet != nil {
-			err := s.loadBytes(savedSet.Content)
+			err := s.verifyCache(savedSet)
 			if err != nil {
-				return E.Cause(err, "restore cached rule-set")
+				s.logger.Error(E.Cause(err, "ignore cached rule-set ", s.options.Tag))
+			} else {
+				err = s.loadBytes(savedSet.Content)
+				if err != nil {
+					return E.Cause(err, "restore cached rule-set")
+				}
+				s.lastUpdated = savedSet.LastUpdated
+				s.lastEtag = savedSet.LastEtag
 			}
-			s.lastUpdated = savedSet.LastUpdated
-			s.lastEtag = savedSet.LastEtag
 		}
 	}
 	if s.lastUpdated.IsZero() {
@@ -176,7 +207,15 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.options.Format)
+		var rules []option.HeadlessRule
+		rules, err = convertor.ToOptions(s.options.Format, bytes.NewReader(content), s.logger)
+		if err != nil {
+			return err
+		}
+		ruleSet = option.PlainRuleSetCompat{
+			Version: C.RuleSetVersionCurrent,
+			Options: option.PlainRuleSet{Rules: rules},
+		}
 	}
 	plainRuleSet, err := ruleSet.Upgrade()
 	if err != nil {
@@ -192,6 +231,8 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 	s.access.Lock()
 	s.metadata.ContainsProcessRule = hasHeadlessRule(plainRuleSet.Rules, isProcessHeadlessRule)
 	s.metadata.ContainsWIFIRule = hasHeadlessRule(plainRuleSet.Rules, isWIFIHeadlessRule)
+	s.metadata.ContainsNeighborRule = hasHeadlessRule(plainRuleSet.Rules, isNeighborHeadlessRule)
+	s.metadata.ContainsASNRule = hasHeadlessRule(plainRuleSet.Rules, isASNHeadlessRule)
 	s.metadata.ContainsIPCIDRRule = hasHeadlessRule(plainRuleSet.Rules, isIPCIDRHeadlessRule)
 	s.rules = rules
 	callbacks := s.callbacks.Array()
@@ -287,6 +328,12 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 		response.Body.Close()
 		return err
 	}
+	// the current rules and the cached copy stay in use if verification fails
+	signature, err := s.verify(ctx, httpClient, content)
+	if err != nil {
+		response.Body.Close()
+		return E.Cause(err, "verify rule-set")
+	}
 	err = s.loadBytes(content)
 	if err != nil {
 		response.Body.Close()
@@ -303,6 +350,7 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 			LastUpdated: s.lastUpdated,
 			Content:     content,
 			LastEtag:    s.lastEtag,
+			Signature:   signature,
 		})
 		if err != nil {
 			s.logger.Error("save rule-set cache: ", err)
@@ -312,6 +360,72 @@ func (s *RemoteRuleSet) fetch(ctx context.Context, startContext *adapter.HTTPSta
 	return nil
 }
 
+// verify checks downloaded content and returns the detached signature, if
+// any, to be cached along with it.
+func (s *RemoteRuleSet) verify(ctx context.Context, httpClient *http.Client, content []byte) ([]byte, error) {
+	err := s.verifyChecksum(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.publicKeys) == 0 {
+		return nil, nil
+	}
+	if s.options.RemoteOptions.SignatureURL == "" {
+		return nil, srs.Verify(content, s.publicKeys)
+	}
+	request, err := http.NewRequest("GET", s.options.RemoteOptions.SignatureURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, E.Cause(err, "fetch signature")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("fetch signature: unexpected status: ", response.Status)
+	}
+	signature, err := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if err != nil {
+		return nil, E.Cause(err, "fetch signature")
+	}
+	err = srs.VerifyDetached(content, signature, s.publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// verifyCache checks a cached copy against the current options, so a
+// tampered cache file or a changed key cannot bypass verification.
+func (s *RemoteRuleSet) verifyCache(savedSet *adapter.SavedBinary) error {
+	err := s.verifyChecksum(savedSet.Content)
+	if err != nil {
+		return err
+	}
+	if len(s.publicKeys) == 0 {
+		return nil
+	}
+	if s.options.RemoteOptions.SignatureURL == "" {
+		return srs.Verify(savedSet.Content, s.publicKeys)
+	}
+	if len(savedSet.Signature) == 0 {
+		return E.New("missing cached signature")
+	}
+	return srs.VerifyDetached(savedSet.Content, savedSet.Signature, s.publicKeys)
+}
+
+func (s *RemoteRuleSet) verifyChecksum(content []byte) error {
+	if s.sha256 == nil {
+		return nil
+	}
+	checksum := sha256.Sum256(content)
+	if !bytes.Equal(checksum[:], s.sha256) {
+		return E.New("sha256 mismatch: got ", hex.EncodeToString(checksum[:]))
+	}
+	return nil
+}
+
 func (s *RemoteRuleSet) Close() error {
 	s.rules = nil
 	s.cancel()
@@ -0,0 +1,37 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteRuleSetVerifyCache(t *testing.T) {
+	t.Parallel()
+	privateKey, err := srs.GenerateKey()
+	require.NoError(t, err)
+	otherKey, err := srs.GenerateKey()
+	require.NoError(t, err)
+	content := []byte(`{"version":3,"rules":[]}`)
+	ruleSet := &RemoteRuleSet{
+		options: option.RuleSet{
+			RemoteOptions: option.RemoteRuleSet{SignatureURL: "https://example.org/rule-set.json.minisig"},
+		},
+		publicKeys: []*srs.PublicKey{privateKey.Public()},
+	}
+	signature := srs.SignDetached(content, privateKey, "rule-set")
+	savedBinary, err := (&adapter.SavedBinary{Content: content, Signature: signature}).MarshalBinary()
+	require.NoError(t, err)
+	var savedSet adapter.SavedBinary
+	require.NoError(t, savedSet.UnmarshalBinary(savedBinary))
+	require.NoError(t, ruleSet.verifyCache(&savedSet))
+
+	// cached copies are verified even when the signature is detached
+	require.Error(t, ruleSet.verifyCache(&adapter.SavedBinary{Content: []byte(`{"version":3}`), Signature: signature}))
+	require.Error(t, ruleSet.verifyCache(&adapter.SavedBinary{Content: content}))
+	require.Error(t, ruleSet.verifyCache(&adapter.SavedBinary{Content: content, Signature: srs.SignDetached(content, otherKey, "rule-set")}))
+}
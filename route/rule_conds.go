@@ -52,3 +52,19 @@ func isWIFIRule(rule option.DefaultRule) bool {
 func isWIFIDNSRule(rule option.DefaultDNSRule) bool {
 	return len(rule.WIFISSID) > 0 || len(rule.WIFIBSSID) > 0
 }
+
+func isASNRule(rule option.DefaultRule) bool {
+	return len(rule.IPASN) > 0 || len(rule.SourceIPASN) > 0
+}
+
+func isASNDNSRule(rule option.DefaultDNSRule) bool {
+	return len(rule.IPASN) > 0 || len(rule.SourceIPASN) > 0
+}
+
+func isNeighborRule(rule option.DefaultRule) bool {
+	return len(rule.SourceMACAddress) > 0 || len(rule.SourceHostname) > 0
+}
+
+func isNeighborDNSRule(rule option.DefaultDNSRule) bool {
+	return len(rule.SourceMACAddress) > 0 || len(rule.SourceHostname) > 0
+}
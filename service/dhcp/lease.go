@@ -0,0 +1,218 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type addressRange struct {
+	from netip.Addr
+	to   netip.Addr
+}
+
+func (r addressRange) contains(address netip.Addr) bool {
+	return r.from.Compare(address) <= 0 && address.Compare(r.to) <= 0
+}
+
+// parseAddressRange accepts IPv4 ranges such as
+// "192.168.1.100-192.168.1.200" and prefixes.
+func parseAddressRange(value string) (addressRange, error) {
+	if from, to, isRange := strings.Cut(value, "-"); isRange {
+		fromAddress, err := netip.ParseAddr(strings.TrimSpace(from))
+		if err != nil {
+			return addressRange{}, err
+		}
+		toAddress, err := netip.ParseAddr(strings.TrimSpace(to))
+		if err != nil {
+			return addressRange{}, err
+		}
+		if !fromAddress.Is4() || !toAddress.Is4() {
+			return addressRange{}, E.New("not an IPv4 range: ", value)
+		}
+		if toAddress.Less(fromAddress) {
+			return addressRange{}, E.New("invalid range: ", value)
+		}
+		return addressRange{fromAddress, toAddress}, nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return addressRange{}, err
+	}
+	if !prefix.Addr().Is4() {
+		return addressRange{}, E.New("not an IPv4 prefix: ", value)
+	}
+	return prefixRange(prefix), nil
+}
+
+func prefixRange(prefix netip.Prefix) addressRange {
+	prefix = prefix.Masked()
+	last := prefix.Addr().As4()
+	for i := prefix.Bits(); i < 32; i++ {
+		last[i/8] |= 1 << (7 - i%8)
+	}
+	return addressRange{prefix.Addr(), netip.AddrFrom4(last)}
+}
+
+func (s *Service) initializePools() error {
+	subnet := prefixRange(s.address)
+	if len(s.poolOptions) == 0 {
+		s.pools = []addressRange{subnet}
+	}
+	for i, poolOption := range s.poolOptions {
+		pool, err := parseAddressRange(poolOption)
+		if err != nil {
+			return E.Cause(err, "parse pool[", i, "]")
+		}
+		if !subnet.contains(pool.from) || !subnet.contains(pool.to) {
+			return E.New("parse pool[", i, "]: ", poolOption, " is not in ", s.address.Masked())
+		}
+		s.pools = append(s.pools, pool)
+	}
+	for _, lease := range s.staticLeases {
+		if !subnet.contains(lease.Address) {
+			return E.New("static lease ", lease.Address, " is not in ", s.address.Masked())
+		}
+	}
+	return nil
+}
+
+// offer selects an address for the client and reserves it for a short time.
+func (s *Service) offer(mac net.HardwareAddr, requested netip.Addr) (netip.Addr, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	address, err := s.selectAddress(mac, requested, time.Now())
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if _, isStatic := s.staticLeases[mac.String()]; !isStatic {
+		s.offers[mac.String()] = &adapter.DHCPLease{
+			Address: address,
+			MAC:     mac,
+			Expire:  time.Now().Add(offerTimeout),
+		}
+	}
+	return address, nil
+}
+
+// bind confirms the address requested by the client.
+func (s *Service) bind(mac net.HardwareAddr, address netip.Addr, hostname string) (adapter.DHCPLease, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if staticLease, isStatic := s.staticLeases[mac.String()]; isStatic {
+		if address != staticLease.Address {
+			return adapter.DHCPLease{}, E.New("static lease is ", staticLease.Address)
+		}
+		if staticLease.Hostname == "" {
+			staticLease.Hostname = hostname
+			s.staticLeases[mac.String()] = staticLease
+		}
+		return staticLease, nil
+	}
+	now := time.Now()
+	if !s.inPool(address) {
+		return adapter.DHCPLease{}, E.New("not in pool")
+	}
+	if !s.available(mac, address, now) {
+		return adapter.DHCPLease{}, E.New("in use")
+	}
+	for key, lease := range s.leases {
+		if !lease.Expire.After(now) {
+			delete(s.leases, key)
+		}
+	}
+	for key, lease := range s.offers {
+		if !lease.Expire.After(now) {
+			delete(s.offers, key)
+		}
+	}
+	delete(s.offers, mac.String())
+	lease := &adapter.DHCPLease{
+		Address:  address,
+		MAC:      mac,
+		Hostname: hostname,
+		Expire:   now.Add(s.leaseTime),
+	}
+	s.leases[mac.String()] = lease
+	return *lease, nil
+}
+
+// release removes the lease of the client, declined addresses are not
+// offered again for a lease time.
+func (s *Service) release(mac net.HardwareAddr, address netip.Addr, declined bool) bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	delete(s.offers, mac.String())
+	lease, loaded := s.leases[mac.String()]
+	if !loaded || lease.Address != address {
+		return false
+	}
+	delete(s.leases, mac.String())
+	if declined {
+		s.declined[address] = time.Now().Add(s.leaseTime)
+	}
+	return true
+}
+
+func (s *Service) selectAddress(mac net.HardwareAddr, requested netip.Addr, now time.Time) (netip.Addr, error) {
+	if staticLease, isStatic := s.staticLeases[mac.String()]; isStatic {
+		return staticLease.Address, nil
+	}
+	for _, lease := range []*adapter.DHCPLease{s.leases[mac.String()], s.offers[mac.String()]} {
+		if lease != nil && s.inPool(lease.Address) && s.available(mac, lease.Address, now) {
+			return lease.Address, nil
+		}
+	}
+	if requested.IsValid() && s.inPool(requested) && s.available(mac, requested, now) {
+		return requested, nil
+	}
+	for _, pool := range s.pools {
+		for address := pool.from; address.IsValid() && pool.contains(address); address = address.Next() {
+			if s.available(mac, address, now) {
+				return address, nil
+			}
+		}
+	}
+	return netip.Addr{}, E.New("address pool exhausted")
+}
+
+func (s *Service) inPool(address netip.Addr) bool {
+	for _, pool := range s.pools {
+		if pool.contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// available checks if the address can be assigned to the client.
+func (s *Service) available(mac net.HardwareAddr, address netip.Addr, now time.Time) bool {
+	subnet := prefixRange(s.address)
+	if address == s.address.Addr() || address == subnet.from || address == subnet.to {
+		return false
+	}
+	if expire, declined := s.declined[address]; declined {
+		if expire.After(now) {
+			return false
+		}
+		delete(s.declined, address)
+	}
+	for _, lease := range s.staticLeases {
+		if lease.Address == address && !bytes.Equal(lease.MAC, mac) {
+			return false
+		}
+	}
+	for _, leases := range []map[string]*adapter.DHCPLease{s.leases, s.offers} {
+		for _, lease := range leases {
+			if lease.Address == address && !bytes.Equal(lease.MAC, mac) && lease.Expire.After(now) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,374 @@
+package dhcp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	boxService "github.com/sagernet/sing-box/adapter/service"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv4/server4"
+)
+
+const (
+	defaultLeaseTime = 12 * time.Hour
+	// offerTimeout is how long an offered address is reserved for the
+	// client to request it.
+	offerTimeout = time.Minute
+)
+
+func RegisterService(registry *boxService.Registry) {
+	boxService.Register[option.DHCPServiceOptions](registry, C.TypeDHCP, NewService)
+}
+
+var _ adapter.DHCPLeaseProvider = (*Service)(nil)
+
+type Service struct {
+	boxService.Adapter
+	ctx            context.Context
+	logger         log.ContextLogger
+	networkManager adapter.NetworkManager
+	cacheFile      adapter.CacheFile
+	interfaceName  string
+	listenPort     uint16
+	address        netip.Prefix
+	poolOptions    []string
+	pools          []addressRange
+	leaseTime      time.Duration
+	router         []netip.Addr
+	dns            []netip.Addr
+	domain         string
+	conn           net.PacketConn
+
+	access       sync.Mutex
+	staticLeases map[string]adapter.DHCPLease
+	leases       map[string]*adapter.DHCPLease
+	offers       map[string]*adapter.DHCPLease
+	declined     map[netip.Addr]time.Time
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, tag string, options option.DHCPServiceOptions) (adapter.Service, error) {
+	s := &Service{
+		Adapter:        boxService.NewAdapter(C.TypeDHCP, tag),
+		ctx:            ctx,
+		logger:         logger,
+		networkManager: service.FromContext[adapter.NetworkManager](ctx),
+		interfaceName:  options.Interface,
+		listenPort:     options.ListenPort,
+		poolOptions:    options.Pool,
+		leaseTime:      time.Duration(options.LeaseTime),
+		router:         options.Router,
+		dns:            options.DNS,
+		domain:         options.Domain,
+		staticLeases:   make(map[string]adapter.DHCPLease),
+		leases:         make(map[string]*adapter.DHCPLease),
+		offers:         make(map[string]*adapter.DHCPLease),
+		declined:       make(map[netip.Addr]time.Time),
+	}
+	if options.Address != nil {
+		s.address = netip.Prefix(*options.Address)
+		if !s.address.Addr().Is4() {
+			return nil, E.New("address must be IPv4")
+		}
+	} else if options.Interface == "" {
+		return nil, E.New("missing interface or address")
+	}
+	for i, poolOption := range options.Pool {
+		_, err := parseAddressRange(poolOption)
+		if err != nil {
+			return nil, E.Cause(err, "parse pool[", i, "]")
+		}
+	}
+	for _, address := range append(append([]netip.Addr(nil), options.Router...), options.DNS...) {
+		if !address.Is4() {
+			return nil, E.New("router and dns must be IPv4: ", address)
+		}
+	}
+	if s.listenPort == 0 {
+		s.listenPort = dhcpv4.ServerPort
+	}
+	if s.leaseTime == 0 {
+		s.leaseTime = defaultLeaseTime
+	}
+	for i, staticLease := range options.StaticLeases {
+		mac, err := net.ParseMAC(staticLease.MAC)
+		if err != nil {
+			return nil, E.Cause(err, "parse static_leases[", i, "]")
+		}
+		if !staticLease.Address.Is4() {
+			return nil, E.New("parse static_leases[", i, "]: missing IPv4 address")
+		}
+		s.staticLeases[mac.String()] = adapter.DHCPLease{
+			Address:  staticLease.Address,
+			MAC:      mac,
+			Hostname: staticLease.Hostname,
+		}
+	}
+	return s, nil
+}
+
+func (s *Service) Start(stage adapter.StartStage) error {
+	if stage != adapter.StartStateStart {
+		return nil
+	}
+	if s.interfaceName == "" {
+		// never serve on all interfaces, which would include the upstream one
+		iif, err := s.networkManager.InterfaceFinder().ByAddr(s.address.Addr())
+		if err != nil {
+			return E.Cause(err, "find interface for ", s.address.Addr())
+		}
+		s.interfaceName = iif.Name
+	}
+	if !s.address.IsValid() {
+		iif, err := s.networkManager.InterfaceFinder().ByName(s.interfaceName)
+		if err != nil {
+			return E.Cause(err, "find interface ", s.interfaceName)
+		}
+		for _, address := range iif.Addresses {
+			if address.Addr().Is4() {
+				s.address = address
+				break
+			}
+		}
+		if !s.address.IsValid() {
+			return E.New("missing IPv4 address on interface ", s.interfaceName)
+		}
+	}
+	err := s.initializePools()
+	if err != nil {
+		return err
+	}
+	if len(s.router) == 0 {
+		s.router = []netip.Addr{s.address.Addr()}
+	}
+	if len(s.dns) == 0 {
+		s.dns = []netip.Addr{s.address.Addr()}
+	}
+	s.cacheFile = service.FromContext[adapter.CacheFile](s.ctx)
+	if s.cacheFile != nil {
+		s.loadLeases()
+	}
+	conn, err := server4.NewIPv4UDPConn(s.interfaceName, &net.UDPAddr{Port: int(s.listenPort)})
+	if err != nil {
+		return E.Cause(err, "listen DHCP")
+	}
+	s.conn = conn
+	s.logger.Info("DHCP server started at ", s.address, " on ", conn.LocalAddr())
+	go s.loopPackets()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	s.saveLeases()
+	return common.Close(s.conn)
+}
+
+// Leases returns the static leases and the active dynamic leases.
+func (s *Service) Leases() []adapter.DHCPLease {
+	s.access.Lock()
+	defer s.access.Unlock()
+	now := time.Now()
+	leases := make([]adapter.DHCPLease, 0, len(s.staticLeases)+len(s.leases))
+	for _, lease := range s.staticLeases {
+		leases = append(leases, lease)
+	}
+	for _, lease := range s.leases {
+		if lease.Expire.After(now) {
+			leases = append(leases, *lease)
+		}
+	}
+	return leases
+}
+
+func (s *Service) loadLeases() {
+	now := time.Now()
+	s.access.Lock()
+	defer s.access.Unlock()
+	for _, lease := range s.cacheFile.LoadDHCPLeases(s.Tag()) {
+		if !lease.Expire.After(now) || !s.address.Contains(lease.Address) {
+			continue
+		}
+		if _, isStatic := s.staticLeases[lease.MAC.String()]; isStatic {
+			continue
+		}
+		s.leases[lease.MAC.String()] = lease
+	}
+}
+
+func (s *Service) saveLeases() {
+	if s.cacheFile == nil {
+		return
+	}
+	now := time.Now()
+	s.access.Lock()
+	leases := make([]*adapter.DHCPLease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		if lease.Expire.After(now) {
+			leases = append(leases, lease)
+		}
+	}
+	s.access.Unlock()
+	err := s.cacheFile.SaveDHCPLeases(s.Tag(), leases)
+	if err != nil {
+		s.logger.Warn(E.Cause(err, "save leases"))
+	}
+}
+
+func (s *Service) loopPackets() {
+	buffer := make([]byte, 1500)
+	for {
+		n, source, err := s.conn.ReadFrom(buffer)
+		if err != nil {
+			if !E.IsClosed(err) {
+				s.logger.Error(E.Cause(err, "read DHCP packet"))
+			}
+			return
+		}
+		request, err := dhcpv4.FromBytes(buffer[:n])
+		if err != nil {
+			s.logger.Debug(E.Cause(err, "parse DHCP packet from ", source))
+			continue
+		}
+		if request.OpCode != dhcpv4.OpcodeBootRequest {
+			continue
+		}
+		reply := s.handle(request)
+		if reply == nil {
+			continue
+		}
+		_, err = s.conn.WriteTo(reply.ToBytes(), replyAddress(request, reply, source))
+		if err != nil {
+			s.logger.Error(E.Cause(err, "write DHCP ", reply.MessageType()))
+		}
+	}
+}
+
+func (s *Service) handle(request *dhcpv4.DHCPv4) *dhcpv4.DHCPv4 {
+	mac := request.ClientHWAddr
+	switch request.MessageType() {
+	case dhcpv4.MessageTypeDiscover:
+		address, err := s.offer(mac, addrFromIP(request.RequestedIPAddress()))
+		if err != nil {
+			s.logger.Warn("no address for ", mac, ": ", err)
+			return nil
+		}
+		s.logger.Debug("offer ", address, " to ", mac)
+		return s.reply(request, dhcpv4.MessageTypeOffer, address)
+	case dhcpv4.MessageTypeRequest:
+		if serverID := request.ServerIdentifier(); serverID != nil && addrFromIP(serverID) != s.address.Addr() {
+			// the client accepted the offer of another server
+			s.access.Lock()
+			delete(s.offers, mac.String())
+			s.access.Unlock()
+			return nil
+		}
+		address := addrFromIP(request.RequestedIPAddress())
+		if !address.IsValid() {
+			address = addrFromIP(request.ClientIPAddr)
+		}
+		lease, err := s.bind(mac, address, request.HostName())
+		if err != nil {
+			s.logger.Info("reject ", address, " for ", mac, ": ", err)
+			return s.reply(request, dhcpv4.MessageTypeNak, netip.Addr{})
+		}
+		if lease.Hostname != "" {
+			s.logger.Info("lease ", lease.Address, " to ", mac, " (", lease.Hostname, ")")
+		} else {
+			s.logger.Info("lease ", lease.Address, " to ", mac)
+		}
+		s.saveLeases()
+		return s.reply(request, dhcpv4.MessageTypeAck, lease.Address)
+	case dhcpv4.MessageTypeRelease:
+		if s.release(mac, addrFromIP(request.ClientIPAddr), false) {
+			s.logger.Info("release ", addrFromIP(request.ClientIPAddr), " from ", mac)
+			s.saveLeases()
+		}
+		return nil
+	case dhcpv4.MessageTypeDecline:
+		address := addrFromIP(request.RequestedIPAddress())
+		if s.release(mac, address, true) {
+			s.logger.Warn("address ", address, " declined by ", mac, ", it may be in use")
+			s.saveLeases()
+		}
+		return nil
+	case dhcpv4.MessageTypeInform:
+		return s.reply(request, dhcpv4.MessageTypeAck, netip.Addr{})
+	default:
+		return nil
+	}
+}
+
+func (s *Service) reply(request *dhcpv4.DHCPv4, messageType dhcpv4.MessageType, address netip.Addr) *dhcpv4.DHCPv4 {
+	modifiers := []dhcpv4.Modifier{
+		dhcpv4.WithMessageType(messageType),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(s.address.Addr().AsSlice())),
+	}
+	if messageType != dhcpv4.MessageTypeNak {
+		modifiers = append(modifiers,
+			dhcpv4.WithNetmask(net.CIDRMask(s.address.Bits(), 32)),
+			dhcpv4.WithRouter(toIPs(s.router)...),
+			dhcpv4.WithDNS(toIPs(s.dns)...),
+		)
+		if s.domain != "" {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptDomainName(s.domain)))
+		}
+	}
+	if address.IsValid() {
+		modifiers = append(modifiers,
+			dhcpv4.WithYourIP(address.AsSlice()),
+			dhcpv4.WithLeaseTime(uint32(s.leaseTime/time.Second)),
+		)
+	}
+	reply, err := dhcpv4.NewReplyFromRequest(request, modifiers...)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "create DHCP ", messageType))
+		return nil
+	}
+	return reply
+}
+
+// replyAddress follows RFC 2131 section 4.1, except that unicast replies to
+// clients without an address are broadcast, as sending them needs a raw
+// socket.
+func replyAddress(request *dhcpv4.DHCPv4, reply *dhcpv4.DHCPv4, source net.Addr) net.Addr {
+	if !isUnspecified(request.GatewayIPAddr) {
+		return &net.UDPAddr{IP: request.GatewayIPAddr, Port: dhcpv4.ServerPort}
+	}
+	if reply.MessageType() != dhcpv4.MessageTypeNak && !isUnspecified(request.ClientIPAddr) {
+		return &net.UDPAddr{IP: request.ClientIPAddr, Port: dhcpv4.ClientPort}
+	}
+	if udpAddr, isUDPAddr := source.(*net.UDPAddr); isUDPAddr && !isUnspecified(udpAddr.IP) {
+		return udpAddr
+	}
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+}
+
+func isUnspecified(ip net.IP) bool {
+	return ip == nil || ip.IsUnspecified()
+}
+
+func addrFromIP(ip net.IP) netip.Addr {
+	address, _ := netip.AddrFromSlice(ip.To4())
+	return address
+}
+
+func toIPs(addresses []netip.Addr) []net.IP {
+	ips := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		ips = append(ips, address.AsSlice())
+	}
+	return ips
+}
@@ -0,0 +1,167 @@
+package dhcp_test
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/service/dhcp"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	_, serverAddr := startServer(t, option.DHCPServiceOptions{
+		Address:   common.Ptr(badoption.Prefix(netip.MustParsePrefix("127.0.0.1/24"))),
+		Pool:      []string{"127.0.0.100-127.0.0.101"},
+		LeaseTime: badoption.Duration(time.Hour),
+		DNS:       []netip.Addr{netip.MustParseAddr("127.0.0.53")},
+		Domain:    "lan",
+		StaticLeases: []option.DHCPStaticLease{{
+			MAC:      "02:00:00:00:00:50",
+			Address:  netip.MustParseAddr("127.0.0.50"),
+			Hostname: "printer",
+		}},
+	})
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	laptop := net.HardwareAddr{2, 0, 0, 0, 0, 1}
+	offer := exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewDiscovery(laptop)))
+	require.Equal(t, dhcpv4.MessageTypeOffer, offer.MessageType())
+	require.Equal(t, "127.0.0.100", offer.YourIPAddr.String())
+	require.Equal(t, "127.0.0.1", offer.ServerIdentifier().String())
+	require.Equal(t, "127.0.0.1", offer.Router()[0].String())
+	require.Equal(t, "127.0.0.53", offer.DNS()[0].String())
+	require.Equal(t, "lan", offer.DomainName())
+	require.Equal(t, net.CIDRMask(24, 32), offer.SubnetMask())
+	require.Equal(t, time.Hour, offer.IPAddressLeaseTime(0))
+
+	ack := exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewRequestFromOffer(offer, dhcpv4.WithOption(dhcpv4.OptHostName("laptop")))))
+	require.Equal(t, dhcpv4.MessageTypeAck, ack.MessageType())
+	require.Equal(t, "127.0.0.100", ack.YourIPAddr.String())
+
+	// a second client gets the other address, then the pool is exhausted
+	phone := net.HardwareAddr{2, 0, 0, 0, 0, 2}
+	offer = exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewDiscovery(phone, dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.IPv4(127, 0, 0, 100))))))
+	require.Equal(t, "127.0.0.101", offer.YourIPAddr.String())
+	ack = exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewRequestFromOffer(offer)))
+	require.Equal(t, dhcpv4.MessageTypeAck, ack.MessageType())
+
+	// requesting a leased address is refused
+	request := common.Must1(dhcpv4.NewRequestFromOffer(offer))
+	request.ClientHWAddr = net.HardwareAddr{2, 0, 0, 0, 0, 3}
+	nak := exchange(t, conn, serverAddr, request)
+	require.Equal(t, dhcpv4.MessageTypeNak, nak.MessageType())
+
+	printer := net.HardwareAddr{2, 0, 0, 0, 0, 0x50}
+	offer = exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewDiscovery(printer)))
+	require.Equal(t, "127.0.0.50", offer.YourIPAddr.String())
+
+	// released addresses are offered again
+	release := common.Must1(dhcpv4.NewReleaseFromACK(ack))
+	_, err = conn.WriteTo(release.ToBytes(), serverAddr)
+	require.NoError(t, err)
+	offer = exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewDiscovery(net.HardwareAddr{2, 0, 0, 0, 0, 3})))
+	require.Equal(t, "127.0.0.101", offer.YourIPAddr.String())
+}
+
+func TestServerLeases(t *testing.T) {
+	t.Parallel()
+	service, serverAddr := startServer(t, option.DHCPServiceOptions{
+		Address: common.Ptr(badoption.Prefix(netip.MustParsePrefix("127.0.0.1/24"))),
+		StaticLeases: []option.DHCPStaticLease{{
+			MAC:      "02:00:00:00:00:50",
+			Address:  netip.MustParseAddr("127.0.0.50"),
+			Hostname: "printer",
+		}},
+	})
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	offer := exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewDiscovery(net.HardwareAddr{2, 0, 0, 0, 0, 1})))
+	exchange(t, conn, serverAddr, common.Must1(dhcpv4.NewRequestFromOffer(offer, dhcpv4.WithOption(dhcpv4.OptHostName("laptop")))))
+
+	leases := service.(adapter.DHCPLeaseProvider).Leases()
+	require.Len(t, leases, 2)
+	hostnames := make(map[string]string)
+	for _, lease := range leases {
+		hostnames[lease.Address.String()] = lease.Hostname
+	}
+	require.Equal(t, map[string]string{
+		"127.0.0.2":  "laptop",
+		"127.0.0.50": "printer",
+	}, hostnames)
+}
+
+func TestServerRejectIPv6(t *testing.T) {
+	t.Parallel()
+	address := common.Ptr(badoption.Prefix(netip.MustParsePrefix("192.168.1.1/24")))
+	for _, options := range []option.DHCPServiceOptions{
+		{Address: common.Ptr(badoption.Prefix(netip.MustParsePrefix("fd00::1/64")))},
+		{Address: address, Pool: []string{"fd00::/64"}},
+		{Address: address, Pool: []string{"fd00::100-fd00::200"}},
+		{Address: address, DNS: []netip.Addr{netip.MustParseAddr("fd00::53")}},
+		{Address: address, StaticLeases: []option.DHCPStaticLease{{
+			MAC:     "02:00:00:00:00:50",
+			Address: netip.MustParseAddr("fd00::50"),
+		}}},
+	} {
+		_, err := dhcp.NewService(context.Background(), log.NewNOPFactory().NewLogger("dhcp"), "dhcp", options)
+		require.Error(t, err)
+	}
+}
+
+func startServer(t *testing.T, options option.DHCPServiceOptions) (adapter.Service, *net.UDPAddr) {
+	options.Interface = loopbackInterface(t)
+	options.ListenPort = freePort(t)
+	service, err := dhcp.NewService(context.Background(), log.NewNOPFactory().NewLogger("dhcp"), "dhcp", options)
+	require.NoError(t, err)
+	require.NoError(t, service.Start(adapter.StartStateStart))
+	t.Cleanup(func() {
+		service.Close()
+	})
+	return service, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(options.ListenPort)}
+}
+
+func loopbackInterface(t *testing.T) string {
+	interfaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, iif := range interfaces {
+		if iif.Flags&net.FlagLoopback != 0 {
+			return iif.Name
+		}
+	}
+	t.Skip("missing loopback interface")
+	return ""
+}
+
+func freePort(t *testing.T) uint16 {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func exchange(t *testing.T, conn *net.UDPConn, serverAddr net.Addr, request *dhcpv4.DHCPv4) *dhcpv4.DHCPv4 {
+	_, err := conn.WriteTo(request.ToBytes(), serverAddr)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buffer := make([]byte, 1500)
+	n, err := conn.Read(buffer)
+	require.NoError(t, err)
+	reply, err := dhcpv4.FromBytes(buffer[:n])
+	require.NoError(t, err)
+	require.Equal(t, request.TransactionID, reply.TransactionID)
+	return reply
+}
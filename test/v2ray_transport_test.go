@@ -28,6 +28,42 @@ func TestV2RayHTTPPlainSelf(t *testing.T) {
 	})
 }
 
+func TestV2RayXHTTPSelf(t *testing.T) {
+	testV2RayTransportSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeXHTTP,
+		XHTTPOptions: option.V2RayXHTTPOptions{
+			Path: "/xhttp",
+		},
+	})
+}
+
+func TestV2RayXHTTPPacketUpSelf(t *testing.T) {
+	testV2RayTransportSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeXHTTP,
+		XHTTPOptions: option.V2RayXHTTPOptions{
+			Mode: "packet-up",
+		},
+	})
+}
+
+func TestV2RayXHTTPStreamOneSelf(t *testing.T) {
+	testV2RayTransportSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeXHTTP,
+		XHTTPOptions: option.V2RayXHTTPOptions{
+			Mode: "stream-one",
+		},
+	})
+}
+
+func TestV2RayXHTTPPlainSelf(t *testing.T) {
+	testV2RayTransportNOTLSSelf(t, &option.V2RayTransportOptions{
+		Type: C.V2RayTransportTypeXHTTP,
+		XHTTPOptions: option.V2RayXHTTPOptions{
+			XPaddingBytes: &option.XHTTPRange{From: 10, To: 20},
+		},
+	})
+}
+
 func testV2RayTransportSelf(t *testing.T, transport *option.V2RayTransportOptions) {
 	testV2RayTransportSelfWith(t, transport, transport)
 }
@@ -0,0 +1,120 @@
+//go:build with_wireguard
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/netip"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestWireGuardAmneziaSelf(t *testing.T) {
+	serverPrivateKey, serverPublicKey := newWireGuardKeyPair(t)
+	clientPrivateKey, clientPublicKey := newWireGuardKeyPair(t)
+	amnezia := &option.WireGuardAmneziaOptions{
+		JunkPacketCount:            4,
+		JunkPacketMinSize:          40,
+		JunkPacketMaxSize:          70,
+		InitPacketJunkSize:         15,
+		ResponsePacketJunkSize:     18,
+		InitPacketMagicHeader:      1020325451,
+		ResponsePacketMagicHeader:  3288052141,
+		UnderloadPacketMagicHeader: 1766607858,
+		TransportPacketMagicHeader: 2528465083,
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				Options: &option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     common.Ptr(badoption.Addr(netip.IPv4Unspecified())),
+						ListenPort: clientPort,
+					},
+				},
+			},
+		},
+		Endpoints: []option.Endpoint{
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-server",
+				Options: &option.WireGuardEndpointOptions{
+					Address:    []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+					PrivateKey: serverPrivateKey,
+					ListenPort: serverPort,
+					Peers: []option.WireGuardPeer{
+						{
+							PublicKey:  clientPublicKey,
+							AllowedIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+						},
+					},
+					Amnezia: amnezia,
+				},
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-client",
+				Options: &option.WireGuardEndpointOptions{
+					Address:    []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+					PrivateKey: clientPrivateKey,
+					Peers: []option.WireGuardPeer{
+						{
+							Address:    "127.0.0.1",
+							Port:       serverPort,
+							PublicKey:  serverPublicKey,
+							AllowedIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+						},
+					},
+					Amnezia: amnezia,
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+				Tag:  "direct",
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					Type: C.RuleTypeDefault,
+					DefaultOptions: option.DefaultRule{
+						RawDefaultRule: option.RawDefaultRule{
+							Inbound: []string{"mixed-in"},
+						},
+						RuleAction: option.RuleAction{
+							Action: C.RuleActionTypeRoute,
+							RouteOptions: option.RouteActionOptions{
+								Outbound: "wg-client",
+							},
+						},
+					},
+				},
+			},
+			Final: "direct",
+		},
+	})
+	testSuitWg(t, clientPort, testPort)
+}
+
+func newWireGuardKeyPair(t *testing.T) (privateKey string, publicKey string) {
+	var privateKeyBytes [32]byte
+	_, err := rand.Read(privateKeyBytes[:])
+	require.NoError(t, err)
+	privateKeyBytes[0] &= 248
+	privateKeyBytes[31] = (privateKeyBytes[31] & 127) | 64
+	publicKeyBytes, err := curve25519.X25519(privateKeyBytes[:], curve25519.Basepoint)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(privateKeyBytes[:]), base64.StdEncoding.EncodeToString(publicKeyBytes)
+}
@@ -0,0 +1,303 @@
+package v2rayxhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	"github.com/gofrs/uuid/v5"
+	"golang.org/x/net/http2"
+)
+
+var _ adapter.V2RayClientTransport = (*Client)(nil)
+
+type roundTripper interface {
+	http.RoundTripper
+	io.Closer
+}
+
+type Client struct {
+	ctx        context.Context
+	config     *config
+	transport  http.RoundTripper
+	requestURL url.URL
+	host       string
+	mode       string
+}
+
+func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayXHTTPOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
+	clientConfig, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		transport http.RoundTripper
+		http1     bool
+	)
+	if tlsConfig == nil {
+		transport = &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, serverAddr)
+			},
+		}
+		http1 = true
+	} else {
+		if len(tlsConfig.NextProtos()) == 0 {
+			tlsConfig.SetNextProtos([]string{http2.NextProtoTLS})
+		}
+		switch tlsConfig.NextProtos()[0] {
+		case http3NextProto:
+			transport, err = newHTTP3RoundTripper(ctx, dialer, serverAddr, tlsConfig)
+			if err != nil {
+				return nil, err
+			}
+		case "http/1.1":
+			transport = &http.Transport{
+				DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					conn, err := dialer.DialContext(ctx, network, serverAddr)
+					if err != nil {
+						return nil, err
+					}
+					return tls.ClientHandshake(ctx, conn, tlsConfig)
+				},
+			}
+			http1 = true
+		default:
+			transport = &http2.Transport{
+				ReadIdleTimeout: C.TCPKeepAliveInterval,
+				PingTimeout:     C.TCPTimeout,
+				DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.STDConfig) (net.Conn, error) {
+					conn, err := dialer.DialContext(ctx, network, serverAddr)
+					if err != nil {
+						return nil, err
+					}
+					return tls.ClientHandshake(ctx, conn, tlsConfig)
+				},
+			}
+		}
+	}
+	var host string
+	if clientConfig.host != "" {
+		host = clientConfig.host
+	} else if tlsConfig != nil && tlsConfig.ServerName() != "" {
+		host = tlsConfig.ServerName()
+	} else {
+		host = serverAddr.String()
+	}
+	var requestURL url.URL
+	if tlsConfig == nil {
+		requestURL.Scheme = "http"
+	} else {
+		requestURL.Scheme = "https"
+	}
+	requestURL.Host = serverAddr.String()
+	err = sHTTP.URLSetPath(&requestURL, clientConfig.path)
+	if err != nil {
+		return nil, E.Cause(err, "parse path")
+	}
+	mode := clientConfig.mode
+	if mode == ModeAuto {
+		if http1 {
+			mode = ModePacketUp
+		} else {
+			mode = ModeStreamUp
+		}
+	}
+	return &Client{
+		ctx:        ctx,
+		config:     clientConfig,
+		transport:  transport,
+		requestURL: requestURL,
+		host:       host,
+		mode:       mode,
+	}, nil
+}
+
+func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
+	if c.mode == ModeStreamOne {
+		pipeReader, pipeWriter := io.Pipe()
+		conn := v2rayhttp.NewLateHTTPConn(pipeWriter)
+		go c.openStream(c.requestURL, pipeReader, conn)
+		return conn, nil
+	}
+	sessionURL := c.requestURL
+	sessionURL.Path += uuid.Must(uuid.NewV4()).String()
+	var conn *v2rayhttp.HTTP2Conn
+	if c.mode == ModeStreamUp {
+		pipeReader, pipeWriter := io.Pipe()
+		conn = v2rayhttp.NewLateHTTPConn(pipeWriter)
+		go c.upload(sessionURL, pipeReader, pipeWriter)
+	} else {
+		uploader := newPacketUploader(c, sessionURL)
+		conn = v2rayhttp.NewLateHTTPConn(uploader)
+		go uploader.loop()
+	}
+	go c.openStream(sessionURL, nil, conn)
+	return conn, nil
+}
+
+func (c *Client) newRequest(method string, requestURL url.URL, body io.Reader) *http.Request {
+	request, _ := http.NewRequestWithContext(c.ctx, method, requestURL.String(), body)
+	request.Host = c.host
+	request.Header = c.config.requestHeader(requestURL)
+	if method == http.MethodPost && !c.config.noGRPCHeader {
+		request.Header.Set("Content-Type", "application/grpc")
+	}
+	return request
+}
+
+// openStream sends the download request, which is a GET in split modes and
+// the single full-duplex POST in stream-one mode.
+func (c *Client) openStream(requestURL url.URL, body io.Reader, conn *v2rayhttp.HTTP2Conn) {
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+	response, err := c.transport.RoundTrip(c.newRequest(method, requestURL, body))
+	if err != nil {
+		conn.Setup(nil, err)
+	} else if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		conn.Setup(nil, E.New("xhttp: unexpected status: ", response.Status))
+	} else {
+		conn.Setup(response.Body, nil)
+	}
+}
+
+func (c *Client) upload(sessionURL url.URL, pipeReader *io.PipeReader, pipeWriter *io.PipeWriter) {
+	response, err := c.transport.RoundTrip(c.newRequest(http.MethodPost, sessionURL, pipeReader))
+	if err != nil {
+		pipeWriter.CloseWithError(err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		pipeWriter.CloseWithError(E.New("xhttp: unexpected upload status: ", response.Status))
+		return
+	}
+	io.Copy(io.Discard, response.Body)
+}
+
+func (c *Client) Close() error {
+	if closer, isCloser := c.transport.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	c.transport = v2rayhttp.ResetTransport(c.transport)
+	return nil
+}
+
+// packetUploader batches writes into POST requests carrying a sequence
+// number, for servers or CDNs that do not support streaming request bodies.
+type packetUploader struct {
+	client     *Client
+	sessionURL url.URL
+	access     sync.Mutex
+	cond       *sync.Cond
+	buffer     []byte
+	closed     bool
+	err        error
+}
+
+func newPacketUploader(client *Client, sessionURL url.URL) *packetUploader {
+	uploader := &packetUploader{
+		client:     client,
+		sessionURL: sessionURL,
+	}
+	uploader.cond = sync.NewCond(&uploader.access)
+	return uploader
+}
+
+func (u *packetUploader) Write(p []byte) (n int, err error) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	for !u.closed && len(u.buffer) >= int(u.client.config.maxEachPostBytes.To) {
+		u.cond.Wait()
+	}
+	if u.err != nil {
+		return 0, u.err
+	}
+	if u.closed {
+		return 0, io.ErrClosedPipe
+	}
+	u.buffer = append(u.buffer, p...)
+	u.cond.Broadcast()
+	return len(p), nil
+}
+
+func (u *packetUploader) loop() {
+	var seq uint64
+	for {
+		u.access.Lock()
+		for !u.closed && len(u.buffer) == 0 {
+			u.cond.Wait()
+		}
+		if len(u.buffer) == 0 || u.err != nil {
+			u.access.Unlock()
+			return
+		}
+		chunkSize := min(len(u.buffer), int(randomInRange(u.client.config.maxEachPostBytes)))
+		chunk := u.buffer[:chunkSize]
+		u.buffer = append([]byte(nil), u.buffer[chunkSize:]...)
+		u.cond.Broadcast()
+		u.access.Unlock()
+		err := u.post(seq, chunk)
+		if err != nil {
+			u.closeWithError(err)
+			return
+		}
+		seq++
+		interval := randomInRange(u.client.config.minPostsIntervalMs)
+		if interval > 0 {
+			time.Sleep(time.Duration(interval) * time.Millisecond)
+		}
+	}
+}
+
+func (u *packetUploader) post(seq uint64, chunk []byte) error {
+	requestURL := u.sessionURL
+	requestURL.Path += "/" + strconv.FormatUint(seq, 10)
+	request := u.client.newRequest(http.MethodPost, requestURL, bytes.NewReader(chunk))
+	request.Header.Del("Content-Type")
+	request.ContentLength = int64(len(chunk))
+	response, err := u.client.transport.RoundTrip(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("xhttp: unexpected upload status: ", response.Status)
+	}
+	return nil
+}
+
+func (u *packetUploader) closeWithError(err error) {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.closed = true
+	u.err = err
+	u.cond.Broadcast()
+}
+
+func (u *packetUploader) Close() error {
+	u.access.Lock()
+	defer u.access.Unlock()
+	u.closed = true
+	u.cond.Broadcast()
+	return nil
+}
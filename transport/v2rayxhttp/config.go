@@ -0,0 +1,130 @@
+package v2rayxhttp
+
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	ModeAuto      = "auto"
+	ModePacketUp  = "packet-up"
+	ModeStreamUp  = "stream-up"
+	ModeStreamOne = "stream-one"
+)
+
+const (
+	sessionTimeout = 30 * time.Second
+	// maxSessions and maxBufferedBytes bound the memory a server holds for
+	// sessions, which any client can open before the proxy protocol
+	// authenticates it.
+	maxSessions      = 1024
+	maxBufferedBytes = 64 * 1024 * 1024
+)
+
+type config struct {
+	host               string
+	path               string
+	mode               string
+	headers            http.Header
+	paddingBytes       option.XHTTPRange
+	noGRPCHeader       bool
+	noSSEHeader        bool
+	maxEachPostBytes   option.XHTTPRange
+	maxBufferedPosts   int
+	minPostsIntervalMs option.XHTTPRange
+}
+
+func newConfig(options option.V2RayXHTTPOptions) (*config, error) {
+	c := &config{
+		host:               options.Host,
+		path:               options.Path,
+		mode:               options.Mode,
+		headers:            options.Headers.Build(),
+		paddingBytes:       rangeOrDefault(options.XPaddingBytes, 100, 1000),
+		noGRPCHeader:       options.NoGRPCHeader,
+		noSSEHeader:        options.NoSSEHeader,
+		maxEachPostBytes:   rangeOrDefault(options.ScMaxEachPostBytes, 1000000, 1000000),
+		maxBufferedPosts:   options.ScMaxBufferedPosts,
+		minPostsIntervalMs: rangeOrDefault(options.ScMinPostsIntervalMs, 30, 30),
+	}
+	switch c.mode {
+	case "":
+		c.mode = ModeAuto
+	case ModeAuto, ModePacketUp, ModeStreamUp, ModeStreamOne:
+	default:
+		return nil, E.New("unknown xhttp mode: ", c.mode)
+	}
+	if !strings.HasPrefix(c.path, "/") {
+		c.path = "/" + c.path
+	}
+	if !strings.HasSuffix(c.path, "/") {
+		c.path += "/"
+	}
+	if c.maxBufferedPosts == 0 {
+		c.maxBufferedPosts = 30
+	}
+	if c.maxEachPostBytes.From <= 0 {
+		return nil, E.New("invalid sc_max_each_post_bytes")
+	}
+	return c, nil
+}
+
+func rangeOrDefault(value *option.XHTTPRange, from int32, to int32) option.XHTTPRange {
+	if value == nil {
+		return option.XHTTPRange{From: from, To: to}
+	}
+	return *value
+}
+
+func randomInRange(value option.XHTTPRange) int32 {
+	if value.To <= value.From {
+		return value.From
+	}
+	return value.From + rand.Int31n(value.To-value.From+1)
+}
+
+// The padding uses 'X' since it has an 8 bit code in the static huffman table
+// used by HPACK and QPACK, so the compressed length matches the raw length.
+func generatePadding(value option.XHTTPRange) string {
+	return strings.Repeat("X", int(randomInRange(value)))
+}
+
+func (c *config) requestHeader(requestURL url.URL) http.Header {
+	header := c.headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	requestURL.RawQuery = "x_padding=" + generatePadding(c.paddingBytes)
+	header.Set("Referer", requestURL.String())
+	return header
+}
+
+func (c *config) writeResponseHeader(writer http.ResponseWriter) {
+	for key, values := range c.headers {
+		for _, value := range values {
+			writer.Header().Set(key, value)
+		}
+	}
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+	writer.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+	writer.Header().Set("X-Padding", generatePadding(c.paddingBytes))
+}
+
+func (c *config) checkPadding(request *http.Request) bool {
+	var paddingLength int
+	if referrer := request.Header.Get("Referer"); referrer != "" {
+		referrerURL, err := url.Parse(referrer)
+		if err == nil {
+			paddingLength = len(referrerURL.Query().Get("x_padding"))
+		}
+	} else {
+		paddingLength = len(request.URL.Query().Get("x_padding"))
+	}
+	return int32(paddingLength) >= c.paddingBytes.From && int32(paddingLength) <= c.paddingBytes.To
+}
@@ -0,0 +1,112 @@
+//go:build with_quic
+
+package v2rayxhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-quic"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const http3NextProto = http3.NextProtoH3
+
+func serveHTTP3(packetConn net.PacketConn, tlsConfig tls.ServerConfig, handler http.Handler, logger logger.Logger) (io.Closer, error) {
+	err := qtls.ConfigureHTTP3(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	quicListener, err := qtls.ListenEarly(packetConn, tlsConfig, &quic.Config{
+		MaxIncomingStreams: 1 << 60,
+		Allow0RTT:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h3Server := &http3.Server{
+		Handler: handler,
+	}
+	go func() {
+		sErr := h3Server.ServeListener(quicListener)
+		if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+			logger.Error("http3 server closed: ", sErr)
+		}
+	}()
+	return quicListener, nil
+}
+
+type http3RoundTripper struct {
+	ctx        context.Context
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	tlsConfig  tls.Config
+	transport  *http3.Transport
+	connAccess sync.Mutex
+	quicConn   quic.EarlyConnection
+	clientConn *http3.ClientConn
+}
+
+func newHTTP3RoundTripper(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) (roundTripper, error) {
+	return &http3RoundTripper{
+		ctx:        ctx,
+		dialer:     dialer,
+		serverAddr: serverAddr,
+		tlsConfig:  tlsConfig,
+		transport:  &http3.Transport{},
+	}, nil
+}
+
+func (t *http3RoundTripper) offer() (*http3.ClientConn, error) {
+	t.connAccess.Lock()
+	defer t.connAccess.Unlock()
+	if t.quicConn != nil && !common.Done(t.quicConn.Context()) {
+		return t.clientConn, nil
+	}
+	udpConn, err := t.dialer.DialContext(t.ctx, N.NetworkUDP, t.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	quicConn, err := qtls.DialEarly(t.ctx, bufio.NewUnbindPacketConn(udpConn), udpConn.RemoteAddr(), t.tlsConfig, &quic.Config{
+		KeepAlivePeriod: C.TCPKeepAliveInterval,
+	})
+	if err != nil {
+		udpConn.Close()
+		return nil, err
+	}
+	t.quicConn = quicConn
+	t.clientConn = t.transport.NewClientConn(quicConn)
+	return t.clientConn, nil
+}
+
+func (t *http3RoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	clientConn, err := t.offer()
+	if err != nil {
+		return nil, err
+	}
+	return clientConn.RoundTrip(request)
+}
+
+func (t *http3RoundTripper) Close() error {
+	t.connAccess.Lock()
+	defer t.connAccess.Unlock()
+	if t.quicConn == nil {
+		return nil
+	}
+	err := t.quicConn.CloseWithError(0, "")
+	t.quicConn = nil
+	t.clientConn = nil
+	return err
+}
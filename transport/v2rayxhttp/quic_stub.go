@@ -0,0 +1,26 @@
+//go:build !with_quic
+
+package v2rayxhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const http3NextProto = "h3"
+
+func serveHTTP3(packetConn net.PacketConn, tlsConfig tls.ServerConfig, handler http.Handler, logger logger.Logger) (io.Closer, error) {
+	return nil, C.ErrQUICNotIncluded
+}
+
+func newHTTP3RoundTripper(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) (roundTripper, error) {
+	return nil, C.ErrQUICNotIncluded
+}
@@ -0,0 +1,330 @@
+package v2rayxhttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	aTLS "github.com/sagernet/sing/common/tls"
+	sHttp "github.com/sagernet/sing/protocol/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+var _ adapter.V2RayServerTransport = (*Server)(nil)
+
+type Server struct {
+	ctx           context.Context
+	logger        logger.ContextLogger
+	tlsConfig     tls.ServerConfig
+	handler       adapter.V2RayServerTransportHandler
+	config        *config
+	httpServer    *http.Server
+	h2cHandler    http.Handler
+	h3Server      io.Closer
+	sessionAccess sync.Mutex
+	sessions      map[string]*serverSession
+	buffered      atomic.Int64
+}
+
+type serverSession struct {
+	queue     *uploadQueue
+	timer     *time.Timer
+	connected bool
+}
+
+func NewServer(ctx context.Context, logger logger.ContextLogger, options option.V2RayXHTTPOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (*Server, error) {
+	serverConfig, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	server := &Server{
+		ctx:       ctx,
+		logger:    logger,
+		tlsConfig: tlsConfig,
+		handler:   handler,
+		config:    serverConfig,
+		sessions:  make(map[string]*serverSession),
+	}
+	server.httpServer = &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: C.TCPTimeout,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return log.ContextWithNewID(ctx)
+		},
+	}
+	server.h2cHandler = h2c.NewHandler(server, &http2.Server{})
+	return server, nil
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == "PRI" && len(request.Header) == 0 && request.URL.Path == "*" && request.Proto == "HTTP/2.0" {
+		s.h2cHandler.ServeHTTP(writer, request)
+		return
+	}
+	host := request.Host
+	if s.config.host != "" && host != s.config.host {
+		s.invalidRequest(writer, request, http.StatusBadRequest, E.New("bad host: ", host))
+		return
+	}
+	requestPath := request.URL.Path
+	if !strings.HasSuffix(requestPath, "/") {
+		requestPath += "/"
+	}
+	if !strings.HasPrefix(requestPath, s.config.path) {
+		s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad path: ", request.URL.Path))
+		return
+	}
+	if !s.config.checkPadding(request) {
+		s.invalidRequest(writer, request, http.StatusBadRequest, E.New("invalid padding length"))
+		return
+	}
+	var sessionID, seqString string
+	if sessionPath := strings.Trim(strings.TrimPrefix(requestPath, s.config.path), "/"); sessionPath != "" {
+		sessionID, seqString, _ = strings.Cut(sessionPath, "/")
+	}
+	var mode string
+	switch {
+	case request.Method == http.MethodGet && sessionID != "" && seqString == "":
+		s.serveDownload(writer, request, sessionID)
+		return
+	case request.Method != http.MethodPost:
+		s.invalidRequest(writer, request, http.StatusNotFound, E.New("bad method: ", request.Method))
+		return
+	case sessionID == "":
+		mode = ModeStreamOne
+	case seqString == "":
+		mode = ModeStreamUp
+	default:
+		mode = ModePacketUp
+	}
+	if s.config.mode != ModeAuto && s.config.mode != mode {
+		s.invalidRequest(writer, request, http.StatusBadRequest, E.New("mode not allowed: ", mode))
+		return
+	}
+	switch mode {
+	case ModeStreamOne:
+		s.serveStreamOne(writer, request)
+	case ModeStreamUp:
+		s.serveStreamUp(writer, request, sessionID)
+	case ModePacketUp:
+		s.servePacketUp(writer, request, sessionID, seqString)
+	}
+}
+
+func (s *Server) writeStreamHeader(writer http.ResponseWriter) {
+	s.config.writeResponseHeader(writer)
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.Header().Set("Cache-Control", "no-store")
+	if !s.config.noSSEHeader {
+		writer.Header().Set("Content-Type", "text/event-stream")
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.(http.Flusher).Flush()
+}
+
+func (s *Server) serveDownload(writer http.ResponseWriter, request *http.Request, sessionID string) {
+	session, err := s.upsertSession(sessionID)
+	if err != nil {
+		s.invalidRequest(writer, request, http.StatusServiceUnavailable, err)
+		return
+	}
+	s.sessionAccess.Lock()
+	if session.connected {
+		s.sessionAccess.Unlock()
+		s.invalidRequest(writer, request, http.StatusConflict, E.New("duplicate download for session ", sessionID))
+		return
+	}
+	session.connected = true
+	session.timer.Stop()
+	s.sessionAccess.Unlock()
+	defer s.removeSession(sessionID, session)
+	s.writeStreamHeader(writer)
+	s.newConnection(request, session.queue, writer)
+}
+
+func (s *Server) serveStreamUp(writer http.ResponseWriter, request *http.Request, sessionID string) {
+	session, err := s.upsertSession(sessionID)
+	if err != nil {
+		s.invalidRequest(writer, request, http.StatusServiceUnavailable, err)
+		return
+	}
+	http.NewResponseController(writer).EnableFullDuplex()
+	s.writeStreamHeader(writer)
+	_, err = session.queue.ReadFrom(request.Body)
+	if err != nil && !E.IsClosedOrCanceled(err) {
+		s.logger.DebugContext(request.Context(), E.Cause(err, "read stream upload"))
+	}
+	session.queue.Close()
+}
+
+func (s *Server) servePacketUp(writer http.ResponseWriter, request *http.Request, sessionID string, seqString string) {
+	seq, err := strconv.ParseUint(seqString, 10, 64)
+	if err != nil {
+		s.invalidRequest(writer, request, http.StatusBadRequest, E.Cause(err, "parse seq"))
+		return
+	}
+	maxPostBytes := int64(s.config.maxEachPostBytes.To)
+	if request.ContentLength > maxPostBytes {
+		s.invalidRequest(writer, request, http.StatusRequestEntityTooLarge, E.New("post too large: ", request.ContentLength))
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(request.Body, maxPostBytes+1))
+	if err != nil {
+		s.invalidRequest(writer, request, http.StatusInternalServerError, E.Cause(err, "read post"))
+		return
+	}
+	if int64(len(payload)) > maxPostBytes {
+		s.invalidRequest(writer, request, http.StatusRequestEntityTooLarge, E.New("post too large"))
+		return
+	}
+	session, err := s.upsertSession(sessionID)
+	if err != nil {
+		s.invalidRequest(writer, request, http.StatusServiceUnavailable, err)
+		return
+	}
+	err = session.queue.Push(seq, payload)
+	if err == errBufferFull {
+		s.invalidRequest(writer, request, http.StatusTooManyRequests, E.Cause(err, "push post"))
+		return
+	} else if err != nil {
+		s.invalidRequest(writer, request, http.StatusInternalServerError, E.Cause(err, "push post"))
+		return
+	}
+	s.config.writeResponseHeader(writer)
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveStreamOne(writer http.ResponseWriter, request *http.Request) {
+	http.NewResponseController(writer).EnableFullDuplex()
+	s.writeStreamHeader(writer)
+	s.newConnection(request, request.Body, writer)
+}
+
+func (s *Server) newConnection(request *http.Request, reader io.Reader, writer http.ResponseWriter) {
+	done := make(chan struct{})
+	conn := v2rayhttp.NewHTTP2Wrapper(&v2rayhttp.ServerHTTPConn{
+		HTTP2Conn: v2rayhttp.NewHTTPConn(reader, writer),
+		Flusher:   writer.(http.Flusher),
+	})
+	go func() {
+		select {
+		case <-request.Context().Done():
+			common.Close(reader)
+		case <-done:
+		}
+	}()
+	s.handler.NewConnectionEx(v2rayhttp.DupContext(request.Context()), conn, sHttp.SourceAddress(request), M.Socksaddr{}, N.OnceClose(func(it error) {
+		close(done)
+	}))
+	<-done
+	conn.CloseWrapper()
+}
+
+func (s *Server) upsertSession(sessionID string) (*serverSession, error) {
+	s.sessionAccess.Lock()
+	defer s.sessionAccess.Unlock()
+	session, loaded := s.sessions[sessionID]
+	if loaded {
+		return session, nil
+	}
+	if len(s.sessions) >= maxSessions {
+		return nil, E.New("too many sessions")
+	}
+	session = &serverSession{
+		queue: newUploadQueue(s.config.maxBufferedPosts, &s.buffered),
+	}
+	session.timer = time.AfterFunc(sessionTimeout, func() {
+		s.sessionAccess.Lock()
+		connected := session.connected
+		s.sessionAccess.Unlock()
+		if !connected {
+			s.removeSession(sessionID, session)
+		}
+	})
+	s.sessions[sessionID] = session
+	return session, nil
+}
+
+func (s *Server) removeSession(sessionID string, session *serverSession) {
+	s.sessionAccess.Lock()
+	if s.sessions[sessionID] == session {
+		delete(s.sessions, sessionID)
+	}
+	s.sessionAccess.Unlock()
+	session.queue.Discard()
+}
+
+func (s *Server) invalidRequest(writer http.ResponseWriter, request *http.Request, statusCode int, err error) {
+	if statusCode > 0 {
+		writer.WriteHeader(statusCode)
+	}
+	s.logger.ErrorContext(request.Context(), E.Cause(err, "process connection from ", request.RemoteAddr))
+}
+
+func (s *Server) Network() []string {
+	if s.tlsConfig != nil && common.Contains(s.tlsConfig.NextProtos(), http3NextProto) {
+		return []string{N.NetworkUDP}
+	}
+	return []string{N.NetworkTCP}
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	if s.tlsConfig != nil {
+		if len(s.tlsConfig.NextProtos()) == 0 {
+			s.tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+		}
+		listener = aTLS.NewListener(listener, s.tlsConfig)
+	}
+	return s.httpServer.Serve(listener)
+}
+
+func (s *Server) ServePacket(listener net.PacketConn) error {
+	if s.tlsConfig == nil {
+		return C.ErrTLSRequired
+	}
+	h3Server, err := serveHTTP3(listener, s.tlsConfig, s, s.logger)
+	if err != nil {
+		return err
+	}
+	s.h3Server = h3Server
+	return nil
+}
+
+func (s *Server) Close() error {
+	s.closeSessions()
+	return common.Close(common.PtrOrNil(s.httpServer), s.h3Server)
+}
+
+func (s *Server) closeSessions() {
+	s.sessionAccess.Lock()
+	sessions := s.sessions
+	s.sessions = make(map[string]*serverSession)
+	s.sessionAccess.Unlock()
+	for _, session := range sessions {
+		session.timer.Stop()
+		session.queue.Discard()
+	}
+}
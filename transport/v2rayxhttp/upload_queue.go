@@ -0,0 +1,129 @@
+package v2rayxhttp
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var errBufferFull = E.New("too many buffered bytes")
+
+// uploadQueue reassembles uplink posts, which may arrive out of order
+// over different connections, into a stream. Buffered bytes are counted
+// in buffered, which is shared by all sessions of a server.
+type uploadQueue struct {
+	access     sync.Mutex
+	cond       *sync.Cond
+	packets    map[uint64][]byte
+	nextSeq    uint64
+	current    []byte
+	maxPackets int
+	buffered   *atomic.Int64
+	closed     bool
+}
+
+func newUploadQueue(maxPackets int, buffered *atomic.Int64) *uploadQueue {
+	queue := &uploadQueue{
+		packets:    make(map[uint64][]byte),
+		maxPackets: maxPackets,
+		buffered:   buffered,
+	}
+	queue.cond = sync.NewCond(&queue.access)
+	return queue
+}
+
+func (q *uploadQueue) Push(seq uint64, payload []byte) error {
+	q.access.Lock()
+	defer q.access.Unlock()
+	if q.closed {
+		return io.ErrClosedPipe
+	}
+	if seq < q.nextSeq {
+		return E.New("duplicate packet: ", seq)
+	}
+	if len(q.packets) >= q.maxPackets {
+		return E.New("too many buffered posts")
+	}
+	if q.buffered.Add(int64(len(payload))) > maxBufferedBytes {
+		q.buffered.Add(-int64(len(payload)))
+		return errBufferFull
+	}
+	q.packets[seq] = payload
+	q.cond.Broadcast()
+	return nil
+}
+
+// ReadFrom pushes a streaming upload, waiting for the reader instead of
+// failing when the queue is full.
+func (q *uploadQueue) ReadFrom(reader io.Reader) (n int64, err error) {
+	for {
+		buffer := make([]byte, 32*1024)
+		readN, readErr := reader.Read(buffer)
+		if readN > 0 {
+			n += int64(readN)
+			q.access.Lock()
+			for !q.closed && len(q.packets) >= q.maxPackets {
+				q.cond.Wait()
+			}
+			if q.closed {
+				q.access.Unlock()
+				return n, io.ErrClosedPipe
+			}
+			q.buffered.Add(int64(readN))
+			q.packets[q.nextSeq+uint64(len(q.packets))] = buffer[:readN]
+			q.cond.Broadcast()
+			q.access.Unlock()
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return n, nil
+			}
+			return n, readErr
+		}
+	}
+}
+
+func (q *uploadQueue) Read(p []byte) (n int, err error) {
+	q.access.Lock()
+	defer q.access.Unlock()
+	for len(q.current) == 0 {
+		if packet, loaded := q.packets[q.nextSeq]; loaded {
+			delete(q.packets, q.nextSeq)
+			q.buffered.Add(-int64(len(packet)))
+			q.nextSeq++
+			q.current = packet
+			q.cond.Broadcast()
+			continue
+		}
+		if q.closed {
+			return 0, io.EOF
+		}
+		q.cond.Wait()
+	}
+	n = copy(p, q.current)
+	q.current = q.current[n:]
+	return
+}
+
+func (q *uploadQueue) Close() error {
+	q.access.Lock()
+	defer q.access.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+	return nil
+}
+
+// Discard closes the queue and releases the buffered packets, which are
+// no longer read.
+func (q *uploadQueue) Discard() {
+	q.access.Lock()
+	defer q.access.Unlock()
+	q.closed = true
+	for seq, packet := range q.packets {
+		delete(q.packets, seq)
+		q.buffered.Add(-int64(len(packet)))
+	}
+	q.cond.Broadcast()
+}
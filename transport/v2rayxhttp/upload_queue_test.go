@@ -0,0 +1,31 @@
+package v2rayxhttp
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadQueueBufferLimit(t *testing.T) {
+	t.Parallel()
+	var buffered atomic.Int64
+	queue := newUploadQueue(30, &buffered)
+	other := newUploadQueue(30, &buffered)
+	post := make([]byte, maxBufferedBytes/2)
+	require.NoError(t, queue.Push(1, post))
+	require.NoError(t, other.Push(0, post))
+	// the limit is shared by all sessions of a server
+	require.ErrorIs(t, queue.Push(2, []byte{0}), errBufferFull)
+
+	// reading releases the buffered bytes
+	other.Close()
+	_, err := io.ReadAll(other)
+	require.NoError(t, err)
+	require.NoError(t, queue.Push(2, []byte{0}))
+
+	// discarded packets are released
+	queue.Discard()
+	require.Zero(t, buffered.Load())
+}
@@ -0,0 +1,116 @@
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	mRand "math/rand"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/wireguard-go/device"
+)
+
+// amneziaObfuscator implements the AmneziaWG packet format: junk packets
+// before handshake initiations, random prefixes on handshake messages and
+// custom message type headers.
+type amneziaObfuscator struct {
+	junkPacketCount   int
+	junkPacketMinSize int
+	junkPacketMaxSize int
+	initJunkSize      int
+	responseJunkSize  int
+	headers           [4]uint32
+}
+
+func newAmneziaObfuscator(options AmneziaOptions) (*amneziaObfuscator, error) {
+	if options.JunkPacketCount < 0 || options.JunkPacketCount > 128 {
+		return nil, E.New("amnezia: jc must be between 0 and 128")
+	}
+	if options.JunkPacketMinSize < 0 || options.JunkPacketMaxSize > 1280 || options.JunkPacketMinSize > options.JunkPacketMaxSize {
+		return nil, E.New("amnezia: jmin and jmax must satisfy 0 <= jmin <= jmax <= 1280")
+	}
+	if options.InitPacketJunkSize < 0 || options.InitPacketJunkSize > 1132 {
+		return nil, E.New("amnezia: s1 must be between 0 and 1132")
+	}
+	if options.ResponsePacketJunkSize < 0 || options.ResponsePacketJunkSize > 1188 {
+		return nil, E.New("amnezia: s2 must be between 0 and 1188")
+	}
+	if options.InitPacketJunkSize+device.MessageInitiationSize == options.ResponsePacketJunkSize+device.MessageResponseSize {
+		return nil, E.New("amnezia: s1 + 56 must not equal s2")
+	}
+	obfuscator := &amneziaObfuscator{
+		junkPacketCount:   options.JunkPacketCount,
+		junkPacketMinSize: options.JunkPacketMinSize,
+		junkPacketMaxSize: options.JunkPacketMaxSize,
+		initJunkSize:      options.InitPacketJunkSize,
+		responseJunkSize:  options.ResponsePacketJunkSize,
+		headers: [4]uint32{
+			options.InitPacketMagicHeader,
+			options.ResponsePacketMagicHeader,
+			options.UnderloadPacketMagicHeader,
+			options.TransportPacketMagicHeader,
+		},
+	}
+	for index := range obfuscator.headers {
+		if obfuscator.headers[index] == 0 {
+			obfuscator.headers[index] = uint32(index + 1)
+		}
+	}
+	if len(common.Uniq(obfuscator.headers[:])) != len(obfuscator.headers) {
+		return nil, E.New("amnezia: h1, h2, h3 and h4 must be distinct")
+	}
+	return obfuscator, nil
+}
+
+// Encode returns the packets to send for a WireGuard message, in order.
+func (o *amneziaObfuscator) Encode(packet []byte) [][]byte {
+	var (
+		packets  [][]byte
+		junkSize int
+	)
+	messageType := packet[0]
+	switch messageType {
+	case device.MessageInitiationType:
+		for i := 0; i < o.junkPacketCount; i++ {
+			packets = append(packets, randomBytes(o.junkPacketMinSize+mRand.Intn(o.junkPacketMaxSize-o.junkPacketMinSize+1)))
+		}
+		junkSize = o.initJunkSize
+	case device.MessageResponseType:
+		junkSize = o.responseJunkSize
+	}
+	if messageType >= device.MessageInitiationType && messageType <= device.MessageTransportType {
+		binary.LittleEndian.PutUint32(packet[:4], o.headers[messageType-1])
+	}
+	if junkSize > 0 {
+		packet = append(randomBytes(junkSize), packet...)
+	}
+	return append(packets, packet)
+}
+
+// Decode restores a received packet in place and returns its new length,
+// or false if the packet is junk and should be dropped.
+func (o *amneziaObfuscator) Decode(packet []byte) (int, bool) {
+	n := len(packet)
+	if o.initJunkSize > 0 && n == o.initJunkSize+device.MessageInitiationSize && binary.LittleEndian.Uint32(packet[o.initJunkSize:]) == o.headers[0] {
+		n = copy(packet, packet[o.initJunkSize:])
+	} else if o.responseJunkSize > 0 && n == o.responseJunkSize+device.MessageResponseSize && binary.LittleEndian.Uint32(packet[o.responseJunkSize:]) == o.headers[1] {
+		n = copy(packet, packet[o.responseJunkSize:])
+	}
+	if n < 4 {
+		return 0, false
+	}
+	header := binary.LittleEndian.Uint32(packet[:4])
+	for index, magicHeader := range o.headers {
+		if header == magicHeader {
+			binary.LittleEndian.PutUint32(packet[:4], uint32(index+1))
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func randomBytes(size int) []byte {
+	content := make([]byte, size)
+	rand.Read(content)
+	return content
+}
@@ -34,6 +34,9 @@ type ClientBind struct {
 	isConnect           bool
 	connectAddr         netip.AddrPort
 	reserved            [3]uint8
+	obfuscator          *amneziaObfuscator
+	listener            conn.Listener
+	listenPort          uint16
 }
 
 func NewClientBind(ctx context.Context, logger logger.Logger, dialer N.Dialer, isConnect bool, connectAddr netip.AddrPort, reserved [3]uint8) *ClientBind {
@@ -86,7 +89,15 @@ func (c *ClientBind) connect() (*wireConn, error) {
 			done:       make(chan struct{}),
 		}
 	} else {
-		udpConn, err := c.dialer.ListenPacket(c.bindCtx, M.Socksaddr{Addr: netip.IPv4Unspecified()})
+		var (
+			udpConn net.PacketConn
+			err     error
+		)
+		if c.listener != nil && c.listenPort != 0 {
+			udpConn, err = c.listener.ListenPacketCompat("udp", M.SocksaddrFrom(netip.IPv6Unspecified(), c.listenPort).String())
+		} else {
+			udpConn, err = c.dialer.ListenPacket(c.bindCtx, M.Socksaddr{Addr: netip.IPv4Unspecified()})
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -133,11 +144,17 @@ func (c *ClientBind) receive(packets [][]byte, sizes []int, eps []conn.Endpoint)
 		}
 		return
 	}
-	sizes[0] = n
-	if n > 3 {
+	if c.obfuscator != nil {
+		var isPacket bool
+		n, isPacket = c.obfuscator.Decode(packets[0][:n])
+		if !isPacket {
+			return
+		}
+	} else if n > 3 {
 		b := packets[0]
 		common.ClearArray(b[1:4])
 	}
+	sizes[0] = n
 	eps[0] = remoteEndpoint(M.SocksaddrFromNet(addr).Unwrap().AddrPort())
 	count = 1
 	return
@@ -171,6 +188,16 @@ func (c *ClientBind) Send(bufs [][]byte, ep conn.Endpoint) error {
 	}
 	destination := netip.AddrPort(ep.(remoteEndpoint))
 	for _, b := range bufs {
+		if c.obfuscator != nil && len(b) > 3 {
+			for _, packet := range c.obfuscator.Encode(b) {
+				_, err = udpConn.WriteToUDPAddrPort(packet, destination)
+				if err != nil {
+					udpConn.Close()
+					return err
+				}
+			}
+			continue
+		}
 		if len(b) > 3 {
 			reserved, loaded := c.reservedForEndpoint[destination]
 			if !loaded {
@@ -199,6 +226,14 @@ func (c *ClientBind) BatchSize() int {
 	return 1
 }
 
+// SetObfuscation enables AmneziaWG obfuscation. If listener is not nil,
+// the bind listens on listenPort instead of an ephemeral port.
+func (c *ClientBind) SetObfuscation(obfuscator *amneziaObfuscator, listener conn.Listener, listenPort uint16) {
+	c.obfuscator = obfuscator
+	c.listener = listener
+	c.listenPort = listenPort
+}
+
 func (c *ClientBind) SetReservedForEndpoint(destination netip.AddrPort, reserved [3]byte) {
 	c.reservedForEndpoint[destination] = reserved
 }
@@ -25,6 +25,7 @@ import (
 
 type Endpoint struct {
 	options        EndpointOptions
+	obfuscator     *amneziaObfuscator
 	peers          []peerConfig
 	ipcConf        string
 	allowedAddress []netip.Prefix
@@ -81,6 +82,18 @@ func NewEndpoint(options EndpointOptions) (*Endpoint, error) {
 		}
 		peers = append(peers, peer)
 	}
+	var obfuscator *amneziaObfuscator
+	if options.Amnezia != nil {
+		if common.Any(peers, func(peer peerConfig) bool {
+			return peer.reserved != [3]uint8{}
+		}) {
+			return nil, E.New("reserved can not be used with amnezia obfuscation")
+		}
+		obfuscator, err = newAmneziaObfuscator(*options.Amnezia)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var allowedPrefixBuilder netipx.IPSetBuilder
 	for _, peer := range options.Peers {
 		for _, prefix := range peer.AllowedIPs {
@@ -113,6 +126,7 @@ func NewEndpoint(options EndpointOptions) (*Endpoint, error) {
 	}
 	return &Endpoint{
 		options:        options,
+		obfuscator:     obfuscator,
 		peers:          peers,
 		ipcConf:        ipcConf,
 		allowedAddress: allowedAddresses,
@@ -142,7 +156,7 @@ func (e *Endpoint) Start(resolve bool) error {
 	}
 	var bind conn.Bind
 	wgListener, isWgListener := common.Cast[conn.Listener](e.options.Dialer)
-	if isWgListener {
+	if isWgListener && e.obfuscator == nil {
 		bind = conn.NewStdNetBind(wgListener)
 	} else {
 		var (
@@ -150,12 +164,16 @@ func (e *Endpoint) Start(resolve bool) error {
 			connectAddr netip.AddrPort
 			reserved    [3]uint8
 		)
-		if len(e.peers) == 1 && e.peers[0].endpoint.IsValid() {
+		if len(e.peers) == 1 && e.peers[0].endpoint.IsValid() && (e.obfuscator == nil || e.options.ListenPort == 0) {
 			isConnect = true
 			connectAddr = e.peers[0].endpoint
 			reserved = e.peers[0].reserved
 		}
-		bind = NewClientBind(e.options.Context, e.options.Logger, e.options.Dialer, isConnect, connectAddr, reserved)
+		clientBind := NewClientBind(e.options.Context, e.options.Logger, e.options.Dialer, isConnect, connectAddr, reserved)
+		if e.obfuscator != nil {
+			clientBind.SetObfuscation(e.obfuscator, wgListener, e.options.ListenPort)
+		}
+		bind = clientBind
 	}
 	if isWgListener || len(e.peers) > 1 {
 		for _, peer := range e.peers {